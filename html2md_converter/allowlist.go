@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// droppedTags have no text worth keeping, so -allowlist-tags removes them
+// with their content instead of unwrapping them.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "object": true, "embed": true, "svg": true, "canvas": true,
+}
+
+// parseTagList splits a comma-separated -allowlist-tags value.
+func parseTagList(list string) map[string]bool {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	tags := make(map[string]bool)
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+// applyTagAllowlist unwraps every element of the body whose tag is not in
+// allowed, keeping its content, and drops those in droppedTags. Elements
+// marked by the converter's own passes are kept.
+func applyTagAllowlist(doc *goquery.Document, allowed map[string]bool) {
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		tag := goquery.NodeName(s)
+		if allowed[tag] || hasConverterMarker(s.Nodes[0]) {
+			return
+		}
+		if droppedTags[tag] {
+			s.Remove()
+			return
+		}
+		s.ReplaceWithSelection(s.Contents())
+	})
+	mergeTextNodes(doc.Selection.Nodes[0])
+}
+
+// mergeTextNodes joins adjacent text nodes, such as those left behind by
+// unwrapping, since the converter drops text nodes that are only whitespace.
+func mergeTextNodes(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		for c.Type == html.TextNode && c.NextSibling != nil && c.NextSibling.Type == html.TextNode {
+			next := c.NextSibling
+			c.Data += next.Data
+			n.RemoveChild(next)
+		}
+		mergeTextNodes(c)
+	}
+}
+
+func hasConverterMarker(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if strings.HasPrefix(attr.Key, "data-html2md-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTagAllowlist(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<p>Some <span class="x">spanned</span> <font color="red">text</font> and <code>code</code>.</p>
+<script>alert("x")</script><div class="wrapper"><p>Wrapped</p></div>`,
+	}
+	cfg := testConfig(t)
+	cfg.allowedTags = parseTagList("p, code, h1")
+	page := convertPages(t, cfg, pages)["docs/page.md"]
+	for _, want := range []string{"Some spanned text and `code`.", "Wrapped"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+	for _, dropped := range []string{"<span", "<font", "alert", "wrapper"} {
+		if strings.Contains(page, dropped) {
+			t.Errorf("page contains %q:\n%s", dropped, page)
+		}
+	}
+}
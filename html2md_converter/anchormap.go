@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// pageAnchor is a heading of a page in an anchor map.
+type pageAnchor struct {
+	// ID is the heading's id in the source HTML, if any
+	ID     string `json:"id,omitempty"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+	// Aliases are earlier anchors of the heading that are kept working
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// anchorMap lists the heading anchors of each page, keyed by output path,
+// as written by -out-anchors and read back by -prev-anchors on the next
+// build.
+type anchorMap map[string][]pageAnchor
+
+func loadAnchorMap(mapPath string) (anchorMap, error) {
+	if mapPath == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anchor map: %w", err)
+	}
+	var anchors anchorMap
+	if err := json.Unmarshal(content, &anchors); err != nil {
+		return nil, fmt.Errorf("failed to parse anchor map %s: %w", mapPath, err)
+	}
+	return anchors, nil
+}
+
+func writeAnchorMap(outputPath string, anchors anchorMap) error {
+	if anchors == nil {
+		anchors = anchorMap{}
+	}
+	content, err := json.MarshalIndent(anchors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode anchor map: %w", err)
+	}
+	if err := writeFile(outputPath, append(content, '\n')); err != nil {
+		return fmt.Errorf("failed to write anchor map: %w", err)
+	}
+
+	fmt.Printf("Wrote the anchors of %d page(s) to %s\n", len(anchors), outputPath)
+	return nil
+}
+
+// pageHeadings returns the headings that get an anchor, in order, with
+// their entries for the anchor map. Like headingAnchors, headings demoted
+// by -max-heading-depth are skipped.
+func pageHeadings(doc *goquery.Document, cfg config) ([]*goquery.Selection, []pageAnchor) {
+	anchors := newAnchorSet(cfg.anchors)
+	var headings []*goquery.Selection
+	var entries []pageAnchor
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		if cfg.maxHeadingDepth > 0 && headingLevel(goquery.NodeName(s)) > cfg.maxHeadingDepth {
+			return
+		}
+		headings = append(headings, s)
+		entries = append(entries, pageAnchor{ID: s.AttrOr("id", ""), Text: collapseWhitespace(s.Text()), Anchor: anchors.add(s.Text())})
+	})
+	return headings, entries
+}
+
+// aliasChangedAnchors keeps the anchors that the page's headings had in
+// earlier builds working. Each previous anchor, and each alias carried
+// over from before it, that the page lost becomes an empty anchor in front
+// of its heading. The heading is found by anchor, by source id or, when the
+// page has as many headings as before, by position. It returns the page's
+// current anchors with their aliases.
+func aliasChangedAnchors(doc *goquery.Document, cfg config, previous []pageAnchor) []pageAnchor {
+	headings, entries := pageHeadings(doc, cfg)
+	taken := make(map[string]bool)
+	current := make(map[string]int)
+	for i, entry := range entries {
+		taken[entry.Anchor] = true
+		current[entry.Anchor] = i
+	}
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		taken[s.AttrOr("id", "")] = true
+	})
+
+	for i, prev := range previous {
+		match, ok := current[prev.Anchor]
+		if !ok {
+			match = -1
+			for j, entry := range entries {
+				if prev.ID != "" && entry.ID == prev.ID {
+					match = j
+					break
+				}
+			}
+			if match < 0 && len(previous) == len(entries) {
+				match = i
+			}
+		}
+
+		if match < 0 {
+			if !taken[prev.Anchor] {
+				fmt.Printf("  Warning: anchor #%s of the previous build has no heading to move to\n", prev.Anchor)
+			}
+			continue
+		}
+		for _, alias := range append([]string{prev.Anchor}, prev.Aliases...) {
+			if taken[alias] {
+				continue
+			}
+			taken[alias] = true
+			entries[match].Aliases = append(entries[match].Aliases, alias)
+			headings[match].BeforeNodes(&html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: attrNameAnchor, Val: alias}}})
+		}
+	}
+	return entries
+}
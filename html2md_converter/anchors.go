@@ -0,0 +1,250 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// AnchorStrategy derives a heading anchor from the heading's text the way a
+// particular docs renderer does.
+type AnchorStrategy interface {
+	Slug(text string) string
+}
+
+// anchorStrategies holds the strategies selectable with -anchor-style.
+var anchorStrategies = map[string]AnchorStrategy{
+	"mintlify":   mintlifyAnchors{},
+	"github":     githubAnchors{},
+	"docusaurus": docusaurusAnchors{},
+}
+
+// githubAnchors follows github-slugger: lowercase, drop punctuation and
+// symbols, and turn each space into a hyphen without collapsing runs.
+type githubAnchors struct{}
+
+func (githubAnchors) Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// mintlifyAnchors lowercases, keeps letters and digits, and joins the words
+// with single hyphens, so "Build & Test" becomes "build-test".
+type mintlifyAnchors struct{}
+
+func (mintlifyAnchors) Slug(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// docusaurusAnchors matches Docusaurus, which generates heading ids with
+// github-slugger.
+type docusaurusAnchors struct {
+	githubAnchors
+}
+
+// anchorSet hands out unique anchors within one page, suffixing repeats with
+// -1, -2, ... like the renderers do.
+type anchorSet struct {
+	strategy AnchorStrategy
+	seen     map[string]int
+}
+
+func newAnchorSet(strategy AnchorStrategy) *anchorSet {
+	return &anchorSet{strategy: strategy, seen: make(map[string]int)}
+}
+
+func (a *anchorSet) add(text string) string {
+	slug := a.strategy.Slug(text)
+	n, dup := a.seen[slug]
+	a.seen[slug] = n + 1
+	if !dup {
+		return slug
+	}
+
+	unique := slug + "-" + strconv.Itoa(n)
+	for a.seen[unique] > 0 {
+		n++
+		unique = slug + "-" + strconv.Itoa(n)
+	}
+	a.seen[slug] = n + 1
+	a.seen[unique] = 1
+	return unique
+}
+
+var (
+	// A backslash escape of an ASCII punctuation character
+	markdownEscapeRegex = regexp.MustCompile("\\\\([!-/:-@[-`{-~])")
+	// A run of emphasis delimiters next to whitespace or other punctuation
+	emphasisDelimiterRegex = regexp.MustCompile("(^|[\\s!-)+-/:-@[-^`{-}])[*_~]+|[*_~]+([\\s!-)+-/:-@[-^`{-}]|$)")
+)
+
+// headingText returns the text a renderer shows for a markdown heading and
+// derives its anchor from: link text, the content of code spans, and the
+// rest without emphasis, tags and escapes. The anchors of converted pages
+// come from the HTML text of their headings; this lets markdown be checked
+// against them.
+func headingText(markdown string) string {
+	markdown = inlineLinkRegex.ReplaceAllString(markdown, "$1")
+
+	var b strings.Builder
+	unmarked := func(text string) {
+		text = htmlTagRegex.ReplaceAllString(text, "")
+		b.WriteString(html.UnescapeString(emphasisDelimiterRegex.ReplaceAllString(text, "$1$2")))
+	}
+	// Escaped characters are text, never delimiters or tags
+	plain := func(text string) {
+		last := 0
+		for _, loc := range markdownEscapeRegex.FindAllStringSubmatchIndex(text, -1) {
+			unmarked(text[last:loc[0]])
+			b.WriteString(text[loc[2]:loc[3]])
+			last = loc[1]
+		}
+		unmarked(text[last:])
+	}
+	last := 0
+	for _, loc := range inlineCodeRegex.FindAllStringIndex(markdown, -1) {
+		plain(markdown[last:loc[0]])
+		b.WriteString(strings.TrimSpace(strings.Trim(markdown[loc[0]:loc[1]], "`")))
+		last = loc[1]
+	}
+	plain(markdown[last:])
+	return b.String()
+}
+
+// headingAnchors maps the id of each heading to the anchor the renderer will
+// generate from its text. Headings demoted by -max-heading-depth produce no
+// anchor, and keep their id as an explicit one, but still do not shift the
+// numbering of later duplicates.
+func headingAnchors(doc *goquery.Document, cfg config) map[string]string {
+	anchors := newAnchorSet(cfg.anchors)
+	ids := make(map[string]string)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		if cfg.maxHeadingDepth > 0 && headingLevel(goquery.NodeName(s)) > cfg.maxHeadingDepth {
+			return
+		}
+		slug := anchors.add(s.Text())
+		if id, ok := s.Attr("id"); ok && id != "" {
+			ids[id] = slug
+		}
+	})
+	return ids
+}
+
+// defaultAutogeneratedIDPattern matches the ids Devsite and the frameworks
+// it embeds generate, such as gc-wrapper or a hash, rather than authors.
+const defaultAutogeneratedIDPattern = `^(?:gc-|devsite-|goog-|ng-|mat-|ember\d|_)|^[0-9a-f-]{8,}$|[0-9a-f]{12,}|\d{5,}`
+
+// stripAutogeneratedIDs drops the ids, and named anchors, of a page that
+// pattern matches, so that they are not kept as anchors, while the ids
+// authors gave keep theirs.
+func stripAutogeneratedIDs(doc *goquery.Document, pattern *regexp.Regexp) {
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		if pattern.MatchString(s.AttrOr("id", "")) {
+			s.RemoveAttr("id")
+		}
+	})
+	doc.Find("a[name]:not([href])").Each(func(i int, s *goquery.Selection) {
+		if pattern.MatchString(s.AttrOr("name", "")) {
+			s.RemoveAttr("name")
+		}
+	})
+}
+
+// lowercaseAnchors lowercases the ids and named anchors of a page, and the
+// fragments of its links other than those to other sites, so that a link
+// to #CcLibrary reaches the anchor of a heading with id cclibrary, and the
+// other way round. Every anchor strategy already generates lowercase
+// anchors from heading text.
+func lowercaseAnchors(doc *goquery.Document) {
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		s.SetAttr("id", strings.ToLower(s.AttrOr("id", "")))
+	})
+	doc.Find("a[name]").Each(func(i int, s *goquery.Selection) {
+		s.SetAttr("name", strings.ToLower(s.AttrOr("name", "")))
+	})
+	doc.Find(`a[href*="#"]`).Each(func(i int, a *goquery.Selection) {
+		href := a.AttrOr("href", "")
+		if u, err := url.Parse(strings.TrimSpace(href)); err != nil || u.Scheme != "" || u.Host != "" {
+			return
+		}
+		ref, fragment, _ := strings.Cut(href, "#")
+		a.SetAttr("href", ref+"#"+strings.ToLower(fragment))
+	})
+}
+
+// rewriteFragmentLinks points "#id" links at the generated anchor of the
+// heading that carries that id in the source HTML.
+func rewriteFragmentLinks(doc *goquery.Document, cfg config) {
+	ids := headingAnchors(doc, cfg)
+	if len(ids) == 0 {
+		return
+	}
+
+	doc.Find(`a[href^="#"]`).Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if slug, ok := ids[strings.TrimPrefix(href, "#")]; ok {
+			s.SetAttr("href", "#"+slug)
+		}
+	})
+}
+
+// pageAnchorPrefix returns the -anchor-prefix of the page at pagePath, with
+// {page} replaced by the slug of its output path, e.g. docs-rules for
+// docs/rules.md.
+func pageAnchorPrefix(template, pagePath string) string {
+	if template == "" {
+		return ""
+	}
+	page := mintlifyAnchors{}.Slug(strings.TrimSuffix(pagePath, path.Ext(pagePath)))
+	return strings.ReplaceAll(template, "{page}", page)
+}
+
+// prefixAnchors namespaces the anchors of a page with prefix, for
+// -anchor-prefix, so that pages combined or embedded into one do not share
+// anchors. Every id and named anchor gets the prefix, and so does each "#id"
+// link. Headings, whose anchors renderers generate from their text, are
+// given an explicit anchor of the prefix and their id, or their generated
+// anchor when they have none.
+func prefixAnchors(doc *goquery.Document, cfg config, prefix string) {
+	anchors := newAnchorSet(cfg.anchors)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		anchor := anchors.add(h.Text())
+		if id := h.AttrOr("id", ""); id != "" {
+			anchor = id
+		}
+		if anchor != "" {
+			h.SetAttr(attrExplicitAnchor, prefix+anchor)
+		}
+	})
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		if id := s.AttrOr("id", ""); id != "" {
+			s.SetAttr("id", prefix+id)
+		}
+	})
+	// convertNameAnchors has already turned a[name] into these markers
+	doc.Find("a[" + attrNameAnchor + "]").Each(func(i int, s *goquery.Selection) {
+		s.SetAttr(attrNameAnchor, prefix+s.AttrOr(attrNameAnchor, ""))
+	})
+	doc.Find(`a[href^="#"]`).Each(func(i int, a *goquery.Selection) {
+		if fragment := strings.TrimPrefix(a.AttrOr("href", ""), "#"); fragment != "" {
+			a.SetAttr("href", "#"+prefix+fragment)
+		}
+	})
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The slugs of each strategy, from the rules its renderer documents:
+// github-slugger, which GitHub and Docusaurus use, lowercases, drops
+// punctuation and turns each space into a hyphen; Mintlify keeps runs of
+// letters and digits joined by single hyphens.
+var slugTests = map[string][]struct{ text, want string }{
+	"github": {
+		{"Hello World", "hello-world"},
+		{"Build & Test", "build--test"},
+		{"cc_library", "cc_library"},
+		{"C++ toolchains", "c-toolchains"},
+		{"foo.bar()", "foobar"},
+		{"1.2 Overview", "12-overview"},
+		{"--flag", "--flag"},
+		{"Émoji 😄 test", "émoji--test"},
+		{"  Padded  ", "padded"},
+	},
+	"docusaurus": {
+		{"Hello World", "hello-world"},
+		{"Build & Test", "build--test"},
+		{"cc_library", "cc_library"},
+		{"C++ toolchains", "c-toolchains"},
+		{"foo.bar()", "foobar"},
+		{"1.2 Overview", "12-overview"},
+		{"--flag", "--flag"},
+		{"Émoji 😄 test", "émoji--test"},
+		{"  Padded  ", "padded"},
+	},
+	"mintlify": {
+		{"Hello World", "hello-world"},
+		{"Build & Test", "build-test"},
+		{"cc_library", "cc-library"},
+		{"C++ toolchains", "c-toolchains"},
+		{"foo.bar()", "foo-bar"},
+		{"1.2 Overview", "1-2-overview"},
+		{"--flag", "flag"},
+		{"Émoji 😄 test", "émoji-test"},
+		{"  Padded  ", "padded"},
+	},
+}
+
+func TestSlug(t *testing.T) {
+	for style, tests := range slugTests {
+		for _, tt := range tests {
+			if got := anchorStrategies[style].Slug(tt.text); got != tt.want {
+				t.Errorf("%s: Slug(%q) = %q, want %q", style, tt.text, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestAnchorSetDuplicates(t *testing.T) {
+	tests := []struct {
+		texts, want []string
+	}{
+		{[]string{"Examples", "Examples", "Examples"}, []string{"examples", "examples-1", "examples-2"}},
+		// A heading that looks like a suffixed repeat pushes the next one on
+		{[]string{"Examples", "Examples 1", "Examples"}, []string{"examples", "examples-1", "examples-2"}},
+		{[]string{"Examples 1", "Examples", "Examples"}, []string{"examples-1", "examples", "examples-2"}},
+	}
+	for style, strategy := range anchorStrategies {
+		for _, tt := range tests {
+			anchors := newAnchorSet(strategy)
+			var got []string
+			for _, text := range tt.texts {
+				got = append(got, anchors.add(text))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("%s: anchors of %q = %q, want %q", style, tt.texts, got, tt.want)
+			}
+		}
+	}
+}
+
+// TestFragmentLinks checks that links to the heading ids of a page reach
+// the anchors generated for those headings.
+func TestFragmentLinks(t *testing.T) {
+	page := `<p><a href="#cc_library">cc_library</a> <a href="#second">second</a> <a href="#missing">missing</a></p>
+<h2 id="cc_library">cc_library</h2>
+<h2 id="first">Examples</h2>
+<h2 id="second">Examples</h2>`
+	want := map[string][]string{
+		"mintlify":   {"(#cc-library)", "(#examples-1)", "(#missing)"},
+		"github":     {"(#cc_library)", "(#examples-1)", "(#missing)"},
+		"docusaurus": {"(#cc_library)", "(#examples-1)", "(#missing)"},
+	}
+	for style, links := range want {
+		cfg := testConfig(t)
+		cfg.anchors = anchorStrategies[style]
+		got := convertPages(t, cfg, map[string]string{"a.html": page})["a.md"]
+		for _, link := range links {
+			if !strings.Contains(got, link) {
+				t.Errorf("%s: page does not link %s:\n%s", style, link, got)
+			}
+		}
+	}
+}
+
+// TestCrossPageFragments checks that links to the heading ids of other
+// pages reach the anchors generated on those pages.
+func TestCrossPageFragments(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html": `<h1>A</h1>
+<p><a href="other.html#x">X</a> <a href="other.html#cc_library">cc_library</a>
+<a href="other.html#second">second</a> <a href="other.html#missing">missing</a>
+<a href="a.html#own">own</a></p>
+<h2 id="own">Own heading</h2>`,
+		"docs/other.html": `<h1>Other</h1>
+<h2 id="x">X heading</h2>
+<h2 id="cc_library">cc_library</h2>
+<h2 id="first">Examples</h2>
+<h2 id="second">Examples</h2>`,
+	}
+	want := map[string][]string{
+		"mintlify":   {"(other.md#x-heading)", "(other.md#cc-library)", "(other.md#examples-1)", "(other.md#missing)", "(#own-heading)"},
+		"github":     {"(other.md#x-heading)", "(other.md#cc_library)", "(other.md#examples-1)", "(other.md#missing)", "(#own-heading)"},
+		"docusaurus": {"(other.md#x-heading)", "(other.md#cc_library)", "(other.md#examples-1)", "(other.md#missing)", "(#own-heading)"},
+	}
+	for style, links := range want {
+		cfg := testConfig(t)
+		cfg.anchors = anchorStrategies[style]
+		page := convertPages(t, cfg, pages)["docs/a.md"]
+		for _, link := range links {
+			if !strings.Contains(page, link) {
+				t.Errorf("%s: page does not link %s:\n%s", style, link, page)
+			}
+		}
+	}
+}
+
+func TestNameAnchors(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<p><a href="#legacy">heading</a> <a href="#para">paragraph</a></p>
+<h2><a name="legacy"></a>Legacy heading</h2>
+<p><a name="para"></a>Anchored paragraph</p>
+<h2 id="kept"><a name="both"></a>Both</h2>
+<p><a name="kept"></a>Repeated</p>`,
+		"docs/other.html": `<h1>Other</h1><p><a href="page.html#legacy">Legacy</a></p>`,
+	}
+	written := convertPages(t, testConfig(t), pages)
+	page := written["docs/page.md"]
+	for _, want := range []string{
+		"[heading](#legacy-heading)", "[paragraph](#para)",
+		"## Legacy heading", `<a id="para"></a>Anchored paragraph`,
+		"<a id=\"both\"></a>\n\n## Both",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, `id="kept"`) {
+		t.Errorf("page repeats the anchor of an id:\n%s", page)
+	}
+	if other := written["docs/other.md"]; !strings.Contains(other, "(page.md#legacy-heading)") {
+		t.Errorf("other page does not link the heading anchor:\n%s", other)
+	}
+}
+
+func TestHeadingText(t *testing.T) {
+	tests := []struct{ markdown, want string }{
+		{"`cc_library`", "cc_library"},
+		{"The `cc_library` _rule_", "The cc_library rule"},
+		{"**Bold** and *em*", "Bold and em"},
+		{"[Linked](other.md) heading", "Linked heading"},
+		{`Escaped \*star\* and snake_case_name`, "Escaped *star* and snake_case_name"},
+		{"A <Badge>tag</Badge> &amp; entity", "A tag & entity"},
+		{"`**not emphasis**`", "**not emphasis**"},
+		{`Set \<name\>`, "Set <name>"},
+	}
+	for _, tt := range tests {
+		if got := headingText(tt.markdown); got != tt.want {
+			t.Errorf("headingText(%q) = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+// TestCodeHeadingAnchors checks that a code heading keeps its backticks and
+// gets an anchor without them, which -lint accepts.
+func TestCodeHeadingAnchors(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.anchors = anchorStrategies["github"]
+	page := convertPages(t, cfg, map[string]string{
+		"a.html": `<h1>A</h1><p><a href="#cc_library">cc_library</a></p><h2 id="cc_library"><code>cc_library</code></h2>`,
+	})["a.md"]
+	for _, want := range []string{"## `cc_library`", "[cc\\_library](#cc_library)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+	l := &linter{anchors: cfg.anchors, pageAnchors: make(map[string]map[string]bool)}
+	if anchors := l.anchorsOf("a.md", page); !anchors["cc_library"] {
+		t.Errorf("lint anchors of the page are %v, want cc_library", anchors)
+	}
+}
+
+// TestPrevAnchors converts a page twice, renaming a heading in between, and
+// checks that the second build keeps the heading's first anchor as an
+// alias and records it for the builds after.
+func TestPrevAnchors(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "anchors.json")
+	cfg := testConfig(t)
+	cfg.outAnchors = mapPath
+	convertPages(t, cfg, map[string]string{"page.html": `<h1>Page</h1><h2>Old name</h2><p>Body</p><h2>Kept</h2>`})
+
+	prev, err := loadAnchorMap(mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.prevAnchors = prev
+	page := convertPages(t, cfg, map[string]string{"page.html": `<h1>Page</h1><h2>New name</h2><p>Body</p><h2>Kept</h2>`})["page.md"]
+	if want := "<a id=\"old-name\"></a>\n\n## New name"; !strings.Contains(page, want) {
+		t.Errorf("page does not contain\n%s\ngot\n%s", want, page)
+	}
+	if strings.Contains(page, `<a id="kept">`) {
+		t.Errorf("unchanged anchor aliased:\n%s", page)
+	}
+
+	next, err := loadAnchorMap(mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next["page.md"]; len(got) != 2 || got[0].Anchor != "new-name" || strings.Join(got[0].Aliases, " ") != "old-name" {
+		t.Errorf("anchor map is %+v, want new-name with the alias old-name", got)
+	}
+}
+
+// TestRewriteAnchorCase checks that with -rewrite-anchor-case, fragments in
+// either case reach a heading with a mixed-case id, on the page and from
+// another page.
+func TestRewriteAnchorCase(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html": `<h1>A</h1><p><a href="#CcLibrary">exact</a> <a href="#cclibrary">lower</a> <a href="#NAMED">named</a>
+<a href="b.html#Other">other</a> <a href="https://example.com/#Keep">external</a></p>
+<h2 id="CcLibrary">cc_library</h2><p><a name="Named"></a>Text</p>`,
+		"docs/b.html": `<h1>B</h1><h2 id="OTHER">Other heading</h2>`,
+	}
+	cfg := testConfig(t)
+	cfg.rewriteAnchorCase = true
+	page := convertPages(t, cfg, pages)["docs/a.md"]
+	for _, want := range []string{"[exact](#cc-library)", "[lower](#cc-library)", "[named](#named)", "[other](b.md#other-heading)", "(https://example.com/#Keep)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not link %s:\n%s", want, page)
+		}
+	}
+}
+
+// TestStripAutogeneratedIDs checks that with -strip-autogenerated-ids,
+// generated ids are not kept as anchors while readable ones are.
+func TestStripAutogeneratedIDs(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Page</h1>
+<h2 id="install-steps">Install</h2><p><a name="a1b2c3d4e5f60718"></a>Text</p>
+<h2 id="gc-wrapper">Wrapped</h2><h2 id="3f2a9c1e-77b4">Hashed</h2>`,
+	}
+	for _, strip := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.preserveHeadingIDs = true
+		if strip {
+			cfg.autogeneratedIDs = regexp.MustCompile(defaultAutogeneratedIDPattern)
+		}
+		page := convertPages(t, cfg, pages)["page.md"]
+		if !strings.Contains(page, `<a id="install-steps"></a>`) {
+			t.Errorf("strip %v: page lost the install-steps anchor:\n%s", strip, page)
+		}
+		for _, id := range []string{"a1b2c3d4e5f60718", "gc-wrapper", "3f2a9c1e-77b4"} {
+			if got := strings.Contains(page, `"`+id+`"`); got == strip {
+				t.Errorf("strip %v: page has anchor %s: %v, want %v:\n%s", strip, id, got, !strip, page)
+			}
+		}
+	}
+}
+
+// TestAnchorPrefix checks that with -anchor-prefix "{page}-", two pages with
+// an Examples heading get distinct anchors, and that links within and
+// between the pages point at them.
+func TestAnchorPrefix(t *testing.T) {
+	pages := map[string]string{
+		"docs/cc.html": `<h1>C++</h1><p><a href="#examples">Examples</a>, <a href="cc.html#usage">usage</a>, <a href="java.html#examples">Java examples</a></p>
+<h2>Examples</h2><h2 id="usage">Usage</h2><p><a name="flags"></a>Flags</p><h4>Deep notes</h4>`,
+		"docs/java.html": `<h1>Java</h1><p><a href="cc.html#usage">C++ usage</a></p><h2 id="examples">Examples</h2>`,
+	}
+	cfg := testConfig(t)
+	cfg.anchorPrefix = "{page}-"
+	cfg.maxHeadingDepth = 3
+	written := convertPages(t, cfg, pages)
+	want := map[string][]string{
+		"docs/cc.md": {
+			"[Examples](#docs-cc-examples), [usage](#docs-cc-usage), [Java examples](java.md#docs-java-examples)",
+			`<a id="docs-cc-examples"></a>` + "\n\n## Examples",
+			`<a id="docs-cc-usage"></a>` + "\n\n## Usage",
+			`<a id="docs-cc-flags"></a>`,
+			// Demoted past -max-heading-depth, with no anchor generated
+			`<a id="docs-cc-deep-notes"></a>` + "\n\n**Deep notes**",
+		},
+		"docs/java.md": {
+			"[C++ usage](cc.md#docs-cc-usage)",
+			`<a id="docs-java-examples"></a>` + "\n\n## Examples",
+		},
+	}
+	for path, links := range want {
+		for _, link := range links {
+			if !strings.Contains(written[path], link) {
+				t.Errorf("%s does not contain\n%s\nin\n%s", path, link, written[path])
+			}
+		}
+	}
+}
+
+// TestSectionAnchors checks that -preserve-section-ids-as-anchors keeps the
+// id of a section that no heading carries as an anchor, which -lint then
+// finds links to, and adds none for a section whose heading has the id.
+func TestSectionAnchors(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<section id="remote-caching"><h2>Caching remotely</h2><p>Text.</p></section>
+<section id="usage"><h2>Usage</h2><p>More.</p></section>`,
+		"docs/other.html": `<h1>Other</h1><p><a href="page.html#remote-caching">Caching</a> <a href="page.html#usage">Usage</a></p>`,
+	}
+	for _, preserve := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.preserveSectionIDs = preserve
+		dir := t.TempDir()
+		zipPath := filepath.Join(dir, "input.zip")
+		writeZip(t, zipPath, pages)
+		outputDir := filepath.Join(dir, "output")
+		if err := convertZipToMarkdown(zipPath, outputDir, cfg); err != nil {
+			t.Fatal(err)
+		}
+		page := readTree(t, outputDir)["docs/page.md"]
+		if got := strings.Contains(page, `<a id="remote-caching"></a>`+"\n\n## Caching remotely"); got != preserve {
+			t.Errorf("preserve %v: page is\n%s", preserve, page)
+		}
+		if strings.Contains(page, `<a id="usage">`) {
+			t.Errorf("preserve %v: anchor for a section its heading names:\n%s", preserve, page)
+		}
+
+		violations, err := lintOutput(outputDir, 0, cfg.anchors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if broken := len(violations) > 0; broken == preserve {
+			t.Errorf("preserve %v: lint found %v", preserve, violations)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// apiPages renders JSON API dumps in the zip, such as those of the Starlark
+// API, into reference pages with the -api-json-template Go template instead
+// of skipping them. The template is executed with the decoded JSON; the
+// page title is its top-level title or name field, or the file name.
+type apiPages struct {
+	template *template.Template
+	// pattern matches the zip paths of the dumps; empty matches every
+	// .json file
+	pattern string
+}
+
+// apiTemplateFuncs are available to -api-json-template besides the
+// built-in functions of text/template.
+var apiTemplateFuncs = template.FuncMap{
+	// code writes a value as inline code
+	"code": func(v interface{}) string { return "`" + fmt.Sprint(v) + "`" },
+	// oneline collapses the whitespace of a value, e.g. of a doc string in
+	// a table cell
+	"oneline": func(v interface{}) string { return collapseWhitespace(fmt.Sprint(v)) },
+	// join joins the items of a list
+	"join": func(list []interface{}, sep string) string {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, sep)
+	},
+}
+
+func newAPIPages(templatePath, pattern string) (*apiPages, error) {
+	if templatePath == "" {
+		if pattern != "" {
+			return nil, fmt.Errorf("-api-json-pattern needs an -api-json-template")
+		}
+		return nil, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid -api-json-pattern: %w", err)
+	}
+
+	text, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read -api-json-template: %w", err)
+	}
+	t, err := template.New(filepath.Base(templatePath)).Funcs(apiTemplateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid -api-json-template: %w", err)
+	}
+	return &apiPages{template: t, pattern: pattern}, nil
+}
+
+// matches reports whether the zip entry is an API dump to render.
+func (a *apiPages) matches(name string) bool {
+	if a == nil || strings.ToLower(path.Ext(name)) != ".json" {
+		return false
+	}
+	if a.pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(a.pattern, name)
+	return ok
+}
+
+// render returns the title and markdown body of the page for an API dump.
+func (a *apiPages) render(name string, content []byte) (string, string, error) {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return "", "", fmt.Errorf("failed to parse API JSON %s: %w", name, err)
+	}
+
+	title := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if fields, ok := data.(map[string]interface{}); ok {
+		for _, key := range []string{"title", "name"} {
+			if s, ok := fields[key].(string); ok && strings.TrimSpace(s) != "" {
+				title = collapseWhitespace(s)
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	if err := a.template.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render API JSON %s: %w", name, err)
+	}
+	return title, strings.TrimSpace(b.String()) + "\n", nil
+}
+
+// processAPIJSON writes the reference page of an API dump.
+func (c *conversion) processAPIJSON(f *zip.File) error {
+	fmt.Printf("Rendering API JSON: %s\n", f.Name)
+	content, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	c.stats.bytesRead += int64(len(content))
+
+	title, body, err := c.cfg.apiPages.render(f.Name, content)
+	if err != nil {
+		return err
+	}
+	fm := frontmatter{{Key: "title", Value: title}}
+	if c.cfg.frontmatterSchema != nil {
+		c.checkFrontmatter(fm)
+	}
+	markdown := fm.String() + body
+	if c.cfg.noFrontmatter {
+		markdown = "# " + title + "\n\n" + body
+	}
+	c.checkMDX(markdown)
+
+	pagePath := c.outputPathFor(f.Name)
+	if c.cfg.emitSitemap {
+		c.sitemap = append(c.sitemap, c.sitemapEntry(f, pagePath))
+	}
+	c.claimOutput(pagePath, f.Name)
+	outputPath := filepath.Join(c.outputDir, pagePath)
+	if err := writeOutputFile(f, outputPath, []byte(markdown), c.cfg.preserveMtime); err != nil {
+		return err
+	}
+	c.stats.pagesConverted++
+	c.stats.bytesWritten += int64(len(markdown))
+	c.manifest.add(manifestRecord{Source: f.Name, Output: pagePath, Bytes: int64(len(markdown)), Kind: recordPage, Title: title})
+	return nil
+}
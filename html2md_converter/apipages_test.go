@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIPages(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "api.tmpl")
+	tmpl := `# {{.name}}
+
+{{range .functions}}## {{.name}}
+
+{{oneline .doc}}
+
+| Parameter | Type |
+| --- | --- |
+{{range .params}}| {{code .name}} | {{.type}} |
+{{end}}
+Returns {{code .returns}}.
+
+{{end}}`
+	if err := os.WriteFile(templatePath, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	api, err := newAPIPages(templatePath, "api/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t)
+	cfg.apiPages = api
+	written := convertPages(t, cfg, map[string]string{
+		"api/native.json": `{"name": "native", "functions": [{"name": "glob", "doc": "Returns the files\n  matching the patterns.", "params": [{"name": "include", "type": "list"}, {"name": "exclude", "type": "list"}], "returns": "list"}]}`,
+		"docs/page.html":  `<h1>Page</h1><p>See <a href="../api/native.json">native</a>.</p>`,
+	})
+
+	want := "---\ntitle: 'native'\n---\n\n# native\n\n## glob\n\nReturns the files matching the patterns.\n\n| Parameter | Type |\n| --- | --- |\n| `include` | list |\n| `exclude` | list |\n\nReturns `list`.\n"
+	if got := written["api/native.md"]; got != want {
+		t.Errorf("api/native.md is\n%s\nwant\n%s", got, want)
+	}
+	if page := written["docs/page.md"]; !strings.Contains(page, "[native](../api/native.md)") {
+		t.Errorf("page does not link the reference page:\n%s", page)
+	}
+}
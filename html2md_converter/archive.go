@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveFormat returns the format -output-archive writes, from its
+// extension: "tar", "tar.gz", or "" for other names.
+func archiveFormat(archivePath string) string {
+	name := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// writeOutputArchive packs the output directory into a tar archive, gzipped
+// for .tar.gz and .tgz. Entries are in lexical order. With reproducible,
+// every timestamp is the Unix epoch and owners and modes are normalized, so
+// the same output gives the same archive bytes on every run and machine.
+func writeOutputArchive(archivePath, outputDir string, reproducible bool) error {
+	out, err := os.OpenFile(archivePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, outputFileMode)
+	if err == nil && exactModes {
+		err = out.Chmod(outputFileMode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create -output-archive: %w", err)
+	}
+	defer out.Close()
+	self, _ := filepath.Abs(archivePath)
+
+	var w io.Writer = out
+	var gz *gzip.Writer
+	if archiveFormat(archivePath) == "tar.gz" {
+		gz = gzip.NewWriter(out)
+		if !reproducible {
+			gz.ModTime = time.Now()
+		}
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+
+	count := 0
+	err = filepath.WalkDir(outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if abs, _ := filepath.Abs(p); abs == self {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if reproducible {
+			header.ModTime = time.Unix(0, 0)
+			header.AccessTime, header.ChangeTime = time.Time{}, time.Time{}
+			header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+			header.Mode = 0644
+			if d.IsDir() {
+				header.Mode = 0755
+			}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write -output-archive: %w", err)
+	}
+
+	fmt.Printf("Archived %d file(s) to %s\n", count, archivePath)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReproducibleArchive converts the same zip twice, to different
+// directories and at different times, and checks that -reproducible gives
+// byte-identical .tar.gz archives with their entries in lexical order.
+func TestReproducibleArchive(t *testing.T) {
+	pages := map[string]string{
+		"docs/b.html":     `<h1>B</h1><p>Second</p>`,
+		"docs/a.html":     `<h1>A</h1><p>First</p>`,
+		"docs/guide.html": `<h1>Guide</h1><p>See <a href="a.html">A</a>.</p>`,
+	}
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, pages)
+
+	var archives [][]byte
+	for run := 0; run < 2; run++ {
+		cfg := testConfig(t)
+		cfg.preserveMtime = false
+		cfg.outputArchive = filepath.Join(t.TempDir(), "docs.tar.gz")
+		cfg.reproducible = true
+		if err := convertZipToMarkdown(zipPath, filepath.Join(t.TempDir(), "output"), cfg); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(cfg.outputArchive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, content)
+	}
+	if !bytes.Equal(archives[0], archives[1]) {
+		t.Error("the archives of the two runs differ")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archives[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.ModTime.Unix() != 0 {
+			t.Errorf("%s has timestamp %v, want the Unix epoch", header.Name, header.ModTime)
+		}
+		names = append(names, header.Name)
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("entries are not in lexical order: %q", names)
+			break
+		}
+	}
+	if len(names) == 0 {
+		t.Error("the archive is empty")
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Asset layouts accepted by -assets-layout.
+const (
+	// Colocated puts page.assets/img.png beside page.md.
+	assetsColocated = "colocated"
+	// Central puts every image under a shared -assets-dir.
+	assetsCentral = "central"
+)
+
+// assetStore copies images referenced by converted pages out of the zip.
+// Paths are slash-separated and relative to the output directory.
+type assetStore struct {
+	layout    string
+	assetsDir string
+	outputDir string
+	// outputCase is the -output-case mode applied to asset names
+	outputCase string
+	// preserveMtime copies zip modification times onto the copies
+	preserveMtime bool
+	// maxBytes and maxWidth are the limits checkAssetSize warns about
+	maxBytes int64
+	maxWidth int
+	// relativize points references to images in the zip, when none are
+	// copied, at their zip paths relative to the page's output path
+	relativize bool
+	// imageExtensions maps lowercase image extensions to the ones links
+	// to internal images get instead, for -rewrite-image-extensions
+	imageExtensions map[string]string
+
+	// files indexes the zip entries by name
+	files map[string]*zip.File
+	// sources index the entries of the -sources-root zips, which are
+	// searched in order for images the zip lacks
+	sources []map[string]*zip.File
+	stats   *stats
+	// manifest records the copied images
+	manifest *manifest
+	// written maps an output path to the hash of the content stored there
+	written map[string]string
+	// byContent maps a destination directory and content hash to the
+	// output path already holding that content
+	byContent map[string]string
+}
+
+func newAssetStore(cfg config, outputDir string, files map[string]*zip.File, sources []map[string]*zip.File, st *stats, m *manifest) *assetStore {
+	s := &assetStore{
+		layout:          cfg.assetsLayout,
+		assetsDir:       cfg.assetsDir,
+		outputDir:       outputDir,
+		outputCase:      cfg.outputCase,
+		preserveMtime:   cfg.preserveMtime,
+		maxBytes:        cfg.assetsMaxBytes,
+		maxWidth:        cfg.assetsMaxWidth,
+		relativize:      cfg.relativizeAssets,
+		imageExtensions: cfg.imageExtensions,
+		files:           files,
+		sources:         sources,
+		stats:           st,
+		manifest:        m,
+		written:         make(map[string]string),
+		byContent:       make(map[string]string),
+	}
+	return s
+}
+
+func isImageFile(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif":
+		return true
+	}
+	return false
+}
+
+// rewriteImages copies every image the page references and rewrites the
+// img src attributes, the srcset candidates of img and <picture> sources and
+// the links to images to point at the copies. sourcePath is the page's name inside the
+// zip and pagePath its output path. Without a layout, images are not copied
+// and references stay as they are, or with -relativize-assets point at the
+// images' zip paths, where they are deployed beside the output, from the
+// page's output path.
+func (s *assetStore) rewriteImages(doc *goquery.Document, sourcePath, pagePath string) error {
+	if s.layout == "" && !s.relativize && s.imageExtensions == nil {
+		return nil
+	}
+
+	var err error
+	doc.Find("img[src], img[srcset], picture source[srcset]").EachWithBreak(func(i int, img *goquery.Selection) bool {
+		if src, ok := img.Attr("src"); ok && goquery.NodeName(img) == "img" {
+			var link string
+			if link, err = s.assetLink(sourcePath, pagePath, src); err != nil {
+				return false
+			}
+			img.SetAttr("src", link)
+		}
+
+		if srcset, ok := img.Attr("srcset"); ok {
+			candidates := strings.Split(srcset, ",")
+			for i, candidate := range candidates {
+				fields := strings.Fields(candidate)
+				if len(fields) == 0 {
+					continue
+				}
+				if fields[0], err = s.assetLink(sourcePath, pagePath, fields[0]); err != nil {
+					return false
+				}
+				candidates[i] = strings.Join(fields, " ")
+			}
+			img.SetAttr("srcset", strings.Join(candidates, ", "))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// Links to images in the zip, such as click-to-enlarge links around a
+	// thumbnail, point at copies too
+	doc.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		var link string
+		if link, err = s.assetLink(sourcePath, pagePath, a.AttrOr("href", "")); err != nil {
+			return false
+		}
+		a.SetAttr("href", link)
+		return true
+	})
+	return err
+}
+
+// assetLink copies the image ref points to and returns the link to the copy
+// from the page, or ref itself for external or missing images.
+func (s *assetStore) assetLink(sourcePath, pagePath, ref string) (string, error) {
+	if s.layout == "" {
+		name, u, ok := resolveZipRef(sourcePath, ref)
+		if !ok || !isImageFile(name) {
+			return ref, nil
+		}
+		if !s.relativize || s.files[name] == nil {
+			return s.imageExtension(ref), nil
+		}
+		link := relativeLink(pagePath, name)
+		if u.RawQuery != "" {
+			link += "?" + u.RawQuery
+		}
+		return s.imageExtension(link), nil
+	}
+
+	f := s.lookup(sourcePath, ref)
+	if f == nil {
+		return ref, nil
+	}
+
+	assetPath, err := s.copy(f, pagePath)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(pagePath)), filepath.FromSlash(assetPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to relativize %s: %w", assetPath, err)
+	}
+	return s.imageExtension(filepath.ToSlash(rel)), nil
+}
+
+// imageExtension swaps the extension of the image link points to as
+// -rewrite-image-extensions maps it, keeping any query or fragment. The
+// image itself is not converted; that is left to a separate step.
+func (s *assetStore) imageExtension(link string) string {
+	target, rest := link, ""
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		target, rest = link[:i], link[i:]
+	}
+	ext := path.Ext(target)
+	if to, ok := s.imageExtensions[strings.ToLower(ext)]; ok {
+		return strings.TrimSuffix(target, ext) + to + rest
+	}
+	return link
+}
+
+// parseImageExtensions parses a -rewrite-image-extensions value, a
+// comma-separated list of from=to pairs such as ".png=.webp", where both
+// are image extensions, with or without the dot.
+func parseImageExtensions(list string) (map[string]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	extensions := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from = "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(from)), ".")
+		to = "." + strings.TrimPrefix(strings.ToLower(strings.TrimSpace(to)), ".")
+		if !ok || !isImageFile(from) || !isImageFile(to) {
+			return nil, fmt.Errorf("-rewrite-image-extensions entry %q must map one image extension to another, such as .png=.webp", pair)
+		}
+		extensions[from] = to
+	}
+	return extensions, nil
+}
+
+// copyDownloads copies the targets of download links, <a download>, from
+// the zip like images, into the -assets-layout directory or -assets-dir
+// without a layout, and points the links at the copies. Targets missing
+// from the zip keep their links.
+func (s *assetStore) copyDownloads(doc *goquery.Document, sourcePath, pagePath string) error {
+	var err error
+	doc.Find("a[download][href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		href := a.AttrOr("href", "")
+		name, u, ok := resolveZipRef(sourcePath, href)
+		if !ok {
+			return true
+		}
+		f := s.files[name]
+		for _, files := range s.sources {
+			if f == nil {
+				f = files[name]
+			}
+		}
+		if f == nil {
+			fmt.Printf("  Warning: download %s is not in the zip or any -sources-root zip\n", href)
+			return true
+		}
+
+		var assetPath string
+		if assetPath, err = s.copy(f, pagePath); err != nil {
+			return false
+		}
+		link := relativeLink(pagePath, assetPath)
+		if u.Fragment != "" {
+			link += "#" + u.Fragment
+		}
+		a.SetAttr("href", link)
+		return true
+	})
+	return err
+}
+
+// lookup resolves an img src against the referencing page and returns the
+// entry of the zip or of the first source zip it points to, or nil for
+// external or missing images. Missing images are warned about.
+func (s *assetStore) lookup(sourcePath, src string) *zip.File {
+	name, _, ok := resolveZipRef(sourcePath, src)
+	if !ok || !isImageFile(name) {
+		return nil
+	}
+
+	if f := s.files[name]; f != nil {
+		return f
+	}
+	for _, files := range s.sources {
+		if f := files[name]; f != nil {
+			return f
+		}
+	}
+	fmt.Printf("  Warning: image %s is not in the zip or any -sources-root zip\n", src)
+	s.stats.missingAssets++
+	return nil
+}
+
+// copy writes the image into the layout's destination directory, reusing an
+// existing copy with identical content, and returns its output path.
+func (s *assetStore) copy(f *zip.File, pagePath string) (string, error) {
+	content, err := readZipFile(f)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	dir := s.assetsDir
+	if s.layout == assetsColocated {
+		dir = changeExtension(pagePath, ".assets")
+	}
+
+	// De-duplicate identical images within the destination directory
+	if existing, ok := s.byContent[dir+"\x00"+hash]; ok {
+		return existing, nil
+	}
+
+	// Different images sharing a base name get a content-hash suffix
+	name := applyOutputCase(path.Base(f.Name), s.outputCase)
+	assetPath := path.Join(dir, name)
+	if h, taken := s.written[assetPath]; taken && h != hash {
+		ext := path.Ext(name)
+		assetPath = path.Join(dir, strings.TrimSuffix(name, ext)+"-"+hash[:8]+ext)
+	}
+
+	fullPath := filepath.Join(s.outputDir, filepath.FromSlash(assetPath))
+	if err := mkdirAll(filepath.Dir(fullPath)); err != nil {
+		return "", fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	if err := writeFile(fullPath, content); err != nil {
+		return "", fmt.Errorf("failed to write asset: %w", err)
+	}
+	if err := preserveModTime(fullPath, f, s.preserveMtime); err != nil {
+		return "", err
+	}
+	fmt.Printf("  -> Copied asset: %s\n", fullPath)
+	if isImageFile(f.Name) {
+		s.checkAssetSize(assetPath, content)
+	}
+
+	s.stats.assetsCopied++
+	s.stats.bytesWritten += int64(len(content))
+	s.manifest.add(manifestRecord{Source: f.Name, Output: assetPath, Bytes: int64(len(content)), Kind: recordAsset})
+	s.written[assetPath] = hash
+	s.byContent[dir+"\x00"+hash] = assetPath
+	return assetPath, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file in zip: %w", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssetsLayout(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html":          `<p><img src="images/logo.png" alt="Logo"></p>`,
+		"docs/guide/b.html":    `<p><img src="/docs/images/logo.png" alt="Logo"> <img src="https://bazel.build/x.png" alt="X"></p>`,
+		"docs/images/logo.png": "PNG",
+	}
+	tests := []struct {
+		layout string
+		// links are the image links of a.md and b.md
+		links  [2]string
+		copies []string
+	}{
+		{assetsColocated, [2]string{"![Logo](a.assets/logo.png)", "![Logo](b.assets/logo.png)"}, []string{"docs/a.assets/logo.png", "docs/guide/b.assets/logo.png"}},
+		{assetsCentral, [2]string{"![Logo](../assets/logo.png)", "![Logo](../../assets/logo.png)"}, []string{"assets/logo.png"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.assetsLayout = tt.layout
+		written := convertPages(t, cfg, pages)
+		for i, page := range []string{"docs/a.md", "docs/guide/b.md"} {
+			if !strings.Contains(written[page], tt.links[i]) {
+				t.Errorf("%s: %s lacks %s:\n%s", tt.layout, page, tt.links[i], written[page])
+			}
+		}
+		if !strings.Contains(written["docs/guide/b.md"], "(https://bazel.build/x.png)") {
+			t.Errorf("%s: external image rewritten:\n%s", tt.layout, written["docs/guide/b.md"])
+		}
+		assets := 0
+		for path := range written {
+			if strings.HasSuffix(path, "logo.png") {
+				assets++
+			}
+		}
+		if assets != len(tt.copies) {
+			t.Errorf("%s: wrote %d copies of the image, want %d", tt.layout, assets, len(tt.copies))
+		}
+		for _, path := range tt.copies {
+			if written[path] != "PNG" {
+				t.Errorf("%s: %s not copied", tt.layout, path)
+			}
+		}
+	}
+}
+
+func TestLinkedImages(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.assetsLayout = assetsCentral
+	written := convertPages(t, cfg, map[string]string{
+		"docs/page.html":     `<p><a href="img/full.png"><img src="img/thumb.png" alt="Shot"></a> <a href="other.html"><img src="img/thumb.png" alt="Other"></a></p>`,
+		"docs/other.html":    `<p>Other</p>`,
+		"docs/img/full.png":  "FULL",
+		"docs/img/thumb.png": "THUMB",
+	})
+	for _, want := range []string{"[![Shot](../assets/thumb.png)](../assets/full.png)", "[![Other](../assets/thumb.png)](other.md)"} {
+		if !strings.Contains(written["docs/page.md"], want) {
+			t.Errorf("page lacks %s:\n%s", want, written["docs/page.md"])
+		}
+	}
+	if written["assets/full.png"] != "FULL" || written["assets/thumb.png"] != "THUMB" {
+		t.Errorf("images not copied; wrote %v", written)
+	}
+}
+
+func TestSourcesRoot(t *testing.T) {
+	root := t.TempDir()
+	writeZip(t, filepath.Join(root, "a.zip"), map[string]string{"docs/images/shared.png": "FIRST"})
+	writeZip(t, filepath.Join(root, "b.zip"), map[string]string{"docs/images/shared.png": "SECOND", "docs/images/other.png": "OTHER"})
+	pages := map[string]string{
+		"docs/page.html":      `<h1>Page</h1><p><img src="images/own.png" alt="Own"> <img src="images/shared.png" alt="Shared"> <img src="images/other.png" alt="Other"> <img src="images/missing.png" alt="Missing"></p>`,
+		"docs/images/own.png": "OWN",
+	}
+
+	cfg := testConfig(t)
+	cfg.assetsLayout = assetsCentral
+	cfg.sourcesRoot = root
+	written := convertPages(t, cfg, pages)
+	for path, want := range map[string]string{"assets/own.png": "OWN", "assets/shared.png": "FIRST", "assets/other.png": "OTHER"} {
+		if written[path] != want {
+			t.Errorf("%s is %q, want %q", path, written[path], want)
+		}
+	}
+	if page := written["docs/page.md"]; !strings.Contains(page, "![Other](../assets/other.png)") || !strings.Contains(page, "(images/missing.png)") {
+		t.Errorf("page does not link the images:\n%s", page)
+	}
+
+	// The missing image fails the run with -strict-assets
+	cfg.strict["assets"] = true
+	var strictErr *strictError
+	if err := convertTestZip(t, cfg, pages); !errors.As(err, &strictErr) || strictErr.code != 32 {
+		t.Errorf("-strict-assets: run returned %v, want exit status 32", err)
+	}
+}
+
+// TestAssetsMaxDimensions checks that -strict-images fails a run that
+// copies a PNG wider than -assets-max-width, or an image of any format
+// larger than -assets-max-bytes, and only then.
+func TestAssetsMaxDimensions(t *testing.T) {
+	page := `<h1>Page</h1><p><img src="images/shot.png" alt="Shot"> <img src="images/logo.svg" alt="Logo"></p>`
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" width="5000"></svg>`
+	tests := []struct {
+		width    int
+		maxWidth int
+		maxBytes int64
+		failed   bool
+	}{
+		{200, 0, 0, false},
+		{200, 100, 0, true},
+		{50, 100, 0, false},
+		{50, 0, int64(len(svg)) - 1, true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.assetsLayout = assetsCentral
+		cfg.assetsMaxWidth = tt.maxWidth
+		cfg.assetsMaxBytes = tt.maxBytes
+		cfg.strict["images"] = true
+		pages := map[string]string{
+			"docs/page.html":       page,
+			"docs/images/shot.png": testPNG(t, tt.width, 10),
+			"docs/images/logo.svg": svg,
+		}
+
+		err := convertTestZip(t, cfg, pages)
+		var strictErr *strictError
+		switch {
+		case !tt.failed && err != nil:
+			t.Errorf("%dpx, -assets-max-width=%d -assets-max-bytes=%d: run failed: %v", tt.width, tt.maxWidth, tt.maxBytes, err)
+		case tt.failed && (!errors.As(err, &strictErr) || strictErr.code != 1):
+			t.Errorf("%dpx, -assets-max-width=%d -assets-max-bytes=%d: run returned %v, want exit status 1", tt.width, tt.maxWidth, tt.maxBytes, err)
+		}
+	}
+}
+
+// testPNG returns a blank PNG of the given size.
+func testPNG(t *testing.T, width, height int) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// TestRelativizeAssets checks that with -relativize-assets, two pages at
+// different output depths reference the same image in the zip by correct
+// relative paths, however they wrote the reference.
+func TestRelativizeAssets(t *testing.T) {
+	pages := map[string]string{
+		"docs/intro.html":        `<h1>Intro</h1><img src="images/logo.png" alt="Logo">`,
+		"docs/rules/cc/lib.html": `<h1>Lib</h1><img src="/docs/images/logo.png" alt="Logo"><img src="https://example.com/x.png" alt="External">`,
+		"docs/images/logo.png":   "PNG",
+	}
+	cfg := testConfig(t)
+	cfg.relativizeAssets = true
+	var err error
+	if cfg.pathTemplate, err = newPathTemplate("reference/{lang}/{name}.{ext}", `^docs/rules/(?P<lang>\w+)/`); err != nil {
+		t.Fatal(err)
+	}
+	written := convertPages(t, cfg, pages)
+	want := map[string][]string{
+		"docs/intro.md":       {"![Logo](images/logo.png)"},
+		"reference/cc/lib.md": {"![Logo](../../docs/images/logo.png)", "![External](https://example.com/x.png)"},
+	}
+	for path, links := range want {
+		for _, link := range links {
+			if !strings.Contains(written[path], link) {
+				t.Errorf("%s does not contain %s:\n%s", path, link, written[path])
+			}
+		}
+	}
+}
+
+// TestDownloadLinks checks that download links are kept as links, even to
+// pages, and that -copy-downloads copies their targets and links the copies.
+func TestDownloadLinks(t *testing.T) {
+	pages := map[string]string{
+		"docs/install.html":    `<h1>Install</h1><p><a href="files/bazel.zip" download>Bazel</a> <a href="cheatsheet.html" download>Cheat sheet</a> <a href="missing.tar" download>Missing</a></p>`,
+		"docs/files/bazel.zip": "ZIP",
+		"docs/cheatsheet.html": `<h1>Cheat sheet</h1><p>Commands</p>`,
+	}
+	for _, copyDownloads := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.copyDownloads = copyDownloads
+		written := convertPages(t, cfg, pages)
+		page := written["docs/install.md"]
+		want := []string{"[Bazel](files/bazel.zip)", "[Cheat sheet](cheatsheet.html)", "[Missing](missing.tar)"}
+		if copyDownloads {
+			want = []string{"[Bazel](../assets/bazel.zip)", "[Cheat sheet](../assets/cheatsheet.html)", "[Missing](missing.tar)"}
+			if written["assets/bazel.zip"] != "ZIP" {
+				t.Errorf("-copy-downloads did not copy bazel.zip; wrote %v", written)
+			}
+		}
+		for _, link := range want {
+			if !strings.Contains(page, link) {
+				t.Errorf("copy %v: page does not contain %s:\n%s", copyDownloads, link, page)
+			}
+		}
+	}
+}
+
+func TestRewriteImageExtensions(t *testing.T) {
+	extensions, err := parseImageExtensions(".png=.webp, JPG=webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extensions[".png"] != ".webp" || extensions[".jpg"] != ".webp" || len(extensions) != 2 {
+		t.Errorf("parseImageExtensions = %v", extensions)
+	}
+	for _, list := range []string{"png=txt", "png"} {
+		if _, err := parseImageExtensions(list); err == nil {
+			t.Errorf("parseImageExtensions(%q) accepted", list)
+		}
+	}
+
+	pages := map[string]string{
+		"docs/page.html": `<p><img src="x.png?v=2" alt="X"> <img src="https://bazel.build/y.png" alt="Y"></p>`,
+		"docs/x.png":     "PNG",
+	}
+	for layout, want := range map[string]string{"": "![X](x.webp?v=2)", assetsCentral: "![X](../assets/x.webp)"} {
+		cfg := testConfig(t)
+		cfg.assetsLayout = layout
+		cfg.stripQuery = false
+		cfg.imageExtensions = extensions
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		if !strings.Contains(page, want) {
+			t.Errorf("layout %q: page lacks %s:\n%s", layout, want, page)
+		}
+		if !strings.Contains(page, "(https://bazel.build/y.png)") {
+			t.Errorf("layout %q: external image rewritten:\n%s", layout, page)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// checkAssetSize warns when a copied image is larger than -assets-max-bytes
+// or wider than -assets-max-width. Widths are read only from the raster
+// formats the standard library decodes, PNG, JPEG and GIF; other formats
+// are checked for size alone.
+func (s *assetStore) checkAssetSize(assetPath string, content []byte) {
+	oversized := false
+	if s.maxBytes > 0 && int64(len(content)) > s.maxBytes {
+		fmt.Printf("  Warning: image %s is %d bytes, over -assets-max-bytes of %d\n", assetPath, len(content), s.maxBytes)
+		oversized = true
+	}
+	if s.maxWidth > 0 {
+		if img, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil && img.Width > s.maxWidth {
+			fmt.Printf("  Warning: image %s is %dx%d, wider than -assets-max-width of %d\n", assetPath, img.Width, img.Height, s.maxWidth)
+			oversized = true
+		}
+	}
+	if oversized {
+		s.stats.oversizedAssets++
+	}
+}
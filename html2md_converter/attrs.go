@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// collectDroppedAttrs counts the class names and inline style properties in
+// the page body. Markdown has no place for either, so the converter drops
+// them; the counts show which Devsite classes deserve a dedicated rule.
+func collectDroppedAttrs(doc *goquery.Document) map[string]int {
+	counts := make(map[string]int)
+	doc.Find("body [class], body [style]").Each(func(i int, s *goquery.Selection) {
+		tag := goquery.NodeName(s)
+		if class, ok := s.Attr("class"); ok {
+			for _, name := range strings.Fields(class) {
+				counts[tag+"."+name]++
+			}
+		}
+		if style, ok := s.Attr("style"); ok {
+			for _, decl := range strings.Split(style, ";") {
+				prop, _, _ := strings.Cut(decl, ":")
+				if prop = strings.ToLower(strings.TrimSpace(prop)); prop != "" {
+					counts[tag+"[style:"+prop+"]"]++
+				}
+			}
+		}
+	})
+	return counts
+}
+
+// printDroppedAttrs prints the counts most frequent first.
+func printDroppedAttrs(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	indent := strings.Repeat(" ", len(title)-len(strings.TrimLeft(title, " "))+2)
+	fmt.Println(title)
+	for _, key := range keys {
+		fmt.Printf("%s%s x%d\n", indent, key, counts[key])
+	}
+}
+
+// dataAttrFilter removes data-* attributes from the HTML that rules pass
+// through to the output as is. Devsite sets many of them, and they clutter
+// the page and can trip up MDX. Those in keep stay.
+type dataAttrFilter struct {
+	strip bool
+	keep  map[string]bool
+}
+
+// outerHTML renders s with its data-* attributes, and those of the elements
+// in it, filtered. The document itself is left as it is.
+func (f dataAttrFilter) outerHTML(s *goquery.Selection) (string, error) {
+	if !f.strip {
+		return goquery.OuterHtml(s)
+	}
+	clone := s.Clone()
+	clone.Find("*").AddSelection(clone).Each(func(i int, el *goquery.Selection) {
+		n := el.Nodes[0]
+		kept := n.Attr[:0]
+		for _, attr := range n.Attr {
+			if !strings.HasPrefix(attr.Key, "data-") || f.keep[attr.Key] {
+				kept = append(kept, attr)
+			}
+		}
+		n.Attr = kept
+	})
+	return goquery.OuterHtml(clone)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestCollectDroppedAttrs(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<p class="note external">A <span class="external" style="color: red; Font-Weight:bold">B</span></p><a class="external" href="x">C</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collectDroppedAttrs(doc)
+	want := map[string]int{
+		"p.note":                  1,
+		"p.external":              1,
+		"span.external":           1,
+		"span[style:color]":       1,
+		"span[style:font-weight]": 1,
+		"a.external":              1,
+	}
+	if len(got) != len(want) {
+		t.Errorf("counted %v, want %v", got, want)
+	}
+	for key, n := range want {
+		if got[key] != n {
+			t.Errorf("%s counted %d times, want %d", key, got[key], n)
+		}
+	}
+}
+
+func TestDataAttrFilter(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<math data-foo="1" data-lang="en" display="block"><mi data-devsite-x="y">x</mi></math>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	math := doc.Find("math")
+	tests := []struct {
+		filter dataAttrFilter
+		want   string
+	}{
+		{dataAttrFilter{strip: true, keep: parseTagList("data-lang")}, `<math data-lang="en" display="block"><mi>x</mi></math>`},
+		{dataAttrFilter{strip: true}, `<math display="block"><mi>x</mi></math>`},
+		{dataAttrFilter{}, `<math data-foo="1" data-lang="en" display="block"><mi data-devsite-x="y">x</mi></math>`},
+	}
+	for _, tt := range tests {
+		got, err := tt.filter.outerHTML(math)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%+v: rendered %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+	if _, ok := math.Attr("data-foo"); !ok {
+		t.Error("filtering removed data-foo from the document")
+	}
+}
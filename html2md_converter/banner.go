@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Forms of the -banner notice.
+const (
+	// An HTML comment, which transform-docs.awk turns into an MDX comment.
+	bannerComment = "comment"
+	// A note callout, visible to readers.
+	bannerNote = "note"
+)
+
+// Places of the -source-link URL.
+const (
+	// A source_url frontmatter field
+	sourceLinkFrontmatter = "frontmatter"
+	// An "Edit this page" link at the end of the page
+	sourceLinkFooter = "footer"
+)
+
+// sourceURL returns the -source-link URL of the page generated from
+// sourcePath, or "" when there is none. The path is escaped segment by
+// segment.
+func sourceURL(cfg config, sourcePath string) string {
+	if cfg.sourceLink == "" {
+		return ""
+	}
+	segments := strings.Split(sourcePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.ReplaceAll(cfg.sourceLink, "{source}", strings.Join(segments, "/"))
+}
+
+// pageBanner returns the -banner notice for the page generated from
+// sourcePath, or "" when there is none.
+func pageBanner(cfg config, sourcePath string) string {
+	if cfg.banner == "" {
+		return ""
+	}
+
+	text := strings.ReplaceAll(cfg.banner, "{source}", sourcePath)
+	if cfg.bannerStyle == bannerNote {
+		return strings.TrimLeft(cfg.components.callout("Note", text), "\n")
+	}
+	return "<!-- " + strings.ReplaceAll(text, "-->", "--&gt;") + " -->\n\n"
+}
+
+// sourceComment returns the -include-source-comment MDX comment naming
+// sourcePath, the page's path in the zip, so that reviewers can map the
+// generated page back to its source, or "" without the flag.
+func sourceComment(cfg config, sourcePath string) string {
+	if !cfg.includeSourceComment {
+		return ""
+	}
+	return "{/* source: " + strings.ReplaceAll(sourcePath, "*/", "*\\/") + " */}\n\n"
+}
+
+// insertAfterFrontmatter puts text right after the YAML frontmatter of a
+// markdown file, or at its start when it has none.
+func insertAfterFrontmatter(content, text string) string {
+	if strings.HasPrefix(content, "---\n") {
+		if end := strings.Index(content[3:], "\n---\n"); end >= 0 {
+			split := 3 + end + len("\n---\n")
+			return content[:split] + "\n" + text + strings.TrimLeft(content[split:], "\n")
+		}
+	}
+	return text + content
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBanner(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1><p>Body</p>`,
+		"docs/notes.md":  "---\ntitle: 'Notes'\n---\n\nNotes\n",
+		"docs/plain.md":  "Plain\n",
+	}
+	tests := []struct {
+		style  string
+		copied bool
+		want   map[string]string
+	}{
+		{bannerComment, false, map[string]string{
+			"docs/page.md":  "---\ntitle: 'Page'\n---\n\n<!-- Generated from docs/page.html; do not edit --&gt; -->\n\nBody",
+			"docs/notes.md": "---\ntitle: 'Notes'\n---\n\nNotes\n",
+			"docs/plain.md": "Plain\n",
+		}},
+		{bannerNote, true, map[string]string{
+			"docs/page.md":  "---\ntitle: 'Page'\n---\n\n<Note>\n\nGenerated from docs/page.html; do not edit -->\n\n</Note>\n\nBody",
+			"docs/notes.md": "---\ntitle: 'Notes'\n---\n\n<Note>\n\nGenerated from docs/notes.md; do not edit -->\n\n</Note>\n\nNotes\n",
+			"docs/plain.md": "<Note>\n\nGenerated from docs/plain.md; do not edit -->\n\n</Note>\n\nPlain\n",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.banner = "Generated from {source}; do not edit -->"
+		cfg.bannerStyle = tt.style
+		cfg.bannerCopied = tt.copied
+		written := convertPages(t, cfg, pages)
+		for path, want := range tt.want {
+			if !strings.HasPrefix(written[path], want) {
+				t.Errorf("%s, -banner-copied=%v: %s is\n%s\nwant\n%s", tt.style, tt.copied, path, written[path], want)
+			}
+		}
+	}
+}
+
+func TestSourceLink(t *testing.T) {
+	pages := map[string]string{"docs/C++ rules.html": `<h1>Rules</h1><p>Body</p>`}
+	tests := []struct {
+		style, want string
+	}{
+		{sourceLinkFrontmatter, "---\ntitle: 'Rules'\nsource_url: 'https://github.com/bazelbuild/bazel/blob/master/site/en/docs/C++%20rules.html'\n---\n\nBody"},
+		{sourceLinkFooter, "---\ntitle: 'Rules'\n---\n\nBody\n\n[Edit this page](https://github.com/bazelbuild/bazel/blob/master/site/en/docs/C++%20rules.html)"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.sourceLink = "https://github.com/bazelbuild/bazel/blob/master/site/en/{source}"
+		cfg.sourceLinkStyle = tt.style
+		page := convertPages(t, cfg, pages)["docs/C++ rules.md"]
+		if got := strings.TrimSpace(page); got != tt.want {
+			t.Errorf("-source-link-style %s: page is\n%s\nwant\n%s", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestSourceComment(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.includeSourceComment = true
+	cfg.banner = "Generated; do not edit"
+	// The comment is an MDX expression, not literal braces
+	cfg.strict["mdx"] = true
+	written := convertPages(t, cfg, map[string]string{
+		"reference/be/general.html": `<h1>General rules</h1><p>Body</p>`,
+		"reference/notes.md":        "Notes\n",
+	})
+	want := "---\ntitle: 'General rules'\n---\n\n{/* source: reference/be/general.html */}\n\n<!-- Generated; do not edit -->\n\nBody"
+	if page := written["reference/be/general.md"]; !strings.HasPrefix(page, want) {
+		t.Errorf("page is\n%s\nwant\n%s", page, want)
+	}
+	if notes := written["reference/notes.md"]; notes != "Notes\n" {
+		t.Errorf("copied markdown is %q, want it unchanged", notes)
+	}
+}
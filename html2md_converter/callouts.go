@@ -0,0 +1,68 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// asideOptions maps Devsite <aside> classes to the Mintlify component they
+// become. Entries from the config file are merged over defaultAsideComponents.
+type asideOptions map[string]string
+
+var defaultAsideComponents = map[string]string{
+	"note":       "Note",
+	"tip":        "Tip",
+	"key-point":  "Info",
+	"objective":  "Info",
+	"special":    "Info",
+	"beta":       "Info",
+	"caution":    "Warning",
+	"warning":    "Warning",
+	"important":  "Warning",
+	"deprecated": "Warning",
+	"success":    "Check",
+}
+
+// component returns the component for the first mapped class, if any.
+func (o asideOptions) component(classes string) (string, bool) {
+	for _, class := range strings.Fields(classes) {
+		if name, ok := o[class]; ok {
+			return name, name != ""
+		}
+		if name, ok := defaultAsideComponents[class]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// asideLabelRegex matches the "Note:" style label Devsite authors start
+// callouts with, which the component already conveys. A label the HTML
+// runs straight into a table or code block is written as a <strong> tag,
+// since ** cannot close against the block's text.
+var asideLabelRegex = regexp.MustCompile(`^(?:\*\*[\w ]+:?\*\*|<(?:strong|b)>[\w ]+:?</(?:strong|b)>):?\s*`)
+
+// asidePlugin converts Devsite callouts, <aside class="note">, into callout
+// components. Some pages mark callouts up as <blockquote class="note">
+// instead; blockquotes without a mapped class stay quotes.
+func asidePlugin(options asideOptions, style componentStyle) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"aside", "blockquote"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					name, ok := options.component(selec.AttrOr("class", ""))
+					if !ok {
+						return nil
+					}
+
+					body := asideLabelRegex.ReplaceAllString(strings.TrimSpace(content), "")
+					return md.String(style.callout(name, body))
+				},
+			},
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// censusEntry counts one tag or class across the pages of a zip.
+type censusEntry struct {
+	Name string `json:"name"`
+	// Count is the number of elements, Pages the number of pages with any
+	Count int `json:"count"`
+	Pages int `json:"pages"`
+	// Sample is the first page it appears on
+	Sample string `json:"sample"`
+}
+
+// census is the -report-unknown-tags report: which tags and classes the
+// pages use and how often, to show where rules are missing.
+type census struct {
+	Pages   int           `json:"pages"`
+	Tags    []censusEntry `json:"tags"`
+	Classes []censusEntry `json:"classes"`
+}
+
+type censusCounter map[string]*censusEntry
+
+func (c censusCounter) add(name, page string, seen map[string]bool) {
+	e := c[name]
+	if e == nil {
+		e = &censusEntry{Name: name, Sample: page}
+		c[name] = e
+	}
+	e.Count++
+	if !seen[name] {
+		seen[name] = true
+		e.Pages++
+	}
+}
+
+// sorted returns the entries, most frequent first.
+func (c censusCounter) sorted() []censusEntry {
+	entries := make([]censusEntry, 0, len(c))
+	for _, e := range c {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// runCensus parses every HTML page of the zip, without converting them, and
+// writes the census of their tags and classes to reportPath as JSON.
+func runCensus(zipPath, reportPath string, cfg config) error {
+	r, closeInput, err := openInput(zipPath, cfg.maxInputBytes)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	var report census
+	tags, classes := make(censusCounter), make(censusCounter)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !isHTMLFile(f.Name) {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		page, _, err := decodeHTML(content, cfg.inputEncoding)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		doc, err := html.Parse(strings.NewReader(page))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", f.Name, err)
+		}
+		report.Pages++
+
+		seenTags, seenClasses := make(map[string]bool), make(map[string]bool)
+		var walk func(n *html.Node)
+		walk = func(n *html.Node) {
+			if n.Type == html.ElementNode {
+				tags.add(n.Data, f.Name, seenTags)
+				for _, attr := range n.Attr {
+					if attr.Key == "class" {
+						for _, class := range strings.Fields(attr.Val) {
+							classes.add(class, f.Name, seenClasses)
+						}
+					}
+				}
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(doc)
+	}
+	report.Tags, report.Classes = tags.sorted(), classes.sorted()
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(reportPath, append(out, '\n')); err != nil {
+		return fmt.Errorf("failed to write tag report: %w", err)
+	}
+	fmt.Printf("Wrote %d tag(s) and %d class(es) across %d page(s) to %s\n", len(report.Tags), len(report.Classes), report.Pages, reportPath)
+	return nil
+}
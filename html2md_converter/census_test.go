@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCensus(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "input.zip")
+	writeZip(t, zipPath, map[string]string{
+		"docs/a.html": `<p class="intro">One <code>x</code></p><p>Two <devsite-code>y</devsite-code></p>`,
+		"docs/b.html": `<aside class="note intro"><p>Three</p></aside>`,
+		"docs/c.md":   "Not parsed",
+	})
+	reportPath := filepath.Join(dir, "census.json")
+	if err := runCensus(zipPath, reportPath, testConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report census
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Pages != 2 {
+		t.Errorf("census of %d pages, want 2", report.Pages)
+	}
+	if first := report.Tags[0]; first != (censusEntry{Name: "p", Count: 3, Pages: 2, Sample: "docs/a.html"}) {
+		t.Errorf("most frequent tag is %+v, want p", first)
+	}
+	tags := make(map[string]censusEntry)
+	for _, e := range report.Tags {
+		tags[e.Name] = e
+	}
+	if e := tags["devsite-code"]; e != (censusEntry{Name: "devsite-code", Count: 1, Pages: 1, Sample: "docs/a.html"}) {
+		t.Errorf("devsite-code entry is %+v", e)
+	}
+	if e := tags["aside"]; e.Sample != "docs/b.html" {
+		t.Errorf("aside entry is %+v, want docs/b.html as sample", e)
+	}
+	want := []censusEntry{
+		{Name: "intro", Count: 2, Pages: 2, Sample: "docs/a.html"},
+		{Name: "note", Count: 1, Pages: 1, Sample: "docs/b.html"},
+	}
+	if len(report.Classes) != len(want) || report.Classes[0] != want[0] || report.Classes[1] != want[1] {
+		t.Errorf("classes are %+v, want %+v", report.Classes, want)
+	}
+}
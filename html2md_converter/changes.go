@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Forms of <ins> insertions accepted by -ins-style.
+const (
+	// <u>text</u>, which MDX passes through
+	insUnderline = "underline"
+	// <ins>text</ins>, kept as inline HTML
+	insTag = "ins"
+	// The text alone
+	insPlain = "plain"
+)
+
+// changeRules convert the change markers of diff-like docs: deletions
+// become ~~strikethrough~~ and insertions take the -ins-style form. The
+// content keeps its nested formatting.
+func changeRules(insStyle string) []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"del", "s", "strike"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				if selec.Parent().Is("del, s, strike") {
+					return &content
+				}
+				return md.String(emphasize(content, selec, "del", "~~"))
+			},
+		},
+		{
+			Filter: []string{"ins"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				switch {
+				case insStyle == insPlain || selec.Parent().Is("ins"):
+					return &content
+				case insStyle == insTag:
+					return md.String(wrapInTag(content, "ins"))
+				}
+				return md.String(wrapInTag(content, "u"))
+			},
+		},
+	}
+}
+
+// wrapInTag wraps content in an inline HTML tag, leaving the whitespace at
+// its edges outside, where markdown keeps it.
+func wrapInTag(content, tag string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return content
+	}
+	start := strings.Index(content, trimmed)
+	return content[:start] + "<" + tag + ">" + trimmed + "</" + tag + ">" + content[start+len(trimmed):]
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChangeMarkers(t *testing.T) {
+	tests := []struct {
+		insStyle, html, want string
+	}{
+		{insUnderline, `Run <del>old</del> <ins>new</ins>.`, "Run ~~old~~ <u>new</u>."},
+		{insUnderline, `<del>a <em>b</em></del> <ins>c <strong>d</strong></ins>`, "~~a _b_~~ <u>c **d**</u>"},
+		{insUnderline, `<s>gone</s><strike> too</strike>`, "~~gone~~ ~~too~~"},
+		{insUnderline, `a<del>“quote”</del>text`, "a<del>“quote”</del>text"},
+		{insTag, `<ins>new <em>text</em></ins>`, "<ins>new _text_</ins>"},
+		{insPlain, `<ins>new <em>text</em></ins>`, "new _text_"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.insStyle = tt.insStyle
+		got, err := newConverter(cfg).ConvertString("<p>" + tt.html + "</p>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got = strings.TrimSpace(restoreInterElementSpaces(got)); got != tt.want {
+			t.Errorf("-ins-style %s: %s converted to %q, want %q", tt.insStyle, tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// codeOptions configures the code-blocks rule set.
+type codeOptions struct {
+	// Languages renames detected languages, e.g. {py: python}.
+	Languages map[string]string `yaml:"languages"`
+	// DefaultLanguage tags blocks without a detected language; empty
+	// leaves their fence bare.
+	DefaultLanguage string `yaml:"default_language"`
+	// DiagramLanguage tags blocks without a detected language that look
+	// like ASCII diagrams, which some renderers show as quotes when the
+	// fence is bare; empty treats them like other blocks.
+	DiagramLanguage string `yaml:"diagram_language"`
+	// DropLineHighlights drops the data-highlight line ranges of blocks,
+	// for renderers without {2,4-6} highlight syntax on the fence.
+	DropLineHighlights bool `yaml:"drop_line_highlights"`
+}
+
+func (o codeOptions) language(pre *goquery.Selection, code string) string {
+	lang := codeLanguage(pre)
+	if lang == "" {
+		if o.DiagramLanguage != "" && isDiagram(code) {
+			return o.DiagramLanguage
+		}
+		return o.DefaultLanguage
+	}
+	if renamed, ok := o.Languages[lang]; ok {
+		return renamed
+	}
+	return lang
+}
+
+// A data-highlight line spec, e.g. 2,4-6
+var lineHighlightRegex = regexp.MustCompile(`^\d+(?:-\d+)?(?:,\d+(?:-\d+)?)*$`)
+
+// meta returns the text following the language on the opening fence of
+// block: the title, if any, then the lines to highlight from a
+// data-highlight attribute on the block, its <pre> or its <code>, as
+// Mintlify and Docusaurus write them: title="x" {2,4-6}.
+func (o codeOptions) meta(block *goquery.Selection, title string) string {
+	var meta []string
+	if title != "" {
+		meta = append(meta, title)
+	}
+	if !o.DropLineHighlights {
+		for _, s := range []*goquery.Selection{block, block.Find("pre").First(), block.Find("code").First()} {
+			if spec := strings.TrimSpace(s.AttrOr("data-highlight", "")); lineHighlightRegex.MatchString(spec) {
+				meta = append(meta, "{"+spec+"}")
+				break
+			}
+		}
+	}
+	return strings.Join(meta, " ")
+}
+
+// Corners of a box drawn in ASCII, e.g. +----+
+var asciiBoxRegex = regexp.MustCompile(`[+*][-=]{2,}[+*]`)
+
+// isDiagram reports whether code looks like a diagram: it uses box-drawing
+// characters, or draws at least two ASCII box edges.
+func isDiagram(code string) bool {
+	for _, r := range code {
+		if r >= 0x2500 && r <= 0x259F {
+			return true
+		}
+	}
+	edges := 0
+	for _, line := range strings.Split(code, "\n") {
+		if asciiBoxRegex.MatchString(line) {
+			edges++
+		}
+	}
+	return edges >= 2
+}
+
+// codePlugin converts <pre> blocks into fenced code with the language detected
+// from Devsite and Prettify markup, and <devsite-code> wrappers into fenced
+// code titled with their filename caption.
+func codePlugin(options codeOptions) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return codeRules(options)
+	}
+}
+
+func codeRules(options codeOptions) []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"pre"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				code := codeText(selec)
+				return md.String(fencedCode(code, options.language(selec, code), options.meta(selec, ""), opt))
+			},
+		},
+		{
+			Filter: []string{"devsite-code"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				pre := selec.Find("pre").First()
+				if pre.Length() == 0 {
+					return nil
+				}
+
+				title := ""
+				if filename := codeFilename(selec); filename != "" {
+					title = `title="` + strings.ReplaceAll(filename, `"`, `\"`) + `"`
+				}
+				code := codeText(pre)
+				return md.String(fencedCode(code, options.language(pre, code), options.meta(selec, title), opt))
+			},
+		},
+	}
+}
+
+// templateRule writes <template> elements, whose content browsers do not
+// show, as nothing, or with asCode as a fenced html block of their markup,
+// for pages that keep example markup in them.
+func templateRule(asCode bool) md.Rule {
+	return md.Rule{
+		Filter: []string{"template"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			if !asCode {
+				return md.String("")
+			}
+			markup, err := selec.Html()
+			if err != nil || strings.TrimSpace(markup) == "" {
+				return md.String("")
+			}
+			return md.String(fencedCode(dedent(strings.TrimLeft(strings.TrimRight(markup, " \t\n"), "\n")), "html", "", opt))
+		},
+	}
+}
+
+// dedent removes the indentation all non-blank lines of text share.
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	common, first := "", true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			common, first = indent, false
+		}
+		for !strings.HasPrefix(indent, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, common)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fencedCode renders a fenced code block. meta follows the language on the
+// opening fence; a language is required in front of it, so "text" is used
+// when none was detected.
+func fencedCode(code, language, meta string, opt *md.Options) string {
+	info := language
+	if meta != "" {
+		if info == "" {
+			info = "text"
+		}
+		info += " " + meta
+	}
+
+	fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+	if fenceChar == '`' && strings.Contains(info, "`") {
+		// A backtick fence cannot carry backticks in its info string
+		fenceChar = '~'
+	}
+	fence := md.CalculateCodeFence(fenceChar, code)
+	return "\n\n" + fence + info + "\n" + strings.TrimRight(code, "\n") + "\n" + fence + "\n\n"
+}
+
+// A fence opening a code block, after any blockquote markers and list
+// indentation, and its info string
+var fenceOpenRegex = regexp.MustCompile("^([ >]*)(`{3,}|~{3,})(.*)$")
+
+// normalizeCodeFences rewrites the fences of a page's code blocks, whichever
+// rule wrote them, as the shortest run of backticks that no line of the
+// block could close, rather than one longer than any run of backticks in
+// it. Blocks whose info string holds a backtick, which a backtick fence
+// cannot carry, are fenced with tildes instead.
+func normalizeCodeFences(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fenceOpenRegex.FindStringSubmatch(lines[i])
+		if m == nil || (m[2][0] == '`' && strings.Contains(m[3], "`")) {
+			continue
+		}
+		prefix, info := m[1], m[3]
+		end := i + 1
+		for end < len(lines) && fenceRun(lines[end], prefix, m[2][0]) < len(m[2]) {
+			end++
+		}
+		if end == len(lines) {
+			// An unclosed block runs to the end of the page; leave it be
+			break
+		}
+
+		char := byte('`')
+		if strings.Contains(info, "`") {
+			char = '~'
+		}
+		length := 3
+		for _, line := range lines[i+1 : end] {
+			if run := fenceRun(line, prefix, char); run >= length {
+				length = run + 1
+			}
+		}
+		fence := strings.Repeat(string(char), length)
+		lines[i] = prefix + fence + info
+		lines[end] = prefix + fence
+		i = end
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fenceRun returns the length of the run of char making up line after
+// prefix, which is what a closing fence looks like, or 0 when the line is
+// anything else.
+func fenceRun(line, prefix string, char byte) int {
+	rest, ok := strings.CutPrefix(line, prefix)
+	if !ok {
+		return 0
+	}
+	rest = strings.TrimRight(strings.TrimLeft(rest, " "), " ")
+	if rest == "" || strings.Trim(rest, string(char)) != "" {
+		return 0
+	}
+	return len(rest)
+}
+
+// codeLanguage detects the language of a <pre> from the markup Devsite and
+// Prettify use: a language/syntax attribute or a lang-x/language-x class on
+// the <pre> or its <code>.
+func codeLanguage(pre *goquery.Selection) string {
+	for _, s := range []*goquery.Selection{pre, pre.ChildrenFiltered("code").First()} {
+		for _, attr := range []string{"data-language", "language", "syntax", "lang"} {
+			if lang := strings.TrimSpace(s.AttrOr(attr, "")); lang != "" {
+				return strings.ToLower(lang)
+			}
+		}
+		for _, class := range strings.Fields(s.AttrOr("class", "")) {
+			for _, prefix := range []string{"lang-", "language-"} {
+				if lang := strings.TrimPrefix(class, prefix); lang != class && lang != "" {
+					return strings.ToLower(lang)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// codeFilename returns the filename caption of a <devsite-code>, given either
+// as an attribute or as a caption element inside it.
+func codeFilename(s *goquery.Selection) string {
+	for _, attr := range []string{"data-filename", "filename", "data-title", "title"} {
+		if name := strings.TrimSpace(s.AttrOr(attr, "")); name != "" {
+			return name
+		}
+	}
+	caption := s.Find("figcaption, .devsite-code-filename, .filename, .code-filename").First()
+	return collapseWhitespace(caption.Text())
+}
+
+// codeText returns the text of a code block, turning <br> and <div> into line
+// breaks like the default <pre> rule does.
+func codeText(pre *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.ElementNode && (n.Data == "style" || n.Data == "script" || n.Data == "textarea"):
+			return
+		case n.Type == html.ElementNode && (n.Data == "br" || n.Data == "div"):
+			b.WriteString("\n")
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range pre.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDevsiteCode(t *testing.T) {
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<devsite-code><figcaption>MODULE.bazel</figcaption><pre class="lang-py">bazel_dep(name = "rules_go")</pre></devsite-code>
+<devsite-code data-filename="BUILD"><pre>cc_library(name = "x")</pre></devsite-code>
+<pre><code class="language-shell">bazel build //...</code></pre>`,
+	})["page.md"]
+
+	for _, want := range []string{
+		"```py title=\"MODULE.bazel\"\nbazel_dep(name = \"rules_go\")\n```",
+		"```text title=\"BUILD\"\ncc_library(name = \"x\")\n```",
+		"```shell\nbazel build //...\n```",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks\n%s\nin\n%s", want, page)
+		}
+	}
+	if strings.Count(page, "MODULE.bazel") != 1 {
+		t.Errorf("caption repeated outside the title:\n%s", page)
+	}
+}
+
+func TestDiagramLanguage(t *testing.T) {
+	html := "<pre>+------+     +------+\n| repo | --> | deps |\n+------+     +------+</pre>\n" +
+		"<pre>┌───┐\n│ x │\n└───┘</pre>\n" +
+		"<pre>a = 1 + 2</pre>"
+	tests := []struct {
+		name, config string
+		want         []string
+	}{
+		{"defaults", "", []string{
+			"```text\n+------+     +------+\n| repo | --> | deps |\n+------+     +------+\n```",
+			"```text\n┌───┐\n│ x │\n└───┘\n```",
+			"```\na = 1 + 2\n```",
+		}},
+		{"customized", "rules:\n  code-blocks:\n    diagram_language: ascii\n    default_language: sh\n", []string{
+			"```ascii\n+------+",
+			"```ascii\n┌───┐",
+			"```sh\na = 1 + 2\n```",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		if tt.config != "" {
+			ruleSets, _, err := loadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.ruleSets = ruleSets
+		}
+		page := convertPages(t, cfg, map[string]string{"page.html": "<h1>Page</h1>" + html})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page lacks\n%s\nin\n%s", tt.name, want, page)
+			}
+		}
+	}
+}
+
+func TestNormalizeCodeFences(t *testing.T) {
+	tests := []struct{ name, html, want string }{
+		{"nested fence", "<pre>```python\nprint(1)\n```</pre>", "````\n```python\nprint(1)\n```\n````"},
+		{"inline backticks", "<pre>say `hi` and ``` inline</pre>", "```\nsay `hi` and ``` inline\n```"},
+		{"longer nested fence", "<pre>````\n```\n````</pre>", "`````\n````\n```\n````\n`````"},
+		{"backtick in the title", "<devsite-code><figcaption>The `x` rule</figcaption><pre>x()</pre></devsite-code>", "~~~text title=\"The `x` rule\"\nx()\n~~~"},
+		{"in a list", "<ul><li><p>Item</p><pre>```\nx\n```</pre></li></ul>", "- Item\n\n  ````\n  ```\n  x\n  ```\n  ````"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.normalizeCodeFences = true
+		cfg.noFrontmatter = true
+		page := convertPages(t, cfg, map[string]string{"page.html": "<h1>Page</h1>" + tt.html})["page.md"]
+		if got := strings.TrimSpace(strings.TrimPrefix(page, "# Page\n")); got != tt.want {
+			t.Errorf("%s: converted to\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLineHighlights(t *testing.T) {
+	html := `<devsite-code data-filename="BUILD"><pre class="lang-py" data-highlight="1,3-4">a
+b
+c
+d</pre></devsite-code>
+<pre><code class="language-shell" data-highlight="2">bazel build
+bazel test</code></pre>`
+	tests := []struct {
+		name, config string
+		want         []string
+	}{
+		{"kept", "", []string{"```py title=\"BUILD\" {1,3-4}\n", "```shell {2}\n"}},
+		{"dropped", "rules:\n  code-blocks:\n    drop_line_highlights: true\n", []string{"```py title=\"BUILD\"\n", "```shell\n"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		if tt.config != "" {
+			ruleSets, _, err := loadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.ruleSets = ruleSets
+		}
+		page := convertPages(t, cfg, map[string]string{"page.html": "<h1>Page</h1>" + html})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page lacks\n%s\nin\n%s", tt.name, want, page)
+			}
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	html := `<h1>Page</h1><p>Before</p>
+<template id="row">
+  <tr>
+    <td class="name">Name &amp; value</td>
+  </tr>
+</template>
+<p>After</p>`
+	tests := []struct {
+		asCode bool
+		want   string
+	}{
+		{false, "Before\n\nAfter"},
+		{true, "Before\n\n```html\n<tr>\n  <td class=\"name\">Name &amp; value</td>\n</tr>\n```\n\nAfter"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.templateAsCode = tt.asCode
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("-template-as-code=%v: page lacks\n%s\nin\n%s", tt.asCode, tt.want, page)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// componentStyle writes the components that rules emit, such as callouts and
+// accordions, in the syntax of the site generator the output is for. Each
+// method returns a markdown block surrounded by blank lines.
+type componentStyle interface {
+	// callout writes body in a callout of the given kind, named after the
+	// Mintlify component: Note, Tip, Info, Warning, Check, ...
+	callout(kind, body string) string
+	accordion(title string, open bool, body string) string
+	// accordionGroup writes accordions that belong together, such as the
+	// questions of an FAQ.
+	accordionGroup(accordions []string) string
+	// columns writes the blocks side by side, where the style can.
+	columns(blocks []string) string
+	// tabs writes alternatives of which a reader picks one, where the
+	// style can.
+	tabs(tabs []tab) string
+	// codeGroup writes tabs that each hold one code block, in the given
+	// language, as a group of titled code blocks.
+	codeGroup(tabs []tab) string
+}
+
+// calloutKinds are the kinds every style can write.
+var calloutKinds = []string{"Note", "Tip", "Info", "Warning", "Check", "Danger"}
+
+var componentStyles = map[string]componentStyle{
+	"mintlify":   mintlifyComponents{},
+	"docusaurus": docusaurusComponents{},
+}
+
+type mintlifyComponents struct{}
+
+func (mintlifyComponents) callout(kind, body string) string {
+	return "\n\n<" + kind + ">\n\n" + body + "\n\n</" + kind + ">\n\n"
+}
+
+func (mintlifyComponents) accordion(title string, open bool, body string) string {
+	attrs := ` title="` + jsxAttrEscaper.Replace(title) + `"`
+	if open {
+		attrs += " defaultOpen"
+	}
+	return "\n\n<Accordion" + attrs + ">" + blockBody(body) + "</Accordion>\n\n"
+}
+
+func (mintlifyComponents) accordionGroup(accordions []string) string {
+	var b strings.Builder
+	b.WriteString("\n\n<AccordionGroup>\n")
+	for _, accordion := range accordions {
+		b.WriteString(strings.TrimSpace(accordion) + "\n")
+	}
+	b.WriteString("</AccordionGroup>\n\n")
+	return b.String()
+}
+
+func (mintlifyComponents) columns(blocks []string) string {
+	var b strings.Builder
+	b.WriteString("\n\n<Columns cols={" + strconv.Itoa(len(blocks)) + "}>\n")
+	for _, block := range blocks {
+		b.WriteString("<div>" + blockBody(block) + "</div>\n")
+	}
+	b.WriteString("</Columns>\n\n")
+	return b.String()
+}
+
+func (mintlifyComponents) tabs(tabs []tab) string {
+	var b strings.Builder
+	b.WriteString("\n\n<Tabs>\n")
+	for _, t := range tabs {
+		b.WriteString(`<Tab title="` + jsxAttrEscaper.Replace(t.title) + `">` + blockBody(t.body) + "</Tab>\n")
+	}
+	b.WriteString("</Tabs>\n\n")
+	return b.String()
+}
+
+// Mintlify titles each code block of a <CodeGroup> with the text after its
+// language.
+func (mintlifyComponents) codeGroup(tabs []tab) string {
+	blocks := make([]string, len(tabs))
+	for i, t := range tabs {
+		blocks[i] = retitleFence(t.body, t.title)
+	}
+	return "\n\n<CodeGroup>\n\n" + strings.Join(blocks, "\n\n") + "\n\n</CodeGroup>\n\n"
+}
+
+// docusaurusComponents uses :::note admonitions and <details>, which
+// Docusaurus renders as a collapsible.
+type docusaurusComponents struct{}
+
+// docusaurusAdmonitions maps callout kinds to admonition types; others
+// become notes.
+var docusaurusAdmonitions = map[string]string{
+	"Note":    "note",
+	"Tip":     "tip",
+	"Check":   "tip",
+	"Info":    "info",
+	"Warning": "warning",
+	"Danger":  "danger",
+}
+
+func (docusaurusComponents) callout(kind, body string) string {
+	admonition, ok := docusaurusAdmonitions[kind]
+	if !ok {
+		admonition = "note"
+	}
+	// A longer fence lets admonitions nest inside the body
+	fence := ":::"
+	for strings.Contains(body, fence) {
+		fence += ":"
+	}
+	return "\n\n" + fence + admonition + "\n\n" + body + "\n\n" + fence + "\n\n"
+}
+
+func (docusaurusComponents) accordion(title string, open bool, body string) string {
+	tag := "<details>"
+	if open {
+		tag = "<details open>"
+	}
+	return "\n\n" + tag + "\n<summary>" + jsxAttrEscaper.Replace(title) + "</summary>" + blockBody(body) + "</details>\n\n"
+}
+
+// Docusaurus has no accordion groups, so the <details> follow one another.
+func (docusaurusComponents) accordionGroup(accordions []string) string {
+	return stackBlocks(accordions)
+}
+
+// Docusaurus has no columns component, so the blocks are stacked.
+func (docusaurusComponents) columns(blocks []string) string {
+	return stackBlocks(blocks)
+}
+
+// Docusaurus tabs need imports, so each tab becomes a bold title followed
+// by its content.
+func (docusaurusComponents) tabs(tabs []tab) string {
+	blocks := make([]string, len(tabs))
+	for i, t := range tabs {
+		blocks[i] = "**" + t.title + "**\n\n" + t.body
+	}
+	return stackBlocks(blocks)
+}
+
+// Docusaurus code blocks are titled with a title attribute; each is one
+// after another, as it has no code groups without imports.
+func (docusaurusComponents) codeGroup(tabs []tab) string {
+	blocks := make([]string, len(tabs))
+	for i, t := range tabs {
+		blocks[i] = retitleFence(t.body, `title="`+strings.ReplaceAll(t.title, `"`, `\"`)+`"`)
+	}
+	return stackBlocks(blocks)
+}
+
+// stackBlocks writes the blocks one after another.
+func stackBlocks(blocks []string) string {
+	return "\n\n" + strings.Join(blocks, "\n\n") + "\n\n"
+}
+
+// blockBody puts body between blank lines, or returns a single blank line
+// for an empty body.
+func blockBody(body string) string {
+	if body = strings.TrimSpace(body); body == "" {
+		return "\n\n"
+	}
+	return "\n\n" + body + "\n\n"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestComponentStyles converts callouts and accordions, including a nested
+// callout, in each -component-style.
+func TestComponentStyles(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<aside class="warning"><p>Careful.</p><aside class="note"><p>Nested.</p></aside></aside>
+<details open><summary>Question "one"</summary><p>Answer.</p></details>`,
+	}
+	tests := []struct {
+		style string
+		want  []string
+	}{
+		{"mintlify", []string{
+			"<Warning>\n\nCareful.\n\n<Note>\n\nNested.\n\n</Note>\n\n</Warning>",
+			`<Accordion title="Question &quot;one&quot;" defaultOpen>` + "\n\nAnswer.\n\n</Accordion>",
+		}},
+		{"docusaurus", []string{
+			"::::warning\n\nCareful.\n\n:::note\n\nNested.\n\n:::\n\n::::",
+			"<details open>\n<summary>Question &quot;one&quot;</summary>\n\nAnswer.\n\n</details>",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.components = componentStyles[tt.style]
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page does not contain\n%s\ngot\n%s", tt.style, want, page)
+			}
+		}
+	}
+}
+
+// TestAccordionGroup checks that a run of sibling <details> becomes one
+// group, which Docusaurus writes as <details> one after another, and that
+// a lone <details> stays a single accordion.
+func TestAccordionGroup(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>FAQ</h1>
+<details><summary>One</summary><p>A.</p></details>
+<!-- separator -->
+<details><summary>Two</summary><p>B.</p></details>
+<p>Between</p>
+<details><summary>Three</summary><p>C.</p></details>`,
+	}
+	tests := []struct{ style, want string }{
+		{"mintlify", "<AccordionGroup>\n<Accordion title=\"One\">\n\nA.\n\n</Accordion>\n<Accordion title=\"Two\">\n\nB.\n\n</Accordion>\n</AccordionGroup>\n\nBetween\n\n<Accordion title=\"Three\">\n\nC.\n\n</Accordion>"},
+		{"docusaurus", "<details>\n<summary>One</summary>\n\nA.\n\n</details>\n\n<details>\n<summary>Two</summary>\n\nB.\n\n</details>\n\nBetween\n\n<details>\n<summary>Three</summary>\n\nC.\n\n</details>"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.components = componentStyles[tt.style]
+		page := convertPages(t, cfg, pages)["page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("%s: page is\n%s\nwant it to contain\n%s", tt.style, page, tt.want)
+		}
+	}
+}
+
+func TestBlockquoteCallouts(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Page</h1>
+<blockquote class="warning"><p>Careful.</p></blockquote>
+<blockquote><p>Quoted.</p></blockquote>
+<blockquote class="pullquote"><p>Pulled.</p></blockquote>`,
+	}
+	page := convertPages(t, testConfig(t), pages)["page.md"]
+	for _, want := range []string{"<Warning>\n\nCareful.\n\n</Warning>", "> Quoted.", "> Pulled."} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain\n%s\ngot\n%s", want, page)
+		}
+	}
+}
+
+// TestCodeGroupStyles checks the code-tabs golden page with Docusaurus
+// components, where the blocks follow one another with their titles.
+func TestCodeGroupStyles(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "golden", "code-tabs.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t)
+	cfg.components = componentStyles["docusaurus"]
+	page := convertPages(t, cfg, map[string]string{"page.html": string(input)})["page.md"]
+	for _, want := range []string{"```java title=\"Java\"\n", "```cpp title=\"C++\"\n"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain\n%s\ngot\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "CodeGroup") || strings.Contains(page, "<Tab") {
+		t.Errorf("page has components:\n%s", page)
+	}
+}
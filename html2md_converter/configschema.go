@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonSchema is the part of JSON Schema that describes the config file.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Enum        []interface{}          `json:"enum,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	// AdditionalProperties is false, or the schema of values under keys
+	// that Properties does not list.
+	AdditionalProperties interface{}   `json:"additionalProperties,omitempty"`
+	AnyOf                []*jsonSchema `json:"anyOf,omitempty"`
+	// Items is the schema of the elements of an array.
+	Items *jsonSchema `json:"items,omitempty"`
+	// Required are the keys an object must have.
+	Required []string `json:"required,omitempty"`
+}
+
+// stringEnum returns the enum of a schema admitting the given strings.
+func stringEnum(values ...string) []interface{} {
+	enum := make([]interface{}, len(values))
+	for i, v := range values {
+		enum[i] = v
+	}
+	return enum
+}
+
+// closedObject is an object schema that admits only the given properties.
+func closedObject(description string, properties map[string]*jsonSchema) *jsonSchema {
+	return &jsonSchema{Description: description, Type: "object", Properties: properties, AdditionalProperties: false}
+}
+
+// configSchema returns the schema of the -config file, with each rule set
+// either false or a mapping of its options.
+func configSchema() *jsonSchema {
+	rules := make(map[string]*jsonSchema, len(ruleSets))
+	for _, set := range ruleSets {
+		toggle := &jsonSchema{Type: "boolean", Description: "false turns the rule set off"}
+		if set.schema == nil {
+			rules[set.name] = toggle
+			continue
+		}
+		rules[set.name] = &jsonSchema{AnyOf: []*jsonSchema{toggle, set.schema}}
+	}
+	selector := &jsonSchema{Type: "string"}
+	schema := closedObject("Config file of html-to-md, passed with -config.", map[string]*jsonSchema{
+		"rules": closedObject("Rule sets to turn off or configure.", rules),
+		"before_convert": {
+			Type:        "array",
+			Description: "HTML transforms applied to each page, in order, before conversion.",
+			Items: closedObject("A step doing one of remove, unwrap or rename.", map[string]*jsonSchema{
+				"remove": {Type: "array", Description: "Selectors of elements to drop with their content.", Items: selector},
+				"unwrap": {Type: "array", Description: "Selectors of elements to replace with their content.", Items: selector},
+				"rename": {Type: "object", Description: "New tag names of the elements matching each selector.", AdditionalProperties: &jsonSchema{Type: "string"}},
+			}),
+		},
+	})
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	return schema
+}
+
+func printConfigSchema() error {
+	out, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// validate checks a value decoded from YAML against the schema and returns
+// a problem for each mismatch, prefixed with the path to the offending value.
+func (s *jsonSchema) validate(value interface{}, path string) []string {
+	if len(s.AnyOf) > 0 {
+		// Report the problems of the alternative of the value's type
+		var problems []string
+		var types []string
+		for _, alt := range s.AnyOf {
+			p := alt.validate(value, path)
+			if len(p) == 0 {
+				return nil
+			}
+			if alt.Type == yamlType(value) {
+				problems = p
+			}
+			types = append(types, alt.Type)
+		}
+		if problems == nil {
+			problems = []string{fmt.Sprintf("%s: must be %s, not %s", path, strings.Join(types, " or "), yamlType(value))}
+		}
+		return problems
+	}
+
+	// Integers are numbers too
+	if t := yamlType(value); s.Type != "" && t != s.Type && !(s.Type == "number" && t == "integer") {
+		return []string{fmt.Sprintf("%s: must be %s, not %s", path, s.Type, t)}
+	}
+	if s.Enum != nil {
+		for _, allowed := range s.Enum {
+			if sameValue(value, allowed) {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: %s is not one of %s", path, quoteValue(value), quoteList(s.Enum))}
+	}
+
+	if list, ok := value.([]interface{}); ok && s.Items != nil {
+		var problems []string
+		for i, item := range list {
+			problems = append(problems, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	}
+
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range s.Required {
+		if _, ok := m[key]; !ok {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			problems = append(problems, fmt.Sprintf("%s: required key is missing", keyPath))
+		}
+	}
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if prop, ok := s.Properties[key]; ok {
+			problems = append(problems, prop.validate(m[key], keyPath)...)
+			continue
+		}
+		switch extra := s.AdditionalProperties.(type) {
+		case bool:
+			if extra {
+				continue
+			}
+			known := make([]string, 0, len(s.Properties))
+			for name := range s.Properties {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			problems = append(problems, fmt.Sprintf("%s: unknown key, expected one of %s", keyPath, strings.Join(known, ", ")))
+		case *jsonSchema:
+			problems = append(problems, extra.validate(m[key], keyPath)...)
+		}
+	}
+	return problems
+}
+
+// yamlType returns the JSON Schema type of a value decoded by yaml.v2.
+func yamlType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[interface{}]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// sameValue reports whether a value decoded from YAML equals an enum
+// value, which may have been decoded from JSON, where every number is a
+// float64.
+func sameValue(value, allowed interface{}) bool {
+	if a, ok := numberValue(value); ok {
+		b, ok := numberValue(allowed)
+		return ok && a == b
+	}
+	return reflect.DeepEqual(jsonValue(value), allowed)
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// quoteValue writes a value as a schema problem quotes it: strings in
+// quotes, other values as they are.
+func quoteValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(value)
+}
+
+func quoteList(values []interface{}) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteValue(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files of TestGolden with the current output")
+
+// testConfig returns the config of a run with the default flags; keep it in
+// step with main.
+func testConfig(t *testing.T) config {
+	t.Helper()
+	ruleSets, transforms, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config{
+		assetsDir:              "assets",
+		anchors:                anchorStrategies["mintlify"],
+		outputCase:             casePreserve,
+		math:                   mathOff,
+		trailingSlash:          slashKeep,
+		preserveMtime:          true,
+		devsiteConditions:      conditionsTrue,
+		dynamicBranch:          dynamicPublic,
+		strict:                 make(map[string]bool),
+		bannerStyle:            bannerComment,
+		stripQuery:             true,
+		components:             componentStyles["mintlify"],
+		pageNavSelector:        defaultPageNavSelector,
+		postHookTimeout:        30e9,
+		quoteStyle:             quotesCurly,
+		varStyle:               varItalic,
+		keepCodeTemplates:      true,
+		manifestFormat:         manifestJSON,
+		footerDateKey:          "last_updated",
+		checkThreshold:         0.02,
+		maxInputBytes:          1024 << 20,
+		insStyle:               insUnderline,
+		embedMode:              embedIframe,
+		sourceLinkStyle:        sourceLinkFrontmatter,
+		protocolRelativeScheme: "https",
+		dataAttrs:              dataAttrFilter{strip: true, keep: parseTagList("data-lang")},
+		hgroupSubtitle:         hgroupDescription,
+		gaugeStyle:             gaugePercent,
+		rawHTMLAllowed:         parseTagList("br"),
+		frontmatterOrder:       parseKeyList("title,description"),
+		sampleSeed:             1,
+		longPageBytes:          100000,
+		longPageWords:          10000,
+		htmlParser:             parserLenient,
+		listBullet:             "-",
+		listNumbering:          numberingSequential,
+		strayListContent:       strayAttach,
+		emoji:                  emojiOff,
+		outputEncoding:         outputUTF8,
+		ruleSets:               ruleSets,
+		transforms:             transforms,
+	}
+}
+
+// convertPages converts a zip of the pages, keyed by path, in the order of
+// their paths, and returns the files written, keyed by their path under the
+// output directory.
+func convertPages(t *testing.T, cfg config, pages map[string]string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "input.zip")
+	writeZip(t, zipPath, pages)
+
+	outputDir := filepath.Join(dir, "output")
+	if err := convertZipToMarkdown(zipPath, outputDir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	return readTree(t, outputDir)
+}
+
+// writeZip writes a zip of the files, keyed by path, in the order of their
+// paths.
+func writeZip(t *testing.T, zipPath string, files map[string]string) {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	w := zip.NewWriter(out)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTree returns the files under dir, keyed by their slash-separated path
+// relative to it.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestConverterConcurrent converts varied pages with one converter shared
+// by many goroutines, as newConverter allows, and checks that each result
+// and the rule counts match a serial run. Run it with -race.
+func TestConverterConcurrent(t *testing.T) {
+	cfg := testConfig(t)
+	ruleSets, err := enableRuleSets(configFile{Rules: map[string]interface{}{
+		"signatures":     map[string]interface{}{"selector": "table.signature"},
+		"faq-accordions": map[string]interface{}{"selector": "dl.faq"},
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ruleSets = ruleSets
+	cfg.math = mathKaTeX
+	cfg.externalNewTab = true
+	cfg.maxHeadingDepth = 3
+	cfg.preserveHeadingIDs = true
+	cfg.paramFields = true
+	cfg.ruleCounts = &ruleCounts{counts: make(map[string]int)}
+
+	inputs := []string{
+		`<h2 id="x">Heading</h2><p>Text with <em>emphasis</em>, <strong>bold</strong> and <a href="https://bazel.build">a link</a>.</p>`,
+		`<h5>Deep heading</h5><ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul>`,
+		`<p>Inline <math><mfrac><mn>1</mn><mn>2</mn></mfrac></math> and $x^2$.</p>`,
+		`<aside class="warning"><p>Careful <code>rm -rf</code>.</p></aside>`,
+		`<details><summary>Q</summary><p>A</p></details><details><summary>Q2</summary><p>A2</p></details>`,
+		`<pre class="lang-python">print("hi")</pre>`,
+		`<table><thead><tr><th>A</th></tr></thead><tbody><tr><td>1</td></tr></tbody></table>`,
+		`<dl><dt>Term</dt><dd>Definition</dd></dl>`,
+		`<picture><source srcset="a.webp" type="image/webp"><img src="a.png" alt="A"></picture>`,
+	}
+	matches, err := filepath.Glob(filepath.Join("testdata", "golden", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range matches {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(content))
+	}
+
+	converter := newConverter(cfg)
+	want := make([]string, len(inputs))
+	for i, input := range inputs {
+		if want[i], err = converter.ConvertString(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts := cfg.ruleCounts.counts
+	if len(counts) == 0 {
+		t.Fatal("no rules counted")
+	}
+	cfg.ruleCounts.counts = make(map[string]int)
+
+	const goroutines, rounds = 16, 8
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for j := range inputs {
+					// Vary the order the goroutines convert in
+					i := (j + g + r) % len(inputs)
+					got, err := converter.ConvertString(inputs[i])
+					if err != nil {
+						errs <- err
+						return
+					}
+					if got != want[i] {
+						t.Errorf("input %d converted concurrently to\n%s\nwant\n%s", i, got, want[i])
+						return
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	for name, n := range counts {
+		if got := cfg.ruleCounts.counts[name]; got != n*goroutines*rounds {
+			t.Errorf("rules %s counted %d times, want %d", name, got, n*goroutines*rounds)
+		}
+	}
+	if len(cfg.ruleCounts.counts) != len(counts) {
+		t.Errorf("rules counted concurrently: %v, want those of %v", cfg.ruleCounts.counts, counts)
+	}
+}
+
+// goldenCases are the pages of testdata/golden. Each NAME.html converts to
+// NAME.md with the default flags, changed by configure, and the rule sets
+// configured by NAME.yaml if there is one.
+var goldenCases = []struct {
+	name      string
+	configure func(*config)
+}{
+	{name: "captioned-table"},
+	{name: "glossary"},
+	{name: "nested-details"},
+	{name: "list-paragraphs"},
+	{name: "param-fields", configure: func(cfg *config) { cfg.paramFields = true }},
+	{name: "signature-grid"},
+	{name: "faq-accordion-group"},
+	{name: "setup-tabs"},
+	{name: "restarted-sublist"},
+	{name: "code-tabs"},
+	{name: "highlighted-code"},
+	{name: "callout-table"},
+	{name: "faq-dl"},
+}
+
+// TestGolden converts the pages of testdata/golden and compares them with
+// their golden output; -update rewrites it.
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			base := filepath.Join("testdata", "golden", tc.name)
+			input, err := os.ReadFile(base + ".html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := testConfig(t)
+			if _, err := os.Stat(base + ".yaml"); err == nil {
+				if cfg.ruleSets, cfg.transforms, err = loadConfig(base + ".yaml"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.configure != nil {
+				tc.configure(&cfg)
+			}
+
+			got := convertPages(t, cfg, map[string]string{tc.name + ".html": string(input)})[tc.name+".md"]
+			checkGolden(t, base+".md", got)
+		})
+	}
+}
+
+// checkGolden compares got with the golden file at path, or with -update
+// rewrites the file.
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from the golden file:\n%s", unifiedDiff(path, "output", string(want), got))
+	}
+}
+
+// captureStdout returns what f prints, such as the converter's warnings.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(r)
+		done <- string(content)
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// definitionListPlugin converts <dl> lists, such as the glossary, into a
+// bold term per <dt> followed by its <dd> definition. Each term keeps an
+// explicit anchor with its id so that other pages can deep-link to it, e.g.
+// glossary#workspace; assignTermIDs gives every term an id beforehand.
+// The defining instance of a term in prose, <dfn>, is written the same
+// way, an anchored bold term.
+func definitionListPlugin(conv *md.Converter) []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"dt"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				term := collapseWhitespace(content)
+				if term == "" {
+					return md.String("")
+				}
+
+				anchor := ""
+				if id := selec.AttrOr("id", ""); id != "" {
+					anchor = `<a id="` + jsxAttrEscaper.Replace(id) + `"></a>`
+				}
+				return md.String("\n\n" + anchor + opt.StrongDelimiter + term + opt.StrongDelimiter + "\n\n")
+			},
+		},
+		{
+			Filter: []string{"dfn"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				term := strings.TrimSpace(content)
+				// A term's <dt> is already bold and anchored
+				if term == "" || selec.ParentsFiltered("dt").Length() > 0 {
+					return &content
+				}
+
+				anchor := ""
+				if id := selec.AttrOr("id", ""); id != "" {
+					anchor = `<a id="` + jsxAttrEscaper.Replace(id) + `"></a>`
+				}
+				return md.String(anchor + opt.StrongDelimiter + term + opt.StrongDelimiter)
+			},
+		},
+		{
+			Filter: []string{"dd"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				return md.String("\n\n" + strings.TrimSpace(content) + "\n\n")
+			},
+		},
+		{
+			Filter: []string{"dl"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				return md.String("\n\n" + strings.TrimSpace(content) + "\n\n")
+			},
+		},
+	}
+}
+
+// faqOptions configures the faq-accordions rule set, which writes FAQs laid
+// out as definition lists, a question per <dt> and its answer in the <dd>
+// after it, as a group of accordions titled by the questions.
+type faqOptions struct {
+	// Selector matches the FAQ lists; empty turns the rule set off.
+	Selector string `yaml:"selector"`
+}
+
+func faqPlugin(options faqOptions, style componentStyle) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		if options.Selector == "" {
+			return nil
+		}
+		return []md.Rule{
+			{
+				Filter: []string{"dl"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if !selec.Is(options.Selector) {
+						return nil
+					}
+					accordions := faqAccordions(conv, selec, style)
+					if accordions == nil {
+						// Fall back to the definition-lists rules
+						return nil
+					}
+					return md.String(style.accordionGroup(accordions))
+				},
+			},
+		}
+	}
+}
+
+// faqAccordions converts each question of the list and the answers after
+// it into an accordion, with the question's id as an explicit anchor at the
+// start of the answer. It returns nil when the list has no answered
+// question.
+func faqAccordions(conv *md.Converter, dl *goquery.Selection, style componentStyle) []string {
+	var accordions []string
+	title, anchor := "", ""
+	var answers []string
+	flush := func() {
+		if title != "" && len(answers) > 0 {
+			accordions = append(accordions, style.accordion(title, false, anchor+strings.Join(answers, "\n\n")))
+		}
+		answers = nil
+	}
+	dl.ChildrenFiltered("dt, dd, div").Each(func(i int, item *goquery.Selection) {
+		// Items may be grouped in a <div> each
+		item.ChildrenFiltered("dt, dd").AddSelection(item.Filter("dt, dd")).Each(func(i int, s *goquery.Selection) {
+			if goquery.NodeName(s) == "dt" {
+				flush()
+				title = collapseWhitespace(s.Text())
+				anchor = ""
+				if id := s.AttrOr("id", ""); id != "" {
+					anchor = `<a id="` + jsxAttrEscaper.Replace(id) + `"></a>` + "\n\n"
+				}
+				return
+			}
+			if answer := strings.TrimSpace(conv.Convert(s)); answer != "" {
+				answers = append(answers, answer)
+			}
+		})
+	})
+	flush()
+	return accordions
+}
+
+// assignTermIDs gives each <dt>, and each <dfn> outside one, without an id
+// one slugged from its text. The slugs share the page's anchor set with the
+// headings, so a term never takes an anchor a heading already produces.
+func assignTermIDs(doc *goquery.Document, cfg config) {
+	terms := doc.Find("dt, dfn").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return goquery.NodeName(s) == "dt" || s.ParentsFiltered("dt").Length() == 0
+	})
+	if terms.Length() == 0 {
+		return
+	}
+
+	anchors := newAnchorSet(cfg.anchors)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		if cfg.maxHeadingDepth == 0 || headingLevel(goquery.NodeName(s)) <= cfg.maxHeadingDepth {
+			anchors.add(s.Text())
+		}
+	})
+	terms.Each(func(i int, dt *goquery.Selection) {
+		if id, ok := dt.Attr("id"); ok && id != "" {
+			return
+		}
+		if slug := anchors.add(dt.Text()); slug != "" {
+			dt.SetAttr("id", slug)
+		}
+	})
+}
+
+// Attributes of the elements markParamFields puts in place of each entry of
+// an attribute list; attrParam holds the attribute's name.
+const (
+	attrParam         = "data-html2md-param"
+	attrParamType     = "data-html2md-param-type"
+	attrParamDefault  = "data-html2md-param-default"
+	attrParamRequired = "data-html2md-param-required"
+)
+
+var (
+	// The name of an attribute, followed by an optional "(type)"
+	paramTermRegex = regexp.MustCompile(`^([A-Za-z_][\w.-]*)(?:\s*\(([^()]+)\))?$`)
+	// "List of labels; default is []", "Name; required"
+	paramInfoRegex = regexp.MustCompile(`(?i)^(.+?);\s*(?:(required|mandatory)|(?:default(?: value)? is|defaults to|default:)\s*(.+?))\.?$`)
+)
+
+// markParamFields finds definition lists of rule and function attributes, in
+// which every term is a <code> name, and replaces each term and its
+// definitions with an element that paramFieldRule writes as a Mintlify
+// <ParamField>. A leading "type; default is value" or "type; required"
+// paragraph of the definition gives the field its type and default. Other
+// lists are left to the definition-lists rules.
+func markParamFields(doc *goquery.Document) {
+	doc.Find("dl").Each(func(i int, dl *goquery.Selection) {
+		terms := dl.ChildrenFiltered("dt")
+		if terms.Length() == 0 || dl.Children().First().Is("dd") {
+			return
+		}
+		identified := true
+		terms.EachWithBreak(func(i int, dt *goquery.Selection) bool {
+			first := dt.Children().First()
+			identified = first.Is("code") && strings.HasPrefix(strings.TrimSpace(dt.Text()), strings.TrimSpace(first.Text())) &&
+				paramTermRegex.MatchString(collapseWhitespace(dt.Text()))
+			return identified
+		})
+		if !identified {
+			return
+		}
+
+		terms.Each(func(i int, dt *goquery.Selection) {
+			m := paramTermRegex.FindStringSubmatch(collapseWhitespace(dt.Text()))
+			field := &html.Node{Type: html.ElementNode, Data: "div", Attr: []html.Attribute{{Key: attrParam, Val: m[1]}}}
+			setAttr := func(key, val string) {
+				field.Attr = append(field.Attr, html.Attribute{Key: key, Val: val})
+			}
+			if id := dt.AttrOr("id", ""); id != "" {
+				setAttr("id", id)
+			}
+			if m[2] != "" {
+				setAttr(attrParamType, strings.TrimSpace(m[2]))
+			}
+
+			definitions := dt.NextUntil("dt").Filter("dd")
+			if info := definitions.First().Children().First(); info.Is("p, div") && info.Prev().Length() == 0 {
+				if im := paramInfoRegex.FindStringSubmatch(collapseWhitespace(info.Text())); im != nil {
+					if m[2] == "" {
+						setAttr(attrParamType, im[1])
+					}
+					if im[2] != "" {
+						setAttr(attrParamRequired, "")
+					} else {
+						setAttr(attrParamDefault, im[3])
+					}
+					info.Remove()
+				}
+			}
+			definitions.Each(func(i int, dd *goquery.Selection) {
+				for _, n := range dd.Contents().Nodes {
+					n.Parent.RemoveChild(n)
+					field.AppendChild(n)
+				}
+			})
+			definitions.Remove()
+			dt.ReplaceWithNodes(field)
+		})
+	})
+}
+
+// paramFieldRule writes the elements markParamFields left as <ParamField>
+// components, after an explicit anchor keeping the term's id.
+func paramFieldRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"div"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			name, ok := selec.Attr(attrParam)
+			if !ok {
+				return nil
+			}
+
+			anchor := ""
+			if id := selec.AttrOr("id", ""); id != "" {
+				anchor = `<a id="` + jsxAttrEscaper.Replace(id) + `"></a>` + "\n\n"
+			}
+			attrs := ` path="` + jsxAttrEscaper.Replace(name) + `"`
+			if typ, ok := selec.Attr(attrParamType); ok {
+				attrs += ` type="` + jsxAttrEscaper.Replace(typ) + `"`
+			}
+			if def, ok := selec.Attr(attrParamDefault); ok {
+				attrs += ` default="` + jsxAttrEscaper.Replace(def) + `"`
+			}
+			if _, ok := selec.Attr(attrParamRequired); ok {
+				attrs += " required"
+			}
+			return md.String("\n\n" + anchor + "<ParamField" + attrs + ">" + blockBody(content) + "</ParamField>\n\n")
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParamFieldsFallback checks that definition lists that are not
+// attribute lists keep the plain rendering under -param-fields.
+func TestParamFieldsFallback(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.paramFields = true
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<h1>Page</h1>
+<dl><dt>Hermeticity</dt><dd><p>Builds depend only on declared inputs.</p></dd></dl>
+<dl><dt><code>name</code></dt><dd><p>The name.</p></dd><dt>Mixed term</dt><dd><p>Not an attribute.</p></dd></dl>`,
+	})["page.md"]
+	if strings.Contains(page, "<ParamField") {
+		t.Errorf("plain definition list written as <ParamField>s:\n%s", page)
+	}
+	for _, want := range []string{"Hermeticity", "Builds depend only on declared inputs.", "Mixed term"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Which branch of a Devsite conditional is kept.
+const (
+	conditionsTrue  = "true"
+	conditionsFalse = "false"
+)
+
+// Which branches of a Devsite {% dynamic if %} block are kept.
+const (
+	// The branch a signed-out reader sees
+	dynamicPublic = "public"
+	// Every branch
+	dynamicAll = "all"
+)
+
+var (
+	// {% setvar name %}value{% endsetvar %}
+	setvarBlockRegex = regexp.MustCompile(`(?s)\{%-?\s*setvar\s+([A-Za-z_][\w]*)\s*-?%\}(.*?)\{%-?\s*endsetvar\s*-?%\}`)
+	// {% dynamic setvar name "value" %}
+	dynamicSetvarRegex = regexp.MustCompile(`\{%-?\s*dynamic\s+setvar\s+([A-Za-z_][\w]*)\s+(?:"([^"]*)"|'([^']*)')\s*-?%\}`)
+	// {% if cond %}, {% elif cond %}, {% else %} and {% endif %}
+	conditionalTagRegex = regexp.MustCompile(`\{%-?\s*(if|elif|else|endif)\b[^%]*?-?%\}`)
+	// {% dynamic if cond %}, {% dynamic elif cond %}, {% dynamic else %}
+	// and {% dynamic endif %}
+	dynamicConditionalTagRegex = regexp.MustCompile(`\{%-?\s*dynamic\s+(if|elif|else|endif)\b([^%]*?)-?%\}`)
+	// A condition on the reader being signed in, such as
+	// request.is_signed_in, possibly negated
+	signedInConditionRegex = regexp.MustCompile(`^(not\s+)?[\w.]*signed_in$`)
+	// {% comment %} and {% endcomment %}
+	commentTagRegex = regexp.MustCompile(`\{%-?\s*(comment|endcomment)\s*-?%\}`)
+	// {{ name }}
+	varReferenceRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w]*)\s*\}\}`)
+	// Any {% tag %} or {{ expression }}
+	templateSyntaxRegex = regexp.MustCompile(`(?s)\{%.*?%\}|\{\{.*?\}\}`)
+	// {% verbatim %}text{% endverbatim %}
+	verbatimBlockRegex = regexp.MustCompile(`(?s)\{%-?\s*verbatim\s*-?%\}(.*?)\{%-?\s*endverbatim\s*-?%\}`)
+	// A tag, whose braces are attribute values
+	tagRegex = regexp.MustCompile(`<[^>]*>`)
+	// <pre> and <code> elements
+	codeElementRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<pre\b[^>]*>.*?</pre>`),
+		regexp.MustCompile(`(?is)<code\b[^>]*>.*?</code>`),
+	}
+)
+
+// Private-use stand-ins for the braces of verbatim text and code, which Devsite
+// template syntax cannot match. restoreVerbatim turns them back into braces
+// once the page is converted.
+const (
+	verbatimOpen  = "\uE000"
+	verbatimClose = "\uE001"
+)
+
+var (
+	hideVerbatimBraces  = strings.NewReplacer("{", verbatimOpen, "}", verbatimClose)
+	entityBraces        = strings.NewReplacer("{", "&#123;", "}", "&#125;")
+	plainVerbatimBraces = strings.NewReplacer(verbatimOpen, "{", verbatimClose, "}")
+	// MDX reads braces in prose as expressions, so they are escaped there
+	escapedVerbatimBraces = strings.NewReplacer(verbatimOpen, `\{`, verbatimClose, `\}`)
+)
+
+// protectVerbatim drops the tags of Devsite {% verbatim %} blocks and hides
+// the braces inside, so their content is taken literally by the template
+// handling that follows.
+func protectVerbatim(html string) string {
+	return verbatimBlockRegex.ReplaceAllStringFunc(html, func(m string) string {
+		return hideBraces(verbatimBlockRegex.FindStringSubmatch(m)[1])
+	})
+}
+
+// protectCodeTemplates hides the braces inside <pre> and <code> elements like
+// protectVerbatim does, since template syntax there more often documents Go
+// or Jinja templates than uses Devsite's. References to variables that the
+// page defines are still expanded.
+func protectCodeTemplates(html string) string {
+	defined := make(map[string]bool)
+	for _, re := range []*regexp.Regexp{setvarBlockRegex, dynamicSetvarRegex} {
+		for _, m := range re.FindAllStringSubmatch(html, -1) {
+			defined[m[1]] = true
+		}
+	}
+
+	for _, re := range codeElementRegexes {
+		html = re.ReplaceAllStringFunc(html, func(code string) string {
+			var b strings.Builder
+			last := 0
+			for _, loc := range varReferenceRegex.FindAllStringSubmatchIndex(code, -1) {
+				if defined[code[loc[2]:loc[3]]] {
+					b.WriteString(hideBraces(code[last:loc[0]]))
+					b.WriteString(code[loc[0]:loc[1]])
+					last = loc[1]
+				}
+			}
+			b.WriteString(hideBraces(code[last:]))
+			return b.String()
+		})
+	}
+	return html
+}
+
+// hideBraces hides the braces of HTML text. Braces in tags become character
+// references instead, which the HTML parser turns back into braces in
+// attribute values.
+func hideBraces(html string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range tagRegex.FindAllStringIndex(html, -1) {
+		b.WriteString(hideVerbatimBraces.Replace(html[last:loc[0]]))
+		b.WriteString(entityBraces.Replace(html[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(hideVerbatimBraces.Replace(html[last:]))
+	return b.String()
+}
+
+// restoreVerbatim turns the braces protectVerbatim hid in converted markdown
+// back into braces, escaped in prose and literal in frontmatter and code.
+func restoreVerbatim(markdown string) string {
+	if !strings.Contains(markdown, verbatimOpen) && !strings.Contains(markdown, verbatimClose) {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	prose := make(map[int]bool)
+	for _, line := range proseLines(markdown) {
+		prose[line.Number-1] = true
+	}
+	for i, line := range lines {
+		if !prose[i] {
+			lines[i] = plainVerbatimBraces.Replace(line)
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range inlineCodeRegex.FindAllStringIndex(line, -1) {
+			b.WriteString(escapedVerbatimBraces.Replace(line[last:loc[0]]))
+			b.WriteString(plainVerbatimBraces.Replace(line[loc[0]:loc[1]]))
+			last = loc[1]
+		}
+		b.WriteString(escapedVerbatimBraces.Replace(line[last:]))
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// expandDevsiteVariables substitutes Devsite template variables in a page's
+// HTML. The definitions are collected and removed first, so a reference
+// may appear before its definition. References to undefined variables are
+// stripped and returned so that they can be reported.
+func expandDevsiteVariables(html string) (string, []string) {
+	vars := make(map[string]string)
+
+	html = setvarBlockRegex.ReplaceAllStringFunc(html, func(m string) string {
+		sub := setvarBlockRegex.FindStringSubmatch(m)
+		vars[sub[1]] = strings.TrimSpace(sub[2])
+		return ""
+	})
+	html = dynamicSetvarRegex.ReplaceAllStringFunc(html, func(m string) string {
+		sub := dynamicSetvarRegex.FindStringSubmatch(m)
+		vars[sub[1]] = sub[2] + sub[3]
+		return ""
+	})
+
+	var undefined []string
+	seen := make(map[string]bool)
+	html = varReferenceRegex.ReplaceAllStringFunc(html, func(m string) string {
+		name := varReferenceRegex.FindStringSubmatch(m)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			undefined = append(undefined, name)
+		}
+		return ""
+	})
+
+	return html, undefined
+}
+
+// stripDevsiteTags removes the template syntax that the other passes left,
+// such as {% include %} tags and {{ .Name }} expressions outside code, and
+// returns each distinct piece removed so that it can be reported. Syntax that
+// is meant literally has its braces hidden by then.
+func stripDevsiteTags(html string) (string, []string) {
+	var removed []string
+	seen := make(map[string]bool)
+	html = templateSyntaxRegex.ReplaceAllStringFunc(html, func(m string) string {
+		if !seen[m] {
+			seen[m] = true
+			removed = append(removed, m)
+		}
+		return ""
+	})
+	return html, removed
+}
+
+// stripDevsiteComments drops Devsite {% comment %} blocks, the editorial
+// notes in them included. Comments may nest. A comment left open runs to
+// the end of the page, and an {% endcomment %} without one is dropped;
+// either is returned as a problem to warn about.
+func stripDevsiteComments(html string) (string, []string) {
+	var (
+		b        strings.Builder
+		problems []string
+		depth    int
+		last     int
+	)
+	for _, loc := range commentTagRegex.FindAllStringSubmatchIndex(html, -1) {
+		if depth == 0 {
+			b.WriteString(html[last:loc[0]])
+		}
+		last = loc[1]
+
+		switch html[loc[2]:loc[3]] {
+		case "comment":
+			depth++
+		case "endcomment":
+			if depth == 0 {
+				problems = append(problems, "{% endcomment %} without a matching {% comment %} removed")
+				continue
+			}
+			depth--
+		}
+	}
+	if depth > 0 {
+		problems = append(problems, "{% comment %} without a matching {% endcomment %} removed to the end of the page")
+		return b.String(), problems
+	}
+	b.WriteString(html[last:])
+	return b.String(), problems
+}
+
+// resolveDevsiteConditionals drops the tags of Devsite {% if %} blocks and
+// keeps one branch of each. With conditionsTrue every condition is assumed
+// to hold, as it usually does in the published build, so the first branch
+// is kept; with conditionsFalse the {% else %} branch is, if any.
+func resolveDevsiteConditionals(html, conditions string) (string, error) {
+	type block struct {
+		// emitting reports whether the enclosing blocks keep their content
+		emitting bool
+		active   bool
+		sawElse  bool
+	}
+	var (
+		b     strings.Builder
+		stack []block
+		last  int
+	)
+	emitting := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].emitting && stack[len(stack)-1].active
+	}
+
+	for _, loc := range conditionalTagRegex.FindAllStringSubmatchIndex(html, -1) {
+		if emitting() {
+			b.WriteString(html[last:loc[0]])
+		}
+		last = loc[1]
+
+		tag := html[loc[2]:loc[3]]
+		if tag == "if" {
+			stack = append(stack, block{emitting: emitting(), active: conditions == conditionsTrue})
+			continue
+		}
+		if len(stack) == 0 {
+			return "", fmt.Errorf("Devsite {%% %s %%} without a matching {%% if %%}", tag)
+		}
+		top := &stack[len(stack)-1]
+		switch tag {
+		case "elif", "else":
+			if top.sawElse {
+				return "", fmt.Errorf("Devsite {%% %s %%} after {%% else %%}", tag)
+			}
+			top.sawElse = tag == "else"
+			top.active = top.sawElse && conditions == conditionsFalse
+		case "endif":
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("Devsite {%% if %%} without a matching {%% endif %%}")
+	}
+	b.WriteString(html[last:])
+	return b.String(), nil
+}
+
+// resolveDynamicConditionals drops the tags of Devsite {% dynamic if %}
+// blocks, which show content depending on the reader, such as whether they
+// are signed in. With dynamicPublic one branch of each is kept, the one a
+// signed-out reader gets: conditions on being signed in are taken to be false
+// and their negations true, and any other condition false, leaving the
+// {% dynamic else %} branch, if any. With dynamicAll every branch is kept.
+func resolveDynamicConditionals(html, branch string) (string, error) {
+	type block struct {
+		// emitting reports whether the enclosing blocks keep their content
+		emitting bool
+		active   bool
+		// taken reports whether a branch of the block was kept
+		taken   bool
+		sawElse bool
+	}
+	var (
+		b     strings.Builder
+		stack []block
+		last  int
+	)
+	emitting := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].emitting && stack[len(stack)-1].active
+	}
+	holds := func(condition string) bool {
+		m := signedInConditionRegex.FindStringSubmatch(strings.TrimSpace(condition))
+		return branch == dynamicAll || m != nil && m[1] != ""
+	}
+
+	for _, loc := range dynamicConditionalTagRegex.FindAllStringSubmatchIndex(html, -1) {
+		if emitting() {
+			b.WriteString(html[last:loc[0]])
+		}
+		last = loc[1]
+
+		tag, condition := html[loc[2]:loc[3]], html[loc[4]:loc[5]]
+		if tag == "if" {
+			active := holds(condition)
+			stack = append(stack, block{emitting: emitting(), active: active, taken: active})
+			continue
+		}
+		if len(stack) == 0 {
+			return "", fmt.Errorf("Devsite {%% dynamic %s %%} without a matching {%% dynamic if %%}", tag)
+		}
+		top := &stack[len(stack)-1]
+		switch tag {
+		case "elif", "else":
+			if top.sawElse {
+				return "", fmt.Errorf("Devsite {%% dynamic %s %%} after {%% dynamic else %%}", tag)
+			}
+			top.sawElse = tag == "else"
+			top.active = branch == dynamicAll || !top.taken && (top.sawElse || holds(condition))
+			top.taken = top.taken || top.active
+		case "endif":
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("Devsite {%% dynamic if %%} without a matching {%% dynamic endif %%}")
+	}
+	b.WriteString(html[last:])
+	return b.String(), nil
+}
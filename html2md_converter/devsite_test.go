@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandDevsiteVariables(t *testing.T) {
+	html, undefined := expandDevsiteVariables(`<p>Use {{ version }} with {{product}} and {{ missing }} or {{ missing }}.</p>
+{% setvar version %} 7.4.1 {% endsetvar %}{% dynamic setvar product "Bazel" %}`)
+
+	if want := "<p>Use 7.4.1 with Bazel and  or .</p>"; !strings.HasPrefix(html, want) || strings.Contains(html, "setvar") {
+		t.Errorf("expanded to %q, want %q", html, want)
+	}
+	if len(undefined) != 1 || undefined[0] != "missing" {
+		t.Errorf("undefined variables %q, want [missing]", undefined)
+	}
+}
+
+func TestResolveDevsiteConditionals(t *testing.T) {
+	html := `A{% if a %}B{% if b %}C{% else %}D{% endif %}E{% elif c %}F{% else %}G{% if d %}H{% endif %}{% endif %}I`
+	tests := map[string]string{
+		conditionsTrue:  "ABCEI",
+		conditionsFalse: "AGI",
+	}
+	for conditions, want := range tests {
+		got, err := resolveDevsiteConditionals(html, conditions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("conditions %s: resolved to %q, want %q", conditions, got, want)
+		}
+	}
+
+	for _, unbalanced := range []string{"{% if a %}x", "x{% endif %}", "{% else %}x"} {
+		if _, err := resolveDevsiteConditionals(unbalanced, conditionsTrue); err == nil {
+			t.Errorf("%q resolved without an error", unbalanced)
+		}
+	}
+}
+
+func TestResolveDynamicConditionals(t *testing.T) {
+	html := `A{% dynamic if request.is_signed_in %}B{% dynamic else %}C{% dynamic endif %}D{% dynamic if not request.is_signed_in %}E{% dynamic endif %}F{% dynamic if user.is_googler %}G{% dynamic elif request.is_signed_in %}H{% dynamic endif %}I`
+	tests := map[string]string{
+		dynamicPublic: "ACDEFI",
+		dynamicAll:    "ABCDEFGHI",
+	}
+	for branch, want := range tests {
+		got, err := resolveDynamicConditionals(html, branch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("branch %s: resolved to %q, want %q", branch, got, want)
+		}
+	}
+
+	for _, unbalanced := range []string{"{% dynamic if x %}x", "x{% dynamic endif %}", "{% dynamic if x %}{% dynamic else %}{% dynamic else %}{% dynamic endif %}"} {
+		if _, err := resolveDynamicConditionals(unbalanced, dynamicPublic); err == nil {
+			t.Errorf("%q resolved without an error", unbalanced)
+		}
+	}
+
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<h1>Downloads</h1>{% dynamic if request.is_signed_in %}<p>Your saved builds.</p>{% dynamic else %}<p>Sign in to save builds.</p>{% dynamic endif %}`,
+	})["page.md"]
+	if !strings.Contains(page, "Sign in to save builds.") || strings.Contains(page, "Your saved builds") || strings.Contains(page, "dynamic") {
+		t.Errorf("page is\n%s\nwant only the signed-out branch", page)
+	}
+}
+
+func TestStripDevsiteComments(t *testing.T) {
+	tests := []struct {
+		html, want string
+		problems   int
+	}{
+		{"<p>Kept.</p>{% comment %}TODO: rewrite{% endcomment %}<p>Also kept.</p>", "<p>Kept.</p><p>Also kept.</p>", 0},
+		{"A{%- comment -%}x{% comment %}y{% endcomment %}z{% endcomment %}B", "AB", 0},
+		{"A{% comment %}never closed", "A", 1},
+		{"A{% endcomment %}B", "AB", 1},
+	}
+	for _, tt := range tests {
+		got, problems := stripDevsiteComments(tt.html)
+		if got != tt.want || len(problems) != tt.problems {
+			t.Errorf("%q stripped to %q with problems %q, want %q with %d", tt.html, got, problems, tt.want, tt.problems)
+		}
+	}
+
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<h1>Notes</h1><p>Before.</p>{% comment %}<p>Editorial {% if x %}note{% endif %}.</p>{% endcomment %}<p>After.</p>`,
+	})["page.md"]
+	if !strings.Contains(page, "Before.\n\nAfter.") || strings.Contains(page, "Editorial") || strings.Contains(page, "comment") {
+		t.Errorf("comment block not dropped:\n%s", page)
+	}
+}
+
+func TestVerbatim(t *testing.T) {
+	cfg := testConfig(t)
+	// Escaped braces are not MDX expressions
+	cfg.strict["mdx"] = true
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<h1>Templates</h1>
+{% setvar name %}Bazel{% endsetvar %}
+<p>{{ name }} and {% verbatim %}{{ example }} with {% if x %}{% endverbatim %}.</p>
+<pre>{% verbatim %}{{ example }}{% endverbatim %}</pre>`,
+	})["page.md"]
+	for _, want := range []string{
+		`Bazel and \{\{ example \}\} with \{% if x %\}.`,
+		"```\n{{ example }}\n```",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks\n%s\nin\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "verbatim") {
+		t.Errorf("page keeps the verbatim tags:\n%s", page)
+	}
+}
+
+func TestCodeTemplates(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Templates</h1>
+{% setvar version %}7.4.1{% endsetvar %}
+<pre>Hello, {{ .Name }}! {% range .Items %}item{% end %} {{ version }}</pre>
+<p>Use <code>{{ .Name }}</code> in templates.</p>
+<p>{% include "x.html" %}visible {{ .Leaked }}text</p>`,
+	}
+	tests := []struct {
+		keep bool
+		want []string
+	}{
+		{true, []string{"```\nHello, {{ .Name }}! {% range .Items %}item{% end %} 7.4.1\n```", "Use `{{ .Name }}` in templates.", "visible text"}},
+		{false, []string{"```\nHello, ! item 7.4.1\n```", "visible text"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.keepCodeTemplates = tt.keep
+		page := convertPages(t, cfg, pages)["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-keep-go-templates=%v: page lacks\n%s\nin\n%s", tt.keep, want, page)
+			}
+		}
+		for _, leaked := range []string{"include", "Leaked"} {
+			if strings.Contains(page, leaked) {
+				t.Errorf("-keep-go-templates=%v: page contains %q:\n%s", tt.keep, leaked, page)
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines kept around each change.
+const diffContext = 3
+
+// runDiff converts into a scratch directory and prints how the result
+// differs from existingDir. It reports whether the trees are identical.
+func runDiff(zipPath, existingDir string, cfg config) (bool, error) {
+	if _, err := os.Stat(existingDir); err != nil {
+		return false, fmt.Errorf("cannot diff against %s: %w", existingDir, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "html2md-diff-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := convertZipToMarkdown(zipPath, tmpDir, cfg); err != nil {
+		return false, err
+	}
+	return diffTrees(os.Stdout, existingDir, tmpDir)
+}
+
+// diffTrees writes a unified diff of every file that differs between the
+// oldDir and newDir trees, followed by the files only one of them has.
+func diffTrees(w io.Writer, oldDir, newDir string) (bool, error) {
+	oldFiles, err := treeFiles(oldDir)
+	if err != nil {
+		return false, err
+	}
+	newFiles, err := treeFiles(newDir)
+	if err != nil {
+		return false, err
+	}
+
+	var added, removed []string
+	same := true
+	for _, rel := range newFiles.sorted() {
+		if !oldFiles[rel] {
+			added = append(added, rel)
+			continue
+		}
+		oldContent, err := os.ReadFile(filepath.Join(oldDir, rel))
+		if err != nil {
+			return false, err
+		}
+		newContent, err := os.ReadFile(filepath.Join(newDir, rel))
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(oldContent, newContent) {
+			continue
+		}
+		same = false
+		fmt.Fprint(w, unifiedDiff("a/"+rel, "b/"+rel, string(oldContent), string(newContent)))
+	}
+	for _, rel := range oldFiles.sorted() {
+		if !newFiles[rel] {
+			removed = append(removed, rel)
+		}
+	}
+
+	if len(added) > 0 {
+		same = false
+		fmt.Fprintf(w, "Added files (%d):\n", len(added))
+		for _, rel := range added {
+			fmt.Fprintf(w, "  + %s\n", rel)
+		}
+	}
+	if len(removed) > 0 {
+		same = false
+		fmt.Fprintf(w, "Removed files (%d):\n", len(removed))
+		for _, rel := range removed {
+			fmt.Fprintf(w, "  - %s\n", rel)
+		}
+	}
+	return same, nil
+}
+
+type fileSet map[string]bool
+
+func (s fileSet) sorted() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// treeFiles lists the regular files under dir by slash-separated relative
+// path.
+func treeFiles(dir string) (fileSet, error) {
+	files := make(fileSet)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the differences between oldText and newText in the
+// unified format, or "" when they are equal.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and grow the hunk while changes are close
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				hunkEnd = i + 1
+			} else if i-hunkEnd >= 2*diffContext {
+				break
+			}
+		}
+		hunkEnd = min(hunkEnd+diffContext, len(ops))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		var oldCount, newCount int
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return b.String()
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a shortest edit script of the two line slices with
+// Myers' algorithm, in linear space, so that long pages do not need a table
+// of every pair of lines.
+func diffLines(a, b []string) []diffOp {
+	return appendDiff(nil, a, b)
+}
+
+// appendDiff appends the edit script of a into b to ops. The lines they
+// start and end with in common are kept; the rest is split at the middle
+// snake of the shortest script and each side diffed on its own.
+func appendDiff(ops []diffOp, a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		ops = appendDiff(ops, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			ops = append(ops, diffOp{' ', line})
+		}
+		ops = appendDiff(ops, a[u:], b[v:])
+	}
+
+	for _, line := range common {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// middleSnake returns the start, (x, y), and end, (u, v), of the diagonal
+// run of equal lines in the middle of a shortest edit script of a into b,
+// searching forward from the start and backward from the end at once until
+// the paths meet. a and b must both be non-empty.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit
+	// forward[offset+k] is the furthest x reached on diagonal k = x - y;
+	// backward is the same from the end, with a and b reversed
+	forward := make([]int, 2*limit+2)
+	backward := make([]int, 2*limit+2)
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && x+backward[offset+kb] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if kf := delta - k; !odd && kf >= -d && kf <= d && x+forward[offset+kf] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\np\n"
+	new := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\np\nq\n"
+	want := `--- old
++++ new
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -14,3 +14,4 @@
+ n
+ o
+ p
++q
+`
+	if got := unifiedDiff("old", "new", old, new); got != want {
+		t.Errorf("diff is\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("old", "new", old, old); got != "" {
+		t.Errorf("diff of equal texts is\n%s", got)
+	}
+}
+
+// lcsLength is the length of the longest common subsequence of a and b, by
+// the quadratic table that diffLines avoids.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		row := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				row[j+1] = prev[j] + 1
+			case prev[j+1] > row[j]:
+				row[j+1] = prev[j+1]
+			default:
+				row[j+1] = row[j]
+			}
+		}
+		prev = row
+	}
+	return prev[len(b)]
+}
+
+// TestDiffLinesMinimal checks on random texts that the edit script turns
+// one text into the other with the fewest edits.
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for n := 0; n < 500; n++ {
+		a, b := randomLines(), randomLines()
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("script of %q into %q does not reproduce them", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("script of %q into %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLong(t *testing.T) {
+	const n = 60000
+	a := make([]string, n)
+	b := make([]string, n)
+	for i := range a {
+		a[i] = strconv.Itoa(i)
+		b[i] = strconv.Itoa(i)
+	}
+	b[n/3] = "changed"
+	b[2*n/3] = "changed"
+	edits := 0
+	for _, op := range diffLines(a, b) {
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	if edits != 4 {
+		t.Errorf("%d edits, want 4", edits)
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(oldDir, "same.md", "same\n")
+	write(newDir, "same.md", "same\n")
+	write(oldDir, "changed.md", "old\n")
+	write(newDir, "changed.md", "new\n")
+	write(oldDir, "removed.md", "gone\n")
+	write(newDir, "added.md", "fresh\n")
+
+	var out bytes.Buffer
+	same, err := diffTrees(&out, oldDir, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same {
+		t.Error("trees reported identical")
+	}
+	for _, want := range []string{"--- a/changed.md\n+++ b/changed.md\n@@ -1 +1 @@\n-old\n+new\n", "Added files (1):\n  + added.md\n", "Removed files (1):\n  - removed.md\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("diff lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "same.md") {
+		t.Errorf("diff lists an unchanged file:\n%s", out.String())
+	}
+
+	if same, err := diffTrees(&out, oldDir, oldDir); err != nil || !same {
+		t.Errorf("tree differs from itself: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Values of -emoji.
+const (
+	// Write :shortcode: emoji as the unicode emoji
+	emojiUnicode = "unicode"
+	// Write unicode emoji as their :shortcode:
+	emojiShortcode = "shortcode"
+	// Leave emoji as the page has them
+	emojiOff = "off"
+)
+
+// emojiTable are the emoji -emoji converts, by shortcode. Shortcodes that
+// name the same emoji follow the one it is written back as.
+var emojiTable = []struct{ shortcode, emoji string }{
+	{"smile", "😄"}, {"smiley", "😃"}, {"grin", "😁"}, {"laughing", "😆"},
+	{"wink", "😉"}, {"blush", "😊"}, {"slightly_smiling_face", "🙂"},
+	{"thinking", "🤔"}, {"confused", "😕"}, {"cry", "😢"}, {"sweat_smile", "😅"},
+	{"heart", "❤️"}, {"+1", "👍"}, {"thumbsup", "👍"}, {"-1", "👎"}, {"thumbsdown", "👎"},
+	{"clap", "👏"}, {"wave", "👋"}, {"pray", "🙏"}, {"muscle", "💪"}, {"eyes", "👀"},
+	{"point_right", "👉"}, {"point_left", "👈"}, {"point_up", "☝️"}, {"point_down", "👇"},
+	{"tada", "🎉"}, {"rocket", "🚀"}, {"sparkles", "✨"}, {"star", "⭐"}, {"fire", "🔥"},
+	{"zap", "⚡"}, {"boom", "💥"}, {"100", "💯"},
+	{"warning", "⚠️"}, {"no_entry", "⛔"}, {"stop_sign", "🛑"}, {"x", "❌"},
+	{"white_check_mark", "✅"}, {"heavy_check_mark", "✔️"}, {"ballot_box_with_check", "☑️"},
+	{"question", "❓"}, {"exclamation", "❗"}, {"information_source", "ℹ️"},
+	{"bulb", "💡"}, {"memo", "📝"}, {"pencil2", "✏️"}, {"book", "📖"}, {"books", "📚"},
+	{"bookmark", "🔖"}, {"link", "🔗"}, {"paperclip", "📎"}, {"pushpin", "📌"},
+	{"mag", "🔍"}, {"wrench", "🔧"}, {"hammer", "🔨"}, {"hammer_and_wrench", "🛠️"},
+	{"gear", "⚙️"}, {"package", "📦"}, {"construction", "🚧"}, {"bug", "🐛"},
+	{"lock", "🔒"}, {"unlock", "🔓"}, {"key", "🔑"}, {"shield", "🛡️"},
+	{"computer", "💻"}, {"file_folder", "📁"}, {"page_facing_up", "📄"},
+	{"clipboard", "📋"}, {"calendar", "📅"}, {"hourglass", "⌛"}, {"stopwatch", "⏱️"},
+	{"chart_with_upwards_trend", "📈"}, {"recycle", "♻️"}, {"green_heart", "💚"},
+	{"rotating_light", "🚨"}, {"bell", "🔔"}, {"speech_balloon", "💬"}, {"penguin", "🐧"},
+	{"arrow_right", "➡️"}, {"arrow_left", "⬅️"}, {"arrow_up", "⬆️"}, {"arrow_down", "⬇️"},
+}
+
+var (
+	emojiShortcodeRegex = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	// Matches the emoji of the table, with or without the variation
+	// selector that asks for emoji presentation
+	emojiRegex *regexp.Regexp
+
+	emojiByShortcode = make(map[string]string)
+	shortcodeByEmoji = make(map[string]string)
+)
+
+func init() {
+	var alternatives []string
+	for _, e := range emojiTable {
+		emojiByShortcode[e.shortcode] = e.emoji
+		bare := strings.TrimSuffix(e.emoji, "\uFE0F")
+		if _, ok := shortcodeByEmoji[bare]; ok {
+			continue
+		}
+		shortcodeByEmoji[bare] = e.shortcode
+		alternatives = append(alternatives, regexp.QuoteMeta(bare)+"\uFE0F?")
+	}
+	emojiRegex = regexp.MustCompile(strings.Join(alternatives, "|"))
+}
+
+// attrEmoji marks the elements normalizeEmoji leaves in place of unicode
+// emoji, with the shortcode emojiRule writes, unescaped, for them.
+const attrEmoji = "data-html2md-emoji"
+
+// normalizeEmoji writes the emoji of a page's text one way, for -emoji, so
+// that they render alike everywhere: as unicode emoji, or as the shortcodes
+// the renderer turns into its own. Shortcodes and emoji missing from
+// emojiTable, and the text of code, are left alone.
+func normalizeEmoji(doc *goquery.Document, mode string) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				c.Data = emojiShortcodeRegex.ReplaceAllStringFunc(c.Data, func(m string) string {
+					if emoji, ok := emojiByShortcode[strings.Trim(m, ":")]; ok {
+						return emoji
+					}
+					return m
+				})
+				if mode == emojiShortcode {
+					// Shortcodes too are marked, so that they are written
+					// unescaped, and by the one name of their emoji
+					c = markEmoji(c)
+					continue
+				}
+				// Emoji are written as the table has them, e.g. with the
+				// variation selector
+				c.Data = emojiRegex.ReplaceAllStringFunc(c.Data, func(m string) string {
+					return emojiByShortcode[shortcodeByEmoji[strings.TrimSuffix(m, "\uFE0F")]]
+				})
+			case c.Type == html.ElementNode && !emojiSkipped[c.Data]:
+				walk(c)
+			}
+		}
+	}
+	walk(doc.Nodes[0])
+}
+
+// markEmoji splits text node n around its emoji, each replaced by a marked
+// element, and returns the last node it was split into.
+func markEmoji(n *html.Node) *html.Node {
+	text := n.Data
+	matches := emojiRegex.FindAllStringIndex(text, -1)
+	if matches == nil {
+		return n
+	}
+	last := 0
+	for _, m := range matches {
+		if m[0] > last {
+			n.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: text[last:m[0]]}, n)
+		}
+		marker := &html.Node{Type: html.ElementNode, Data: "span", Attr: []html.Attribute{{Key: attrEmoji, Val: shortcodeByEmoji[strings.TrimSuffix(text[m[0]:m[1]], "\uFE0F")]}}}
+		n.Parent.InsertBefore(marker, n)
+		last = m[1]
+	}
+	if last == len(text) {
+		prev := n.PrevSibling
+		n.Parent.RemoveChild(n)
+		return prev
+	}
+	n.Data = text[last:]
+	return n
+}
+
+// emojiRule writes the shortcodes markEmoji marked.
+func emojiRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"span"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			shortcode, ok := selec.Attr(attrEmoji)
+			if !ok {
+				return nil
+			}
+			return md.String(":" + shortcode + ":")
+		},
+	}
+}
+
+// emojiSkipped are the elements whose text normalizeEmoji leaves alone.
+var emojiSkipped = map[string]bool{
+	"code": true, "pre": true, "kbd": true, "samp": true, "var": true,
+	"script": true, "style": true, "head": true, "textarea": true,
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmoji(t *testing.T) {
+	html := `<h1>Emoji</h1><p>Ship it :rocket: and :thumbsup: 👍 ⚠ :unknown:</p><p><code>:tada: 🎉</code></p>`
+	tests := map[string][]string{
+		emojiUnicode:   {"Ship it 🚀 and 👍 👍 ⚠️ :unknown:", "`:tada: 🎉`"},
+		emojiShortcode: {"Ship it :rocket: and :+1: :+1: :warning: :unknown:", "`:tada: 🎉`"},
+		emojiOff:       {"Ship it :rocket: and :thumbsup: 👍 ⚠ :unknown:", "`:tada: 🎉`"},
+	}
+	for mode, want := range tests {
+		cfg := testConfig(t)
+		cfg.emoji = mode
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		for _, w := range want {
+			if !strings.Contains(page, w) {
+				t.Errorf("-emoji=%s: page is\n%s\nwant it to contain\n%s", mode, page, w)
+			}
+		}
+	}
+}
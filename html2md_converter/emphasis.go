@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// emphasisRules replace the library's <strong> and <em> rules, which pad
+// emphasis with spaces whenever it touches other text. That changes the text
+// ("a<b>b</b>c" became "a **b** c") and splits CJK words. Instead the
+// delimiters are written tight and only when CommonMark's flanking rules let
+// them open and close at that spot; elsewhere the emphasis stays an inline
+// HTML tag. No zero-width space would help there, since CommonMark counts it
+// as neither whitespace nor punctuation.
+func emphasisRules() []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"strong", "b"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				if selec.Parent().Is("strong, b") {
+					return &content
+				}
+				return md.String(emphasize(content, selec, "strong", opt.StrongDelimiter))
+			},
+		},
+		{
+			// Citations of books and specs are italic, as browsers show
+			// them; a linked one is italic link text
+			Filter: []string{"em", "i", "cite"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				if selec.Parent().Is("em, i, cite") {
+					return &content
+				}
+				return md.String(emphasize(content, selec, "em", opt.EmDelimiter, "*"))
+			},
+		},
+	}
+}
+
+// emphasize wraps content in the first of the delimiters that can open and
+// close around it, keeping the whitespace content had at its edges.
+func emphasize(content string, selec *goquery.Selection, tag string, delimiters ...string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return content
+	}
+	// Whitespace at the edges of content is kept, unless the text next to
+	// it already ends or starts with some. The converter drops
+	// whitespace-only text next to the element, so that is put back, except
+	// before padded elements, which put it back themselves.
+	before, spacedBefore, _ := adjacentRune(selec.Nodes[0], true)
+	after, spacedAfter, next := adjacentRune(selec.Nodes[0], false)
+	lead, trail := "", ""
+	if spacedBefore || trimmed[0] != content[0] && !unicode.IsSpace(before) {
+		lead, before = " ", ' '
+	}
+	if !paddedElements[next] && (spacedAfter || trimmed[len(trimmed)-1] != content[len(content)-1] && !unicode.IsSpace(after)) {
+		trail, after = " ", ' '
+	}
+
+	// Delimiters cannot span lines, so each line gets its own
+	if strings.Contains(trimmed, "\n") {
+		lines := strings.Split(trimmed, "\n")
+		for i, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				lines[i] = delimiters[0] + line + delimiters[0]
+			}
+		}
+		return lead + strings.Join(lines, "\n") + trail
+	}
+
+	first, _ := utf8.DecodeRuneInString(trimmed)
+	last, _ := utf8.DecodeLastRuneInString(trimmed)
+
+	for _, delimiter := range delimiters {
+		underscore := delimiter[0] == '_'
+		if canOpen(before, first, underscore) && canClose(last, after, underscore) {
+			return lead + delimiter + trimmed + delimiter + trail
+		}
+	}
+	return lead + "<" + tag + ">" + trimmed + "</" + tag + ">" + trail
+}
+
+// markupElements are converted to markdown that starts and ends with
+// punctuation, such as ** or [...](...), rather than with their text.
+var markupElements = map[string]bool{
+	"a": true, "img": true, "code": true, "kbd": true,
+	"strong": true, "b": true, "em": true, "i": true, "cite": true,
+	"del": true, "s": true, "strike": true, "ins": true,
+}
+
+// paddedElements are those the converter adds a space before when
+// whitespace separated them from preceding text.
+var paddedElements = map[string]bool{
+	"a": true, "code": true, "kbd": true, "tt": true,
+	"strong": true, "b": true, "em": true, "i": true,
+	"del": true, "s": true, "strike": true,
+}
+
+// adjacentRune returns the rune of markdown right before or after node,
+// with a space standing in for the edge of the block and for line breaks,
+// and the name of the element it comes from, if any. spaced reports that
+// whitespace-only text was skipped to find it; the converter drops such
+// text, so the caller has to put the space back.
+func adjacentRune(node *html.Node, before bool) (r rune, spaced bool, element string) {
+	next := func(n *html.Node) *html.Node {
+		if before {
+			return n.PrevSibling
+		}
+		return n.NextSibling
+	}
+	for n := next(node); n != nil; n = next(n) {
+		if n.Type == html.ElementNode {
+			element = n.Data
+		}
+		if element == "br" {
+			return ' ', false, element
+		}
+		text := md.CollectText(n)
+		if strings.TrimSpace(text) == "" {
+			spaced = spaced || text != ""
+			element = ""
+			continue
+		}
+		if spaced {
+			return ' ', true, element
+		}
+		if markupElements[element] {
+			return '*', false, element
+		}
+		if before {
+			r, _ = utf8.DecodeLastRuneInString(text)
+		} else {
+			r, _ = utf8.DecodeRuneInString(text)
+		}
+		return r, false, element
+	}
+	return ' ', false, ""
+}
+
+func isMarkdownPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func leftFlanking(before, after rune) bool {
+	return !unicode.IsSpace(after) && (!isMarkdownPunct(after) || unicode.IsSpace(before) || isMarkdownPunct(before))
+}
+
+func rightFlanking(before, after rune) bool {
+	return !unicode.IsSpace(before) && (!isMarkdownPunct(before) || unicode.IsSpace(after) || isMarkdownPunct(after))
+}
+
+// canOpen reports whether a delimiter run between before and after opens
+// emphasis; underscores may not open inside a word.
+func canOpen(before, after rune, underscore bool) bool {
+	if !underscore {
+		return leftFlanking(before, after)
+	}
+	return leftFlanking(before, after) && (!rightFlanking(before, after) || isMarkdownPunct(before))
+}
+
+func canClose(before, after rune, underscore bool) bool {
+	if !underscore {
+		return rightFlanking(before, after)
+	}
+	return rightFlanking(before, after) && (!leftFlanking(before, after) || isMarkdownPunct(after))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmphasis(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`<strong><em>x</em></strong>`, "**_x_**"},
+		{`a<b>b</b>c`, "a**b**c"},
+		{`中文<strong>强调</strong>文字`, "中文**强调**文字"},
+		{`日本<em>語</em>です`, "日本*語*です"},
+		{`a<b>“quote”</b>text`, "a<strong>“quote”</strong>text"},
+		{`and <em> spaced </em>word`, "and _spaced_ word"},
+	}
+	converter := newConverter(testConfig(t))
+	for _, tt := range tests {
+		got, err := converter.ConvertString("<p>" + tt.html + "</p>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("%s converted to %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestCitations(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`See <cite>The Go Programming Language</cite>.`, "See _The Go Programming Language_."},
+		{`See <a href="https://spec.example/bzl"><cite>The <code>.bzl</code> Spec</cite></a>.`, "See [_The `.bzl` Spec_](https://spec.example/bzl)."},
+		{`See <cite><a href="https://spec.example/bzl">Spec <b>2</b></a></cite>.`, "See _[Spec **2**](https://spec.example/bzl)_."},
+		{`<em>Read <cite>Spec</cite></em>`, "_Read Spec_"},
+	}
+	converter := newConverter(testConfig(t))
+	for _, tt := range tests {
+		got, err := converter.ConvertString("<p>" + tt.html + "</p>")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("%s converted to %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
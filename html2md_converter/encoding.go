@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// metaCharsetRegex finds the charset declared by <meta charset> or
+// <meta http-equiv="Content-Type" content="...; charset=...">.
+var metaCharsetRegex = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([A-Za-z0-9._:-]+)`)
+
+// decodeHTML transcodes a page to UTF-8. The encoding is the forced one if
+// given, else the one a byte order mark or <meta> in the first kilobyte
+// declares, else UTF-8. It returns the text and the encoding used.
+func decodeHTML(content []byte, forced string) (string, string, error) {
+	name := forced
+	if name == "" {
+		name = declaredCharset(content)
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return "", "", fmt.Errorf("unsupported input encoding %q", name)
+	}
+	canonical, _ := htmlindex.Name(enc)
+
+	if canonical == "utf-8" {
+		return string(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))), canonical, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode %s input: %w", canonical, err)
+	}
+	return strings.TrimPrefix(string(decoded), "\ufeff"), canonical, nil
+}
+
+func declaredCharset(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(content, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(content, []byte("\xfe\xff")):
+		return "utf-16be"
+	}
+
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := metaCharsetRegex.FindSubmatch(head); m != nil {
+		return string(m[1])
+	}
+	return "utf-8"
+}
+
+// Values of -output-encoding.
+const (
+	outputUTF8  = "utf8"
+	outputASCII = "ascii"
+)
+
+// A single-quoted frontmatter value, as frontmatter.String writes them
+var quotedFieldRegex = regexp.MustCompile(`^([^:\s][^:]*: )'(.*)'$`)
+
+// escapeNonASCII makes a converted page ASCII for -output-encoding=ascii.
+// Non-ASCII characters of prose become numeric HTML entities, and quoted
+// frontmatter values holding any are rewritten as double-quoted YAML with
+// \u escapes. Code is left as it is, since entities would show literally
+// there.
+func escapeNonASCII(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	if strings.HasPrefix(markdown, "---\n") {
+		for i := 1; i < len(lines) && lines[i] != "---"; i++ {
+			if m := quotedFieldRegex.FindStringSubmatch(lines[i]); m != nil && !isASCII(m[2]) {
+				lines[i] = m[1] + yamlDoubleQuoted(strings.ReplaceAll(m[2], "''", "'"))
+			}
+		}
+	}
+
+	for _, line := range proseLines(markdown) {
+		if isASCII(line.Raw) {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, span := range inlineCodeRegex.FindAllStringIndex(line.Raw, -1) {
+			b.WriteString(htmlEntities(line.Raw[last:span[0]]))
+			b.WriteString(line.Raw[span[0]:span[1]])
+			last = span[1]
+		}
+		b.WriteString(htmlEntities(line.Raw[last:]))
+		lines[line.Number-1] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// htmlEntities writes the non-ASCII characters of s as numeric entities.
+func htmlEntities(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x80 {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "&#%d;", r)
+		}
+	}
+	return b.String()
+}
+
+// yamlDoubleQuoted writes s as a double-quoted YAML scalar of ASCII.
+func yamlDoubleQuoted(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteString(`\` + string(r))
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08X`, r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLatin1Page(t *testing.T) {
+	// "Café déjà vu" in ISO-8859-1
+	latin1 := "<html><head><meta charset=\"iso-8859-1\"></head><body><p>Caf\xe9 d\xe9j\xe0 vu</p></body></html>"
+	undeclared := "<p>Caf\xe9</p>"
+	tests := []struct {
+		name, page, forced, want string
+	}{
+		{"declared", latin1, "", "Café déjà vu"},
+		{"forced", undeclared, "latin1", "Café"},
+		{"utf-8 default", "<p>Café</p>", "", "Café"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.inputEncoding = tt.forced
+		page := convertPages(t, cfg, map[string]string{"page.html": tt.page})["page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("%s: page lacks %q:\n%s", tt.name, tt.want, page)
+		}
+	}
+}
+
+func TestOutputEncoding(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Café résumé</h1><p>Naïve “quotes” — and <code>café</code>.</p><pre>déjà vu</pre>`,
+	}
+	tests := map[string][]string{
+		outputUTF8:  {`title: 'Café résumé'`, "Naïve “quotes” — and `café`.", "```\ndéjà vu\n```"},
+		outputASCII: {`title: "Caf\u00E9 r\u00E9sum\u00E9"`, "Na&#239;ve &#8220;quotes&#8221; &#8212; and `café`.", "```\ndéjà vu\n```"},
+	}
+	for encoding, want := range tests {
+		cfg := testConfig(t)
+		cfg.outputEncoding = encoding
+		page := convertPages(t, cfg, pages)["page.md"]
+		for _, w := range want {
+			if !strings.Contains(page, w) {
+				t.Errorf("-output-encoding=%s: page is\n%s\nwant it to contain\n%s", encoding, page, w)
+			}
+		}
+	}
+}
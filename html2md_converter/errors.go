@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// convertError is returned for a zip entry that could not be converted or
+// copied. Err says why; it may wrap a *writeError.
+type convertError struct {
+	File string
+	Err  error
+}
+
+func (e *convertError) Error() string {
+	return fmt.Sprintf("failed to process %s: %v", e.File, e.Err)
+}
+
+func (e *convertError) Unwrap() error { return e.Err }
+
+// writeError is returned when an output file or its directory could not be
+// written.
+type writeError struct {
+	Path string
+	// Detail says what was being written
+	Detail string
+	Err    error
+}
+
+func (e *writeError) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Detail, e.Err)
+}
+
+func (e *writeError) Unwrap() error { return e.Err }
+
+// linkError is a link from a page to a page or directory missing from the
+// zip. Broken links are warnings; under -strict-links the run's
+// *strictError wraps them.
+type linkError struct {
+	File   string
+	Link   string
+	Detail string
+}
+
+func (e *linkError) Error() string {
+	return fmt.Sprintf("%s: link to %s, %s", e.File, e.Link, e.Detail)
+}
+
+// printLinkErrors lists the broken links behind a failed -strict-links run.
+func printLinkErrors(err error) {
+	var strictErr *strictError
+	if !errors.As(err, &strictErr) {
+		return
+	}
+	for _, l := range strictErr.links {
+		fmt.Printf("  %v\n", l)
+	}
+}
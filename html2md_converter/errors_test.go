@@ -0,0 +1,69 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestErrorTypes checks that a page that fails to convert surfaces as a
+// *convertError naming it, that a failed write is a *writeError within it,
+// and that the broken links of a -strict-links run are *linkErrors.
+func TestErrorTypes(t *testing.T) {
+	// Storing the pages uncompressed lets the test corrupt the bytes of one,
+	// which then fails its checksum when read
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, page := range []struct{ name, content string }{
+		{"a.html", "<h1>A</h1><p>Fine page</p>"},
+		{"b.html", "<h1>B</h1><p>Broken page</p>"},
+	} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: page.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(page.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	content := bytes.Replace(buf.Bytes(), []byte("Broken page"), []byte("Brokem page"), 1)
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = convertZip(r, filepath.Join(t.TempDir(), "output"), testConfig(t))
+	var convertErr *convertError
+	if !errors.As(err, &convertErr) || convertErr.File != "b.html" {
+		t.Errorf("corrupt page: run returned %v, want a convert error for b.html", err)
+	}
+
+	// A file where the output directory should be blocks the write
+	outputDir := filepath.Join(t.TempDir(), "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "docs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, map[string]string{"docs/page.html": "<h1>Page</h1><p>Body</p>"})
+	err = convertZipToMarkdown(zipPath, outputDir, testConfig(t))
+	var writeErr *writeError
+	if !errors.As(err, &convertErr) || convertErr.File != "docs/page.html" || !errors.As(err, &writeErr) || writeErr.Path != filepath.Join(outputDir, "docs", "page.md") {
+		t.Errorf("blocked output: run returned %v, want a write error for docs/page.md", err)
+	}
+
+	cfg := testConfig(t)
+	cfg.strict["links"] = true
+	err = convertTestZip(t, cfg, map[string]string{"docs/a.html": `<p>See <a href="missing.html">the missing page</a>.</p>`})
+	var linkErr *linkError
+	if !errors.As(err, &linkErr) || linkErr.File != "docs/a.html" || linkErr.Link != "missing.html" {
+		t.Errorf("-strict-links: run returned %v, want a link error for missing.html", err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultFooterSelector matches the attribution footers of Devsite pages
+// and <address> contact blocks.
+const defaultFooterSelector = "address, .devsite-content-footer, .devsite-last-updated"
+
+var (
+	// "Last updated 2023-01-02 UTC.", "Updated on: Jan 2, 2023"
+	lastUpdatedRegex = regexp.MustCompile(`(?i)\b(?:last\s+(?:updated|modified|reviewed)|updated)(?:\s+on)?\s*:?\s+(.+)`)
+	// "Authors: A, B", "Written by A and B."
+	authorsRegex = regexp.MustCompile(`(?i)(?:\bauthors?\s*:|\b(?:written|authored|maintained)\s+by\b|^by\b)\s*(.+?)(?:\.(?:\s|$)|\blast\s+(?:updated|modified|reviewed)\b|$)`)
+	// An ISO date anywhere in the text, for the last-updated notes of
+	// localized Devsite pages, such as "最終更新日 2023-01-02 UTC"
+	isoDateRegex = regexp.MustCompile(`\b\d{4}[-/.]\d{1,2}[-/.]\d{1,2}\b`)
+	// Separators between author names
+	authorSeparatorRegex = regexp.MustCompile(`\s*(?:,|&|\band\b)\s*`)
+)
+
+// dateLayouts are tried in order on the words following a last-updated
+// label.
+var dateLayouts = []string{
+	"2006-01-02", "2006/01/02", "2006.01.02", time.RFC3339,
+	"January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006",
+	"January 2 2006", "Jan 2 2006", "01/02/2006",
+	"2006-1-2", "2006/1/2", "2006.1.2", "02.01.2006",
+}
+
+// devsiteFooterSelector matches the footers Devsite writes the page's
+// last-updated note in.
+const devsiteFooterSelector = ".devsite-content-footer, .devsite-last-updated"
+
+// extractFooterMetadata moves the last-updated date and the authors of the
+// footers matched by selector into frontmatter fields, the date under
+// dateKey, removing the footers they came from. Dates become ISO dates; with
+// several footers the first value found wins. The last-updated note of a
+// localized Devsite page, whose label is not English, is read by its ISO
+// date.
+func extractFooterMetadata(doc *goquery.Document, selector, dateKey string) (frontmatter, error) {
+	var updated string
+	var authors []interface{}
+	doc.Find(selector).Each(func(i int, footer *goquery.Selection) {
+		text := collapseWhitespace(footer.Text())
+		found := false
+		date, ok := "", false
+		if m := lastUpdatedRegex.FindStringSubmatch(text); m != nil {
+			date, ok = parseLenientDate(m[1])
+		}
+		if m := isoDateRegex.FindString(text); !ok && m != "" && footer.Is(devsiteFooterSelector) {
+			date, ok = parseLenientDate(m)
+		}
+		if ok {
+			found = true
+			if updated == "" {
+				updated = date
+			}
+		}
+		if m := authorsRegex.FindStringSubmatch(text); m != nil {
+			var names []interface{}
+			for _, name := range authorSeparatorRegex.Split(m[1], -1) {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+			if len(names) > 0 {
+				found = true
+				if authors == nil {
+					authors = names
+				}
+			}
+		}
+		if found {
+			footer.Remove()
+		}
+	})
+
+	var fm frontmatter
+	if updated != "" {
+		fm = append(fm, frontmatterField{Key: dateKey, Value: updated})
+	}
+	if authors != nil {
+		field, err := yamlField("authors", authors)
+		if err != nil {
+			return nil, err
+		}
+		fm = append(fm, field)
+	}
+	return fm, nil
+}
+
+// parseLenientDate parses the date that text starts with, trying ever
+// fewer of its leading words, and returns it as YYYY-MM-DD. Periods after
+// abbreviated months and trailing punctuation or time zones are ignored.
+func parseLenientDate(text string) (string, bool) {
+	words := strings.Fields(strings.ReplaceAll(text, "Sept.", "Sep."))
+	for n := min(len(words), 4); n > 0; n-- {
+		candidate := strings.TrimRight(strings.Join(words[:n], " "), ".,;:")
+		for _, c := range []string{candidate, strings.ReplaceAll(candidate, ". ", " ")} {
+			for _, layout := range dateLayouts {
+				if t, err := time.Parse(layout, c); err == nil {
+					return t.Format("2006-01-02"), true
+				}
+			}
+		}
+	}
+	return "", false
+}
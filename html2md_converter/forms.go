@@ -0,0 +1,184 @@
+package main
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/escape"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// What the forms rule set does with buttons.
+const (
+	// Buttons are dropped, since a static page cannot press them
+	formControlsDrop = "drop"
+	// Buttons keep their caption as text
+	formControlsKeep = "keep"
+)
+
+// formOptions configures the forms rule set, which flattens the small
+// interactive demos some pages embed into a list of their labelled fields.
+type formOptions struct {
+	Buttons string `yaml:"buttons"`
+}
+
+// formFieldSelector matches the elements holding the values of a form.
+const formFieldSelector = "input, select, textarea, output"
+
+// buttonInputTypes are the <input> types that are buttons.
+var buttonInputTypes = map[string]bool{"submit": true, "reset": true, "button": true, "image": true}
+
+func formPlugin(options formOptions) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"form"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					return md.String("\n\n" + tightenFieldList(strings.TrimSpace(content)) + "\n\n")
+				},
+			},
+			{
+				// A label with its field becomes "- **Label:** value"
+				Filter: []string{"label"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					field := labelledField(selec)
+					if field == nil {
+						return &content
+					}
+					label := strings.TrimSuffix(collapseWhitespace(content), ":")
+					return md.String(formFieldLine(label, fieldValue(field), opt))
+				},
+			},
+			{
+				Filter: []string{"input", "select", "textarea", "output"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					kind := strings.ToLower(selec.AttrOr("type", ""))
+					switch {
+					case goquery.NodeName(selec) == "input" && buttonInputTypes[kind]:
+						if options.Buttons == formControlsKeep {
+							return md.String(formFieldLine("", escape.MarkdownCharacters(selec.AttrOr("value", "Submit")), opt))
+						}
+						return md.String("")
+					case kind == "hidden" || fieldLabel(selec) != nil:
+						// Labelled fields are written by their label
+						return md.String("")
+					case goquery.NodeName(selec) == "output":
+						return md.String(formFieldLine("", fieldValue(selec), opt))
+					}
+					return md.String(formFieldLine(selec.AttrOr("name", ""), fieldValue(selec), opt))
+				},
+			},
+			{
+				Filter: []string{"button"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if options.Buttons == formControlsKeep {
+						return md.String(formFieldLine("", strings.TrimSpace(content), opt))
+					}
+					return md.String("")
+				},
+			},
+		}
+	}
+}
+
+// formFieldLine writes a field as a list item of its bold label and value.
+func formFieldLine(label, value string, opt *md.Options) string {
+	var b strings.Builder
+	b.WriteString("\n- ")
+	if label != "" {
+		b.WriteString(opt.StrongDelimiter + label + ":" + opt.StrongDelimiter)
+		if value != "" {
+			b.WriteString(" ")
+		}
+	}
+	b.WriteString(value)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// tightenFieldList removes the blank lines between the field lines of a
+// form, which the whitespace between its fields leaves, so that they make a
+// single list.
+func tightenFieldList(content string) string {
+	blank := func(line string) bool { return strings.Trim(line, " \t"+interElementSpace) == "" }
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for i, line := range lines {
+		if blank(line) && len(kept) > 0 && strings.HasPrefix(kept[len(kept)-1], "- ") {
+			next := i + 1
+			for next < len(lines) && blank(lines[next]) {
+				next++
+			}
+			if next < len(lines) && strings.HasPrefix(lines[next], "- ") {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// labelledField returns the field a label names with its for attribute or
+// wraps, if any.
+func labelledField(label *goquery.Selection) *goquery.Selection {
+	if id := label.AttrOr("for", ""); id != "" {
+		field := label.Parents().Last().Find(formFieldSelector).FilterFunction(func(i int, s *goquery.Selection) bool {
+			return s.AttrOr("id", "") == id
+		})
+		if field.Length() > 0 {
+			return field.First()
+		}
+	}
+	if field := label.Find(formFieldSelector).First(); field.Length() > 0 {
+		return field
+	}
+	return nil
+}
+
+// fieldLabel returns the label of a field, if any.
+func fieldLabel(field *goquery.Selection) *goquery.Selection {
+	if label := field.Closest("label"); label.Length() > 0 {
+		return label
+	}
+	id := field.AttrOr("id", "")
+	if id == "" {
+		return nil
+	}
+	label := field.Parents().Last().Find("label[for]").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.AttrOr("for", "") == id
+	})
+	if label.Length() == 0 {
+		return nil
+	}
+	return label.First()
+}
+
+// fieldValue returns the value a field shows, escaped for markdown.
+func fieldValue(field *goquery.Selection) string {
+	var value string
+	switch goquery.NodeName(field) {
+	case "select":
+		options := field.Find("option[selected]")
+		if options.Length() == 0 {
+			options = field.Find("option").First()
+		}
+		var selected []string
+		options.Each(func(i int, option *goquery.Selection) {
+			selected = append(selected, collapseWhitespace(option.Text()))
+		})
+		value = strings.Join(selected, ", ")
+	case "textarea", "output":
+		value = collapseWhitespace(field.Text())
+	default:
+		switch strings.ToLower(field.AttrOr("type", "")) {
+		case "checkbox", "radio":
+			value = "not selected"
+			if _, checked := field.Attr("checked"); checked {
+				value = "selected"
+			}
+		default:
+			value = field.AttrOr("value", field.AttrOr("placeholder", ""))
+		}
+	}
+	return escape.MarkdownCharacters(collapseWhitespace(value))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForms(t *testing.T) {
+	page := `<form>
+<label for="target">Target:</label> <input id="target" value="//main:app">
+<label>Mode <select><option>fastbuild</option><option selected>opt</option></select></label>
+<label><input type="checkbox" checked> Verbose</label>
+<input type="hidden" name="token" value="secret">
+<output>Build succeeded</output>
+<button>Run</button> <input type="submit" value="Send">
+</form>`
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{"", "- **Target:** //main:app\n- **Mode:** opt\n- **Verbose:** selected\n- Build succeeded"},
+		{"rules:\n  forms:\n    buttons: keep\n", "- **Target:** //main:app\n- **Mode:** opt\n- **Verbose:** selected\n- Build succeeded\n- Run\n- Send"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		ruleSets, _, err := loadConfig(writeConfig(t, tt.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ruleSets = ruleSets
+		got, err := newConverter(cfg).ConvertString(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got = strings.TrimSpace(restoreInterElementSpaces(got)); got != tt.want {
+			t.Errorf("config %q: form converted to\n%s\nwant\n%s", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	stdhtml "html"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Forms of embedded live examples accepted by -embed-mode.
+const (
+	// An <iframe> showing the example
+	embedIframe = "iframe"
+	// A link to the example, or its source when it has no URL
+	embedLink = "link"
+)
+
+var (
+	// {% framebox height="300px" %}...{% endframebox %}
+	frameboxRegex = regexp.MustCompile(`(?s)\{%-?\s*framebox\b((?:[^%]|%[^}])*?)-?%\}(.*?)\{%-?\s*endframebox\s*-?%\}`)
+	// name="value", name='value' or name=value in a template tag
+	templateAttrRegex = regexp.MustCompile(`(\w+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"']+))`)
+)
+
+// frameboxSelector matches the iframes Devsite renders frameboxes as, and
+// those expandFrameboxes writes.
+const frameboxSelector = "iframe.framebox, devsite-iframe iframe"
+
+// attrEmbed marks a framebox for embedRule.
+const attrEmbed = "data-html2md-embed"
+
+// attrCopiedExample marks a link to an example copied by -copy-examples,
+// which already points at its output path.
+const attrCopiedExample = "data-html2md-example"
+
+// embedAttrEscaper escapes iframe attributes, whose srcdoc may span lines
+// and hold braces, which MDX would otherwise read as an expression.
+var embedAttrEscaper = strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;", "\n", "&#10;", "{", "&#123;", "}", "&#125;")
+
+// expandFrameboxes turns the {% framebox %} blocks of a page's template into
+// the iframes Devsite renders them as, holding the example in srcdoc. It
+// runs before braces are protected, so that braces of the example stay
+// what they are.
+func expandFrameboxes(page string) string {
+	return frameboxRegex.ReplaceAllStringFunc(page, func(m string) string {
+		sub := frameboxRegex.FindStringSubmatch(m)
+		var b strings.Builder
+		b.WriteString(`<iframe class="framebox"`)
+		for _, attr := range templateAttrRegex.FindAllStringSubmatch(sub[1], -1) {
+			if attr[1] == "width" || attr[1] == "height" {
+				b.WriteString(" " + attr[1] + `="` + stdhtml.EscapeString(attr[2]+attr[3]+attr[4]) + `"`)
+			}
+		}
+		b.WriteString(` srcdoc="` + stdhtml.EscapeString(strings.TrimSpace(sub[2])) + `"></iframe>`)
+		return b.String()
+	})
+}
+
+// rewriteFrameboxes prepares the page's frameboxes for the -embed-mode
+// form. With -copy-examples, each example is written to the page's
+// .examples directory, from srcdoc or from the zip entry its src points at,
+// and the framebox points at the copy.
+func (c *conversion) rewriteFrameboxes(doc *goquery.Document, sourcePath, pagePath string) error {
+	var err error
+	doc.Find(frameboxSelector).EachWithBreak(func(i int, frame *goquery.Selection) bool {
+		src := frame.AttrOr("src", "")
+		srcdoc, inline := frame.Attr("srcdoc")
+		copied := false
+		if c.cfg.copyExamples {
+			var examplePath string
+			if examplePath, err = c.copyExample(sourcePath, pagePath, src, srcdoc, inline, i+1); err != nil {
+				return false
+			}
+			if examplePath != "" {
+				src, inline, copied = relativeLink(pagePath, examplePath), false, true
+			}
+		}
+
+		switch {
+		case c.cfg.embedMode == embedIframe:
+			frame.SetAttr(attrEmbed, "")
+			if !inline {
+				frame.RemoveAttr("srcdoc")
+				frame.SetAttr("src", src)
+			}
+		case !inline && src != "":
+			link := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: "href", Val: src}}}
+			if copied {
+				link.Attr = append(link.Attr, html.Attribute{Key: attrCopiedExample})
+			}
+			link.AppendChild(&html.Node{Type: html.TextNode, Data: frame.AttrOr("title", "Open the example")})
+			p := &html.Node{Type: html.ElementNode, Data: "p"}
+			p.AppendChild(link)
+			frame.ReplaceWithNodes(p)
+		case inline:
+			code := &html.Node{Type: html.ElementNode, Data: "code", Attr: []html.Attribute{{Key: "class", Val: "language-html"}}}
+			code.AppendChild(&html.Node{Type: html.TextNode, Data: srcdoc})
+			pre := &html.Node{Type: html.ElementNode, Data: "pre"}
+			pre.AppendChild(code)
+			frame.ReplaceWithNodes(pre)
+		default:
+			frame.Remove()
+		}
+		return true
+	})
+	return err
+}
+
+// copyExample writes the example of the n-th framebox of a page to the
+// page's .examples directory and returns its output path. Examples that
+// are neither inline nor in the zip are left where they are.
+func (c *conversion) copyExample(sourcePath, pagePath, src, srcdoc string, inline bool, n int) (string, error) {
+	dir := changeExtension(pagePath, ".examples")
+	var examplePath, from string
+	var content []byte
+	if inline {
+		examplePath = path.Join(dir, fmt.Sprintf("framebox-%d.html", n))
+		from, content = sourcePath, []byte(srcdoc)
+	} else {
+		name, _, ok := resolveZipRef(sourcePath, src)
+		f := c.files[name]
+		if !ok || f == nil {
+			return "", nil
+		}
+		var err error
+		if content, err = readZipFile(f); err != nil {
+			return "", err
+		}
+		examplePath = path.Join(dir, path.Base(name))
+		from = name
+	}
+
+	if c.written[examplePath] != "" {
+		return examplePath, nil
+	}
+	c.claimOutput(examplePath, from)
+	fullPath := filepath.Join(c.outputDir, filepath.FromSlash(examplePath))
+	if err := mkdirAll(filepath.Dir(fullPath)); err != nil {
+		return "", &writeError{Path: fullPath, Detail: "create examples directory", Err: err}
+	}
+	if err := writeFile(fullPath, content); err != nil {
+		return "", &writeError{Path: fullPath, Detail: "write example", Err: err}
+	}
+	fmt.Printf("  -> Copied example: %s\n", fullPath)
+	c.stats.bytesWritten += int64(len(content))
+	c.manifest.add(manifestRecord{Source: from, Output: examplePath, Bytes: int64(len(content)), Kind: recordAsset})
+	return examplePath, nil
+}
+
+// embedRule writes the frameboxes rewriteFrameboxes marked as <iframe>
+// tags.
+func embedRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"iframe"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			if _, ok := selec.Attr(attrEmbed); !ok {
+				return nil
+			}
+			var b strings.Builder
+			b.WriteString("\n\n<iframe")
+			for _, attr := range []struct{ html, jsx string }{
+				{"src", "src"}, {"srcdoc", "srcDoc"}, {"title", "title"}, {"width", "width"}, {"height", "height"},
+			} {
+				if value, ok := selec.Attr(attr.html); ok {
+					b.WriteString(" " + attr.jsx + `="` + embedAttrEscaper.Replace(value) + `"`)
+				}
+			}
+			b.WriteString(" />\n\n")
+			return md.String(b.String())
+		},
+	}
+}
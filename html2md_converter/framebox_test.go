@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFramebox(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+{% framebox height="200px" %}<p>Hello {name}</p>{% endframebox %}
+<devsite-iframe><iframe src="examples/demo.html" title="Demo"></iframe></devsite-iframe>`,
+		"docs/examples/demo.html": `<p>Demo</p>`,
+	}
+	tests := []struct {
+		embedMode    string
+		copyExamples bool
+		want         []string
+	}{
+		{embedIframe, false, []string{
+			`<iframe srcDoc="&lt;p&gt;Hello &#123;name&#125;&lt;/p&gt;" height="200px" />`,
+			`<iframe src="examples/demo.html" title="Demo" />`,
+		}},
+		{embedLink, false, []string{
+			"```html\n<p>Hello {name}</p>\n```",
+			"[Demo](examples/demo.md)",
+		}},
+		{embedLink, true, []string{
+			"[Open the example](page.examples/framebox-1.html)",
+			"[Demo](page.examples/demo.html)",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.embedMode = tt.embedMode
+		cfg.copyExamples = tt.copyExamples
+		written := convertPages(t, cfg, pages)
+		page := written["docs/page.md"]
+		if tt.copyExamples && (written["docs/page.examples/framebox-1.html"] != "<p>Hello {name}</p>" || written["docs/page.examples/demo.html"] != "<p>Demo</p>") {
+			t.Errorf("-copy-examples: examples not copied: %q", written)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-embed-mode %s -copy-examples=%v: page lacks\n%s\ngot\n%s", tt.embedMode, tt.copyExamples, want, page)
+			}
+		}
+		if strings.Contains(page, "{%") {
+			t.Errorf("-embed-mode %s: template tags left in the page:\n%s", tt.embedMode, page)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// frontmatterField is a single key of a page's YAML frontmatter.
+type frontmatterField struct {
+	Key   string
+	Value string
+	// Plain values, such as numbers, are written without quotes; those
+	// starting with a newline are indented YAML blocks
+	Plain bool
+}
+
+// frontmatter is rendered in field order.
+type frontmatter []frontmatterField
+
+// String renders the fields as a YAML block with single-quoted values, the
+// same form transform-docs.awk produces for titles.
+func (fm frontmatter) String() string {
+	if len(fm) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, field := range fm {
+		if field.Plain && strings.HasPrefix(field.Value, "\n") {
+			b.WriteString(field.Key + ":" + field.Value + "\n")
+			continue
+		}
+		if field.Plain {
+			b.WriteString(field.Key + ": " + field.Value + "\n")
+			continue
+		}
+		b.WriteString(field.Key + ": '" + strings.ReplaceAll(field.Value, "'", "''") + "'\n")
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// sorted returns the fields with those named in keys first, in that order,
+// and the rest after them by key, so the frontmatter of a page does not
+// depend on which options added its fields, or in which order pages
+// embedded them.
+func (fm frontmatter) sorted(keys []string) frontmatter {
+	rank := make(map[string]int, len(keys))
+	for i, key := range keys {
+		rank[key] = i + 1
+	}
+	sorted := append(frontmatter(nil), fm...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank[sorted[i].Key], rank[sorted[j].Key]
+		if ri == 0 || rj == 0 {
+			if ri != rj {
+				return ri != 0
+			}
+			return sorted[i].Key < sorted[j].Key
+		}
+		return ri < rj
+	})
+	return sorted
+}
+
+// parseKeyList parses a comma-separated list of frontmatter keys.
+func parseKeyList(list string) []string {
+	var keys []string
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// pageTitle derives the page title from its first <h1>, falling back to the
+// <title> element and finally to the file name. The returned selection is
+// the <h1> the title came from, if any.
+func pageTitle(doc *goquery.Document, sourcePath string) (string, *goquery.Selection) {
+	if h1 := doc.Find("body h1").First(); h1.Length() > 0 {
+		if title := collapseWhitespace(h1.Text()); title != "" {
+			return title, h1
+		}
+	}
+	if title := collapseWhitespace(doc.Find("head title").First().Text()); title != "" {
+		return title, nil
+	}
+
+	base := path.Base(sourcePath)
+	return strings.TrimSuffix(base, path.Ext(base)), nil
+}
+
+// applyTitle moves the page title into frontmatter, removing the <h1> it came
+// from. With -no-frontmatter the title stays a markdown H1 instead, and one
+// is synthesized for pages that have no <h1>. It returns the frontmatter to
+// prepend and any markdown to put before the converted body.
+func applyTitle(doc *goquery.Document, sourcePath string, cfg config) (frontmatter, string) {
+	title, h1 := pageTitle(doc, sourcePath)
+	subtitle := ""
+	if h1 != nil {
+		subtitle = takeHgroupSubtitle(h1, cfg.hgroupSubtitle == hgroupLine || cfg.noFrontmatter)
+	}
+
+	if cfg.noFrontmatter {
+		if h1 != nil {
+			return nil, ""
+		}
+		return nil, "# " + title + "\n\n"
+	}
+
+	if h1 != nil {
+		h1.Remove()
+	}
+	fm := frontmatter{{Key: "title", Value: title}}
+	if subtitle != "" {
+		fm = append(fm, frontmatterField{Key: cfg.hgroupSubtitle, Value: subtitle})
+	}
+	return fm, ""
+}
+
+// Where -hgroup-subtitle puts the subtitle of the title's <hgroup>.
+const (
+	// The description field of the frontmatter
+	hgroupDescription = "description"
+	// A subtitle field of the frontmatter
+	hgroupSubtitle = "subtitle"
+	// An italic line under the title
+	hgroupLine = "line"
+)
+
+// takeHgroupSubtitle returns the subtitle of the <hgroup> the title heading
+// is in, which is the text of the group's other elements, and removes them.
+// With asLine they are replaced by a single italic paragraph after the
+// heading instead, and nothing is returned.
+func takeHgroupSubtitle(h1 *goquery.Selection, asLine bool) string {
+	hgroup := h1.Parent()
+	if goquery.NodeName(hgroup) != "hgroup" {
+		return ""
+	}
+	rest := hgroup.Children().NotSelection(h1)
+	var parts []string
+	rest.Each(func(i int, s *goquery.Selection) {
+		if text := collapseWhitespace(s.Text()); text != "" {
+			parts = append(parts, text)
+		}
+	})
+	rest.Remove()
+	subtitle := strings.Join(parts, " ")
+	if !asLine || subtitle == "" {
+		return subtitle
+	}
+
+	em := &html.Node{Type: html.ElementNode, Data: "em"}
+	em.AppendChild(&html.Node{Type: html.TextNode, Data: subtitle})
+	p := &html.Node{Type: html.ElementNode, Data: "p"}
+	p.AppendChild(em)
+	hgroup.AfterNodes(p)
+	return ""
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// readingTime returns the frontmatter fields for the word count and the
+// reading time, in whole minutes at wpm words per minute, of a page body.
+func readingTime(body string, wpm int) frontmatter {
+	words := len(strings.Fields(stripMarkdown(body)))
+	minutes := (words + wpm - 1) / wpm
+	if minutes < 1 {
+		minutes = 1
+	}
+	return frontmatter{
+		{Key: "word_count", Value: strconv.Itoa(words), Plain: true},
+		{Key: "reading_time", Value: strconv.Itoa(minutes), Plain: true},
+	}
+}
+
+// dropTitleHeading removes the heading a page's body starts with when its
+// text is the page title, which the page would otherwise show twice. skip
+// is the <h1> kept as the title with -no-frontmatter, if any; the heading
+// right after it is the one compared.
+func dropTitleHeading(doc *goquery.Document, title string, skip *goquery.Selection) {
+	var leading *html.Node
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if skip != nil && n == skip.Nodes[0] {
+			return false
+		}
+		switch {
+		case n.Type == html.ElementNode && headingLevel(n.Data) > 0:
+			leading = n
+			return true
+		case n.Type == html.ElementNode && (n.Data == "img" || n.Data == "pre" || n.Data == "table"):
+			return true
+		case n.Type == html.TextNode && strings.TrimSpace(n.Data) != "":
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	body := doc.Find("body")
+	if body.Length() == 0 || !walk(body.Nodes[0]) || leading == nil {
+		return
+	}
+	if strings.EqualFold(collapseWhitespace(doc.FindNodes(leading).Text()), collapseWhitespace(title)) {
+		leading.Parent.RemoveChild(leading)
+	}
+}
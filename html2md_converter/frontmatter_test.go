@@ -0,0 +1,191 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitleFrontmatter(t *testing.T) {
+	tests := []struct {
+		name, page       string
+		noFrontmatter    bool
+		prefix, notFound string
+	}{
+		{"frontmatter", `<html><head><title>Head title</title></head><body><h1>Rule's title</h1><p>Body</p></body></html>`, false, "---\ntitle: 'Rule''s title'\n---\n\nBody", "# Rule"},
+		{"fallback to <title>", `<html><head><title>Head title</title></head><body><p>Body</p></body></html>`, false, "---\ntitle: 'Head title'\n---\n\nBody", "Head title\n"},
+		{"no frontmatter", `<h1>Title</h1><p>Body</p>`, true, "# Title\n\nBody", "---"},
+		{"synthesized h1", `<html><head><title>Head title</title></head><body><p>Body</p></body></html>`, true, "# Head title\n\nBody", "---"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.noFrontmatter = tt.noFrontmatter
+		page := convertPages(t, cfg, map[string]string{"page.html": tt.page})["page.md"]
+		if !strings.HasPrefix(page, tt.prefix) {
+			t.Errorf("%s: page is\n%s\nwant it to start with\n%s", tt.name, page, tt.prefix)
+		}
+		if strings.Contains(page, tt.notFound) {
+			t.Errorf("%s: page contains %q:\n%s", tt.name, tt.notFound, page)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	// 450 words: the title is not part of the body
+	page := "<h1>Title</h1><p>" + strings.Repeat("word ", 449) + "<strong>last</strong></p>"
+	tests := []struct {
+		wpm  int
+		want string
+	}{
+		{200, "---\ntitle: 'Title'\nreading_time: 3\nword_count: 450\n---\n"},
+		{1000, "---\ntitle: 'Title'\nreading_time: 1\nword_count: 450\n---\n"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.readingTimeWPM = tt.wpm
+		got := convertPages(t, cfg, map[string]string{"page.html": page})["page.md"]
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("-reading-wpm=%d: page starts\n%.80s\nwant\n%s", tt.wpm, got, tt.want)
+		}
+	}
+}
+
+func TestRawFrontmatter(t *testing.T) {
+	pages := map[string]string{
+		"comment.html": "<html><head><!--\n---\ntitle: Embedded title\nsidebar_position: 3\ntags:\n  - build\n---\n--></head><body><h1>Computed title</h1><p>Body</p></body></html>",
+		"pre.html":     "<pre>\n---\ndescription: From a pre\n---\n</pre><h1>Pre page</h1><p>Body</p>",
+		"invalid.html": "<pre>\n---\ntitle: [unclosed\n---\n</pre><h1>Invalid</h1><p>Body</p>",
+		"example.html": "<h1>Example</h1><p>Body</p><pre>\n---\ntitle: Example block\n---\n</pre>",
+	}
+	want := map[string]string{
+		"comment.md": "---\ntitle: 'Embedded title'\nsidebar_position: 3\ntags:\n  - build\n---\n\nBody",
+		"pre.md":     "---\ntitle: 'Pre page'\ndescription: 'From a pre'\n---\n\nBody",
+		"invalid.md": "---\ntitle: 'Invalid'\n---\n\n```\n---\ntitle: [unclosed",
+		"example.md": "---\ntitle: 'Example'\n---\n\nBody\n\n```\n---\ntitle: Example block",
+	}
+	cfg := testConfig(t)
+	cfg.rawFrontmatterMarker = "---"
+	written := convertPages(t, cfg, pages)
+	for path, prefix := range want {
+		if !strings.HasPrefix(written[path], prefix) {
+			t.Errorf("%s is\n%s\nwant it to start with\n%s", path, written[path], prefix)
+		}
+	}
+}
+
+func TestFooterFrontmatter(t *testing.T) {
+	pages := map[string]string{
+		"devsite.html": `<h1>Devsite</h1><p>Body</p><div class="devsite-last-updated">Last updated 2023-01-02 UTC.</div>`,
+		"address.html": `<h1>Address</h1><p>Body</p><address>Written by Ada and Grace. Last updated Jan. 2, 2023</address>`,
+		"other.html":   `<h1>Other</h1><p>Body</p><address>Contact the team</address>`,
+	}
+	want := map[string]string{
+		"devsite.md": "---\ntitle: 'Devsite'\nlast_updated: '2023-01-02'\n---\n\nBody",
+		"address.md": "---\ntitle: 'Address'\nauthors:\n  - Ada\n  - Grace\nlast_updated: '2023-01-02'\n---\n\nBody",
+		"other.md":   "---\ntitle: 'Other'\n---\n\nBody\n\nContact the team",
+	}
+	cfg := testConfig(t)
+	cfg.footerSelector = defaultFooterSelector
+	written := convertPages(t, cfg, pages)
+	for path, page := range want {
+		if got := strings.TrimSpace(written[path]); got != page {
+			t.Errorf("%s is\n%s\nwant\n%s", path, got, page)
+		}
+	}
+}
+
+// TestFooterDateKey checks that -footer-date-key names the date field and
+// that localized and unpadded Devsite dates are read.
+func TestFooterDateKey(t *testing.T) {
+	pages := map[string]string{
+		"en.html":   `<h1>En</h1><p>Body</p><div class="devsite-last-updated">Last updated 2023-1-2 UTC.</div>`,
+		"ja.html":   `<h1>Ja</h1><p>Body</p><div class="devsite-content-footer">最終更新日 2023/11/02 UTC</div>`,
+		"de.html":   `<h1>De</h1><p>Body</p><address>Last updated: 02.11.2023</address>`,
+		"none.html": `<h1>None</h1><p>Body</p>`,
+	}
+	want := map[string]string{
+		"en.md":   "---\ntitle: 'En'\nupdated: '2023-01-02'\n---\n\nBody",
+		"ja.md":   "---\ntitle: 'Ja'\nupdated: '2023-11-02'\n---\n\nBody",
+		"de.md":   "---\ntitle: 'De'\nupdated: '2023-11-02'\n---\n\nBody",
+		"none.md": "---\ntitle: 'None'\n---\n\nBody",
+	}
+	cfg := testConfig(t)
+	cfg.footerSelector = defaultFooterSelector
+	cfg.footerDateKey = "updated"
+	written := convertPages(t, cfg, pages)
+	for path, page := range want {
+		if got := strings.TrimSpace(written[path]); got != page {
+			t.Errorf("%s is\n%s\nwant\n%s", path, got, page)
+		}
+	}
+}
+
+func TestDedupTitleHeading(t *testing.T) {
+	tests := []struct {
+		name, page    string
+		noFrontmatter bool
+		want          string
+	}{
+		{"repeated title", `<html><head><title>Build rules</title></head><body><h2>Build  RULES</h2><p>Body</p></body></html>`, false, "---\ntitle: 'Build rules'\n---\n\nBody"},
+		{"repeated h1", `<h1>Build rules</h1><h2>Build rules</h2><p>Body</p>`, false, "---\ntitle: 'Build rules'\n---\n\nBody"},
+		{"different heading", `<html><head><title>Build rules</title></head><body><h2>Overview</h2><p>Body</p></body></html>`, false, "---\ntitle: 'Build rules'\n---\n\n## Overview\n\nBody"},
+		{"after content", `<h1>Build rules</h1><p>Intro</p><h2>Build rules</h2>`, false, "---\ntitle: 'Build rules'\n---\n\nIntro\n\n## Build rules"},
+		{"no frontmatter", `<h1>Build rules</h1><h2>Build rules</h2><p>Body</p>`, true, "# Build rules\n\nBody"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.dedupTitleHeading = true
+		cfg.noFrontmatter = tt.noFrontmatter
+		page := convertPages(t, cfg, map[string]string{"page.html": tt.page})["page.md"]
+		if got := strings.TrimSpace(page); got != tt.want {
+			t.Errorf("%s: page is\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHgroupSubtitle(t *testing.T) {
+	page := `<hgroup><h1>Bazel</h1><p>Fast, correct builds</p></hgroup><p>Body</p>`
+	tests := []struct {
+		mode          string
+		noFrontmatter bool
+		want          string
+	}{
+		{hgroupDescription, false, "---\ntitle: 'Bazel'\ndescription: 'Fast, correct builds'\n---\n\nBody"},
+		{hgroupSubtitle, false, "---\ntitle: 'Bazel'\nsubtitle: 'Fast, correct builds'\n---\n\nBody"},
+		{hgroupLine, false, "---\ntitle: 'Bazel'\n---\n\n_Fast, correct builds_\n\nBody"},
+		{hgroupDescription, true, "# Bazel\n\n_Fast, correct builds_\n\nBody"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.hgroupSubtitle = tt.mode
+		cfg.noFrontmatter = tt.noFrontmatter
+		got := strings.TrimSpace(convertPages(t, cfg, map[string]string{"page.html": page})["page.md"])
+		if got != tt.want {
+			t.Errorf("-hgroup-subtitle %s -no-frontmatter=%v: page is\n%s\nwant\n%s", tt.mode, tt.noFrontmatter, got, tt.want)
+		}
+	}
+}
+
+// TestFrontmatterOrder converts pages whose fields come from options and
+// embedded frontmatter in varying orders, twice, and checks that both runs
+// write the listed keys first and the others alphabetically.
+func TestFrontmatterOrder(t *testing.T) {
+	pages := map[string]string{
+		"a.html": "<html><head><!--\n---\nzeta: 1\ndescription: First\nalpha: 2\n---\n--></head><body><h1>A</h1><p>Body</p></body></html>",
+		"b.html": "<html><head><!--\n---\nalpha: 2\nzeta: 1\ndescription: Second\n---\n--></head><body><h1>B</h1><p>Body</p></body></html>",
+	}
+	want := map[string]string{
+		"a.md": "---\ntitle: 'A'\ndescription: 'First'\nalpha: 2\nreading_time: 1\nword_count: 1\nzeta: 1\n---\n",
+		"b.md": "---\ntitle: 'B'\ndescription: 'Second'\nalpha: 2\nreading_time: 1\nword_count: 1\nzeta: 1\n---\n",
+	}
+	for run := 1; run <= 2; run++ {
+		cfg := testConfig(t)
+		cfg.rawFrontmatterMarker = "---"
+		cfg.readingTimeWPM = 200
+		written := convertPages(t, cfg, pages)
+		for path, prefix := range want {
+			if !strings.HasPrefix(written[path], prefix) {
+				t.Errorf("run %d: %s is\n%s\nwant it to start with\n%s", run, path, written[path], prefix)
+			}
+		}
+	}
+}
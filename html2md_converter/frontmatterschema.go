@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadFrontmatterSchema reads the -frontmatter-schema file, a JSON Schema in
+// YAML or JSON using the subset the config file's schema does, e.g.
+//
+//	type: object
+//	required: [title]
+//	properties:
+//	  title: {type: string}
+//	  icon: {type: string}
+//	additionalProperties: false
+func loadFrontmatterSchema(schemaPath string) (*jsonSchema, error) {
+	if schemaPath == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frontmatter schema: %w", err)
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter schema %s: %w", schemaPath, err)
+	}
+	schema, err := decodeSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("frontmatter schema %s: %w", schemaPath, err)
+	}
+	return schema, nil
+}
+
+// decodeSchema builds a jsonSchema from a schema decoded by yaml.v2,
+// going through JSON so that the struct's json tags apply.
+func decodeSchema(raw interface{}) (*jsonSchema, error) {
+	data, err := json.Marshal(jsonValue(raw))
+	if err != nil {
+		return nil, err
+	}
+	var schema jsonSchema
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, err
+	}
+	return &schema, schema.resolveAdditional()
+}
+
+// resolveAdditional turns additionalProperties decoded as a JSON object into
+// the *jsonSchema validate expects, throughout the schema.
+func (s *jsonSchema) resolveAdditional() error {
+	if extra, ok := s.AdditionalProperties.(map[string]interface{}); ok {
+		schema, err := decodeSchema(extra)
+		if err != nil {
+			return err
+		}
+		s.AdditionalProperties = schema
+	}
+	for _, sub := range append(append([]*jsonSchema{s.Items}, s.AnyOf...), mapValues(s.Properties)...) {
+		if sub == nil {
+			continue
+		}
+		if err := sub.resolveAdditional(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapValues(m map[string]*jsonSchema) []*jsonSchema {
+	values := make([]*jsonSchema, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// jsonValue converts the maps yaml.v2 decodes, keyed by interface{}, into
+// maps JSON can encode.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = jsonValue(item)
+		}
+		return list
+	}
+	return v
+}
+
+// checkFrontmatter warns about each way the page's frontmatter, as it is
+// written, breaks -frontmatter-schema.
+func (c *conversion) checkFrontmatter(fm frontmatter) {
+	block := strings.TrimSuffix(strings.TrimPrefix(restoreVerbatim(fm.String()), "---\n"), "---\n\n")
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(block), &value); err != nil {
+		fmt.Printf("  Warning: frontmatter is not valid YAML: %v\n", err)
+		c.stats.frontmatterIssues++
+		return
+	}
+	if value == nil {
+		value = map[interface{}]interface{}{}
+	}
+	for _, problem := range c.cfg.frontmatterSchema.validate(value, "") {
+		fmt.Printf("  Warning: frontmatter %s\n", problem)
+		c.stats.frontmatterIssues++
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestFrontmatterSchema checks pages against a schema requiring a string
+// title and allowing a string icon and numeric reading fields: a page with
+// a number as its icon fails -strict-frontmatter with exit status 1, and
+// frontmatter without a title is flagged.
+func TestFrontmatterSchema(t *testing.T) {
+	schema, err := loadFrontmatterSchema(writeConfig(t, "type: object\nrequired: [title]\nproperties:\n  title: {type: string}\n  icon: {type: string}\n  reading_time: {type: number}\n  word_count: {type: number}\nadditionalProperties: false\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, page string
+		fails      bool
+	}{
+		{"valid", "<html><head><!--\n---\nicon: rocket\n---\n--></head><body><h1>Valid</h1><p>Body</p></body></html>", false},
+		{"numeric icon", "<html><head><!--\n---\nicon: 3\n---\n--></head><body><h1>Icon</h1><p>Body</p></body></html>", true},
+		{"unknown key", "<html><head><!--\n---\nsidebar: 1\n---\n--></head><body><h1>Extra</h1><p>Body</p></body></html>", true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.frontmatterSchema = schema
+		cfg.rawFrontmatterMarker = "---"
+		cfg.readingTimeWPM = 200
+		cfg.strict["frontmatter"] = true
+		err := convertTestZip(t, cfg, map[string]string{"page.html": tt.page})
+		var strictErr *strictError
+		switch {
+		case !tt.fails && err != nil:
+			t.Errorf("%s: run failed: %v", tt.name, err)
+		case tt.fails && !errors.As(err, &strictErr):
+			t.Errorf("%s: run returned %v, want a strict error", tt.name, err)
+		case tt.fails && strictErr.code != 1:
+			t.Errorf("%s: exit status %d, want 1", tt.name, strictErr.code)
+		}
+	}
+
+	// Converted pages always have a title, so check frontmatter without one
+	// directly
+	c := &conversion{cfg: testConfig(t), stats: &stats{}}
+	c.cfg.frontmatterSchema = schema
+	c.checkFrontmatter(frontmatter{{Key: "icon", Value: "rocket"}})
+	if c.stats.frontmatterIssues != 1 {
+		t.Errorf("frontmatter without a title has %d problem(s), want 1", c.stats.frontmatterIssues)
+	}
+}
+
+func TestFrontmatterSchemaValues(t *testing.T) {
+	schema, err := loadFrontmatterSchema(writeConfig(t, "type: object\nproperties:\n  weight: {type: number}\n  level: {enum: [1, 2, draft]}\nadditionalProperties: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		frontmatter string
+		problems    int
+	}{
+		{"weight: 3\nlevel: 2\nextra: yes\n", 0},
+		{"weight: 2.5\nlevel: draft\n", 0},
+		{"weight: heavy\nlevel: 3\n", 2},
+	}
+	for _, tt := range tests {
+		c := &conversion{cfg: testConfig(t), stats: &stats{}}
+		c.cfg.frontmatterSchema = schema
+		var fm frontmatter
+		for _, line := range strings.Split(strings.TrimSpace(tt.frontmatter), "\n") {
+			key, value, _ := strings.Cut(line, ": ")
+			fm = append(fm, frontmatterField{Key: key, Value: value, Plain: true})
+		}
+		c.checkFrontmatter(fm)
+		if c.stats.frontmatterIssues != tt.problems {
+			t.Errorf("%q has %d problem(s), want %d", tt.frontmatter, c.stats.frontmatterIssues, tt.problems)
+		}
+	}
+}
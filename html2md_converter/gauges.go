@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Forms of <progress> and <meter> accepted by -gauge-style.
+const (
+	// 75%
+	gaugePercent = "percent"
+	// 3/4
+	gaugeFraction = "fraction"
+	// ███████░░░ 75%
+	gaugeBar = "bar"
+)
+
+// gaugeBarCells is the width of the bar, in characters.
+const gaugeBarCells = 10
+
+// gaugeRule writes <progress> and <meter> elements, which have no text of
+// their own, as their value in the given style. An indeterminate
+// <progress>, without a value, keeps its fallback content.
+func gaugeRule(style string) md.Rule {
+	return md.Rule{
+		Filter: []string{"progress", "meter"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			attr := func(name string, fallback float64) float64 {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(selec.AttrOr(name, "")), 64); err == nil {
+					return v
+				}
+				return fallback
+			}
+			if _, ok := selec.Attr("value"); !ok && goquery.NodeName(selec) == "progress" {
+				return &content
+			}
+			low, high := 0.0, attr("max", 1)
+			if goquery.NodeName(selec) == "meter" {
+				low = attr("min", 0)
+			}
+			if high <= low {
+				return &content
+			}
+			value := math.Max(low, math.Min(high, attr("value", 0)))
+			percent := strconv.Itoa(int(math.Round((value-low)/(high-low)*100))) + "%"
+
+			switch style {
+			case gaugeFraction:
+				return md.String(formatGaugeNumber(value) + "/" + formatGaugeNumber(high))
+			case gaugeBar:
+				filled := int(math.Round((value - low) / (high - low) * gaugeBarCells))
+				return md.String(strings.Repeat("█", filled) + strings.Repeat("░", gaugeBarCells-filled) + " " + percent)
+			}
+			return md.String(percent)
+		},
+	}
+}
+
+func formatGaugeNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
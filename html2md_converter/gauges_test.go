@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGauges(t *testing.T) {
+	html := `<p>Done: <progress value="3" max="4"></progress> <meter min="10" max="20" value="15"></meter></p>
+<p>Over: <meter value="2"></meter> Loading: <progress>still working</progress></p>`
+	tests := []struct {
+		style string
+		want  []string
+	}{
+		{gaugePercent, []string{"Done: 75% 50%", "Over: 100% Loading: still working"}},
+		{gaugeFraction, []string{"Done: 3/4 15/20"}},
+		{gaugeBar, []string{"Done: ████████░░ 75% █████░░░░░ 50%"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.gaugeStyle = tt.style
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page lacks %q:\n%s", tt.style, want, page)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v2"
+)
+
+// glossary is a -glossary file: the output path of the glossary page and the
+// anchor on it of each term, e.g.
+//
+//	page: reference/glossary.md
+//	terms:
+//	  target: target
+//	  Bazel module: bazel-module
+//	  label:
+//
+// A term without an anchor links to the one its <dt> or <dfn> gets from
+// assignTermIDs, the slug of the term.
+type glossary struct {
+	Page  string            `yaml:"page"`
+	Terms map[string]string `yaml:"terms"`
+
+	// patterns match each term, longest first
+	patterns []glossaryTerm
+	// anchorPrefix is the glossary page's -anchor-prefix
+	anchorPrefix string
+}
+
+type glossaryTerm struct {
+	term    string
+	pattern *regexp.Regexp
+}
+
+func loadGlossary(glossaryPath string, anchors AnchorStrategy) (*glossary, error) {
+	if glossaryPath == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(glossaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+	var g glossary
+	if err := yaml.UnmarshalStrict(content, &g); err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", glossaryPath, err)
+	}
+	if g.Page == "" {
+		return nil, fmt.Errorf("glossary %s: page is required", glossaryPath)
+	}
+	g.Page = path.Clean(g.Page)
+
+	for term, anchor := range g.Terms {
+		if strings.TrimSpace(term) == "" {
+			return nil, fmt.Errorf("glossary %s: empty term", glossaryPath)
+		}
+		if anchor == "" {
+			g.Terms[term] = anchors.Slug(term)
+		}
+		// Runs of whitespace in the text match a space in the term
+		words := strings.Fields(term)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		g.patterns = append(g.patterns, glossaryTerm{term, regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`))})
+	}
+	sort.Slice(g.patterns, func(i, j int) bool {
+		if len(g.patterns[i].term) != len(g.patterns[j].term) {
+			return len(g.patterns[i].term) > len(g.patterns[j].term)
+		}
+		return g.patterns[i].term < g.patterns[j].term
+	})
+	return &g, nil
+}
+
+// glossarySkipped are the elements whose text is not linked to the glossary:
+// links, code, headings and the terms being defined.
+var glossarySkipped = map[string]bool{
+	"a": true, "code": true, "pre": true, "kbd": true, "samp": true, "var": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"dt": true, "dfn": true, "script": true, "style": true, "head": true,
+}
+
+// linkTerms links the first occurrence of each glossary term in the
+// page's prose to its entry, matching whole words regardless of case.
+// pageLink gives the link from the page to the glossary page.
+func (g *glossary) linkTerms(doc *goquery.Document, pageLink string) {
+	linked := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.TextNode:
+				c = g.linkText(c, pageLink, linked)
+			case c.Type == html.ElementNode && !glossarySkipped[c.Data] && !hasConverterMarker(c):
+				walk(c)
+			}
+		}
+	}
+	walk(doc.Nodes[0])
+}
+
+// linkText links the terms in text node n that are not linked yet and returns
+// the last node the text was split into.
+func (g *glossary) linkText(n *html.Node, pageLink string, linked map[string]bool) *html.Node {
+	for {
+		start, end, term := -1, -1, ""
+		for _, t := range g.patterns {
+			if linked[t.term] {
+				continue
+			}
+			for _, loc := range t.pattern.FindAllStringIndex(n.Data, -1) {
+				if isWordBoundary(n.Data, loc[0], loc[1]) {
+					if start < 0 || loc[0] < start {
+						start, end, term = loc[0], loc[1], t.term
+					}
+					break
+				}
+			}
+		}
+		if start < 0 {
+			return n
+		}
+		linked[term] = true
+
+		link := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: "href", Val: pageLink + "#" + g.anchorPrefix + g.Terms[term]}}}
+		link.AppendChild(&html.Node{Type: html.TextNode, Data: collapseWhitespace(n.Data[start:end])})
+		rest := &html.Node{Type: html.TextNode, Data: n.Data[end:]}
+		n.Data = n.Data[:start]
+		n.Parent.InsertBefore(link, n.NextSibling)
+		n.Parent.InsertBefore(rest, link.NextSibling)
+		n = rest
+	}
+}
+
+// isWordBoundary reports whether text[start:end] is neither preceded nor
+// followed by a letter, digit or underscore.
+func isWordBoundary(text string, start, end int) bool {
+	isWord := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWord(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWord(after) {
+		return false
+	}
+	return true
+}
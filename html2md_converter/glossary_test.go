@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlossaryLinks(t *testing.T) {
+	g, err := loadGlossary(writeConfig(t, "page: reference/glossary.md\nterms:\n  target: target\n  Bazel module: bazel-module\n  module: module\n"), anchorStrategies["mintlify"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<h2>Target</h2>
+<p>A <code>target</code> is not linked, nor <a href="other.html">a target link</a>, but this Target is, and this target not.</p>
+<p>Each Bazel
+module is a module; submodules and targets stay.</p>`,
+		"reference/glossary.html": `<h1>Glossary</h1><p>A target is built.</p>`,
+	}
+	cfg := testConfig(t)
+	cfg.glossary = g
+	written := convertPages(t, cfg, pages)
+	page := written["docs/page.md"]
+	for _, want := range []string{
+		"## Target\n",
+		"A `target` is not linked, nor [a target link](other.html), but this [Target](../reference/glossary.md#target) is, and this target not.",
+		"Each [Bazel module](../reference/glossary.md#bazel-module) is a [module](../reference/glossary.md#module); submodules and targets stay.",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks\n%s\nin\n%s", want, page)
+		}
+	}
+	if glossaryPage := written["reference/glossary.md"]; strings.Contains(glossaryPage, "](") {
+		t.Errorf("glossary page links its own terms:\n%s", glossaryPage)
+	}
+}
+
+// TestDfnTerms checks that a <dfn> becomes a bold term behind an anchor
+// slugged from it, which a glossary term without an anchor links to.
+func TestDfnTerms(t *testing.T) {
+	g, err := loadGlossary(writeConfig(t, "page: reference/glossary.md\nterms:\n  build target:\n  label: label-syntax\n"), anchorStrategies["mintlify"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := map[string]string{
+		"reference/glossary.html": `<h1>Glossary</h1>
+<p>A <dfn>build target</dfn> is what Bazel builds.</p>
+<p>A <dfn id="label-syntax">label</dfn> names a target.</p>
+<dl><dt><dfn>Workspace</dfn></dt><dd>The root.</dd></dl>`,
+		"docs/page.html": `<h1>Page</h1><p>Each build target has a label.</p>`,
+	}
+	cfg := testConfig(t)
+	cfg.glossary = g
+	written := convertPages(t, cfg, pages)
+	glossaryPage := written["reference/glossary.md"]
+	for _, want := range []string{
+		`A <a id="build-target"></a>**build target** is what Bazel builds.`,
+		`A <a id="label-syntax"></a>**label** names a target.`,
+		"<a id=\"workspace\"></a>**Workspace**\n\nThe root.",
+	} {
+		if !strings.Contains(glossaryPage, want) {
+			t.Errorf("glossary page lacks\n%s\nin\n%s", want, glossaryPage)
+		}
+	}
+	if want := "Each [build target](../reference/glossary.md#build-target) has a [label](../reference/glossary.md#label-syntax)."; !strings.Contains(written["docs/page.md"], want) {
+		t.Errorf("page lacks\n%s\nin\n%s", want, written["docs/page.md"])
+	}
+}
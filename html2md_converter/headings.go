@@ -0,0 +1,58 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	// Runs of whitespace, including non-breaking and zero-width spaces
+	headingSpaceRegex = regexp.MustCompile(`[\s\x{00a0}\x{200b}\x{2007}\x{202f}]+`)
+	// A section number: "1. ", "1) ", "1.2 ", "1.2.3. ". A bare number, as in
+	// "3 ways to ...", is part of the title.
+	headingNumberRegex = regexp.MustCompile(`^(?:\d{1,3}(?:\.\d{1,3})*[.)]|\d{1,3}(?:\.\d{1,3})+)(?:\s+|$)`)
+)
+
+// normalizeHeadings collapses the whitespace in headings, trims it at their
+// edges and, unless keepNumbers is set, strips leading section numbers. It
+// runs before titles and anchors are taken from the headings, so that the
+// cleaned text is what the title, the slugs and the table of contents use.
+func normalizeHeadings(doc *goquery.Document, keepNumbers bool) {
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		var texts []*html.Node
+		var collect func(n *html.Node)
+		collect = func(n *html.Node) {
+			if n.Type == html.TextNode {
+				texts = append(texts, n)
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				collect(c)
+			}
+		}
+		collect(h.Nodes[0])
+
+		start, spaced := true, false
+		var last *html.Node
+		for _, n := range texts {
+			text := headingSpaceRegex.ReplaceAllString(n.Data, " ")
+			if start || spaced {
+				text = strings.TrimLeft(text, " ")
+			}
+			if start && !keepNumbers {
+				text = headingNumberRegex.ReplaceAllString(text, "")
+			}
+			if text != "" {
+				start = false
+				spaced = strings.HasSuffix(text, " ")
+				last = n
+			}
+			n.Data = text
+		}
+		if last != nil {
+			last.Data = strings.TrimRight(last.Data, " ")
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalizeHeadings checks that section numbers and odd whitespace are
+// stripped from headings before their title and anchors are derived.
+func TestNormalizeHeadings(t *testing.T) {
+	page := "<h1>1. Guide</h1><p><a href=\"#overview\">See</a></p>" +
+		"<h2 id=\"overview\">1.2\u00a0 Overview</h2><h2>3 ways\u200b to  build</h2><h3>4) Next</h3>"
+	tests := []struct {
+		keepNumbers bool
+		want        []string
+	}{
+		{false, []string{"title: 'Guide'", "[See](#overview)", "## Overview\n", "## 3 ways to build", "### Next"}},
+		{true, []string{"title: '1. Guide'", "[See](#1-2-overview)", "## 1.2 Overview\n", "## 3 ways to build", "### 4) Next"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.normalizeHeadings = true
+		cfg.keepHeadingNumbers = tt.keepNumbers
+		got := convertPages(t, cfg, map[string]string{"page.html": page})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("-keep-heading-numbers=%v: page does not contain %q:\n%s", tt.keepNumbers, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runPostHook runs the -post-hook command on a converted page after it is
+// written. The command line is run by sh with the privileges of the
+// converter, so it must come from trusted configuration only. {file} and
+// {source} are replaced with the shell-quoted output and zip paths, which
+// keeps names from the zip from injecting shell syntax.
+func runPostHook(command string, timeout time.Duration, file, source string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	line := strings.NewReplacer("{file}", shellQuote(file), "{source}", shellQuote(source)).Replace(command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", line)
+	// Don't wait on children of the shell still holding the output open
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("post hook timed out after %s", timeout)
+	}
+	if err != nil {
+		if output != "" {
+			return fmt.Errorf("post hook failed: %w: %s", err, output)
+		}
+		return fmt.Errorf("post hook failed: %w", err)
+	}
+	if output != "" {
+		fmt.Printf("  Post hook: %s\n", output)
+	}
+	return nil
+}
+
+// shellQuote quotes s as a single sh word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostHook(t *testing.T) {
+	pages := map[string]string{"docs/page.html": `<h1>Page</h1><p>Body</p>`}
+
+	cfg := testConfig(t)
+	cfg.postHook = `sed 's/Body/Rewritten/' {file} > {file}.tmp && mv {file}.tmp {file} && echo " from" {source} >> {file}`
+	page := convertPages(t, cfg, pages)["docs/page.md"]
+	if !strings.Contains(page, "Rewritten") || strings.Contains(page, "Body") || !strings.Contains(page, "Rewritten from docs/page.html") {
+		t.Errorf("post hook did not rewrite the page:\n%s", page)
+	}
+
+	cfg.postHook = "echo broken >&2; exit 3"
+	if err := convertTestZip(t, cfg, pages); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("failing post hook: error %v, want its output", err)
+	}
+
+	cfg.postHook = "sleep 5"
+	cfg.postHookTimeout = 100 * time.Millisecond
+	if err := convertTestZip(t, cfg, pages); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow post hook: error %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Values of -html-parser.
+const (
+	// Repair broken HTML silently, as browsers do
+	parserLenient = "lenient"
+	// Also warn about what had to be repaired
+	parserStrict = "strict"
+)
+
+// Elements without an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Elements whose end tag HTML allows to be left out, and the elements
+// whose start closes them when open
+var impliedEnds = map[string][]string{
+	"html": nil, "head": nil, "body": nil, "colgroup": nil, "caption": nil,
+	"p":        {"p"},
+	"li":       {"li"},
+	"dt":       {"dt", "dd"},
+	"dd":       {"dt", "dd"},
+	"option":   {"option", "optgroup"},
+	"optgroup": {"optgroup"},
+	"rt":       {"rt", "rp"},
+	"rp":       {"rt", "rp"},
+	"tr":       {"tr", "tbody", "tfoot"},
+	"td":       {"td", "th", "tr", "tbody", "tfoot"},
+	"th":       {"td", "th", "tr", "tbody", "tfoot"},
+	"thead":    {"tbody", "tfoot"},
+	"tbody":    {"tbody", "tfoot"},
+	"tfoot":    {"tbody"},
+}
+
+// openElement is an element parseAnomalies has seen the start tag of.
+type openElement struct {
+	name string
+	line int
+}
+
+// parseAnomalies returns what the parser has to repair in the page, for
+// -html-parser=strict: elements left open, end tags without a start tag,
+// self-closing tags on elements that cannot be empty, and duplicate
+// attributes, which the parser drops silently. End tags that HTML allows to
+// be left out, such as those of <p> and <li>, are not anomalies.
+func parseAnomalies(page string) []string {
+	var anomalies []string
+	report := func(line int, format string, args ...interface{}) {
+		anomalies = append(anomalies, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+	}
+
+	var stack []openElement
+	// closeTo pops the element at index i, by its end tag, and reports the
+	// elements above it whose end tag is missing; -1 reports every open
+	// element
+	closeTo := func(i int, line int, by string) {
+		for j := len(stack) - 1; j > i; j-- {
+			if _, ok := impliedEnds[stack[j].name]; !ok {
+				report(stack[j].line, "<%s> is not closed before %s on line %d", stack[j].name, by, line)
+			}
+		}
+		if i < 0 {
+			i = 0
+		}
+		stack = stack[:i]
+	}
+	// foreign counts the open <svg> and <math> elements, inside which
+	// self-closing tags are allowed
+	foreign := 0
+
+	z := html.NewTokenizer(strings.NewReader(page))
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				report(line, "%v", err)
+			}
+			break
+		}
+		start := line
+		line += strings.Count(string(z.Raw()), "\n")
+		if tt != html.StartTagToken && tt != html.EndTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := z.Token()
+		name := token.Data
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			seen := make(map[string]bool, len(token.Attr))
+			for _, a := range token.Attr {
+				if seen[a.Key] {
+					report(start, "<%s> has a duplicate %s attribute; the parser keeps the first", name, a.Key)
+				}
+				seen[a.Key] = true
+			}
+			if voidElements[name] {
+				continue
+			}
+			if tt == html.SelfClosingTagToken {
+				if foreign == 0 {
+					report(start, "<%s/> cannot be self-closing and is read as a start tag", name)
+				} else {
+					continue
+				}
+			}
+			// A start tag closes the open elements with an implied end
+			// tag that it ends, such as the previous item of a list
+			for i := len(stack) - 1; i >= 0; i-- {
+				closes, ok := impliedEnds[stack[i].name]
+				if !ok {
+					break
+				}
+				if contains(closes, name) {
+					stack = stack[:i]
+				}
+			}
+			if name == "svg" || name == "math" {
+				foreign++
+			}
+			stack = append(stack, openElement{name: name, line: start})
+		case html.EndTagToken:
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != name {
+				i--
+			}
+			if i < 0 {
+				if !voidElements[name] {
+					report(start, "</%s> has no open <%s> to close", name, name)
+				}
+				continue
+			}
+			closeTo(i, start, "</"+name+">")
+			if name == "svg" || name == "math" {
+				foreign--
+			}
+		}
+	}
+	closeTo(-1, line, "the end of the page")
+	return anomalies
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pageAnomalies are the parse anomalies of a page, for the end-of-run list.
+type pageAnomalies struct {
+	path      string
+	anomalies []string
+}
+
+// checkParse warns, with -html-parser=strict, about the parse anomalies of
+// the page, with the line of the source HTML each is on.
+func (c *conversion) checkParse(sourcePath, page string) {
+	anomalies := parseAnomalies(page)
+	if len(anomalies) == 0 {
+		return
+	}
+	for _, a := range anomalies {
+		fmt.Printf("  Warning: HTML %s\n", a)
+	}
+	c.parseAnomalies = append(c.parseAnomalies, pageAnomalies{path: sourcePath, anomalies: anomalies})
+	c.stats.parseAnomalies += len(anomalies)
+}
+
+// printParseAnomalies lists the anomalies checkParse warned about, by page.
+func printParseAnomalies(pages []pageAnomalies) {
+	if len(pages) == 0 {
+		return
+	}
+	fmt.Println("HTML parse anomalies, with -html-parser=strict:")
+	for _, p := range pages {
+		fmt.Printf("  %s\n", p.path)
+		for _, a := range p.anomalies {
+			fmt.Printf("    %s\n", a)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAnomalies(t *testing.T) {
+	tests := []struct {
+		page string
+		want []string
+	}{
+		{"<p>One\n<p>Two<ul><li>A<li>B</ul>\n<br/><img src=x.png/>", nil},
+		{"<div>\n<p>Open</section>\n</div>", []string{"line 2: </section> has no open <section>"}},
+		{"<div>\n<p>Open\n", []string{"line 1: <div> is not closed before the end of the page"}},
+		{`<span/>x</span><a href="a" href="b">x</a>`, []string{"<span/> cannot be self-closing", "<a> has a duplicate href attribute"}},
+	}
+	for _, tt := range tests {
+		got := parseAnomalies(tt.page)
+		if len(got) != len(tt.want) {
+			t.Errorf("parseAnomalies(%q) = %q, want %d anomalies", tt.page, got, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(got[i], want) {
+				t.Errorf("parseAnomalies(%q)[%d] = %q, want it to contain %q", tt.page, i, got[i], want)
+			}
+		}
+	}
+}
+
+// TestStrictParser checks that -html-parser=strict fails -strict-html on an
+// unclosed tag that the lenient parser repairs silently.
+func TestStrictParser(t *testing.T) {
+	pages := map[string]string{"page.html": "<h1>Page</h1>\n<div><p>Unclosed\n"}
+	for _, parser := range []string{parserLenient, parserStrict} {
+		cfg := testConfig(t)
+		cfg.htmlParser = parser
+		cfg.strict["html"] = true
+		err := convertTestZip(t, cfg, pages)
+		if parser == parserLenient && err != nil {
+			t.Errorf("%s: run failed: %v", parser, err)
+		}
+		if parser == parserStrict && err == nil {
+			t.Errorf("%s: run succeeded with an unclosed <div>", parser)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// isIconFont reports whether a class list marks an element as an icon font
+// ligature, such as <span class="material-icons">arrow_forward</span>,
+// whose text is the icon's name.
+func isIconFont(classes string) bool {
+	for _, class := range strings.Fields(classes) {
+		if strings.HasPrefix(class, "material-icons") || strings.HasPrefix(class, "material-symbols") {
+			return true
+		}
+	}
+	return false
+}
+
+// iconLabel returns the accessible name of an icon, if it has one.
+func iconLabel(icon *goquery.Selection) string {
+	if label := collapseWhitespace(icon.AttrOr("aria-label", "")); label != "" {
+		return label
+	}
+	if goquery.NodeName(icon) == "svg" {
+		if title := collapseWhitespace(icon.ChildrenFiltered("title").First().Text()); title != "" {
+			return title
+		}
+	}
+	return collapseWhitespace(icon.AttrOr("title", ""))
+}
+
+// replaceIcons turns inline <svg> and icon font icons into their accessible
+// name, since their markup or ligature text would otherwise leak into the
+// page. Decorative icons, which are aria-hidden or have no name, are dropped
+// even with -preserve-hidden. A link left without text by that takes its
+// own aria-label or title as text.
+func replaceIcons(doc *goquery.Document) {
+	doc.Find("svg, i, span").Each(func(i int, icon *goquery.Selection) {
+		if goquery.NodeName(icon) != "svg" && !isIconFont(icon.AttrOr("class", "")) {
+			return
+		}
+		label := iconLabel(icon)
+		switch icon.AttrOr("role", "") {
+		case "presentation", "none":
+			label = ""
+		}
+		if icon.AttrOr("aria-hidden", "") == "true" || label == "" {
+			icon.Remove()
+			return
+		}
+		icon.ReplaceWithNodes(&html.Node{Type: html.TextNode, Data: label})
+	})
+	mergeTextNodes(doc.Nodes[0])
+
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		if strings.TrimSpace(a.Text()) != "" || a.Find("img, picture, video, iframe").Length() > 0 {
+			return
+		}
+		if label := iconLabel(a); label != "" {
+			a.SetText(label)
+		}
+	})
+}
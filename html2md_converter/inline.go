@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Forms of <var> placeholders accepted by -var-style.
+const (
+	// _name_, as browsers render <var>
+	varItalic = "italic"
+	// _`name`_
+	varCode = "code"
+	// \<name\>, the placeholder convention of command synopses
+	varPlaceholder = "placeholder"
+)
+
+// sampleRules convert <var> placeholders in the -var-style form and <samp>
+// sample output to inline code. A <samp> of several lines, or with block
+// children, is a fenced text block instead, keeping its whitespace. Inside
+// code, both stay plain text.
+func sampleRules(varStyle string) []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"var"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				text := collapseWhitespace(selec.Text())
+				if text == "" {
+					return md.String("")
+				}
+				switch varStyle {
+				case varCode:
+					return md.String(emphasize(inlineCode(text), selec, "em", opt.EmDelimiter, "*"))
+				case varPlaceholder:
+					return md.String(`\<` + content + `\>`)
+				}
+				return md.String(emphasize(content, selec, "em", opt.EmDelimiter, "*"))
+			},
+		},
+		{
+			Filter: []string{"samp"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				if selec.ParentsFiltered("pre").Length() > 0 {
+					return nil
+				}
+				if text := strings.Trim(codeText(selec), "\n"); strings.Contains(text, "\n") || selec.Find("div, p").Length() > 0 {
+					return md.String(fencedCode(text, "text", "", opt))
+				}
+				return md.String(inlineCode(selec.Text()))
+			},
+		},
+	}
+}
+
+// inlineCode puts text in a code span delimited by more backticks than any
+// run of backticks inside it.
+func inlineCode(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSampleRules(t *testing.T) {
+	html := "<p>Run <code>bazel build <var>target</var></code> with <var>target</var> set; it prints <samp>INFO: Build `ok`</samp>.</p>"
+	tests := map[string]string{
+		varItalic:      "Run `bazel build target` with _target_ set; it prints `` INFO: Build `ok` ``.",
+		varCode:        "Run `bazel build target` with _`target`_ set; it prints `` INFO: Build `ok` ``.",
+		varPlaceholder: "Run `bazel build target` with \\<target\\> set; it prints `` INFO: Build `ok` ``.",
+	}
+	for style, want := range tests {
+		cfg := testConfig(t)
+		cfg.varStyle = style
+		got, err := newConverter(cfg).ConvertString(html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(got) != want {
+			t.Errorf("-var-style=%s: got\n%s\nwant\n%s", style, got, want)
+		}
+	}
+}
+
+func TestSampleBlocks(t *testing.T) {
+	html := "<p>It prints <samp>OK</samp>.</p>\n<samp>INFO: Analyzed target //:hello\n  Target //:hello up-to-date:\n    bazel-bin/hello</samp>\n<p>Or:</p><samp><div>Line one</div><div>Line two</div></samp>"
+	page := convertPages(t, testConfig(t), map[string]string{"page.html": html})["page.md"]
+	for _, want := range []string{
+		"It prints `OK`.",
+		"```text\nINFO: Analyzed target //:hello\n  Target //:hello up-to-date:\n    bazel-bin/hello\n```",
+		"```text\nLine one\nLine two\n```",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks\n%s\nin\n%s", want, page)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stdinPageName is the zip entry name of a single page read from stdin.
+const stdinPageName = "index.html"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// openInput opens the -zip input: a zip file, or a single HTML page, which
+// is wrapped in an in-memory zip holding just that page. Either may be
+// gzipped, and "-" reads the input from stdin. Decompressed input larger
+// than maxBytes is an error. The returned function releases the input.
+func openInput(inputPath string, maxBytes int64) (*zip.Reader, func(), error) {
+	done := func() {}
+	name, modified := stdinPageName, time.Time{}
+	if inputPath == "-" {
+		// A streamed zip is spilled to a temp file since zip needs random access
+		tmpPath, err := spillToTempFile(os.Stdin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read input from stdin: %w", err)
+		}
+		done = func() { os.Remove(tmpPath) }
+		inputPath = tmpPath
+	} else {
+		name = strings.TrimSuffix(filepath.Base(inputPath), ".gz")
+		if info, err := os.Stat(inputPath); err == nil {
+			modified = info.ModTime()
+		}
+	}
+
+	magic, err := readMagic(inputPath)
+	if err != nil {
+		done()
+		return nil, nil, fmt.Errorf("failed to open input: %w", err)
+	}
+	compressed := bytes.HasPrefix(magic, gzipMagic)
+	if !compressed && (bytes.HasPrefix(magic, zipMagic) || !isHTMLFile(name)) {
+		r, err := zip.OpenReader(inputPath)
+		if err != nil {
+			done()
+			return nil, nil, fmt.Errorf("failed to open zip file: %w", err)
+		}
+		return &r.Reader, func() { r.Close(); done() }, nil
+	}
+
+	content, err := readInputFile(inputPath, compressed, maxBytes)
+	done()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.HasPrefix(content, zipMagic) {
+		if !isHTMLFile(name) {
+			return nil, nil, fmt.Errorf("%s is neither a zip nor an HTML page", name)
+		}
+		if content, err = singlePageZip(name, modified, content); err != nil {
+			return nil, nil, err
+		}
+	}
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open zip file: %w", err)
+	}
+	return r, func() {}, nil
+}
+
+func readMagic(inputPath string) ([]byte, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(zipMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return magic[:n], nil
+}
+
+// readInputFile reads the input, gunzipping it if compressed, and fails
+// once more than maxBytes come out.
+func readInputFile(inputPath string, compressed bool, maxBytes int64) ([]byte, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress input: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if int64(len(content)) > maxBytes {
+		return nil, fmt.Errorf("input is larger than -max-input-mb of %d MiB", maxBytes>>20)
+	}
+	return content, nil
+}
+
+// singlePageZip returns a zip holding content as the page name.
+func singlePageZip(name string, modified time.Time, content []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	entry, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap %s: %w", name, err)
+	}
+	if _, err := entry.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to wrap %s: %w", name, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to wrap %s: %w", name, err)
+	}
+	return b.Bytes(), nil
+}
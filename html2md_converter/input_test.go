@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGzipInput converts a page given as plain HTML, gzipped, and as a
+// gzipped zip, and checks that each converts identically and that the
+// decompressed size is limited.
+func TestGzipInput(t *testing.T) {
+	page := `<html><head><title>Page</title></head><body><h1>Page</h1><p>Text with <a href="https://bazel.build">a link</a>.</p></body></html>`
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "site.zip"), map[string]string{"page.html": page})
+	zipped, err := os.ReadFile(filepath.Join(dir, "site.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		"page.html":    []byte(page),
+		"page.html.gz": gzipped(t, []byte(page)),
+		"site.zip.gz":  gzipped(t, zipped),
+	}
+
+	cfg := testConfig(t)
+	var want string
+	for _, name := range []string{"page.html", "page.html.gz", "site.zip.gz"} {
+		inputPath := filepath.Join(dir, name)
+		if err := os.WriteFile(inputPath, inputs[name], 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputDir := filepath.Join(dir, name+".out")
+		if err := convertZipToMarkdown(inputPath, outputDir, cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := os.ReadFile(filepath.Join(outputDir, "page.md"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want == "" {
+			want = string(got)
+		} else if string(got) != want {
+			t.Errorf("%s converted to\n%s\nwant\n%s", name, got, want)
+		}
+	}
+
+	cfg.maxInputBytes = int64(len(page)) - 1
+	err = convertZipToMarkdown(filepath.Join(dir, "page.html.gz"), filepath.Join(dir, "limited"), cfg)
+	if err == nil || !strings.Contains(err.Error(), "-max-input-mb") {
+		t.Errorf("oversized input: run returned %v, want the limit reported", err)
+	}
+}
+
+func gzipped(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
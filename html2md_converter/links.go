@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// isExternalURL reports whether href points to another site.
+func isExternalURL(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// absolutizeProtocolRelative gives protocol-relative URLs, such as
+// //bazel.build/about, the scheme, so that they count as links and images on
+// another site rather than paths on this one.
+func absolutizeProtocolRelative(doc *goquery.Document, scheme string) {
+	absolute := func(ref string) string {
+		if trimmed := strings.TrimSpace(ref); strings.HasPrefix(trimmed, "//") {
+			return scheme + ":" + trimmed
+		}
+		return ref
+	}
+	doc.Find("[href], [src], [srcset]").Each(func(i int, s *goquery.Selection) {
+		for _, attr := range []string{"href", "src"} {
+			if ref, ok := s.Attr(attr); ok {
+				s.SetAttr(attr, absolute(ref))
+			}
+		}
+		if srcset, ok := s.Attr("srcset"); ok {
+			candidates := strings.Split(srcset, ",")
+			for i, candidate := range candidates {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					fields[0] = absolute(fields[0])
+					candidates[i] = strings.Join(fields, " ")
+				}
+			}
+			s.SetAttr("srcset", strings.Join(candidates, ", "))
+		}
+	})
+}
+
+// externalLinkRule writes external links as HTML anchors that open in a new
+// tab, which markdown link syntax cannot express. rel="noopener" is always
+// set and any rel from the source, such as nofollow, is kept. Internal links
+// fall through to the default markdown rule.
+func externalLinkRule(class string) md.Rule {
+	return md.Rule{
+		Filter: []string{"a"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			href, ok := selec.Attr("href")
+			if !ok || !isExternalURL(href) {
+				return nil
+			}
+
+			rel := []string{"noopener"}
+			for _, value := range strings.Fields(selec.AttrOr("rel", "")) {
+				if value != "noopener" {
+					rel = append(rel, value)
+				}
+			}
+
+			attrs := `href="` + jsxAttrEscaper.Replace(strings.TrimSpace(href)) + `" target="_blank" rel="` + strings.Join(rel, " ") + `"`
+			if class != "" {
+				attrs += ` className="` + jsxAttrEscaper.Replace(class) + `"`
+			}
+			return md.String("<a " + attrs + ">" + strings.TrimSpace(content) + "</a>")
+		},
+	}
+}
+
+// resolveZipRef resolves a relative or root-relative reference found in the
+// page at sourcePath to the name of the zip entry it points at. ok is false
+// for external URLs and pure fragments.
+func resolveZipRef(sourcePath, ref string) (name string, u *url.URL, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", nil, false
+	}
+
+	if strings.HasPrefix(u.Path, "/") {
+		name = strings.TrimPrefix(path.Clean(u.Path), "/")
+	} else {
+		name = path.Join(path.Dir(sourcePath), u.Path)
+	}
+	return name, u, true
+}
+
+// brokenLink warns about a link from the page at sourcePath to something
+// missing from the zip, and records it for -strict-links.
+func (c *conversion) brokenLink(sourcePath, href, detail string) {
+	fmt.Printf("  Warning: link to %s, %s\n", href, detail)
+	c.brokenLinks = append(c.brokenLinks, &linkError{File: sourcePath, Link: href, Detail: detail})
+	c.stats.brokenLinks++
+}
+
+// rewritePageLinks points links to other pages in the zip at the output
+// paths those pages are written to, relative to this page. Links back to
+// the page itself become plain fragments. Download links, <a download>, are
+// not links to pages and are left to copyDownloads. Both ends are output
+// paths, after -path-template, -output-case and -rename-map, so links stay
+// valid however those reshape the tree; only the href is resolved against
+// the zip path. Links to copied examples already point at their output.
+func (c *conversion) rewritePageLinks(doc *goquery.Document, sourcePath, pagePath string) {
+	doc.Find("a[href]:not([download]):not([" + attrCopiedExample + "])").Each(func(i int, a *goquery.Selection) {
+		href := a.AttrOr("href", "")
+		name, u, ok := resolveZipRef(sourcePath, href)
+		if !ok {
+			return
+		}
+
+		// Links to a directory point at its index page
+		if strings.HasSuffix(u.Path, "/") || path.Base(u.Path) == "." || path.Base(u.Path) == ".." {
+			index, found := c.directoryIndex(name)
+			if !found {
+				c.brokenLink(sourcePath, href, "which is a directory with no index page in the zip")
+				return
+			}
+			name = index
+		} else if index, found := c.directoryIndex(name); found && path.Ext(name) == "" {
+			name = index
+		}
+
+		if !(isHTMLFile(name) || isMarkdownFile(name) || c.cfg.apiPages.matches(name)) {
+			return
+		}
+		if c.files[name] == nil {
+			c.brokenLink(sourcePath, href, "which is not in the zip")
+			return
+		}
+
+		fragment := u.Fragment
+		if c.cfg.stripFragment {
+			fragment = ""
+		}
+
+		_, target := c.pagePaths(name)
+		if fragment != "" && target != pagePath && c.cfg.anchorPrefix == "" {
+			// Like links within a page, links to a heading's id point at
+			// the anchor generated for it, in the part of a split page it
+			// went to
+			t := c.linkTarget(name)
+			if anchor, ok := t.anchors[fragment]; ok {
+				fragment = anchor
+			}
+			if part, anchor, ok := t.split.target(fragment); ok {
+				target, fragment = part, anchor
+			}
+		}
+		if fragment != "" {
+			fragment = pageAnchorPrefix(c.cfg.anchorPrefix, target) + fragment
+		}
+		if target == pagePath && fragment != "" {
+			a.SetAttr("href", "#"+fragment)
+			return
+		}
+
+		link := pageLink(pagePath, target, c.cfg.trailingSlash, c.cfg.stripLinkExtensions)
+		if c.cfg.linkBase != "" {
+			link = absoluteLink(c.cfg.linkBase, target, c.cfg.trailingSlash)
+		}
+		if u.RawQuery != "" && !c.cfg.stripQuery {
+			link += "?" + u.RawQuery
+		}
+		if fragment != "" {
+			// Like links within a page, links to a heading's id point at
+			// the anchor generated for it
+			if anchor, ok := c.linkTarget(name).anchors[fragment]; ok {
+				fragment = anchor
+			}
+			link += "#" + fragment
+		}
+		a.SetAttr("href", link)
+	})
+}
+
+// stripExternalLinks applies -strip-query and -strip-fragment to external
+// links, e.g. to drop utm_* tracking parameters.
+func stripExternalLinks(doc *goquery.Document, cfg config) {
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href := a.AttrOr("href", "")
+		if !isExternalURL(href) {
+			return
+		}
+		u, _ := url.Parse(strings.TrimSpace(href))
+		if cfg.stripQuery {
+			u.RawQuery = ""
+			u.ForceQuery = false
+		}
+		if cfg.stripFragment {
+			u.Fragment = ""
+			u.RawFragment = ""
+		}
+		a.SetAttr("href", u.String())
+	})
+}
+
+// defaultSourceRefRepos are the repositories whose links -source-ref pins.
+const defaultSourceRefRepos = "bazelbuild/bazel"
+
+// pinSourceLinks points links to the master or main branch of the given
+// GitHub repositories, keyed by lowercase owner/repo, at ref instead, for
+// -source-ref, so that they show the source of the documented release. Links
+// to files, directories, raw files and commits of a branch are pinned,
+// whether on github.com or raw.githubusercontent.com.
+func pinSourceLinks(doc *goquery.Document, ref string, repos map[string]bool) {
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		u, err := url.Parse(strings.TrimSpace(a.AttrOr("href", "")))
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			return
+		}
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 5)
+		branch := 3
+		switch strings.ToLower(u.Host) {
+		case "github.com", "www.github.com":
+			if len(parts) < 4 || (parts[2] != "blob" && parts[2] != "tree" && parts[2] != "raw" && parts[2] != "commits") {
+				return
+			}
+		case "raw.githubusercontent.com":
+			branch = 2
+		default:
+			return
+		}
+		if len(parts) <= branch || !repos[strings.ToLower(parts[0]+"/"+parts[1])] || (parts[branch] != "master" && parts[branch] != "main") {
+			return
+		}
+		parts[branch] = ref
+		u.Path = "/" + strings.Join(parts, "/")
+		u.RawPath = ""
+		a.SetAttr("href", u.String())
+	})
+}
+
+// Modes accepted by -empty-link-mode.
+const (
+	// Remove links without text.
+	emptyLinksDrop = "drop"
+	// Show the target of links without text as their text.
+	emptyLinksURL = "url"
+)
+
+// stripEmptyLinks removes links without text, such as icon links and
+// wrappers of anchors, which the converter silently drops but for a doubled
+// space. In emptyLinksURL mode those with a target show it instead.
+func stripEmptyLinks(doc *goquery.Document, mode string) {
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		if strings.TrimSpace(a.Text()) != "" || a.Find("img, picture, svg, video, iframe").Length() > 0 {
+			return
+		}
+		href := strings.TrimSpace(a.AttrOr("href", ""))
+		if mode == emptyLinksURL && href != "" && href != "#" {
+			a.SetText(href)
+			return
+		}
+		a.Remove()
+	})
+	mergeTextNodes(doc.Nodes[0])
+}
+
+// directoryIndexPages are the names tried, in order, for the index page of a
+// directory.
+var directoryIndexPages = []string{"index.html", "index.htm", "index.md", "index.markdown"}
+
+// directoryIndex returns the zip entry of the index page of directory dir.
+func (c *conversion) directoryIndex(dir string) (string, bool) {
+	for _, page := range directoryIndexPages {
+		if name := path.Join(dir, page); c.files[name] != nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// relativeLink returns the path of target relative to the directory of the
+// page at from. Both are slash-separated output paths.
+func relativeLink(from, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(target))
+	if err != nil {
+		return "/" + target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// pageLink returns the link from one output page to another under the
+// -trailing-slash mode. A page served at a slash URL is itself a directory
+// as far as relative links are concerned, so links from it start one level
+// deeper. Index pages are served at the URL of their directory. With
+// stripExtension, for -normalize-internal-link-extensions, the link drops
+// the extension of the target, as the site serves it without one.
+func pageLink(from, target, trailingSlash string, stripExtension bool) string {
+	if trailingSlash != slashAdd {
+		link := relativeLink(from, target)
+		if stripExtension && isPageFile(link) {
+			link = strings.TrimSuffix(link, path.Ext(link))
+		}
+		return applyTrailingSlash(link, trailingSlash)
+	}
+
+	if !isIndexPage(from) {
+		from = strings.TrimSuffix(from, path.Ext(from)) + "/index"
+	}
+	link := relativeLink(from, target)
+	if isIndexPage(target) {
+		return path.Dir(link) + "/"
+	}
+	return applyTrailingSlash(link, trailingSlash)
+}
+
+// absoluteLink returns the URL of the output page target under -link-base.
+// Like a served page's URL it has no extension, and index pages are linked
+// at their directory.
+func absoluteLink(base, target, trailingSlash string) string {
+	link := strings.TrimSuffix(base, "/") + "/" + strings.TrimSuffix(target, path.Ext(target))
+	if isIndexPage(target) {
+		link = strings.TrimSuffix(link, path.Base(link))
+	}
+	return applyTrailingSlash(link, trailingSlash)
+}
+
+func isIndexPage(pagePath string) bool {
+	base := path.Base(pagePath)
+	return strings.TrimSuffix(base, path.Ext(base)) == "index"
+}
+
+// Modes accepted by -trailing-slash.
+const (
+	// Serve pages at extensionless URLs ending in a slash: general.md -> general/.
+	slashAdd = "add"
+	// Drop trailing slashes: be/ -> be.
+	slashStrip = "strip"
+	// Leave links as rewritten.
+	slashKeep = "keep"
+)
+
+func applyTrailingSlash(link, mode string) string {
+	switch mode {
+	case slashAdd:
+		if ext := path.Ext(link); isPageFile(link) {
+			link = strings.TrimSuffix(link, ext)
+		}
+		if !strings.HasSuffix(link, "/") {
+			link += "/"
+		}
+	case slashStrip:
+		if len(link) > 1 {
+			link = strings.TrimSuffix(link, "/")
+		}
+	}
+	return link
+}
+
+// normalizeLinks collapses "./" and redundant "../" segments in the targets
+// of internal links, keeping the query, fragment and any trailing slash.
+func normalizeLinks(doc *goquery.Document) {
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		u, err := url.Parse(a.AttrOr("href", ""))
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			return
+		}
+		if clean := cleanLinkPath(u.Path); clean != u.Path {
+			u.Path = clean
+			a.SetAttr("href", u.String())
+		}
+	})
+}
+
+func cleanLinkPath(p string) string {
+	clean := path.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExternalNewTab(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.externalNewTab = true
+	cfg.externalLinkClass = "external"
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<p><a href="https://github.com/bazelbuild/bazel" rel="nofollow">Bazel</a> and <a href="other.md">other</a>.</p>`,
+	})["page.md"]
+
+	for _, want := range []string{
+		`<a href="https://github.com/bazelbuild/bazel" target="_blank" rel="noopener nofollow" className="external">Bazel</a>`,
+		"[other](other.md)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %s:\n%s", want, page)
+		}
+	}
+}
+
+func TestTrailingSlash(t *testing.T) {
+	pages := map[string]string{
+		"docs/guide.html": `<p><a href="../be/general.html#rules">general</a> <a href="/be/index.html">index</a> <a href="https://bazel.build/docs/">site</a></p>`,
+		"be/general.html": `<h2 id="rules">Rules</h2>`,
+		"be/index.html":   `<p>Index</p>`,
+	}
+	want := map[string][]string{
+		slashKeep:  {"(../be/general.md#rules)", "(../be/index.md)", "(https://bazel.build/docs/)"},
+		slashAdd:   {"(../../be/general/#rules)", "(../../be/)", "(https://bazel.build/docs/)"},
+		slashStrip: {"(../be/general.md#rules)", "(../be/index.md)", "(https://bazel.build/docs/)"},
+	}
+	for mode, links := range want {
+		cfg := testConfig(t)
+		cfg.trailingSlash = mode
+		page := convertPages(t, cfg, pages)["docs/guide.md"]
+		for _, link := range links {
+			if !strings.Contains(page, link) {
+				t.Errorf("%s: page does not link %s:\n%s", mode, link, page)
+			}
+		}
+	}
+}
+
+func TestApplyTrailingSlash(t *testing.T) {
+	tests := []struct{ link, mode, want string }{
+		{"../be/general.md", slashAdd, "../be/general/"},
+		{"be/", slashAdd, "be/"},
+		{"../be/", slashStrip, "../be"},
+		{"/", slashStrip, "/"},
+		{"../be/", slashKeep, "../be/"},
+	}
+	for _, tt := range tests {
+		if got := applyTrailingSlash(tt.link, tt.mode); got != tt.want {
+			t.Errorf("applyTrailingSlash(%q, %s) = %q, want %q", tt.link, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestStripLinkExtensions(t *testing.T) {
+	pages := map[string]string{
+		"docs/guide.html": `<p><a href="../be/general.html?hl=en#rules">general</a> <a href="notes.md">notes</a> <a href="data.txt">data</a> <a href="https://bazel.build/be/general.html">site</a></p>`,
+		"docs/notes.md":   "Notes\n",
+		"docs/data.txt":   "data",
+		"be/general.html": `<h2 id="rules">Rules</h2>`,
+	}
+	want := map[string][]string{
+		slashKeep: {"(../be/general?hl=en#rules)", "(notes)", "(data.txt)", "(https://bazel.build/be/general.html)"},
+		slashAdd:  {"(../../be/general/?hl=en#rules)", "(../notes/)", "(data.txt)", "(https://bazel.build/be/general.html)"},
+	}
+	for mode, links := range want {
+		cfg := testConfig(t)
+		cfg.trailingSlash = mode
+		cfg.stripLinkExtensions = true
+		cfg.stripQuery = false
+		page := convertPages(t, cfg, pages)["docs/guide.md"]
+		for _, link := range links {
+			if !strings.Contains(page, link) {
+				t.Errorf("%s: page does not link %s:\n%s", mode, link, page)
+			}
+		}
+	}
+}
+
+func TestTrailingSlashRedirects(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.trailingSlash = slashAdd
+	cfg.renames = map[string]string{"be/general.html": "reference/general.md"}
+	written := convertPages(t, cfg, map[string]string{"be/general.html": `<p>General</p>`})
+	if want := `"source": "/be/general/",
+    "destination": "/reference/general/"`; !strings.Contains(written[redirectsFile], want) {
+		t.Errorf("redirects are\n%s\nwant\n%s", written[redirectsFile], want)
+	}
+}
+
+func TestNormalizeLinks(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.normalizeLinks = true
+	page := convertPages(t, cfg, map[string]string{
+		"docs/guide.html": `<p><a href="./other.md">other</a> <a href="../a/../b/">b</a> <a href="../../up/x.txt?v=1#frag">up</a> <a href="https://bazel.build/./docs/../x">site</a></p>`,
+	})["docs/guide.md"]
+	for _, want := range []string{"(other.md)", "(../b/)", "(../../up/x.txt?v=1#frag)", "(https://bazel.build/./docs/../x)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not link %s:\n%s", want, page)
+		}
+	}
+}
+
+func TestDirectoryLinks(t *testing.T) {
+	pages := map[string]string{
+		"docs/guide.html": `<p><a href="../be/">be</a> <a href="../be">extensionless</a> <a href="./">here</a> <a href="../missing/">missing</a></p>`,
+		"docs/index.md":   "Docs",
+		"be/index.html":   `<p>Index</p>`,
+		"be/general.html": `<p>General</p>`,
+	}
+	want := map[string][]string{
+		slashKeep: {"(../be/index.md)", "[extensionless](../be/index.md)", "(index.md)", "(../missing/)"},
+		slashAdd:  {"(../../be/)", "[extensionless](../../be/)", "(../)", "(../missing/)"},
+	}
+	for mode, links := range want {
+		cfg := testConfig(t)
+		cfg.trailingSlash = mode
+		page := convertPages(t, cfg, pages)["docs/guide.md"]
+		for _, link := range links {
+			if !strings.Contains(page, link) {
+				t.Errorf("%s: page does not link %s:\n%s", mode, link, page)
+			}
+		}
+	}
+}
+
+func TestStripQuery(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html": `<p><a href="b.html?hl=en#foo">b</a> <a href="a.html?hl=en#own">own</a> <a href="https://bazel.build/x?utm_source=docs#top">site</a></p><h2 id="own">Own</h2>`,
+		"docs/b.html": `<p>B</p>`,
+	}
+	tests := []struct {
+		name                                     string
+		stripQuery, stripFragment, stripExternal bool
+		want                                     []string
+	}{
+		{"default", true, false, false, []string{"(b.md#foo)", "(#own)", "(https://bazel.build/x?utm_source=docs#top)"}},
+		{"keep query", false, false, false, []string{"(b.md?hl=en#foo)", "(#own)"}},
+		{"strip fragment", true, true, false, []string{"(b.md)", "(a.md)", "(https://bazel.build/x?utm_source=docs#top)"}},
+		{"strip external", true, true, true, []string{"(b.md)", "(https://bazel.build/x)"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.stripQuery = tt.stripQuery
+		cfg.stripFragment = tt.stripFragment
+		cfg.stripExternal = tt.stripExternal
+		page := convertPages(t, cfg, pages)["docs/a.md"]
+		for _, link := range tt.want {
+			if !strings.Contains(page, link) {
+				t.Errorf("%s: page does not link %s:\n%s", tt.name, link, page)
+			}
+		}
+	}
+}
+
+func TestLinkBase(t *testing.T) {
+	pages := map[string]string{
+		"be/c-cpp.html":   `<h1>C++</h1><p><a href="general.html">General</a> <a href="general.html#genrule">genrule</a> <a href="../index.html">Home</a> <a href="#own">own</a> <a href="https://bazel.build/x">External</a></p><h2 id="own">Own</h2>`,
+		"be/general.html": `<h1>General</h1><h2 id="genrule">genrule</h2>`,
+		"index.html":      `<h1>Home</h1><p>Welcome</p>`,
+	}
+	tests := []struct {
+		slash string
+		want  []string
+	}{
+		{slashKeep, []string{"(/reference/be/general)", "(/reference/be/general#genrule)", "(/reference/)", "(#own)", "(https://bazel.build/x)"}},
+		{slashAdd, []string{"(/reference/be/general/)", "(/reference/be/general/#genrule)", "(/reference/)"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.linkBase = "/reference/"
+		cfg.trailingSlash = tt.slash
+		page := convertPages(t, cfg, pages)["be/c-cpp.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-trailing-slash=%s: page does not link %s:\n%s", tt.slash, want, page)
+			}
+		}
+	}
+}
+
+func TestStripEmptyLinks(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Page</h1><p>Before <a href=""></a> <a href="#"> </a>after.</p>
+<p>Icon <a href="https://github.com/bazelbuild"><span class="icon"></span></a> link and <a href="https://bazel.build"><img src="https://bazel.build/logo.png" alt="Logo"></a>.</p>`,
+	}
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{emptyLinksDrop, []string{"Before after.", "Icon link and [![Logo](https://bazel.build/logo.png)](https://bazel.build)."}},
+		{emptyLinksURL, []string{"Before after.", "Icon [https://github.com/bazelbuild](https://github.com/bazelbuild) link"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.emptyLinks = tt.mode
+		page := convertPages(t, cfg, pages)["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-empty-link-mode=%s: page lacks %q:\n%s", tt.mode, want, page)
+			}
+		}
+		if strings.Contains(page, "[]") {
+			t.Errorf("-empty-link-mode=%s: page has an empty link:\n%s", tt.mode, page)
+		}
+	}
+}
+
+// TestProtocolRelative checks that protocol-relative links and images are
+// written as external URLs and not looked up in the zip.
+func TestProtocolRelative(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1><p><a href="//bazel.build/about.html?hl=en">About</a> <img src="//bazel.build/logo.png" alt="Logo"></p>`,
+	}
+	tests := []struct {
+		scheme string
+		want   string
+	}{
+		{"https", `<a href="https://bazel.build/about.html?hl=en" target="_blank" rel="noopener">About</a> ![Logo](https://bazel.build/logo.png)`},
+		{"http", `<a href="http://bazel.build/about.html?hl=en" target="_blank" rel="noopener">About</a> ![Logo](http://bazel.build/logo.png)`},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.protocolRelativeScheme = tt.scheme
+		cfg.externalNewTab = true
+		cfg.assetsLayout = assetsCentral
+		cfg.strict["links"] = true
+		cfg.strict["assets"] = true
+		if err := convertTestZip(t, cfg, pages); err != nil {
+			t.Errorf("-protocol-relative-scheme %s: run returned %v, want no broken links or missing images", tt.scheme, err)
+		}
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("-protocol-relative-scheme %s: page is\n%s\nwant it to contain\n%s", tt.scheme, page, tt.want)
+		}
+	}
+}
+
+func TestPinSourceLinks(t *testing.T) {
+	tests := []struct{ href, want string }{
+		{"https://github.com/bazelbuild/bazel/blob/master/src/main/java/Foo.java#L10-L20", "https://github.com/bazelbuild/bazel/blob/7.4.0/src/main/java/Foo.java#L10-L20"},
+		{"https://github.com/BazelBuild/Bazel/tree/main/tools", "https://github.com/BazelBuild/Bazel/tree/7.4.0/tools"},
+		{"https://github.com/bazelbuild/bazel/commits/master", "https://github.com/bazelbuild/bazel/commits/7.4.0"},
+		{"https://raw.githubusercontent.com/bazelbuild/bazel/master/WORKSPACE?plain=1", "https://raw.githubusercontent.com/bazelbuild/bazel/7.4.0/WORKSPACE?plain=1"},
+		// Other refs, repositories and pages stay as they are
+		{"https://github.com/bazelbuild/bazel/blob/6.5.0/BUILD", "https://github.com/bazelbuild/bazel/blob/6.5.0/BUILD"},
+		{"https://github.com/bazelbuild/rules_go/blob/master/BUILD", "https://github.com/bazelbuild/rules_go/blob/master/BUILD"},
+		{"https://github.com/bazelbuild/bazel/issues/master", "https://github.com/bazelbuild/bazel/issues/master"},
+		{"https://example.com/bazelbuild/bazel/blob/master/BUILD", "https://example.com/bazelbuild/bazel/blob/master/BUILD"},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="` + tt.href + `">x</a>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pinSourceLinks(doc, "7.4.0", parseTagList(defaultSourceRefRepos))
+		if got := doc.Find("a").AttrOr("href", ""); got != tt.want {
+			t.Errorf("%s pinned to %s, want %s", tt.href, got, tt.want)
+		}
+	}
+
+	cfg := testConfig(t)
+	cfg.sourceRef = "7.4.0"
+	cfg.sourceRefRepos = parseTagList(defaultSourceRefRepos)
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<h1>Page</h1><p>See <a href="https://github.com/bazelbuild/bazel/blob/master/src/BUILD">the source</a>.</p>`,
+	})["page.md"]
+	if want := "[the source](https://github.com/bazelbuild/bazel/blob/7.4.0/src/BUILD)"; !strings.Contains(page, want) {
+		t.Errorf("page is\n%s\nwant it to contain\n%s", page, want)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// linkTarget is what links from other pages need to know about a page.
+type linkTarget struct {
+	// anchors maps the ids of the page's headings to the anchors the
+	// renderer will generate for them; see headingAnchors
+	anchors map[string]string
+	// split is where -split-on-heading moves the anchors of the page, if
+	// it splits the page
+	split *splitPlan
+}
+
+// linkTarget returns what links need to know about the page converted from
+// the zip entry name. Pages are read for it once, quietly, whether or not
+// they were converted yet; a page that cannot be read has no anchors, and
+// its error is reported when it is converted.
+func (c *conversion) linkTarget(name string) *linkTarget {
+	if t, ok := c.targets[name]; ok {
+		return t
+	}
+	t := &linkTarget{}
+	c.targets[name] = t
+	f := c.files[name]
+	if f == nil || !isHTMLFile(name) {
+		return t
+	}
+	html, err := c.readPage(f, true)
+	if err != nil {
+		return t
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return t
+	}
+	c.preparePage(doc, name, true)
+	t.anchors = headingAnchors(doc, c.cfg)
+	if c.cfg.split.matches(name) {
+		_, pagePath := c.pagePaths(name)
+		t.split = c.cfg.split.planDocument(doc, pagePath, c.cfg)
+	}
+	return t
+}
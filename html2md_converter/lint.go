@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// lintViolation is a convention the generated docs break.
+type lintViolation struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (v lintViolation) String() string {
+	if v.Line == 0 {
+		return v.Path + ": " + v.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", v.Path, v.Line, v.Message)
+}
+
+// linter checks the markdown pages under dir.
+type linter struct {
+	dir           string
+	maxImageBytes int64
+	anchors       AnchorStrategy
+
+	// pageAnchors caches the anchors of each page, by path below dir
+	pageAnchors map[string]map[string]bool
+}
+
+// lintOutput checks every markdown page under dir: each page has a title,
+// internal links, their fragments and images resolve, no --flag appears
+// outside code, and local images stay under maxImageBytes.
+func lintOutput(dir string, maxImageBytes int64, anchors AnchorStrategy) ([]lintViolation, error) {
+	l := &linter{dir: dir, maxImageBytes: maxImageBytes, anchors: anchors, pageAnchors: make(map[string]map[string]bool)}
+
+	var violations []lintViolation
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isPageFile(p) {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, l.lintPage(filepath.ToSlash(rel), string(content))...)
+		return nil
+	})
+	return violations, err
+}
+
+func isPageFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return isMarkdownFile(filename) || ext == ".mdx"
+}
+
+var (
+	// [text](target) and ![alt](src), capturing the target
+	markdownLinkRegex = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// --flag at the start of a word
+	bareFlagRegex = regexp.MustCompile(`(?:^|[\s(])(--[A-Za-z][\w-]*)`)
+)
+
+func (l *linter) lintPage(pagePath, content string) []lintViolation {
+	var violations []lintViolation
+	report := func(line int, format string, args ...interface{}) {
+		violations = append(violations, lintViolation{Path: pagePath, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !hasTitle(content) {
+		report(0, "page has no title in frontmatter or leading H1")
+	}
+
+	for _, line := range proseLines(content) {
+		for _, m := range bareFlagRegex.FindAllStringSubmatch(line.Text, -1) {
+			report(line.Number, "flag %s outside of code", m[1])
+		}
+		for _, m := range markdownLinkRegex.FindAllStringSubmatch(line.Text, -1) {
+			if fragment, ok := strings.CutPrefix(m[2], "#"); ok {
+				if !l.anchorsOf(pagePath, content)[fragment] {
+					report(line.Number, "link to missing anchor %s", m[2])
+				}
+				continue
+			}
+
+			target, ok := localTarget(pagePath, m[2])
+			if !ok {
+				continue
+			}
+			resolved, found := resolveLocalTarget(l.dir, target)
+			if !found {
+				report(line.Number, "broken internal link %s", m[2])
+				continue
+			}
+			if m[1] == "!" && l.maxImageBytes > 0 {
+				if info, err := os.Stat(resolved); err == nil && info.Size() > l.maxImageBytes {
+					report(line.Number, "image %s is %d KiB, over the %d KiB limit", m[2], info.Size()/1024, l.maxImageBytes/1024)
+				}
+			}
+			if u, err := url.Parse(m[2]); err == nil && u.Fragment != "" && isPageFile(resolved) {
+				if !l.anchorsOf(filepath.ToSlash(mustRel(l.dir, resolved)), "")[u.Fragment] {
+					report(line.Number, "link to missing anchor %s", m[2])
+				}
+			}
+		}
+	}
+	return violations
+}
+
+var (
+	headingLineRegex    = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	explicitAnchorRegex = regexp.MustCompile(`\bid="([^"]+)"`)
+	inlineLinkRegex     = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// anchorsOf returns the anchors a page provides: those the renderer
+// generates for its headings and explicit id attributes. content is read
+// from disk when empty.
+func (l *linter) anchorsOf(pagePath, content string) map[string]bool {
+	if anchors, ok := l.pageAnchors[pagePath]; ok {
+		return anchors
+	}
+
+	if content == "" {
+		raw, _ := os.ReadFile(filepath.Join(l.dir, filepath.FromSlash(pagePath)))
+		content = string(raw)
+	}
+
+	anchors := make(map[string]bool)
+	slugs := newAnchorSet(l.anchors)
+	for _, line := range proseLines(content) {
+		if m := headingLineRegex.FindStringSubmatch(line.Raw); m != nil {
+			anchors[slugs.add(headingText(m[1]))] = true
+		}
+	}
+	for _, m := range explicitAnchorRegex.FindAllStringSubmatch(content, -1) {
+		anchors[m[1]] = true
+	}
+
+	l.pageAnchors[pagePath] = anchors
+	return anchors
+}
+
+func mustRel(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+// hasTitle reports whether the page has a frontmatter title or starts with
+// an H1.
+func hasTitle(content string) bool {
+	if fm, ok := strings.CutPrefix(content, "---\n"); ok {
+		if end := strings.Index(fm, "\n---"); end >= 0 {
+			for _, line := range strings.Split(fm[:end], "\n") {
+				if strings.HasPrefix(line, "title:") && strings.TrimSpace(strings.TrimPrefix(line, "title:")) != "" {
+					return true
+				}
+			}
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return strings.HasPrefix(line, "# ")
+		}
+	}
+	return false
+}
+
+// localTarget resolves a link target relative to the page, returning its
+// slash-separated path below the output root. External links, pure
+// fragments and links leaving the output tree are not local.
+func localTarget(pagePath, target string) (string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	p := u.Path
+	if strings.HasPrefix(p, "/") {
+		p = path.Clean(strings.TrimPrefix(p, "/"))
+	} else {
+		p = path.Join(path.Dir(pagePath), p)
+	}
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
+
+// resolveLocalTarget finds the file a local link refers to, allowing the
+// extensionless and directory-index forms renderers serve pages at.
+func resolveLocalTarget(dir, target string) (string, bool) {
+	base := filepath.Join(dir, filepath.FromSlash(target))
+	candidates := []string{base}
+	for _, ext := range []string{".md", ".mdx"} {
+		candidates = append(candidates, base+ext, filepath.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// proseLine is a line of markdown outside code, with inline code removed.
+type proseLine struct {
+	Number int
+	Text   string
+	// Raw is the line with its inline code intact
+	Raw string
+}
+
+var inlineCodeRegex = regexp.MustCompile("`+[^`]*`+")
+
+// proseLines returns the page's lines outside frontmatter and fenced or
+// indented code blocks, with inline code spans blanked out.
+func proseLines(content string) []proseLine {
+	var lines []proseLine
+	fence := ""
+	inFrontmatter := strings.HasPrefix(content, "---\n")
+	for i, line := range strings.Split(content, "\n") {
+		if inFrontmatter {
+			if i > 0 && line == "---" {
+				inFrontmatter = false
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		lines = append(lines, proseLine{Number: i + 1, Text: inlineCodeRegex.ReplaceAllString(line, "``"), Raw: line})
+	}
+	return lines
+}
+
+// runLint lints the output directory, prints the violations and returns
+// whether there were none.
+func runLint(dir string, maxImageBytes int64, anchors AnchorStrategy) (bool, error) {
+	violations, err := lintOutput(dir, maxImageBytes, anchors)
+	if err != nil {
+		return false, err
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+	fmt.Printf("Lint found %d violation(s)\n", len(violations))
+	return len(violations) == 0, nil
+}
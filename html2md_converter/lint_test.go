@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good.md":      "---\ntitle: 'Good'\n---\n\nSee [bad](bad.md), ![logo](logo.png) and `--flag`.\n\n## Usage\n\n```\nbazel build --config=x\n```\n",
+		"bad.md":       "No title, a [dead link](missing.md), a --bare_flag, ![big](big.png) and [no anchor](good.md#nope).\n",
+		"logo.png":     "PNG",
+		"big.png":      strings.Repeat("x", 2048),
+		"notes/sub.md": "# Sub\n\n[up](../good.md#usage) [own](#sub)\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	violations, err := lintOutput(dir, 1024, anchorStrategies["mintlify"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"bad.md: page has no title",
+		"bad.md:1: flag --bare_flag outside of code",
+		"bad.md:1: ", "missing.md",
+		"big.png",
+		"good.md#nope",
+	}
+	report := strings.Join(got, "\n")
+	for _, w := range want {
+		if !strings.Contains(report, w) {
+			t.Errorf("violations lack %q:\n%s", w, report)
+		}
+	}
+	for _, v := range violations {
+		if v.Path != "bad.md" {
+			t.Errorf("violation in a conforming page: %s", v)
+		}
+	}
+}
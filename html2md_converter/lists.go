@@ -0,0 +1,349 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// attrListPrefix is where the converter stores the marker of each list item
+// before rules run.
+const attrListPrefix = "data-converter-list-prefix"
+
+// Runs of blank lines, which may hold indentation
+var blankLinesRegex = regexp.MustCompile(`\n(?:[ \t]*\n)+`)
+
+// listItemRule replaces the library's <li> rule, which indents an item's
+// lines only up to the first one that looks like a list marker. A nested
+// list was already indented there, but paragraphs and code blocks after it,
+// and code whose lines start with "- ", fell out of the item. Here each
+// item indents all of its lines under its own marker, so nesting adds up
+// through the parent items, and items holding several blocks are followed
+// by a blank line to keep the list loose throughout.
+func listItemRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"li"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			content = strings.Trim(blankLinesRegex.ReplaceAllString(content, "\n\n"), "\n")
+			content = strings.TrimLeft(content, " ")
+			if strings.TrimSpace(content) == "" {
+				return nil
+			}
+
+			// Items that only wrap a nested list keep its indentation
+			prefix := listPrefix(selec)
+			if prefix == "" {
+				width := 0
+				selec.Siblings().Each(func(i int, s *goquery.Selection) {
+					width = max(width, len(listPrefix(s)))
+				})
+				prefix = strings.Repeat(" ", width)
+			}
+			indent := strings.Repeat(" ", len(prefix))
+
+			lines := strings.Split(content, "\n")
+			for i := 1; i < len(lines); i++ {
+				if lines[i] != "" {
+					lines[i] = indent + lines[i]
+				}
+			}
+
+			end := "\n"
+			if strings.Contains(content, "\n\n") {
+				end = "\n\n"
+			}
+			return md.String(prefix + strings.Join(lines, "\n") + end)
+		},
+	}
+}
+
+// listPrefix returns the marker of a list item. Items of <ol reversed>
+// count down from start, or from the number of items, with explicit
+// numbers, since markdown lists can only count up; the first number still
+// starts the list right in renderers that ignore the others.
+func listPrefix(li *goquery.Selection) string {
+	prefix := li.AttrOr(attrListPrefix, "")
+	parent := li.Parent()
+	if prefix == "" || !parent.Is("ol[reversed]") {
+		return prefix
+	}
+
+	items := parent.ChildrenFiltered("li")
+	start, err := strconv.Atoi(parent.AttrOr("start", ""))
+	if err != nil {
+		start = items.Length()
+	}
+	number := start - items.IndexOfSelection(li)
+	if number < 0 {
+		return prefix
+	}
+	return strconv.Itoa(number) + ". "
+}
+
+// nestMisplacedLists fixes up nested lists so that they number on their own.
+// A list placed directly in a list, beside its items rather than in one,
+// counted as an item of the outer list, so the items after it numbered on
+// from it; it moves into the item before it. And an ordered sublist not
+// starting at 1 cannot interrupt a paragraph in markdown, so that it ran
+// into the text of its item; that text becomes a paragraph of its own.
+func nestMisplacedLists(doc *goquery.Document) {
+	doc.Find("ol > ol, ol > ul, ul > ol, ul > ul").Each(func(i int, list *goquery.Selection) {
+		if item := list.PrevAllFiltered("li").First(); item.Length() > 0 {
+			item.AppendSelection(list)
+			return
+		}
+		li := &html.Node{Type: html.ElementNode, Data: "li"}
+		list.BeforeNodes(li)
+		goquery.NewDocumentFromNode(li).AppendSelection(list)
+	})
+
+	doc.Find("li > ol[start]").Each(func(i int, list *goquery.Selection) {
+		if start, err := strconv.Atoi(list.AttrOr("start", "")); err != nil || start == 1 {
+			return
+		}
+		var inline []*html.Node
+		text := false
+		for n := list.Nodes[0].PrevSibling; n != nil; n = n.PrevSibling {
+			if n.Type == html.ElementNode && listBlockElements[n.Data] {
+				break
+			}
+			inline = append([]*html.Node{n}, inline...)
+			text = text || n.Type == html.ElementNode || strings.TrimSpace(n.Data) != ""
+		}
+		if !text {
+			return
+		}
+		p := &html.Node{Type: html.ElementNode, Data: "p"}
+		inline[0].Parent.InsertBefore(p, inline[0])
+		for _, n := range inline {
+			n.Parent.RemoveChild(n)
+			p.AppendChild(n)
+		}
+	})
+}
+
+// Where -stray-list-content moves content placed directly in a list,
+// between its items.
+const (
+	// Into the item before it, as a continuation
+	strayAttach = "attach"
+	// Out of the list, splitting it, with the numbering carrying on after
+	strayLift = "lift"
+)
+
+// moveStrayListContent moves the text and elements other than items that
+// HTML puts directly in a <ul> or <ol>, which is invalid but occurs, to
+// where markdown can hold them, as mode says. Left in place, they broke the
+// list apart and the items after them numbered on from them. Attached
+// content is a paragraph of the item, or the block it is. Content before
+// the first item is always lifted out, before the list. Nested lists are
+// left to nestMisplacedLists.
+func moveStrayListContent(doc *goquery.Document, mode string) {
+	doc.Find("ul, ol").Each(func(i int, list *goquery.Selection) {
+		stray := func(n *html.Node) bool {
+			switch n.Type {
+			case html.TextNode:
+				return strings.TrimSpace(n.Data) != ""
+			case html.ElementNode:
+				return n.Data != "li" && n.Data != "ul" && n.Data != "ol" && n.Data != "script" && n.Data != "template"
+			}
+			return false
+		}
+
+		current := list.Nodes[0]
+		var item *html.Node
+		number := 1
+		if start, err := strconv.Atoi(list.AttrOr("start", "")); err == nil {
+			number = start
+		}
+		// paragraph collects the inline content attached to item
+		var paragraph *html.Node
+		attached := make(map[*html.Node]bool)
+		var children []*html.Node
+		for n := current.FirstChild; n != nil; n = n.NextSibling {
+			children = append(children, n)
+		}
+		for _, n := range children {
+			switch {
+			case n.Type == html.ElementNode && n.Data == "li":
+				if n.Parent != current {
+					n.Parent.RemoveChild(n)
+					current.AppendChild(n)
+				}
+				item = n
+				number++
+			case !stray(n):
+				if n.Parent != current {
+					n.Parent.RemoveChild(n)
+					current.AppendChild(n)
+				}
+			case item != nil && mode == strayAttach:
+				// The item becomes paragraphs, so the content does not run
+				// into its text
+				if paragraph != item.LastChild {
+					paragraph = nil
+				}
+				if !attached[item] {
+					attached[item] = true
+					wrapInlineChildren(item)
+				}
+				n.Parent.RemoveChild(n)
+				if n.Type == html.ElementNode && listBlockElements[n.Data] {
+					item.AppendChild(n)
+					paragraph = nil
+					continue
+				}
+				if paragraph == nil {
+					paragraph = &html.Node{Type: html.ElementNode, Data: "p"}
+					item.AppendChild(paragraph)
+				}
+				paragraph.AppendChild(n)
+			default:
+				// Lift the content after the items so far, and continue
+				// them in a list of their own
+				n.Parent.RemoveChild(n)
+				if item == nil {
+					current.Parent.InsertBefore(n, current)
+					continue
+				}
+				current.Parent.InsertBefore(n, current.NextSibling)
+				rest := &html.Node{Type: html.ElementNode, Data: current.Data, Attr: append([]html.Attribute(nil), current.Attr...)}
+				n.Parent.InsertBefore(rest, n.NextSibling)
+				if rest.Data == "ol" {
+					goquery.NewDocumentFromNode(rest).SetAttr("start", strconv.Itoa(number))
+				}
+				current, item = rest, nil
+			}
+		}
+		if current != list.Nodes[0] && current.FirstChild == nil {
+			current.Parent.RemoveChild(current)
+		}
+	})
+}
+
+// wrapInlineChildren puts the content of a list item in a paragraph when it
+// has no blocks of its own.
+func wrapInlineChildren(li *html.Node) {
+	var inline []*html.Node
+	for n := li.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode && listBlockElements[n.Data] {
+			return
+		}
+		inline = append(inline, n)
+	}
+	if len(inline) == 0 {
+		return
+	}
+	p := &html.Node{Type: html.ElementNode, Data: "p"}
+	for _, n := range inline {
+		li.RemoveChild(n)
+		p.AppendChild(n)
+	}
+	li.AppendChild(p)
+}
+
+// Elements that start a block of their own within a list item
+var listBlockElements = map[string]bool{
+	"p": true, "div": true, "ul": true, "ol": true, "dl": true, "pre": true,
+	"blockquote": true, "table": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// Numbering of ordered lists accepted by -list-numbering.
+const (
+	// 1. 2. 3.
+	numberingSequential = "sequential"
+	// 1. 1. 1., which keeps diffs small when items are added
+	numberingOne = "one"
+)
+
+var (
+	// A list item line: its blockquote markers and indentation, marker and
+	// the spaces after it
+	listMarkerLineRegex = regexp.MustCompile(`^([ >]*)([-*+]|\d{1,9}[.)])( +)\S`)
+	// * * * and other thematic breaks, which look like bullets
+	thematicBreakRegex = regexp.MustCompile(`^[ >]*(?:[-*_][ \t]*){3,}$`)
+)
+
+// normalizeListMarkers rewrites, for -normalize-list-markers, the markers of
+// every list on the page outside code blocks: bullets as bullet, and ordered
+// items with a period, numbered in order from the list's first number, or
+// all with that number. The marker is padded to the width of the one it
+// replaces, so the item's other lines stay under it.
+func normalizeListMarkers(markdown, bullet, numbering string) string {
+	type list struct {
+		indent, content int
+		ordered         bool
+		next            int
+	}
+	var stack []list
+
+	lines := strings.Split(markdown, "\n")
+	fence := ""
+	inFrontmatter := strings.HasPrefix(markdown, "---\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inFrontmatter:
+			if i > 0 && line == "---" {
+				inFrontmatter = false
+			}
+			continue
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			continue
+		case trimmed == "":
+			continue
+		}
+
+		m := listMarkerLineRegex.FindStringSubmatch(line)
+		if m == nil || thematicBreakRegex.MatchString(line) {
+			// Other lines end the lists they are not indented under
+			indent := len(line) - len(strings.TrimLeft(line, " >"))
+			for len(stack) > 0 && indent < stack[len(stack)-1].content {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		prefix, marker, spaces := m[1], m[2], m[3]
+		indent := len(prefix)
+		ordered := marker[0] >= '0' && marker[0] <= '9'
+		for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+			stack = stack[:len(stack)-1]
+		}
+		if n := len(stack); n > 0 && stack[n-1].indent == indent && stack[n-1].ordered != ordered {
+			// A bullet after numbered items, or the reverse, starts a list
+			stack = stack[:n-1]
+		}
+		if len(stack) == 0 || stack[len(stack)-1].indent != indent {
+			start, _ := strconv.Atoi(strings.TrimRight(marker, ".)"))
+			stack = append(stack, list{indent: indent, ordered: ordered, next: start})
+		}
+		top := &stack[len(stack)-1]
+		top.content = indent + len(marker) + len(spaces)
+
+		replacement := bullet
+		if ordered {
+			replacement = strconv.Itoa(top.next) + "."
+			if numbering == numberingSequential {
+				top.next++
+			}
+		}
+		pad := len(marker) + len(spaces) - len(replacement)
+		if pad < 1 {
+			pad = 1
+		}
+		lines[i] = prefix + replacement + strings.Repeat(" ", pad) + line[len(prefix)+len(marker)+len(spaces):]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReversedLists(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`<ol reversed><li>Three</li><li>Two</li><li>One</li></ol>`, "3. Three\n2. Two\n1. One"},
+		{`<ol reversed start="10"><li>Ten</li><li>Nine</li></ol>`, "10. Ten\n9. Nine"},
+		{`<ol start="4"><li>Four</li><li>Five</li></ol>`, "4. Four\n5. Five"},
+	}
+	converter := newConverter(testConfig(t))
+	for _, tt := range tests {
+		got, err := converter.ConvertString(tt.html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Errorf("%s converted to\n%s\nwant\n%s", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeListMarkers(t *testing.T) {
+	markdown := "---\ntitle: 'x'\n---\n\n* One\n+ Two\n  * Nested\n- Three\n\n* * *\n\n3) Third\n7) Fourth\n\n```\n* not a list\n```\n"
+	tests := []struct{ bullet, numbering, want string }{
+		{"-", numberingSequential, "---\ntitle: 'x'\n---\n\n- One\n- Two\n  - Nested\n- Three\n\n* * *\n\n3. Third\n4. Fourth\n\n```\n* not a list\n```\n"},
+		{"*", numberingOne, "---\ntitle: 'x'\n---\n\n* One\n* Two\n  * Nested\n* Three\n\n* * *\n\n3. Third\n3. Fourth\n\n```\n* not a list\n```\n"},
+	}
+	for _, tt := range tests {
+		if got := normalizeListMarkers(markdown, tt.bullet, tt.numbering); got != tt.want {
+			t.Errorf("%s %s: markers normalized to\n%s\nwant\n%s", tt.bullet, tt.numbering, got, tt.want)
+		}
+	}
+}
+
+func TestStrayListContent(t *testing.T) {
+	html := `<h1>Steps</h1><ol><li>Install</li><p>Check the version first.</p><li>Build</li><li>Test</li></ol>`
+	tests := map[string]string{
+		strayAttach: "1. Install\n\n   Check the version first.\n\n2. Build\n3. Test",
+		strayLift:   "1. Install\n\nCheck the version first.\n\n2. Build\n3. Test",
+	}
+	for mode, want := range tests {
+		cfg := testConfig(t)
+		cfg.strayListContent = mode
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		if !strings.Contains(page, want) {
+			t.Errorf("%s: page is\n%s\nwant it to contain\n%s", mode, page, want)
+		}
+	}
+}
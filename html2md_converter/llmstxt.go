@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// llmsTxtFile is written at the root of the output directory with
+// -emit-llms-txt.
+const llmsTxtFile = "llms.txt"
+
+// llmsEntry is a converted page as llms.txt lists it.
+type llmsEntry struct {
+	Path        string
+	Title       string
+	Description string
+}
+
+// pageDescription returns the description field of a page's frontmatter on
+// one line, if any.
+func pageDescription(fm frontmatter) string {
+	for _, field := range fm {
+		if field.Key == "description" {
+			return collapseWhitespace(plainVerbatimBraces.Replace(field.Value))
+		}
+	}
+	return ""
+}
+
+// writeLLMsTxt writes the llms.txt index of the converted pages, following
+// the llms.txt convention: the site title, the root page's description as
+// a summary, then the pages as lists of links, with the pages at the root
+// first and a section for each top-level directory after them. Sections and
+// the pages in them keep the order the pages were converted in. The root
+// index page gives the site title and a directory's index page its
+// section's.
+func writeLLMsTxt(outputDir string, entries []llmsEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	title := filepath.Base(outputDir)
+	summary := ""
+	sectionTitles := make(map[string]string)
+	var sections []string
+	pages := make(map[string][]llmsEntry)
+	for _, entry := range entries {
+		section, rest := "", entry.Path
+		if i := strings.Index(entry.Path, "/"); i >= 0 {
+			section, rest = entry.Path[:i], entry.Path[i+1:]
+		}
+		if !strings.Contains(rest, "/") && isIndexPage(rest) {
+			if section == "" {
+				title, summary = entry.Title, entry.Description
+			} else {
+				sectionTitles[section] = entry.Title
+			}
+		}
+		if _, ok := pages[section]; !ok {
+			sections = append(sections, section)
+		}
+		pages[section] = append(pages[section], entry)
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + title + "\n")
+	if summary != "" {
+		b.WriteString("\n> " + summary + "\n")
+	}
+	write := func(section string) {
+		b.WriteString("\n")
+		for _, entry := range pages[section] {
+			b.WriteString("- [" + entry.Title + "](" + entry.Path + ")")
+			if entry.Description != "" {
+				b.WriteString(": " + entry.Description)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if _, ok := pages[""]; ok {
+		write("")
+	}
+	for _, section := range sections {
+		if section == "" {
+			continue
+		}
+		heading := sectionTitles[section]
+		if heading == "" {
+			heading = section
+		}
+		b.WriteString("\n## " + heading + "\n")
+		write(section)
+	}
+
+	outputPath := filepath.Join(outputDir, llmsTxtFile)
+	if err := writeFile(outputPath, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write %s: %w", llmsTxtFile, err)
+	}
+
+	fmt.Printf("Wrote %d page(s) to %s\n", len(entries), outputPath)
+	return nil
+}
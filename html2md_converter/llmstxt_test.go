@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestLLMsTxt(t *testing.T) {
+	pages := map[string]string{
+		"index.html":      "<pre>\n---\ndescription: Fast, correct builds.\n---\n</pre><h1>Bazel</h1><p>Home</p>",
+		"about.html":      "<h1>About</h1><p>About</p>",
+		"docs/index.html": "<h1>Documentation</h1><p>Docs</p>",
+		"docs/build.html": "<pre>\n---\ndescription: |\n  How to build\n  targets.\n---\n</pre><h1>Build</h1><p>Build</p>",
+		"api/rules.html":  "<h1>Rules</h1><p>Rules</p>",
+	}
+	cfg := testConfig(t)
+	cfg.emitLLMsTxt = true
+	cfg.rawFrontmatterMarker = "---"
+	got := convertPages(t, cfg, pages)[llmsTxtFile]
+
+	want := `# Bazel
+
+> Fast, correct builds.
+
+- [About](about.md)
+- [Bazel](index.md): Fast, correct builds.
+
+## api
+
+- [Rules](api/rules.md)
+
+## Documentation
+
+- [Build](docs/build.md): How to build targets.
+- [Documentation](docs/index.md)
+`
+	if got != want {
+		t.Errorf("llms.txt is\n%s\nwant\n%s", got, want)
+	}
+}
@@ -2,57 +2,1330 @@ package main
 
 import (
 	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 func main() {
-	zipPath := flag.String("zip", "", "Path to the zip file containing HTML files")
+	zipPath := flag.String("zip", "", "Path to the zip file containing HTML files, or to a single HTML page; either may be gzipped, and - reads it from stdin")
 	outputDir := flag.String("output", "output", "Output directory for markdown files")
+	maxHeadingDepth := flag.Int("max-heading-depth", 0, "Demote headings deeper than this level to bold paragraphs (0 keeps all headings)")
+	assetsLayout := flag.String("assets-layout", "", "Copy referenced images: \"colocated\" next to each page or \"central\" under -assets-dir (empty skips images)")
+	assetsDir := flag.String("assets-dir", "assets", "Directory, relative to -output, that holds images in the central assets layout")
+	assetsMaxBytes := flag.Int64("assets-max-bytes", 0, "Warn about images copied by -assets-layout that are larger than this many bytes (0 disables the check)")
+	assetsMaxWidth := flag.Int("assets-max-width", 0, "Warn about PNG, JPEG and GIF images copied by -assets-layout that are wider than this many pixels (0 disables the check)")
+	anchorStyle := flag.String("anchor-style", "mintlify", "Heading anchor rules of the target renderer: mintlify, github, or docusaurus")
+	metricsPath := flag.String("metrics", "", "Write run metrics in the Prometheus text format to this file")
+	statsJSON := flag.String("stats-json", "", "Write the run summary, with the counts, bytes, duration and the warnings of each -strict-<category> category, as JSON to this file")
+	renameMapPath := flag.String("rename-map", "", "YAML file mapping source paths in the zip to explicit output paths")
+	noFrontmatter := flag.Bool("no-frontmatter", false, "Keep the page title as a markdown H1 instead of writing YAML frontmatter")
+	outputCase := flag.String("output-case", casePreserve, "Case of output paths: \"preserve\" keeps the source case with a lowercase extension, \"lower\" lowercases the whole path")
+	math := flag.String("math", mathOff, "Math handling: \"katex\" converts MathML and $...$ to KaTeX syntax, \"off\" leaves them as text")
+	preserveHeadingIDs := flag.Bool("preserve-heading-ids", false, "Keep heading ids that differ from the generated anchor as explicit <a id> anchors")
+	trailingSlash := flag.String("trailing-slash", slashKeep, "Trailing slashes on rewritten internal links and redirects: \"add\" (extensionless with a slash), \"strip\", or \"keep\"")
+	externalNewTab := flag.Bool("external-new-tab", false, "Write external links as <a target=\"_blank\" rel=\"noopener\"> anchors")
+	externalLinkClass := flag.String("external-link-class", "", "className added to external links written by -external-new-tab, e.g. for an icon")
+	preserveMtime := flag.Bool("preserve-mtime", true, "Give written files the modification time of their zip entry")
+	dedupPages := flag.Bool("dedup-pages", false, "Write pages with identical converted content once and redirect the duplicates to it")
+	warnDroppedAttrs := flag.Bool("warn-dropped-attrs", false, "Log the class and style attributes dropped from each page and summarize them at the end")
+	configPath := flag.String("config", "", "YAML file configuring the conversion rule sets")
+	normalizeLinks := flag.Bool("normalize-links", false, "Collapse ./ and ../ segments in internal link targets")
+	inputEncoding := flag.String("input-encoding", "", "Encoding of the HTML input, e.g. iso-8859-1 (default: detect from a byte order mark or <meta charset>, else UTF-8)")
+	diffDir := flag.String("diff", "", "Convert to a scratch directory and print a unified diff against this existing output tree instead of writing -output; exits 1 when they differ")
+	syncTo := flag.String("sync-to", "", "Convert to a scratch directory and sync this output tree to the result instead of writing -output: new and changed files are written, unchanged ones left alone and orphaned ones removed, and the delta is printed")
+	syncDryRun := flag.Bool("sync-dry-run", false, "With -sync-to, only print the delta, changing nothing")
+	devsiteConditions := flag.String("devsite-conditions", conditionsTrue, "Branch kept from Devsite {% if %} blocks: \"true\" keeps the first branch, \"false\" keeps the {% else %} branch")
+	dynamicBranch := flag.String("dynamic-branch", dynamicPublic, "Branches kept from Devsite {% dynamic if %} blocks: \"public\" keeps what a signed-out reader sees, \"all\" keeps every branch")
+	outIndex := flag.String("out-index", "", "Write a JSONL search index with the path, title, headings and plain text of each converted page to this file")
+	strictAll := flag.Bool("strict", false, "Fail the run on warnings of every -strict-<category> category")
+	strictCategories := make(map[string]*bool)
+	for _, cat := range warningCategories {
+		usage := fmt.Sprintf("Fail the run, with exit status bit %d set, on warnings about %s", cat.exitBit, cat.help)
+		if cat.exitBit == 0 {
+			usage = fmt.Sprintf("Fail the run, with exit status 1 unless other categories set bits, on warnings about %s", cat.help)
+		}
+		strictCategories[cat.name] = flag.Bool("strict-"+cat.name, false, usage)
+	}
+	banner := flag.String("banner", "", "Notice, such as \"Generated from {source}; do not edit\", added after the frontmatter of every converted page; {source} is replaced with the path in the zip")
+	bannerStyle := flag.String("banner-style", bannerComment, "Form of the -banner notice: \"comment\" (an HTML comment, an MDX comment after transform-docs.awk) or \"note\" (a visible note callout)")
+	bannerCopied := flag.Bool("banner-copied", false, "Also add the -banner notice to markdown files copied from the zip as-is")
+	stripQuery := flag.Bool("strip-query", true, "Drop query strings, such as Devsite's ?hl=en, from rewritten internal links")
+	stripFragment := flag.Bool("strip-fragment", false, "Drop fragments from rewritten internal links")
+	stripExternal := flag.Bool("strip-external", false, "Apply -strip-query and -strip-fragment to external links too")
+	componentStyle := flag.String("component-style", "mintlify", "Syntax of emitted callouts and accordions: mintlify components or docusaurus admonitions and <details>")
+	pageNavSelector := flag.String("page-nav-selector", defaultPageNavSelector, "CSS selector of embedded \"On this page\" navigation to remove (empty keeps it)")
+	preserveHidden := flag.Bool("preserve-hidden", false, "Keep elements marked hidden or aria-hidden=\"true\" instead of dropping them")
+	readingTime := flag.Bool("reading-time", false, "Add word_count and reading_time, in minutes, to the frontmatter of converted pages")
+	readingWPM := flag.Int("reading-wpm", 200, "Words per minute that -reading-time assumes")
+	postHook := flag.String("post-hook", "", "Shell command run on each converted page after it is written, e.g. \"prettier --write {file}\"; {file} and {source} are replaced with the output and zip paths. It runs with your privileges, so only use trusted commands")
+	postHookTimeout := flag.Duration("post-hook-timeout", 30*time.Second, "Time -post-hook may take per page before it is stopped")
+	allowlistTags := flag.String("allowlist-tags", "", "Comma-separated HTML tags to keep, e.g. a,p,code,pre,ul,ol,li,table,tr,th,td,h2,h3; other tags are unwrapped to their content, and scripts, styles and embeds are dropped")
+	quoteStyle := flag.String("quote-style", quotesCurly, "Quotation marks written for <q> elements: \"curly\" or \"straight\"")
+	wrap := flag.Int("wrap", 0, "Reflow prose lines of converted pages to this many columns; code, tables, headings and HTML are left as they are (0 disables)")
+	varStyle := flag.String("var-style", varItalic, "Form of <var> placeholders: \"italic\" (_name_), \"code\" (_`name`_) or \"placeholder\" (\\<name\\>)")
+	pathTemplateText := flag.String("path-template", "", "Template for output paths of pages, e.g. \"reference/{1}/{name}.{ext}\", with {dir}, {name} and {ext} of the default path and the groups of -path-pattern")
+	pathPattern := flag.String("path-pattern", "", "Regular expression matched against source paths whose groups -path-template can use; pages it does not match keep their default path")
+	rawFrontmatter := flag.Bool("preserve-raw-frontmatter", false, "Use YAML frontmatter embedded at the start of HTML pages, in a comment or <pre> between marker lines, as the frontmatter of the converted page")
+	rawFrontmatterMarker := flag.String("raw-frontmatter-marker", "---", "Line that opens and closes embedded frontmatter for -preserve-raw-frontmatter")
+	linkBase := flag.String("link-base", "", "Rewrite internal links to absolute, extensionless URLs under this base, e.g. \"/reference\" or \"https://example.com/docs\"")
+	sourcesRoot := flag.String("sources-root", "", "Directory of further zips of the same site in which images that pages reference are looked up when the zip lacks them")
+	stripEmptyLinks := flag.Bool("strip-empty-links", false, "Remove links without text, such as icon links, instead of leaving a doubled space where they were")
+	emptyLinkMode := flag.String("empty-link-mode", emptyLinksDrop, "What -strip-empty-links does with links that have a target: \"drop\" them or show the \"url\" as their text")
+	glossaryPath := flag.String("glossary", "", "YAML file with the output path of the glossary page and the anchor of each term on it; the first occurrence of each term in a page's prose is linked to its entry")
+	fileTimeout := flag.Duration("file-timeout", 0, "Longest time parsing and converting one page may take; slower pages are skipped and fail the run at the end (0 disables)")
+	totalTimeout := flag.Duration("total-timeout", 0, "Longest time the whole conversion may take before it is aborted (0 disables)")
+	tagReport := flag.String("report-unknown-tags", "", "Write a JSON census of the tags and classes the pages use, with counts and a sample page each, to this file instead of converting")
+	paramFields := flag.Bool("param-fields", false, "Write definition lists of attributes, whose terms are <code> names, as Mintlify <ParamField> components with the type and default their definitions give")
+	keepGoTemplates := flag.Bool("keep-go-templates", true, "Keep {{ }} and {% %} inside <pre> and <code> literally, as examples of Go or Jinja templates, except references to variables the page defines")
+	manifestPath := flag.String("output-manifest", "", "Write a manifest of the written pages, markdown files and images, with their source, output path, size, kind and title, to this file")
+	manifestFormat := flag.String("manifest-format", manifestJSON, "Format of -output-manifest: json or csv")
+	dropSectionsList := flag.String("drop-sections", "", "Comma-separated heading texts, such as \"Was this helpful?,Feedback\", whose sections are removed up to the next heading of the same or a higher level (case-insensitive)")
+	footerFrontmatter := flag.Bool("footer-frontmatter", false, "Move the last-updated date and authors of attribution footers into last_updated, or -footer-date-key, and authors frontmatter fields, removing the footers")
+	footerSelector := flag.String("footer-selector", defaultFooterSelector, "CSS selector of the footers -footer-frontmatter reads")
+	footerDateKey := flag.String("footer-date-key", "last_updated", "Frontmatter field -footer-frontmatter writes the last-updated date to, such as updated")
+	check := flag.Bool("check", false, "Render each converted page back to HTML and warn when its text lost more than -check-threshold of the source words")
+	checkThreshold := flag.Float64("check-threshold", 0.02, "Share of source words, between 0 and 1, that -check lets a page lose")
+	prevAnchorsPath := flag.String("prev-anchors", "", "Anchor map JSON written by -out-anchors for the previous build; headings whose anchor changed keep the old one as an alias")
+	outAnchors := flag.String("out-anchors", "", "Write the heading anchors of each converted page, as JSON for -prev-anchors of the next build, to this file")
+	maxInputMB := flag.Int64("max-input-mb", 1024, "Largest size, in MiB, that gzipped or single-page input may have when read into memory")
+	insStyle := flag.String("ins-style", insUnderline, "Form of <ins> insertions: \"underline\" (<u>text</u>), \"ins\" (<ins>text</ins>) or \"plain\" (the text alone); <del> becomes ~~strikethrough~~")
+	normalizeHeadingSpace := flag.Bool("normalize-heading-whitespace", false, "Collapse and trim the whitespace in headings and strip leading section numbers such as \"1.2 \" or \"3. \" from them, before titles, anchors and links to them are derived")
+	keepHeadingNumbers := flag.Bool("keep-heading-numbers", false, "With -normalize-heading-whitespace, keep leading section numbers in headings")
+	keepImgDimensions := flag.Bool("keep-img-dimensions", false, "Keep the width and height of images, writing sized images as <img> tags; by default they are dropped along with loading, decoding and other presentational attributes")
+	serveAddr := flag.String("serve", "", "Convert into a temporary directory and serve the pages, rendered to HTML, at this address, e.g. localhost:8080, converting again and reloading them when the -zip file changes")
+	embedMode := flag.String("embed-mode", embedIframe, "Form of Devsite frameboxes with live examples: \"iframe\" (an <iframe> showing the example) or \"link\" (a link to it; inline examples become their HTML source)")
+	copyExamples := flag.Bool("copy-examples", false, "Copy the example HTML of frameboxes, inline or from the zip, into a .examples directory beside the page and point the framebox at the copy")
+	dedupTitleHeading := flag.Bool("dedup-consecutive-headings", false, "Drop the heading a page body starts with when its text is the page title, so that the title is not shown twice")
+	sourceLink := flag.String("source-link", "", "URL template of the source of each page, e.g. \"https://github.com/bazelbuild/bazel/blob/master/site/en/{source}\"; {source} is replaced with the path in the zip")
+	sourceLinkStyle := flag.String("source-link-style", sourceLinkFrontmatter, "Where the -source-link URL goes: \"frontmatter\" (a source_url field) or \"footer\" (an \"Edit this page\" link at the end of the page)")
+	normalizeCodeFences := flag.Bool("normalize-code-fences", false, "Fence every code block with the shortest run of backticks none of its lines could close, or with tildes when its info string holds a backtick, instead of a run longer than any backticks in the block")
+	emitLLMsTxt := flag.Bool("emit-llms-txt", false, "Write llms.txt at the output root, listing each converted page with its title and frontmatter description, grouped by top-level directory")
+	protocolRelativeScheme := flag.String("protocol-relative-scheme", "https", "Scheme given to protocol-relative URLs such as //bazel.build/about, which are then external links and images rather than paths in the zip: \"https\" or \"http\"")
+	stripDataAttrs := flag.Bool("strip-data-attributes", true, "Remove data-* attributes from HTML passed through to the output as is, such as MathML that has no TeX form, except those in -keep-data-attributes")
+	keepDataAttrs := flag.String("keep-data-attributes", "data-lang", "Comma-separated data-* attributes -strip-data-attributes keeps")
+	hgroupSubtitleMode := flag.String("hgroup-subtitle", hgroupDescription, "Where the subtitle of an <hgroup> around the page title goes: the \"description\" or \"subtitle\" frontmatter field, or a \"line\" in italics under the title (always, with -no-frontmatter)")
+	rewriteAnchorCase := flag.Bool("rewrite-anchor-case", false, "Lowercase heading ids, named anchors and the fragments of links to pages in the zip, so that fragments match anchors whatever case either uses")
+	gaugeStyle := flag.String("gauge-style", gaugePercent, "Form of <progress> and <meter> values: \"percent\" (75%), \"fraction\" (3/4) or \"bar\" (███████░░░ 75%)")
+	failOnRawHTML := flag.Bool("fail-on-raw-html", false, "Fail the run when HTML tags are left in the markdown outside code, listing the tags and pages; for plain markdown output, where they point at markup no rule converts")
+	rawHTMLAllow := flag.String("raw-html-allow", "br", "Comma-separated HTML tags -fail-on-raw-html accepts in the markdown")
+	frontmatterOrder := flag.String("output-frontmatter-order", "title,description", "Comma-separated frontmatter keys written first, in that order; the other keys follow alphabetically. Empty keeps the order the fields were added in")
+	stripAutogeneratedIDs := flag.Bool("strip-autogenerated-ids", false, "Drop ids, and named anchors, that look generated, so that they are not kept as anchors; see -autogenerated-id-pattern")
+	autogeneratedIDPattern := flag.String("autogenerated-id-pattern", defaultAutogeneratedIDPattern, "Regular expression matching the ids -strip-autogenerated-ids drops")
+	apiJSONTemplate := flag.String("api-json-template", "", "Go text/template file that renders the JSON API dumps in the zip, such as Starlark API dumps, into reference pages instead of skipping them; it gets the decoded JSON and the code, oneline and join functions")
+	apiJSONPattern := flag.String("api-json-pattern", "", "Glob, such as \"reference/api/*.json\", matching the zip paths of the JSON files -api-json-template renders; empty matches every .json file")
+	autoAlignNumbers := flag.Bool("auto-align-numbers", false, "Right-align the table columns whose cells are all numbers; sets auto_align_numbers of the tables rule set, as -config can")
+	rulesDryRun := flag.Bool("rules-dry-run", false, "Convert pages without writing them, printing for each how many elements each rule set, and each group of built-in rules, converted")
+	outputArchive := flag.String("output-archive", "", "Also pack the output directory into this .tar, .tar.gz or .tgz archive after the conversion")
+	reproducible := flag.Bool("reproducible", false, "Give every -output-archive entry the same timestamp, owner and mode, so that the same output gives byte-identical archives")
+	maxFiles := flag.Int("max-files", 0, "Stop after converting or copying this many pages, e.g. to try out rule changes quickly (0 converts all)")
+	sample := flag.Int("sample", 0, "Convert only this many pages of the zip, picked at random with -sample-seed (0 converts all)")
+	sampleSeed := flag.Int64("sample-seed", 1, "Seed of the random pick of -sample; the same seed picks the same pages")
+	frontmatterSchemaPath := flag.String("frontmatter-schema", "", "JSON Schema, in YAML or JSON, of the frontmatter of converted pages, e.g. the keys and types the docs site requires; each page whose frontmatter breaks it is warned about")
+	relativizeAssets := flag.Bool("relativize-assets", false, "Without -assets-layout, point references to images in the zip at their zip paths relative to each page's output path, for images deployed at those paths beside the output, so that links survive -path-template and -rename-map moving pages")
+	rewriteImageExtensions := flag.String("rewrite-image-extensions", "", "Comma-separated from=to image extensions, such as .png=.webp, swapped in links to internal images whose converted copies are made in a separate step")
+	warnLongPages := flag.Bool("warn-on-long-pages", false, "Warn about converted pages over -long-page-bytes or -long-page-words, which load slowly and are hard to navigate, and list them at the end")
+	longPageBytes := flag.Int("long-page-bytes", 100000, "Size of the written markdown over which -warn-on-long-pages warns about a page (0 disables the limit)")
+	longPageWords := flag.Int("long-page-words", 10000, "Words of prose over which -warn-on-long-pages warns about a page (0 disables the limit)")
+	copyDownloads := flag.Bool("copy-downloads", false, "Copy the targets of download links, <a download>, from the zip into the -assets-layout directory, or -assets-dir without a layout, and link to the copies")
+	htmlParser := flag.String("html-parser", parserLenient, "How to treat broken HTML: \"lenient\" repairs it silently, \"strict\" also warns about each repair, such as an unclosed tag, with its source line, and lists them at the end")
+	emitSitemap := flag.Bool("emit-sitemap", false, "Write sitemap.xml at the output root, listing the URL of each converted page under -site-base-url, with its zip modification time as lastmod under -preserve-mtime")
+	siteBaseURL := flag.String("site-base-url", "", "Absolute URL the converted pages are served under, e.g. \"https://bazel.build\", for the -emit-sitemap locations")
+	collectRefs := flag.Bool("collect-refs", false, "Write the links of each page as reference links to numbered definitions at its end, one per distinct URL, so that link-heavy pages read without the URLs")
+	normalizeListMarkers := flag.Bool("normalize-list-markers", false, "Rewrite the list markers of each page, outside code, as -list-bullet and -list-numbering")
+	listBullet := flag.String("list-bullet", "-", "Marker of bulleted list items with -normalize-list-markers: -, * or +")
+	listNumbering := flag.String("list-numbering", numberingSequential, "Numbering of ordered lists with -normalize-list-markers: \"sequential\" (1. 2. 3.) or \"one\" (every item numbered like the first, 1. 1. 1.)")
+	templateAsCode := flag.Bool("template-as-code", false, "Write <template> elements, which are dropped by default like other markup browsers do not show, as html code blocks of their markup, for pages that keep examples in them")
+	anchorPrefix := flag.String("anchor-prefix", "", "Prefix every anchor of a page, and links to it, with this, where {page} is the slug of the page's output path, e.g. \"{page}-\", so that pages combined or embedded into one keep distinct anchors")
+	manifestHashes := flag.Bool("manifest-include-hashes", false, "Record the SHA-256 digest of each written file in -output-manifest, for content-addressed deploys and telling which files changed")
+	fileMode := flag.String("file-mode", "", "Octal permissions of written files, e.g. 0664, set exactly whatever the umask (default 0644 less the umask)")
+	dirMode := flag.String("dir-mode", "", "Octal permissions of created directories, e.g. 0775, set exactly whatever the umask (default 0755 less the umask)")
+	sourceRef := flag.String("source-ref", "", "Point links to the master or main branch of the -source-ref-repos on GitHub at this tag, branch or commit instead, e.g. \"7.4.0\", so that they show the source of the documented release")
+	sourceRefRepos := flag.String("source-ref-repos", defaultSourceRefRepos, "Comma-separated owner/repo GitHub repositories whose links -source-ref pins")
+	strayListContent := flag.String("stray-list-content", strayAttach, "Where text and elements placed directly in a list, between its items, go: \"attach\" to the item before them, or \"lift\" out of the list, which continues after them")
+	preserveSectionIDs := flag.Bool("preserve-section-ids-as-anchors", false, "Keep the ids of <section> elements that no heading carries as explicit <a id> anchors at the section start")
+	emoji := flag.String("emoji", emojiOff, "Write the emoji of page text one way: \"unicode\" turns :shortcode: emoji into unicode emoji, \"shortcode\" the other way round, \"off\" leaves them; code is left alone")
+	includeSourceComment := flag.Bool("include-source-comment", false, "Add an MDX comment with the path in the zip, such as {/* source: reference/be/general.html */}, after the frontmatter of every converted page")
+	splitOnHeading := flag.String("split-on-heading", "", "Split each page -split-pages matches into a file per section at this heading level, e.g. h2, named after the heading's anchor, and leave an index of the sections on the page; links to its anchors follow them")
+	splitPages := flag.String("split-pages", "", "Comma-separated globs, such as \"reference/be/*.html\", matching the zip paths of the pages -split-on-heading splits")
+	trimTrailingEmptySections := flag.Bool("trim-trailing-empty-sections", false, "Remove the headings that end a page with no content after them, such as a See also section whose links were all page chrome")
+	outputEncoding := flag.String("output-encoding", outputUTF8, "Encoding of converted pages: \"utf8\", or \"ascii\" for pipelines that choke on anything else, which writes non-ASCII characters outside code as numeric HTML entities, and in frontmatter as YAML escapes")
+	normalizeLinkExtensions := flag.Bool("normalize-internal-link-extensions", false, "Drop the .md or .mdx extension from rewritten internal links, for sites such as Mintlify that serve pages at extensionless URLs")
+	dropVisuallyHidden := flag.Bool("drop-visually-hidden", false, "Drop screen-reader-only text, such as .sr-only and .visually-hidden elements, which usually duplicates visible content")
+	visuallyHiddenKeep := flag.String("visually-hidden-keep", "", "CSS selector of screen-reader-only elements -drop-visually-hidden keeps, e.g. where the hidden text is the only label of a link or button")
+	maxOutputDepth := flag.Int("max-output-depth", 0, "Flatten components, such as tabs, accordions and callouts, nested deeper than this to plain markdown, with a warning (0 allows any depth)")
+	lint := flag.Bool("lint", false, "Check the markdown already in -output against the docs conventions instead of converting")
+	lintMaxImageKB := flag.Int64("lint-max-image-kb", 1024, "Largest local image, in KiB, that -lint accepts (0 disables the check)")
+	printSchema := flag.Bool("print-config-schema", false, "Print the JSON Schema of the -config file and exit")
 	flag.Parse()
 
-	if *zipPath == "" {
-		fmt.Println("Error: -zip flag is required")
-		flag.Usage()
-		os.Exit(1)
-	}
+	if *printSchema {
+		if err := printConfigSchema(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	anchors, ok := anchorStrategies[*anchorStyle]
+	if !ok {
+		fmt.Println("Error: -anchor-style must be mintlify, github, or docusaurus")
+		os.Exit(1)
+	}
+
+	components, ok := componentStyles[*componentStyle]
+	if !ok {
+		fmt.Println("Error: -component-style must be mintlify or docusaurus")
+		os.Exit(1)
+	}
+
+	if *lint {
+		ok, err := runLint(*outputDir, *lintMaxImageKB*1024, anchors)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *zipPath == "" {
+		fmt.Println("Error: -zip flag is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *maxHeadingDepth < 0 || *maxHeadingDepth > 6 {
+		fmt.Println("Error: -max-heading-depth must be between 0 and 6")
+		os.Exit(1)
+	}
+
+	if *assetsLayout != "" && *assetsLayout != assetsColocated && *assetsLayout != assetsCentral {
+		fmt.Printf("Error: -assets-layout must be %q or %q\n", assetsColocated, assetsCentral)
+		os.Exit(1)
+	}
+
+	if *outputCase != casePreserve && *outputCase != caseLower {
+		fmt.Printf("Error: -output-case must be %q or %q\n", casePreserve, caseLower)
+		os.Exit(1)
+	}
+
+	if *math != mathOff && *math != mathKaTeX {
+		fmt.Printf("Error: -math must be %q or %q\n", mathKaTeX, mathOff)
+		os.Exit(1)
+	}
+
+	if *trailingSlash != slashAdd && *trailingSlash != slashStrip && *trailingSlash != slashKeep {
+		fmt.Printf("Error: -trailing-slash must be %q, %q, or %q\n", slashAdd, slashStrip, slashKeep)
+		os.Exit(1)
+	}
+
+	ruleSets, transforms, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *autoAlignNumbers {
+		alignNumberColumns(ruleSets)
+	}
+
+	if *inputEncoding != "" {
+		if _, err := htmlindex.Get(*inputEncoding); err != nil {
+			fmt.Printf("Error: unsupported -input-encoding %q\n", *inputEncoding)
+			os.Exit(1)
+		}
+	}
+
+	if *devsiteConditions != conditionsTrue && *devsiteConditions != conditionsFalse {
+		fmt.Printf("Error: -devsite-conditions must be %q or %q\n", conditionsTrue, conditionsFalse)
+		os.Exit(1)
+	}
+
+	if *dynamicBranch != dynamicPublic && *dynamicBranch != dynamicAll {
+		fmt.Printf("Error: -dynamic-branch must be %q or %q\n", dynamicPublic, dynamicAll)
+		os.Exit(1)
+	}
+
+	strict := make(map[string]bool)
+	for name, enabled := range strictCategories {
+		strict[name] = *strictAll || *enabled
+	}
+
+	if *bannerStyle != bannerComment && *bannerStyle != bannerNote {
+		fmt.Printf("Error: -banner-style must be %q or %q\n", bannerComment, bannerNote)
+		os.Exit(1)
+	}
+
+	if *pageNavSelector != "" {
+		if _, err := cascadia.ParseGroup(*pageNavSelector); err != nil {
+			fmt.Printf("Error: invalid -page-nav-selector: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	readingTimeWPM := 0
+	if *readingTime {
+		if *noFrontmatter {
+			fmt.Println("Error: -reading-time needs frontmatter and cannot be combined with -no-frontmatter")
+			os.Exit(1)
+		}
+		if *readingWPM <= 0 {
+			fmt.Println("Error: -reading-wpm must be positive")
+			os.Exit(1)
+		}
+		readingTimeWPM = *readingWPM
+	}
+
+	if *postHookTimeout <= 0 {
+		fmt.Println("Error: -post-hook-timeout must be positive")
+		os.Exit(1)
+	}
+
+	if *quoteStyle != quotesCurly && *quoteStyle != quotesStraight {
+		fmt.Printf("Error: -quote-style must be %q or %q\n", quotesCurly, quotesStraight)
+		os.Exit(1)
+	}
+
+	if *wrap < 0 {
+		fmt.Println("Error: -wrap must not be negative")
+		os.Exit(1)
+	}
+
+	if *varStyle != varItalic && *varStyle != varCode && *varStyle != varPlaceholder {
+		fmt.Printf("Error: -var-style must be %q, %q, or %q\n", varItalic, varCode, varPlaceholder)
+		os.Exit(1)
+	}
+
+	pathTemplate, err := newPathTemplate(*pathTemplateText, *pathPattern)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	apiPages, err := newAPIPages(*apiJSONTemplate, *apiJSONPattern)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	split, err := newPageSplit(*splitOnHeading, *splitPages)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if split != nil && *collectRefs {
+		fmt.Println("Error: -split-on-heading cannot be combined with -collect-refs, whose definitions end the page")
+		os.Exit(1)
+	}
+
+	rawMarker := ""
+	if *rawFrontmatter {
+		if *noFrontmatter {
+			fmt.Println("Error: -preserve-raw-frontmatter cannot be combined with -no-frontmatter")
+			os.Exit(1)
+		}
+		if rawMarker = strings.TrimSpace(*rawFrontmatterMarker); rawMarker == "" {
+			fmt.Println("Error: -raw-frontmatter-marker must not be empty")
+			os.Exit(1)
+		}
+	}
+
+	if *linkBase != "" && !strings.HasPrefix(*linkBase, "/") && !isExternalURL(*linkBase) {
+		fmt.Println("Error: -link-base must be an absolute path or URL")
+		os.Exit(1)
+	}
+
+	emptyLinks := ""
+	if *stripEmptyLinks {
+		if *emptyLinkMode != emptyLinksDrop && *emptyLinkMode != emptyLinksURL {
+			fmt.Printf("Error: -empty-link-mode must be %q or %q\n", emptyLinksDrop, emptyLinksURL)
+			os.Exit(1)
+		}
+		emptyLinks = *emptyLinkMode
+	}
+
+	glossary, err := loadGlossary(*glossaryPath, anchors)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if glossary != nil {
+		glossary.anchorPrefix = pageAnchorPrefix(*anchorPrefix, glossary.Page)
+	}
+
+	if *fileTimeout < 0 || *totalTimeout < 0 {
+		fmt.Println("Error: -file-timeout and -total-timeout must not be negative")
+		os.Exit(1)
+	}
+
+	if *paramFields && *componentStyle != "mintlify" {
+		fmt.Println("Error: -param-fields writes Mintlify components and needs -component-style mintlify")
+		os.Exit(1)
+	}
+
+	if *manifestFormat != manifestJSON && *manifestFormat != manifestCSV {
+		fmt.Printf("Error: -manifest-format must be %q or %q\n", manifestJSON, manifestCSV)
+		os.Exit(1)
+	}
+
+	footerSelectorValue := ""
+	if *footerFrontmatter {
+		if *noFrontmatter {
+			fmt.Println("Error: -footer-frontmatter cannot be combined with -no-frontmatter")
+			os.Exit(1)
+		}
+		if _, err := cascadia.ParseGroup(*footerSelector); err != nil {
+			fmt.Printf("Error: invalid -footer-selector: %v\n", err)
+			os.Exit(1)
+		}
+		if *footerDateKey == "" {
+			fmt.Println("Error: -footer-date-key cannot be empty")
+			os.Exit(1)
+		}
+		footerSelectorValue = *footerSelector
+	}
+
+	if *checkThreshold < 0 || *checkThreshold > 1 {
+		fmt.Println("Error: -check-threshold must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	prevAnchors, err := loadAnchorMap(*prevAnchorsPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *maxInputMB <= 0 {
+		fmt.Println("Error: -max-input-mb must be positive")
+		os.Exit(1)
+	}
+
+	if *insStyle != insUnderline && *insStyle != insTag && *insStyle != insPlain {
+		fmt.Printf("Error: -ins-style must be %q, %q, or %q\n", insUnderline, insTag, insPlain)
+		os.Exit(1)
+	}
+
+	if *assetsMaxBytes < 0 || *assetsMaxWidth < 0 {
+		fmt.Println("Error: -assets-max-bytes and -assets-max-width must not be negative")
+		os.Exit(1)
+	}
+
+	if *keepHeadingNumbers && !*normalizeHeadingSpace {
+		fmt.Println("Error: -keep-heading-numbers requires -normalize-heading-whitespace")
+		os.Exit(1)
+	}
+
+	if *embedMode != embedIframe && *embedMode != embedLink {
+		fmt.Printf("Error: -embed-mode must be %q or %q\n", embedIframe, embedLink)
+		os.Exit(1)
+	}
+
+	if *sourceLinkStyle != sourceLinkFrontmatter && *sourceLinkStyle != sourceLinkFooter {
+		fmt.Printf("Error: -source-link-style must be %q or %q\n", sourceLinkFrontmatter, sourceLinkFooter)
+		os.Exit(1)
+	}
+	if *sourceLink != "" && *sourceLinkStyle == sourceLinkFrontmatter && *noFrontmatter {
+		fmt.Println("Error: -source-link-style frontmatter cannot be combined with -no-frontmatter")
+		os.Exit(1)
+	}
+
+	if *protocolRelativeScheme != "https" && *protocolRelativeScheme != "http" {
+		fmt.Println("Error: -protocol-relative-scheme must be \"https\" or \"http\"")
+		os.Exit(1)
+	}
+
+	if *hgroupSubtitleMode != hgroupDescription && *hgroupSubtitleMode != hgroupSubtitle && *hgroupSubtitleMode != hgroupLine {
+		fmt.Printf("Error: -hgroup-subtitle must be %q, %q, or %q\n", hgroupDescription, hgroupSubtitle, hgroupLine)
+		os.Exit(1)
+	}
+
+	if *gaugeStyle != gaugePercent && *gaugeStyle != gaugeFraction && *gaugeStyle != gaugeBar {
+		fmt.Printf("Error: -gauge-style must be %q, %q, or %q\n", gaugePercent, gaugeFraction, gaugeBar)
+		os.Exit(1)
+	}
+
+	var autogeneratedIDs *regexp.Regexp
+	if *stripAutogeneratedIDs {
+		re, err := regexp.Compile(*autogeneratedIDPattern)
+		if err != nil {
+			fmt.Printf("Error: invalid -autogenerated-id-pattern: %v\n", err)
+			os.Exit(1)
+		}
+		autogeneratedIDs = re
+	}
+
+	var counts *ruleCounts
+	if *rulesDryRun {
+		counts = &ruleCounts{counts: make(map[string]int)}
+	}
+
+	if *outputArchive != "" && archiveFormat(*outputArchive) == "" {
+		fmt.Println("Error: -output-archive must end in .tar, .tar.gz or .tgz")
+		os.Exit(1)
+	}
+	if *reproducible && *outputArchive == "" {
+		fmt.Println("Error: -reproducible needs an -output-archive")
+		os.Exit(1)
+	}
+
+	if *maxFiles < 0 || *sample < 0 {
+		fmt.Println("Error: -max-files and -sample must not be negative")
+		os.Exit(1)
+	}
+
+	frontmatterSchema, err := loadFrontmatterSchema(*frontmatterSchemaPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if frontmatterSchema != nil && *noFrontmatter {
+		fmt.Println("Error: -frontmatter-schema cannot be combined with -no-frontmatter")
+		os.Exit(1)
+	}
+
+	if *relativizeAssets && *assetsLayout != "" {
+		fmt.Println("Error: -relativize-assets cannot be combined with -assets-layout, whose links are relative already")
+		os.Exit(1)
+	}
+
+	imageExtensions, err := parseImageExtensions(*rewriteImageExtensions)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *longPageBytes < 0 || *longPageWords < 0 {
+		fmt.Println("Error: -long-page-bytes and -long-page-words must not be negative")
+		os.Exit(1)
+	}
+
+	if *htmlParser != parserLenient && *htmlParser != parserStrict {
+		fmt.Printf("Error: -html-parser must be %q or %q\n", parserLenient, parserStrict)
+		os.Exit(1)
+	}
+
+	if *emitSitemap != (*siteBaseURL != "") {
+		fmt.Println("Error: -emit-sitemap and -site-base-url must be given together")
+		os.Exit(1)
+	}
+	if *siteBaseURL != "" && !validSiteBaseURL(*siteBaseURL) {
+		fmt.Println("Error: -site-base-url must be an absolute http or https URL, such as https://bazel.build")
+		os.Exit(1)
+	}
+
+	if *listBullet != "-" && *listBullet != "*" && *listBullet != "+" {
+		fmt.Println("Error: -list-bullet must be -, * or +")
+		os.Exit(1)
+	}
+	if *listNumbering != numberingSequential && *listNumbering != numberingOne {
+		fmt.Printf("Error: -list-numbering must be %q or %q\n", numberingSequential, numberingOne)
+		os.Exit(1)
+	}
+
+	if *manifestHashes && *manifestPath == "" {
+		fmt.Println("Error: -manifest-include-hashes needs an -output-manifest")
+		os.Exit(1)
+	}
+
+	for _, m := range []struct {
+		name, value string
+		mode        *os.FileMode
+	}{{"file-mode", *fileMode, &outputFileMode}, {"dir-mode", *dirMode, &outputDirMode}} {
+		if m.value == "" {
+			continue
+		}
+		mode, err := parseMode(m.name, m.value)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		*m.mode, exactModes = mode, true
+	}
+
+	if *sourceRef != "" && (strings.ContainsAny(*sourceRef, "?#") || strings.TrimSpace(*sourceRef) != *sourceRef) {
+		fmt.Println("Error: -source-ref must be a tag, branch or commit, such as 7.4.0")
+		os.Exit(1)
+	}
+
+	if *strayListContent != strayAttach && *strayListContent != strayLift {
+		fmt.Printf("Error: -stray-list-content must be %q or %q\n", strayAttach, strayLift)
+		os.Exit(1)
+	}
+
+	if *emoji != emojiUnicode && *emoji != emojiShortcode && *emoji != emojiOff {
+		fmt.Printf("Error: -emoji must be %q, %q or %q\n", emojiUnicode, emojiShortcode, emojiOff)
+		os.Exit(1)
+	}
+
+	if *syncDryRun && *syncTo == "" {
+		fmt.Println("Error: -sync-dry-run requires -sync-to")
+		os.Exit(1)
+	}
+	if *syncTo != "" && *diffDir != "" {
+		fmt.Println("Error: -sync-to cannot be combined with -diff")
+		os.Exit(1)
+	}
+
+	if *outputEncoding != outputUTF8 && *outputEncoding != outputASCII {
+		fmt.Printf("Error: -output-encoding must be %q or %q\n", outputUTF8, outputASCII)
+		os.Exit(1)
+	}
+
+	if *visuallyHiddenKeep != "" {
+		if !*dropVisuallyHidden {
+			fmt.Println("Error: -visually-hidden-keep needs -drop-visually-hidden")
+			os.Exit(1)
+		}
+		if _, err := cascadia.ParseGroup(*visuallyHiddenKeep); err != nil {
+			fmt.Printf("Error: invalid -visually-hidden-keep: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *maxOutputDepth < 0 {
+		fmt.Println("Error: -max-output-depth cannot be negative")
+		os.Exit(1)
+	}
+
+	renames, err := loadRenameMap(*renameMapPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := config{
+		maxHeadingDepth:           *maxHeadingDepth,
+		assetsLayout:              *assetsLayout,
+		assetsDir:                 filepath.ToSlash(filepath.Clean(*assetsDir)),
+		assetsMaxBytes:            *assetsMaxBytes,
+		assetsMaxWidth:            *assetsMaxWidth,
+		anchors:                   anchors,
+		renames:                   renames,
+		noFrontmatter:             *noFrontmatter,
+		outputCase:                *outputCase,
+		math:                      *math,
+		preserveHeadingIDs:        *preserveHeadingIDs,
+		trailingSlash:             *trailingSlash,
+		externalNewTab:            *externalNewTab,
+		externalLinkClass:         *externalLinkClass,
+		preserveMtime:             *preserveMtime,
+		dedupPages:                *dedupPages,
+		metricsPath:               *metricsPath,
+		statsJSON:                 *statsJSON,
+		warnDropped:               *warnDroppedAttrs,
+		ruleSets:                  ruleSets,
+		transforms:                transforms,
+		normalizeLinks:            *normalizeLinks,
+		inputEncoding:             *inputEncoding,
+		devsiteConditions:         *devsiteConditions,
+		outIndex:                  *outIndex,
+		strict:                    strict,
+		banner:                    *banner,
+		bannerStyle:               *bannerStyle,
+		bannerCopied:              *bannerCopied,
+		stripQuery:                *stripQuery,
+		stripFragment:             *stripFragment,
+		stripExternal:             *stripExternal,
+		components:                components,
+		pageNavSelector:           *pageNavSelector,
+		preserveHidden:            *preserveHidden,
+		readingTimeWPM:            readingTimeWPM,
+		postHook:                  *postHook,
+		postHookTimeout:           *postHookTimeout,
+		allowedTags:               parseTagList(*allowlistTags),
+		quoteStyle:                *quoteStyle,
+		wrap:                      *wrap,
+		varStyle:                  *varStyle,
+		pathTemplate:              pathTemplate,
+		rawFrontmatterMarker:      rawMarker,
+		linkBase:                  *linkBase,
+		sourcesRoot:               *sourcesRoot,
+		emptyLinks:                emptyLinks,
+		glossary:                  glossary,
+		fileTimeout:               *fileTimeout,
+		totalTimeout:              *totalTimeout,
+		paramFields:               *paramFields,
+		keepCodeTemplates:         *keepGoTemplates,
+		manifestPath:              *manifestPath,
+		manifestFormat:            *manifestFormat,
+		dropSections:              parseSectionList(*dropSectionsList),
+		footerSelector:            footerSelectorValue,
+		footerDateKey:             *footerDateKey,
+		check:                     *check,
+		checkThreshold:            *checkThreshold,
+		prevAnchors:               prevAnchors,
+		outAnchors:                *outAnchors,
+		maxInputBytes:             *maxInputMB << 20,
+		insStyle:                  *insStyle,
+		normalizeHeadings:         *normalizeHeadingSpace,
+		keepHeadingNumbers:        *keepHeadingNumbers,
+		keepImgDimensions:         *keepImgDimensions,
+		embedMode:                 *embedMode,
+		copyExamples:              *copyExamples,
+		dedupTitleHeading:         *dedupTitleHeading,
+		sourceLink:                *sourceLink,
+		sourceLinkStyle:           *sourceLinkStyle,
+		normalizeCodeFences:       *normalizeCodeFences,
+		emitLLMsTxt:               *emitLLMsTxt,
+		protocolRelativeScheme:    *protocolRelativeScheme,
+		dataAttrs:                 dataAttrFilter{strip: *stripDataAttrs, keep: parseTagList(*keepDataAttrs)},
+		hgroupSubtitle:            *hgroupSubtitleMode,
+		rewriteAnchorCase:         *rewriteAnchorCase,
+		gaugeStyle:                *gaugeStyle,
+		failOnRawHTML:             *failOnRawHTML,
+		rawHTMLAllowed:            parseTagList(*rawHTMLAllow),
+		frontmatterOrder:          parseKeyList(*frontmatterOrder),
+		autogeneratedIDs:          autogeneratedIDs,
+		apiPages:                  apiPages,
+		ruleCounts:                counts,
+		outputArchive:             *outputArchive,
+		reproducible:              *reproducible,
+		maxFiles:                  *maxFiles,
+		sample:                    *sample,
+		sampleSeed:                *sampleSeed,
+		frontmatterSchema:         frontmatterSchema,
+		relativizeAssets:          *relativizeAssets,
+		imageExtensions:           imageExtensions,
+		warnLongPages:             *warnLongPages,
+		longPageBytes:             *longPageBytes,
+		longPageWords:             *longPageWords,
+		copyDownloads:             *copyDownloads,
+		htmlParser:                *htmlParser,
+		emitSitemap:               *emitSitemap,
+		siteBaseURL:               *siteBaseURL,
+		collectRefs:               *collectRefs,
+		normalizeListMarkers:      *normalizeListMarkers,
+		listBullet:                *listBullet,
+		listNumbering:             *listNumbering,
+		templateAsCode:            *templateAsCode,
+		anchorPrefix:              *anchorPrefix,
+		manifestHashes:            *manifestHashes,
+		sourceRef:                 *sourceRef,
+		sourceRefRepos:            parseTagList(*sourceRefRepos),
+		strayListContent:          *strayListContent,
+		preserveSectionIDs:        *preserveSectionIDs,
+		emoji:                     *emoji,
+		includeSourceComment:      *includeSourceComment,
+		split:                     split,
+		trimTrailingEmptySections: *trimTrailingEmptySections,
+		outputEncoding:            *outputEncoding,
+		stripLinkExtensions:       *normalizeLinkExtensions,
+		dropVisuallyHidden:        *dropVisuallyHidden,
+		visuallyHiddenKeep:        *visuallyHiddenKeep,
+		maxOutputDepth:            *maxOutputDepth,
+		dynamicBranch:             *dynamicBranch,
+	}
+
+	if *tagReport != "" {
+		if err := runCensus(*zipPath, *tagReport, cfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *diffDir != "" {
+		same, err := runDiff(*zipPath, *diffDir, cfg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			printLinkErrors(err)
+			var strictErr *strictError
+			if errors.As(err, &strictErr) {
+				os.Exit(strictErr.code)
+			}
+			os.Exit(1)
+		}
+		if !same {
+			os.Exit(1)
+		}
+		fmt.Printf("No differences from %s\n", *diffDir)
+		return
+	}
+
+	if *syncTo != "" {
+		if err := runSync(*zipPath, *syncTo, *syncDryRun, cfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			printLinkErrors(err)
+			var strictErr *strictError
+			if errors.As(err, &strictErr) {
+				os.Exit(strictErr.code)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *serveAddr != "" {
+		if err := runPreviewServer(*serveAddr, *zipPath, cfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := convertZipToMarkdown(*zipPath, *outputDir, cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		printLinkErrors(err)
+		var strictErr *strictError
+		if errors.As(err, &strictErr) {
+			os.Exit(strictErr.code)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("Conversion completed successfully!")
+}
+
+// config holds the options that shape how pages are converted.
+type config struct {
+	// Headings deeper than this level become bold paragraphs; 0 disables.
+	maxHeadingDepth int
+
+	// Where referenced images are copied; see assets.go.
+	assetsLayout string
+	assetsDir    string
+	// Limits on copied images; 0 disables. See assetsize.go.
+	assetsMaxBytes int64
+	assetsMaxWidth int
+
+	// Generates heading anchors the way the target renderer does.
+	anchors AnchorStrategy
+
+	// Explicit output paths by source path, from -rename-map.
+	renames map[string]string
+
+	// Keep the title as an H1 rather than in frontmatter.
+	noFrontmatter bool
+
+	// Case applied to output paths; see applyOutputCase.
+	outputCase string
+
+	// How math is converted; see math.go.
+	math string
+
+	// Keep source heading ids next to the generated anchors.
+	preserveHeadingIDs bool
+
+	// Trailing slash policy for rewritten links; see links.go.
+	trailingSlash string
+
+	// Open external links in a new tab, optionally with a class.
+	externalNewTab    bool
+	externalLinkClass string
+
+	// Copy zip entry modification times onto written files.
+	preserveMtime bool
+
+	// Replace pages whose output duplicates an earlier page with redirects.
+	dedupPages bool
+
+	// Prometheus text format metrics file, if any.
+	metricsPath string
+	// JSON run summary file, if any.
+	statsJSON string
+
+	// Report class/style attributes that the markdown output cannot carry.
+	warnDropped bool
+
+	// Conversion rule sets in use, from -config; see registry.go.
+	ruleSets []enabledRuleSet
+	// HTML transforms applied before conversion, from -config; see
+	// transforms.go.
+	transforms []htmlTransform
+
+	// Clean up ./ and ../ in internal links.
+	normalizeLinks bool
+
+	// Forced input encoding; empty detects it per page.
+	inputEncoding string
+
+	// Which branches of Devsite conditionals to keep; see devsite.go.
+	devsiteConditions string
+	dynamicBranch     string
+
+	// JSONL search index file, if any; see searchindex.go.
+	outIndex string
+
+	// Warning categories that fail the run; see strict.go.
+	strict map[string]bool
+
+	// Notice added to generated pages; see banner.go.
+	banner       string
+	bannerStyle  string
+	bannerCopied bool
+
+	// Drop query strings and fragments from links; see links.go.
+	stripQuery    bool
+	stripFragment bool
+	stripExternal bool
+
+	// Writes callouts and accordions; see components.go.
+	components componentStyle
+
+	// Embedded page navigation to remove; see pagenav.go.
+	pageNavSelector string
+
+	// Keep hidden and aria-hidden elements.
+	preserveHidden bool
+
+	// Reading speed for the reading_time frontmatter; 0 leaves it out.
+	readingTimeWPM int
+
+	// Command run on each written page; see hook.go.
+	postHook        string
+	postHookTimeout time.Duration
+
+	// Tags kept by -allowlist-tags; nil keeps all. See allowlist.go.
+	allowedTags map[string]bool
+
+	// Quotation marks for <q>; see quoteRule.
+	quoteStyle string
+
+	// Column to wrap prose at; 0 disables. See wrap.go.
+	wrap int
+
+	// Form of <var> placeholders; see inline.go.
+	varStyle string
+
+	// Computes output paths of pages; nil keeps the defaults. See pathtemplate.go.
+	pathTemplate *pathTemplate
+
+	// Marker lines of embedded frontmatter to promote; empty disables.
+	rawFrontmatterMarker string
+
+	// Base of absolute internal links; empty keeps them relative.
+	linkBase string
+
+	// Directory of zips searched for missing images; see sources.go.
+	sourcesRoot string
+
+	// Mode of -strip-empty-links; empty keeps links without text.
+	emptyLinks string
+
+	// Terms linked to the glossary page; see glossary.go.
+	glossary *glossary
+
+	// Limits on converting a page and the whole zip; 0 disables them.
+	fileTimeout  time.Duration
+	totalTimeout time.Duration
+
+	// Write attribute definition lists as <ParamField>s.
+	paramFields bool
+
+	// Leave template syntax in code to the page; see protectCodeTemplates.
+	keepCodeTemplates bool
+
+	// Manifest of the written files, if any; see manifest.go.
+	manifestPath   string
+	manifestFormat string
+
+	// Heading texts of sections to remove; see sections.go.
+	dropSections map[string]bool
+
+	// Footers whose date, under footerDateKey, and authors become
+	// frontmatter; empty leaves them. See footer.go.
+	footerSelector string
+	footerDateKey  string
+
+	// Compare the text of the pages before and after conversion; see
+	// roundtrip.go.
+	check          bool
+	checkThreshold float64
+
+	// Anchors of the previous build to keep as aliases, and the file to
+	// write this build's anchors to; see anchormap.go.
+	prevAnchors anchorMap
+	outAnchors  string
 
-	if err := convertZipToMarkdown(*zipPath, *outputDir); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+	// Limit on input read into memory; see input.go.
+	maxInputBytes int64
+
+	// Form of <ins> insertions; see changes.go.
+	insStyle string
+
+	// Cleans up heading text and section numbers; see headings.go.
+	normalizeHeadings  bool
+	keepHeadingNumbers bool
+
+	// Keeps image widths and heights; see pictures.go.
+	keepImgDimensions bool
+
+	// How frameboxes are written and whether their examples are copied; see
+	// framebox.go.
+	embedMode    string
+	copyExamples bool
+
+	// Drops a leading body heading repeating the title; see frontmatter.go.
+	dedupTitleHeading bool
+
+	// Links each page to its source; see banner.go.
+	sourceLink      string
+	sourceLinkStyle string
+
+	// Rewrites code fences to the shortest safe ones; see code.go.
+	normalizeCodeFences bool
+
+	// Writes llms.txt after the run; see llmstxt.go.
+	emitLLMsTxt bool
+
+	// Scheme of protocol-relative URLs; see links.go.
+	protocolRelativeScheme string
+
+	// data-* attributes of HTML passed through; see attrs.go.
+	dataAttrs dataAttrFilter
+
+	// Where the title's <hgroup> subtitle goes; see frontmatter.go.
+	hgroupSubtitle string
+
+	// Lowercases anchors and link fragments; see anchors.go.
+	rewriteAnchorCase bool
+
+	// Form of <progress> and <meter>; see gauges.go.
+	gaugeStyle string
+
+	// Fails on HTML tags left in the markdown; see strict.go.
+	failOnRawHTML  bool
+	rawHTMLAllowed map[string]bool
+
+	// Order of frontmatter keys; nil keeps them as added. See frontmatter.go.
+	frontmatterOrder []string
+
+	// Ids dropped as generated; nil keeps every id. See anchors.go.
+	autogeneratedIDs *regexp.Regexp
+
+	// Renders JSON API dumps into pages; nil skips them. See apipages.go.
+	apiPages *apiPages
+
+	// Counts the elements rules convert, without writing pages; nil when
+	// off. See ruletrace.go.
+	ruleCounts *ruleCounts
+
+	// Archive of the output directory; empty writes none. See archive.go.
+	outputArchive string
+	reproducible  bool
+
+	// Limit the pages processed, for quick iteration; 0 processes all. See
+	// sample.go.
+	maxFiles   int
+	sample     int
+	sampleSeed int64
+
+	// Schema frontmatter is checked against; nil checks nothing. See
+	// frontmatterschema.go.
+	frontmatterSchema *jsonSchema
+
+	// Relativizes references to uncopied images; see assets.go.
+	relativizeAssets bool
+
+	// New extensions of links to internal images; see assets.go.
+	imageExtensions map[string]string
+
+	// Warns about pages over a size; 0 skips a limit. See pagesize.go.
+	warnLongPages bool
+	longPageBytes int
+	longPageWords int
+
+	// Copies the targets of download links; see assets.go.
+	copyDownloads bool
+
+	// Warns about repaired HTML when parserStrict; see htmlparse.go.
+	htmlParser string
+
+	// Writes sitemap.xml after the run; see sitemap.go.
+	emitSitemap bool
+	siteBaseURL string
+
+	// Moves link URLs to definitions at the page end; see refs.go.
+	collectRefs bool
+
+	// Rewrites list markers; see lists.go.
+	normalizeListMarkers bool
+	listBullet           string
+	listNumbering        string
+
+	// Writes <template> markup as code; see templateRule.
+	templateAsCode bool
+
+	// Namespaces the anchors of each page; see prefixAnchors.
+	anchorPrefix string
+
+	// Hashes the files listed in the manifest; see manifest.go.
+	manifestHashes bool
+
+	// Pins links to GitHub sources; see pinSourceLinks.
+	sourceRef      string
+	sourceRefRepos map[string]bool
+
+	// Where content between list items goes; see moveStrayListContent.
+	strayListContent string
+
+	// Keep ids of sections as anchors; see markSectionAnchors.
+	preserveSectionIDs bool
+
+	// How emoji in page text are written; see emoji.go.
+	emoji string
+
+	// Add the source path of pages as an MDX comment; see sourceComment.
+	includeSourceComment bool
+
+	// Pages split into a file per section; see split.go.
+	split *pageSplit
+
+	// Remove empty sections at the end of pages; see sections.go.
+	trimTrailingEmptySections bool
+
+	// Encoding of converted pages; see escapeNonASCII.
+	outputEncoding string
+
+	// Link to pages at extensionless URLs; see pageLink.
+	stripLinkExtensions bool
+
+	// Drop screen-reader-only elements, but those matching
+	// visuallyHiddenKeep; see removeVisuallyHidden.
+	dropVisuallyHidden bool
+	visuallyHiddenKeep string
+
+	// Deepest component nesting written; see nesting.go.
+	maxOutputDepth int
+}
+
+// conversion carries the state shared by every file of a single run.
+type conversion struct {
+	cfg       config
+	outputDir string
+	converter *md.Converter
+	assets    *assetStore
+	stats     *stats
+
+	// files indexes the zip entries by name
+	files map[string]*zip.File
+
+	// dropped counts dropped attributes across the run, keyed like
+	// "span.external" or "div[style:color]"
+	dropped map[string]int
+
+	// pageHashes maps the hash of written page content to its output path,
+	// for -dedup-pages
+	pageHashes map[string]string
+	// targets caches what links need to know about the pages they point
+	// to, by zip entry name; see linkTarget
+	targets map[string]*linkTarget
+	// redirects are written to redirects.json at the end of the run
+	redirects []redirect
+	// index collects the -out-index entries of the converted pages
+	index []indexEntry
+	// llms collects the converted pages for -emit-llms-txt
+	llms []llmsEntry
+	// sitemap collects the converted pages for -emit-sitemap
+	sitemap []sitemapEntry
+	// rawHTML lists the pages with each raw HTML tag, for
+	// -fail-on-raw-html; nil when the check is off
+	rawHTML map[string][]string
+	// longPages are the pages -warn-on-long-pages warned about
+	longPages []longPage
+	// parseAnomalies are the pages -html-parser=strict warned about
+	parseAnomalies []pageAnomalies
+	// anchors collects the heading anchors of the converted pages, for
+	// -out-anchors; nil when no anchor map is written
+	anchors anchorMap
+	// manifest collects the files written, for -output-manifest; nil when
+	// no manifest is written
+	manifest *manifest
+	// written maps each output path to the zip entry written there
+	written map[string]string
+	// brokenLinks are the links to pages missing from the zip
+	brokenLinks []*linkError
+}
+
+func convertZipToMarkdown(zipPath, outputDir string, cfg config) error {
+	r, closeInput, err := openInput(zipPath, cfg.maxInputBytes)
+	if err != nil {
+		return err
 	}
+	defer closeInput()
 
-	fmt.Println("Conversion completed successfully!")
+	return convertZip(r, outputDir, cfg)
 }
 
-func convertZipToMarkdown(zipPath, outputDir string) error {
-	// Open the zip file
-	r, err := zip.OpenReader(zipPath)
+// convertZip converts an already opened zip, e.g. one read from memory with
+// zip.NewReader.
+func convertZip(r *zip.Reader, outputDir string, cfg config) error {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	sources, err := openSources(cfg.sourcesRoot)
 	if err != nil {
-		return fmt.Errorf("failed to open zip file: %w", err)
+		return err
+	}
+	defer closeSources(sources)
+
+	var m *manifest
+	if cfg.manifestPath != "" {
+		m = &manifest{}
+		if cfg.manifestHashes {
+			m.hashDir = outputDir
+		}
+	}
+
+	st := newStats()
+	c := &conversion{
+		cfg:        cfg,
+		outputDir:  outputDir,
+		converter:  newConverter(cfg),
+		assets:     newAssetStore(cfg, outputDir, files, sourceFiles(sources), st, m),
+		stats:      st,
+		files:      files,
+		dropped:    make(map[string]int),
+		pageHashes: make(map[string]string),
+		targets:    make(map[string]*linkTarget),
+		written:    make(map[string]string),
+		manifest:   m,
+	}
+	if cfg.outAnchors != "" {
+		c.anchors = make(anchorMap)
 	}
-	defer r.Close()
+	if cfg.failOnRawHTML {
+		c.rawHTML = make(map[string][]string)
+	}
+
+	warnUnusedRenames(cfg.renames, r.File)
 
-	// Create markdown converter
-	converter := md.NewConverter("", true, nil)
+	ctx := context.Background()
+	if cfg.totalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.totalTimeout)
+		defer cancel()
+	}
 
 	// Process each file in the zip
-	for _, f := range r.File {
-		if err := processZipFile(f, outputDir, converter); err != nil {
-			return fmt.Errorf("failed to process %s: %w", f.Name, err)
+	entries := r.File
+	if cfg.sample > 0 {
+		entries = c.sampleEntries(entries, cfg.sample, cfg.sampleSeed)
+	}
+	timedOut := 0
+	for _, f := range entries {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("-total-timeout of %s ran out before %s", cfg.totalTimeout, f.Name)
+			st.errors++
+			break
+		}
+		if cfg.maxFiles > 0 && st.pagesConverted+st.filesCopied >= cfg.maxFiles && c.isPageEntry(f) {
+			fmt.Printf("Stopping after -max-files %d page(s)\n", cfg.maxFiles)
+			break
+		}
+
+		fileCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.fileTimeout > 0 {
+			fileCtx, cancel = context.WithTimeout(ctx, cfg.fileTimeout)
+		}
+		err = c.processZipFile(fileCtx, f)
+		cancel()
+
+		switch {
+		case err == nil:
+		case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+			err = fmt.Errorf("-total-timeout of %s ran out while processing %s", cfg.totalTimeout, f.Name)
+		case errors.Is(err, context.DeadlineExceeded):
+			// A stuck page is skipped so that it does not hold up the rest
+			fmt.Printf("  Error: not converted within -file-timeout of %s, skipped\n", cfg.fileTimeout)
+			timedOut++
+			st.errors++
+			err = nil
+			continue
+		default:
+			err = &convertError{File: f.Name, Err: err}
+		}
+		if err != nil {
+			st.errors++
+			break
 		}
 	}
 
-	return nil
+	if err == nil {
+		if cfg.warnDropped {
+			printDroppedAttrs("Dropped attributes across all pages:", c.dropped)
+		}
+		err = writeRedirects(outputDir, c.redirects, cfg.trailingSlash)
+	}
+	if err == nil && cfg.outIndex != "" {
+		err = writeSearchIndex(cfg.outIndex, c.index)
+	}
+	if err == nil && cfg.emitLLMsTxt {
+		err = writeLLMsTxt(outputDir, c.llms)
+	}
+	if err == nil && cfg.emitSitemap {
+		err = writeSitemap(outputDir, cfg.siteBaseURL, cfg.trailingSlash, c.sitemap)
+	}
+	if err == nil && c.anchors != nil {
+		err = writeAnchorMap(cfg.outAnchors, c.anchors)
+	}
+	if err == nil && m != nil {
+		err = m.write(cfg.manifestPath, cfg.manifestFormat)
+	}
+	if err == nil && cfg.outputArchive != "" {
+		err = writeOutputArchive(cfg.outputArchive, outputDir, cfg.reproducible)
+	}
+
+	st.printSummary()
+	printLongPages(c.longPages)
+	printParseAnomalies(c.parseAnomalies)
+	if err == nil {
+		err = rawHTMLError(c.rawHTML)
+	}
+	if err == nil && timedOut > 0 {
+		err = fmt.Errorf("%d page(s) were not converted within -file-timeout", timedOut)
+	}
+	if err == nil {
+		err = checkStrict(st, cfg.strict, c.brokenLinks)
+	}
+
+	// Metrics are written for failed runs too, so that failures are visible
+	if cfg.metricsPath != "" {
+		if metricsErr := st.writeMetrics(cfg.metricsPath); metricsErr != nil && err == nil {
+			err = metricsErr
+		}
+	}
+	if cfg.statsJSON != "" {
+		if statsErr := st.writeJSON(cfg.statsJSON, cfg.strict, err); statsErr != nil && err == nil {
+			err = statsErr
+		}
+	}
+	return err
+}
+
+func spillToTempFile(r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp("", "html2md-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
 }
 
-func processZipFile(f *zip.File, outputDir string, converter *md.Converter) error {
+func (c *conversion) processZipFile(ctx context.Context, f *zip.File) error {
 	// Skip directories
 	if f.FileInfo().IsDir() {
 		return nil
@@ -60,51 +1333,443 @@ func processZipFile(f *zip.File, outputDir string, converter *md.Converter) erro
 
 	// Handle markdown files - copy them as-is
 	if isMarkdownFile(f.Name) {
-		return copyMarkdownFile(f, outputDir)
+		outputPath := c.outputPathFor(f.Name)
+		c.claimOutput(outputPath, f.Name)
+		banner := ""
+		if c.cfg.bannerCopied {
+			banner = pageBanner(c.cfg, f.Name)
+		}
+		if err := copyMarkdownFile(f, c.outputDir, outputPath, banner, c.cfg.preserveMtime); err != nil {
+			return err
+		}
+		c.stats.filesCopied++
+		c.stats.bytesRead += int64(f.UncompressedSize64)
+		c.stats.bytesWritten += int64(f.UncompressedSize64)
+		c.manifest.add(manifestRecord{Source: f.Name, Output: outputPath, Bytes: int64(f.UncompressedSize64), Kind: recordMarkdown})
+		return nil
+	}
+
+	if c.cfg.apiPages.matches(f.Name) {
+		return c.processAPIJSON(f)
 	}
 
 	// Only process HTML files
 	if !isHTMLFile(f.Name) {
 		fmt.Printf("Skipping file: %s\n", f.Name)
+		c.stats.filesSkipped++
 		return nil
 	}
 
 	fmt.Printf("Processing: %s\n", f.Name)
 
+	html, err := c.readPage(f, false)
+	if err != nil {
+		return err
+	}
+
+	doc, err := withDeadline(ctx, func() (*goquery.Document, error) {
+		return goquery.NewDocumentFromReader(strings.NewReader(html))
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// Create output path (replace .html with .md)
+	pagePath := c.outputPathFor(f.Name)
+
+	page := c.preparePage(doc, f.Name, false)
+
+	var footer frontmatter
+	if c.cfg.footerSelector != "" {
+		if footer, err = extractFooterMetadata(doc, c.cfg.footerSelector, c.cfg.footerDateKey); err != nil {
+			return err
+		}
+	}
+
+	moveStrayListContent(doc, c.cfg.strayListContent)
+	nestMisplacedLists(doc)
+	if !c.cfg.keepImgDimensions {
+		dropImageDimensions(doc)
+	}
+
+	absolutizeProtocolRelative(doc, c.cfg.protocolRelativeScheme)
+	// Copy referenced images and point their src at the copies
+	if err := c.assets.rewriteImages(doc, f.Name, pagePath); err != nil {
+		return err
+	}
+	if c.cfg.copyDownloads {
+		if err := c.assets.copyDownloads(doc, f.Name, pagePath); err != nil {
+			return err
+		}
+	}
+	if err := c.rewriteFrameboxes(doc, f.Name, pagePath); err != nil {
+		return err
+	}
+
+	if c.cfg.warnDropped {
+		dropped := collectDroppedAttrs(doc)
+		printDroppedAttrs("  Dropped attributes:", dropped)
+		for key, n := range dropped {
+			c.dropped[key] += n
+		}
+	}
+
+	if c.cfg.math == mathKaTeX {
+		markTeXDelimiters(doc)
+	}
+
+	if c.cfg.preserveHeadingIDs {
+		markExplicitAnchors(doc, c.cfg)
+	}
+	if c.cfg.preserveSectionIDs {
+		markSectionAnchors(doc, c.cfg)
+	}
+	if c.cfg.prevAnchors != nil || c.anchors != nil {
+		anchors := aliasChangedAnchors(doc, c.cfg, c.cfg.prevAnchors[pagePath])
+		if c.anchors != nil {
+			c.anchors[pagePath] = anchors
+		}
+	}
+
+	assignTermIDs(doc, c.cfg)
+	if c.cfg.paramFields {
+		markParamFields(doc)
+	}
+
+	anchorPrefix := pageAnchorPrefix(c.cfg.anchorPrefix, pagePath)
+	if anchorPrefix != "" {
+		prefixAnchors(doc, c.cfg, anchorPrefix)
+	}
+
+	// Point links to other pages in the zip at their output paths
+	c.rewritePageLinks(doc, f.Name, pagePath)
+	if c.cfg.normalizeLinks {
+		normalizeLinks(doc)
+	}
+	if c.cfg.stripExternal {
+		stripExternalLinks(doc, c.cfg)
+	}
+	if c.cfg.sourceRef != "" {
+		pinSourceLinks(doc, c.cfg.sourceRef, c.cfg.sourceRefRepos)
+	}
+
+	// Point in-page links at the anchors the renderer will generate
+	if anchorPrefix == "" {
+		rewriteFragmentLinks(doc, c.cfg)
+	}
+	if c.cfg.emptyLinks != "" {
+		stripEmptyLinks(doc, c.cfg.emptyLinks)
+	}
+
+	// Link glossary terms, except on the glossary itself
+	if g := c.cfg.glossary; g != nil && g.Page != pagePath {
+		link := pageLink(pagePath, g.Page, c.cfg.trailingSlash, c.cfg.stripLinkExtensions)
+		if c.cfg.linkBase != "" {
+			link = absoluteLink(c.cfg.linkBase, g.Page, c.cfg.trailingSlash)
+		}
+		g.linkTerms(doc, link)
+	}
+
+	var source []string
+	if c.cfg.check {
+		source = sourceWords(doc)
+	}
+
+	// Convert HTML to Markdown
+	content, err := withDeadline(ctx, func() (string, error) {
+		return c.converter.Convert(doc.Selection), nil
+	})
+	if err != nil {
+		return err
+	}
+	content = restoreInterElementSpaces(content)
+	if c.cfg.normalizeCodeFences {
+		content = normalizeCodeFences(content)
+	}
+	if c.cfg.normalizeListMarkers {
+		content = normalizeListMarkers(content, c.cfg.listBullet, c.cfg.listNumbering)
+	}
+	if c.cfg.maxOutputDepth > 0 {
+		content = limitNesting(content, c.cfg.maxOutputDepth)
+	}
+	if c.cfg.trimTrailingEmptySections {
+		content = trimTrailingEmptySections(content)
+	}
+	if c.cfg.collectRefs {
+		content = collectRefs(content)
+	}
+	if c.cfg.wrap > 0 {
+		content = wrapMarkdown(content, c.cfg.wrap)
+	}
+	body := restoreVerbatim(page.prefix + content)
+	page.fm = append(page.fm, footer...)
+	if link := sourceURL(c.cfg, f.Name); link != "" {
+		if c.cfg.sourceLinkStyle == sourceLinkFooter {
+			body = strings.TrimRight(body, "\n") + "\n\n[Edit this page](" + link + ")"
+		} else {
+			page.fm = append(page.fm, frontmatterField{Key: "source_url", Value: link})
+		}
+	}
+	if c.cfg.readingTimeWPM > 0 {
+		page.fm = append(page.fm, readingTime(body, c.cfg.readingTimeWPM)...)
+	}
+	// Embedded frontmatter takes precedence over computed fields
+	page.fm = page.fm.merge(page.embedded)
+	if c.cfg.frontmatterOrder != nil {
+		page.fm = page.fm.sorted(c.cfg.frontmatterOrder)
+	}
+	if c.cfg.frontmatterSchema != nil {
+		c.checkFrontmatter(page.fm)
+	}
+	markdown := restoreVerbatim(page.fm.String()) + sourceComment(c.cfg, f.Name) + pageBanner(c.cfg, f.Name) + body
+
+	if strings.TrimSpace(content) == "" {
+		fmt.Printf("  Warning: page has no content\n")
+		c.stats.emptyPages++
+	}
+	c.checkMDX(markdown)
+	if c.rawHTML != nil {
+		c.checkRawHTML(pagePath, markdown)
+	}
+	if c.cfg.warnLongPages {
+		c.checkPageSize(pagePath, markdown, body)
+	}
+	if c.cfg.check {
+		if err := c.checkRoundTrip(source, body, c.cfg.checkThreshold); err != nil {
+			return err
+		}
+	}
+
+	if c.cfg.ruleCounts != nil {
+		fmt.Printf("  Rules: %s\n", c.cfg.ruleCounts.take())
+		return nil
+	}
+
+	// Redirect pages whose content was already written
+	if c.cfg.dedupPages {
+		sum := sha256.Sum256([]byte(markdown))
+		hash := hex.EncodeToString(sum[:])
+		if canonical, ok := c.pageHashes[hash]; ok {
+			c.redirects = append(c.redirects, newRedirect(pagePath, canonical))
+			c.stats.pagesDeduped++
+			fmt.Printf("  -> Duplicate of %s, redirecting\n", canonical)
+			return nil
+		}
+		c.pageHashes[hash] = pagePath
+	}
+
+	// Each part of a split page is a page of its own to the index,
+	// llms.txt and the sitemap
+	files := c.pageFiles(f.Name, pagePath, page.title, markdown, body)
+	for _, file := range files {
+		if c.cfg.outIndex != "" {
+			c.index = append(c.index, newIndexEntry(file.path, plainVerbatimBraces.Replace(file.title), file.body))
+		}
+		if c.cfg.emitLLMsTxt {
+			description := ""
+			if file.path == pagePath {
+				description = pageDescription(page.fm)
+			}
+			c.llms = append(c.llms, llmsEntry{Path: file.path, Title: plainVerbatimBraces.Replace(file.title), Description: description})
+		}
+		if c.cfg.emitSitemap {
+			c.sitemap = append(c.sitemap, c.sitemapEntry(f, file.path))
+		}
+	}
+	for _, file := range files {
+		outputPath := filepath.Join(c.outputDir, file.path)
+		if c.cfg.outputEncoding == outputASCII {
+			file.content = escapeNonASCII(file.content)
+		}
+		c.claimOutput(file.path, f.Name)
+
+		// Create directory structure
+		if err := mkdirAll(filepath.Dir(outputPath)); err != nil {
+			return &writeError{Path: outputPath, Detail: "create output directory", Err: err}
+		}
+
+		// Write markdown file
+		if err := writeFile(outputPath, []byte(file.content)); err != nil {
+			return &writeError{Path: outputPath, Detail: "write markdown file", Err: err}
+		}
+		if c.cfg.postHook != "" {
+			if err := runPostHook(c.cfg.postHook, c.cfg.postHookTimeout, outputPath, f.Name); err != nil {
+				return err
+			}
+		}
+		if err := preserveModTime(outputPath, f, c.cfg.preserveMtime); err != nil {
+			return err
+		}
+		c.stats.bytesWritten += int64(len(file.content))
+		c.manifest.add(manifestRecord{Source: f.Name, Output: file.path, Bytes: int64(len(file.content)), Kind: recordPage, Title: plainVerbatimBraces.Replace(file.title)})
+
+		fmt.Printf("  -> Created: %s\n", outputPath)
+	}
+	c.stats.pagesConverted++
+	return nil
+}
+
+// readPage reads the HTML of the page f, transcoded to UTF-8, with its
+// Devsite template syntax resolved. Unless quiet, it reports and counts what
+// it reads and fixes up on the way; pages read for the links into them, see
+// linkTarget, are read quietly.
+func (c *conversion) readPage(f *zip.File, quiet bool) (string, error) {
 	// Open the file from zip
 	rc, err := f.Open()
 	if err != nil {
-		return fmt.Errorf("failed to open file in zip: %w", err)
+		return "", fmt.Errorf("failed to open file in zip: %w", err)
 	}
 	defer rc.Close()
 
 	// Read HTML content
 	htmlBytes, err := io.ReadAll(rc)
 	if err != nil {
-		return fmt.Errorf("failed to read HTML content: %w", err)
+		return "", fmt.Errorf("failed to read HTML content: %w", err)
+	}
+	if !quiet {
+		c.stats.bytesRead += int64(len(htmlBytes))
 	}
 
-	// Convert HTML to Markdown
-	markdown, err := converter.ConvertString(string(htmlBytes))
+	// Transcode legacy encodings to UTF-8
+	html, encoding, err := decodeHTML(htmlBytes, c.cfg.inputEncoding)
 	if err != nil {
-		return fmt.Errorf("failed to convert HTML to markdown: %w", err)
+		return "", err
+	}
+	if !quiet && encoding != "utf-8" {
+		fmt.Printf("  Transcoded from %s\n", encoding)
+	}
+	if !quiet && c.cfg.htmlParser == parserStrict {
+		c.checkParse(f.Name, html)
 	}
 
-	// Create output path (replace .html with .md)
-	outputPath := filepath.Join(outputDir, changeExtension(f.Name, ".md"))
+	html = expandFrameboxes(html)
 
-	// Create directory structure
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Take {% verbatim %} blocks, and template syntax in code, literally
+	html = protectVerbatim(html)
+	if c.cfg.keepCodeTemplates {
+		html = protectCodeTemplates(html)
 	}
 
-	// Write markdown file
-	if err := os.WriteFile(outputPath, []byte(markdown), 0644); err != nil {
-		return fmt.Errorf("failed to write markdown file: %w", err)
+	// Drop Devsite {% comment %} blocks
+	html, problems := stripDevsiteComments(html)
+	if !quiet {
+		for _, problem := range problems {
+			fmt.Printf("  Warning: Devsite %s\n", problem)
+		}
 	}
 
-	fmt.Printf("  -> Created: %s\n", outputPath)
-	return nil
+	// Keep the public branch of each Devsite {% dynamic if %} block
+	html, err = resolveDynamicConditionals(html, c.cfg.dynamicBranch)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep one branch of each Devsite {% if %} block
+	html, err = resolveDevsiteConditionals(html, c.cfg.devsiteConditions)
+	if err != nil {
+		return "", err
+	}
+
+	// Substitute Devsite {% setvar %} variables
+	html, undefined := expandDevsiteVariables(html)
+	if !quiet {
+		for _, name := range undefined {
+			fmt.Printf("  Warning: undefined Devsite variable {{ %s }} removed\n", name)
+		}
+	}
+
+	// Drop the template syntax left, which would otherwise leak into prose
+	html, unhandled := stripDevsiteTags(html)
+	if !quiet {
+		for _, tag := range unhandled {
+			fmt.Printf("  Warning: unhandled Devsite template syntax %s removed\n", tag)
+		}
+	}
+	return html, nil
+}
+
+// preparedPage is what preparePage takes out of a page to write around its
+// converted body.
+type preparedPage struct {
+	title  string
+	fm     frontmatter
+	prefix string
+	// Frontmatter embedded in the page, which takes precedence over fm
+	embedded frontmatter
+}
+
+// preparePage applies the passes that change the headings of a page, their
+// text or ids, or drop some. Both processZipFile and linkTarget run it, so
+// the anchors linkTarget generates for a page match those of the converted
+// page. Passes with side effects, such as copying files, belong in
+// processZipFile; quiet silences the warnings of the others.
+func (c *conversion) preparePage(doc *goquery.Document, sourcePath string, quiet bool) preparedPage {
+	var page preparedPage
+
+	applyTransforms(doc, c.cfg.transforms)
+	dropWordBreaks(doc)
+
+	if c.cfg.rawFrontmatterMarker != "" {
+		var err error
+		if page.embedded, err = extractRawFrontmatter(doc, c.cfg.rawFrontmatterMarker); err != nil && !quiet {
+			fmt.Printf("  Warning: %v; converting it as content\n", err)
+		}
+	}
+
+	if !c.cfg.preserveHidden {
+		removeHidden(doc)
+	}
+	if c.cfg.dropVisuallyHidden {
+		removeVisuallyHidden(doc, c.cfg.visuallyHiddenKeep)
+	}
+	replaceIcons(doc)
+	if c.cfg.emoji != emojiOff {
+		normalizeEmoji(doc, c.cfg.emoji)
+	}
+	if c.cfg.normalizeHeadings {
+		normalizeHeadings(doc, c.cfg.keepHeadingNumbers)
+	}
+
+	// Take the title out of the body before anchors are assigned
+	var titleH1 *goquery.Selection
+	page.title, titleH1 = pageTitle(doc, sourcePath)
+	page.fm, page.prefix = applyTitle(doc, sourcePath, c.cfg)
+	for _, field := range page.embedded {
+		if field.Key == "title" && !field.Plain {
+			page.title = field.Value
+		}
+	}
+	if c.cfg.dedupTitleHeading {
+		if !c.cfg.noFrontmatter {
+			titleH1 = nil
+		}
+		dropTitleHeading(doc, page.title, titleH1)
+	}
+
+	if c.cfg.pageNavSelector != "" {
+		removePageNav(doc, c.cfg.pageNavSelector, quiet)
+	}
+	if c.cfg.dropSections != nil {
+		dropSections(doc, c.cfg.dropSections)
+	}
+
+	if c.cfg.allowedTags != nil {
+		applyTagAllowlist(doc, c.cfg.allowedTags)
+	}
+
+	if c.cfg.autogeneratedIDs != nil {
+		stripAutogeneratedIDs(doc, c.cfg.autogeneratedIDs)
+	}
+	if c.cfg.rewriteAnchorCase {
+		lowercaseAnchors(doc)
+	}
+	unwrapHeadingSelfLinks(doc)
+	convertNameAnchors(doc)
+
+	return page
 }
 
 func isHTMLFile(filename string) bool {
@@ -122,17 +1787,25 @@ func isYAMLFile(filename string) bool {
 	return ext == ".yaml" || ext == ".yml"
 }
 
-func copyMarkdownFile(f *zip.File, outputDir string) error {
+func copyMarkdownFile(f *zip.File, outputDir string, outputPath string, banner string, preserveMtime bool) error {
 	fmt.Printf("Copying markdown file: %s\n", f.Name)
-	return copyFile(f, outputDir, f.Name)
+	if banner == "" {
+		return copyFile(f, outputDir, outputPath, preserveMtime)
+	}
+
+	content, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	return writeOutputFile(f, filepath.Join(outputDir, outputPath), []byte(insertAfterFrontmatter(string(content), banner)), preserveMtime)
 }
 
-func copyYAMLFile(f *zip.File, outputDir string) error {
+func copyYAMLFile(f *zip.File, outputDir string, preserveMtime bool) error {
 	fmt.Printf("Copying YAML file: %s\n", f.Name)
-	return copyFile(f, outputDir, f.Name)
+	return copyFile(f, outputDir, f.Name, preserveMtime)
 }
 
-func copyFile(f *zip.File, outputDir string, outputPath string) error {
+func copyFile(f *zip.File, outputDir string, outputPath string, preserveMtime bool) error {
 	// Open the file from zip
 	rc, err := f.Open()
 	if err != nil {
@@ -146,23 +1819,41 @@ func copyFile(f *zip.File, outputDir string, outputPath string) error {
 		return fmt.Errorf("failed to read content: %w", err)
 	}
 
-	// Create output path
-	fullOutputPath := filepath.Join(outputDir, outputPath)
+	return writeOutputFile(f, filepath.Join(outputDir, outputPath), content, preserveMtime)
+}
 
+// writeOutputFile writes content copied from the zip entry f.
+func writeOutputFile(f *zip.File, fullOutputPath string, content []byte, preserveMtime bool) error {
 	// Create directory structure
-	if err := os.MkdirAll(filepath.Dir(fullOutputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if err := mkdirAll(filepath.Dir(fullOutputPath)); err != nil {
+		return &writeError{Path: fullOutputPath, Detail: "create output directory", Err: err}
 	}
 
 	// Write file
-	if err := os.WriteFile(fullOutputPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if err := writeFile(fullOutputPath, content); err != nil {
+		return &writeError{Path: fullOutputPath, Detail: "write file", Err: err}
+	}
+	if err := preserveModTime(fullOutputPath, f, preserveMtime); err != nil {
+		return err
 	}
 
 	fmt.Printf("  -> Created: %s\n", fullOutputPath)
 	return nil
 }
 
+// preserveModTime gives a written file the modification time of the zip
+// entry it came from, so rsync and incremental tooling see no change when
+// the source did not change.
+func preserveModTime(outputPath string, f *zip.File, preserve bool) error {
+	if !preserve || f.Modified.IsZero() {
+		return nil
+	}
+	if err := os.Chtimes(outputPath, f.Modified, f.Modified); err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
+	}
+	return nil
+}
+
 func changeExtension(filename, newExt string) string {
 	ext := filepath.Ext(filename)
 	return filename[:len(filename)-len(ext)] + newExt
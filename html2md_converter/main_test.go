@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZipFromStdin(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "input.zip")
+	writeZip(t, zipPath, map[string]string{"docs/page.html": `<h2>Streamed</h2>`})
+
+	stdin, err := os.Open(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	outputDir := filepath.Join(dir, "output")
+	if err := convertZipToMarkdown("-", outputDir, testConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+	if page := readTree(t, outputDir)["docs/page.md"]; !strings.Contains(page, "## Streamed") {
+		t.Errorf("page converted from stdin is\n%s", page)
+	}
+}
+
+func TestPreserveMtime(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "input.zip")
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	w := zip.NewWriter(out)
+	for _, name := range []string{"docs/page.html", "docs/notes.md"} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("<p>Text</p>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, preserve := range []bool{true, false} {
+		cfg := testConfig(t)
+		cfg.preserveMtime = preserve
+		outputDir := filepath.Join(dir, "output", strconv.FormatBool(preserve))
+		if err := convertZipToMarkdown(zipPath, outputDir, cfg); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"docs/page.md", "docs/notes.md"} {
+			info, err := os.Stat(filepath.Join(outputDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.ModTime().Equal(modified); got != preserve {
+				t.Errorf("-preserve-mtime=%v: %s modified at %v", preserve, name, info.ModTime())
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Formats accepted by -manifest-format.
+const (
+	manifestJSON = "json"
+	manifestCSV  = "csv"
+)
+
+// Kinds of manifest records.
+const (
+	// A page converted from HTML.
+	recordPage = "page"
+	// A markdown file copied from the zip.
+	recordMarkdown = "markdown"
+	// An image copied next to the pages.
+	recordAsset = "asset"
+)
+
+// manifestRecord is one file written by the run, as listed by
+// -output-manifest.
+type manifestRecord struct {
+	Source string `json:"source"`
+	Output string `json:"output"`
+	Bytes  int64  `json:"bytes"`
+	Kind   string `json:"kind"`
+	Title  string `json:"title,omitempty"`
+	// SHA256 is the hex digest of the written file, with
+	// -manifest-include-hashes
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// manifest collects the records of a run; a nil manifest collects nothing.
+type manifest struct {
+	records []manifestRecord
+	// hashDir is the output directory, whose files are hashed when the
+	// manifest is written; empty records no hashes
+	hashDir string
+}
+
+func (m *manifest) add(r manifestRecord) {
+	if m != nil {
+		m.records = append(m.records, r)
+	}
+}
+
+// hash records the SHA-256 digest of each file as written, which changes
+// only when the file's content does.
+func (m *manifest) hash() error {
+	for i, r := range m.records {
+		content, err := os.ReadFile(filepath.Join(m.hashDir, r.Output))
+		if err != nil {
+			return fmt.Errorf("failed to hash %s for the manifest: %w", r.Output, err)
+		}
+		sum := sha256.Sum256(content)
+		m.records[i].SHA256 = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+// write writes the records to outputPath as a JSON array or as CSV with a
+// header row.
+func (m *manifest) write(outputPath, format string) error {
+	if m.hashDir != "" {
+		if err := m.hash(); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	switch format {
+	case manifestCSV:
+		w := csv.NewWriter(&b)
+		header := []string{"source", "output", "bytes", "kind", "title"}
+		if m.hashDir != "" {
+			header = append(header, "sha256")
+		}
+		w.Write(header)
+		for _, r := range m.records {
+			row := []string{r.Source, r.Output, strconv.FormatInt(r.Bytes, 10), r.Kind, r.Title}
+			if m.hashDir != "" {
+				row = append(row, r.SHA256)
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+	default:
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		records := m.records
+		if records == nil {
+			records = []manifestRecord{}
+		}
+		if err := enc.Encode(records); err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+	}
+
+	if err := writeFile(outputPath, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	fmt.Printf("Wrote %d manifest records to %s\n", len(m.records), outputPath)
+	return nil
+}
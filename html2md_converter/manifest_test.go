@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestManifest writes the manifest of the same run in both formats and
+// checks that they hold the same records.
+func TestManifest(t *testing.T) {
+	pages := map[string]string{
+		"docs/a, b.html":       `<h1>Title, "quoted"</h1><p><img src="images/logo.png" alt="Logo"></p>`,
+		"docs/notes.md":        "Notes\n",
+		"docs/images/logo.png": "PNG",
+	}
+	want := []manifestRecord{
+		{Source: "docs/a, b.html", Output: "docs/a, b.md", Kind: recordPage, Title: `Title, "quoted"`},
+		{Source: "docs/images/logo.png", Output: "assets/logo.png", Bytes: 3, Kind: recordAsset},
+		{Source: "docs/notes.md", Output: "docs/notes.md", Bytes: 6, Kind: recordMarkdown},
+	}
+	records := make(map[string][]manifestRecord)
+	for _, format := range []string{manifestJSON, manifestCSV} {
+		cfg := testConfig(t)
+		cfg.manifestPath = filepath.Join(t.TempDir(), "manifest."+format)
+		cfg.manifestFormat = format
+		cfg.assetsLayout = assetsCentral
+		written := convertPages(t, cfg, pages)
+		want[0].Bytes = int64(len(written["docs/a, b.md"]))
+
+		content, err := os.ReadFile(cfg.manifestPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []manifestRecord
+		if format == manifestJSON {
+			if err := json.Unmarshal(content, &got); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			rows, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(rows[0], ",") != "source,output,bytes,kind,title" {
+				t.Errorf("CSV header is %q", rows[0])
+			}
+			for _, row := range rows[1:] {
+				bytes, err := strconv.ParseInt(row[2], 10, 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, manifestRecord{Source: row[0], Output: row[1], Bytes: bytes, Kind: row[3], Title: row[4]})
+			}
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i].Source < got[j].Source })
+		records[format] = got
+	}
+	for format, got := range records {
+		if len(got) != len(want) {
+			t.Errorf("%s manifest has %+v, want %+v", format, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s manifest record %d is %+v, want %+v", format, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// TestManifestHashes checks that -manifest-include-hashes records the
+// SHA-256 digest of each written file, in JSON and CSV, and that the digest
+// of a page changes only when its output does.
+func TestManifestHashes(t *testing.T) {
+	hashes := func(format string, pages map[string]string) map[string]string {
+		cfg := testConfig(t)
+		cfg.manifestPath = filepath.Join(t.TempDir(), "manifest."+format)
+		cfg.manifestFormat = format
+		cfg.manifestHashes = true
+		written := convertPages(t, cfg, pages)
+		content, err := os.ReadFile(cfg.manifestPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		if format == manifestJSON {
+			var records []manifestRecord
+			if err := json.Unmarshal(content, &records); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range records {
+				got[r.Output] = r.SHA256
+			}
+		} else {
+			rows, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows[0][5] != "sha256" {
+				t.Errorf("CSV header is %q", rows[0])
+			}
+			for _, row := range rows[1:] {
+				got[row[1]] = row[5]
+			}
+		}
+		for output, hash := range got {
+			sum := sha256.Sum256([]byte(written[output]))
+			if hash != hex.EncodeToString(sum[:]) {
+				t.Errorf("%s: %s has digest %s, want that of\n%s", format, output, hash, written[output])
+			}
+		}
+		return got
+	}
+
+	pages := map[string]string{
+		"a.html": "<h1>A</h1><p>First.</p>",
+		"b.html": "<h1>B</h1><p>Second.</p>",
+	}
+	for _, format := range []string{manifestJSON, manifestCSV} {
+		first := hashes(format, pages)
+		if again := hashes(format, pages); again["a.md"] != first["a.md"] || again["b.md"] != first["b.md"] {
+			t.Errorf("%s: digests changed between runs: %v, then %v", format, first, again)
+		}
+		changed := hashes(format, map[string]string{"a.html": pages["a.html"], "b.html": "<h1>B</h1><p>Edited.</p>"})
+		if changed["a.md"] != first["a.md"] || changed["b.md"] == first["b.md"] {
+			t.Errorf("%s: editing b.html changed the digests %v to %v", format, first, changed)
+		}
+	}
+}
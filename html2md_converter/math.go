@@ -0,0 +1,181 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+)
+
+// Modes accepted by -math.
+const (
+	mathOff   = "off"
+	mathKaTeX = "katex"
+)
+
+// attrMath marks spans created by markTeXDelimiters. Its value is "inline"
+// or "display".
+const attrMath = "data-html2md-math"
+
+// texDelimiterRegex matches $$...$$ and $...$. The inline form must not
+// start or end with a space, which keeps prices like "$5 or $10" as text.
+var texDelimiterRegex = regexp.MustCompile(`\$\$([^$]+?)\$\$|\$([^\s$](?:[^$]*[^\s$])?)\$`)
+
+// markTeXDelimiters wraps already-delimited TeX in the page's text in marker
+// spans, so that the math rule can emit it without markdown escaping.
+func markTeXDelimiters(doc *goquery.Document) {
+	eachTextNode(doc.Find("body"), "pre, code, math, script, style", func(text *goquery.Selection) {
+		raw := text.Text()
+		matches := texDelimiterRegex.FindAllStringSubmatchIndex(raw, -1)
+		if len(matches) == 0 {
+			return
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			b.WriteString(html.EscapeString(raw[last:m[0]]))
+			mode, tex := "inline", raw[m[4]:m[5]]
+			if m[2] >= 0 {
+				mode, tex = "display", raw[m[2]:m[3]]
+			}
+			b.WriteString(`<span ` + attrMath + `="` + mode + `">` + html.EscapeString(tex) + `</span>`)
+			last = m[1]
+		}
+		b.WriteString(html.EscapeString(raw[last:]))
+		text.ReplaceWithHtml(b.String())
+	})
+}
+
+// eachTextNode calls fn for every text node below s, without descending into
+// elements matching skip.
+func eachTextNode(s *goquery.Selection, skip string, fn func(*goquery.Selection)) {
+	s.Contents().Each(func(i int, child *goquery.Selection) {
+		if child.Nodes[0].Type == nethtml.TextNode {
+			fn(child)
+		} else if !child.Is(skip) {
+			eachTextNode(child, skip, fn)
+		}
+	})
+}
+
+// mathRules emit KaTeX-compatible $...$ and $$...$$ for MathML and for TeX
+// marked by markTeXDelimiters. MathML that cannot be mapped to TeX is kept
+// as raw HTML, with its data-* attributes filtered.
+func mathRules(dataAttrs dataAttrFilter) []md.Rule {
+	return []md.Rule{
+		{
+			Filter: []string{"span"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				mode, ok := selec.Attr(attrMath)
+				if !ok {
+					return nil
+				}
+				return md.String(texMath(strings.TrimSpace(selec.Text()), mode == "display"))
+			},
+		},
+		{
+			Filter: []string{"math"},
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				display := selec.AttrOr("display", "") == "block"
+				if tex, ok := mathMLToTeX(selec); ok {
+					return md.String(texMath(tex, display))
+				}
+				raw, err := dataAttrs.outerHTML(selec)
+				if err != nil {
+					return nil
+				}
+				return md.String(raw)
+			},
+		},
+	}
+}
+
+func texMath(tex string, display bool) string {
+	if display {
+		return "\n\n$$\n" + tex + "\n$$\n\n"
+	}
+	return "$" + tex + "$"
+}
+
+// texSymbols maps the common MathML operator and identifier characters to
+// TeX commands.
+var texSymbols = map[string]string{
+	"×": `\times`, "÷": `\div`, "·": `\cdot`, "⋅": `\cdot`, "−": "-", "±": `\pm`,
+	"≤": `\leq`, "≥": `\geq`, "≠": `\neq`, "≈": `\approx`, "∞": `\infty`,
+	"∑": `\sum`, "∏": `\prod`, "∫": `\int`, "→": `\to`, "←": `\leftarrow`,
+	"…": `\ldots`, "∈": `\in`, "∉": `\notin`, "⊂": `\subset`, "∪": `\cup`, "∩": `\cap`,
+	"α": `\alpha`, "β": `\beta`, "γ": `\gamma`, "δ": `\delta`, "ε": `\epsilon`,
+	"θ": `\theta`, "λ": `\lambda`, "μ": `\mu`, "π": `\pi`, "σ": `\sigma`, "Σ": `\Sigma`,
+	"ω": `\omega`, "Ω": `\Omega`, "Δ": `\Delta`,
+	"{": `\{`, "}": `\}`, "%": `\%`, "#": `\#`, "&": `\&`,
+}
+
+// mathMLToTeX converts a <math> element to TeX. A TeX annotation, if the
+// author provided one, wins. ok is false when the element contains MathML
+// that has no mapping.
+func mathMLToTeX(math *goquery.Selection) (string, bool) {
+	if annotation := math.Find(`annotation[encoding="application/x-tex"]`).First(); annotation.Length() > 0 {
+		return strings.TrimSpace(annotation.Text()), true
+	}
+
+	m := &mathMLConverter{ok: true}
+	tex := m.children(math)
+	return strings.TrimSpace(tex), m.ok
+}
+
+// mathMLConverter converts presentation MathML, clearing ok on elements it
+// does not know.
+type mathMLConverter struct {
+	ok bool
+}
+
+func (m *mathMLConverter) children(s *goquery.Selection) string {
+	var b strings.Builder
+	s.Children().Each(func(i int, child *goquery.Selection) {
+		b.WriteString(m.node(child))
+	})
+	return b.String()
+}
+
+func (m *mathMLConverter) node(s *goquery.Selection) string {
+	child := func(i int) string {
+		return m.node(s.Children().Eq(i))
+	}
+
+	switch goquery.NodeName(s) {
+	case "mi", "mn", "mo":
+		text := strings.TrimSpace(s.Text())
+		if symbol, ok := texSymbols[text]; ok {
+			return symbol + " "
+		}
+		return text
+	case "mtext":
+		return `\text{` + s.Text() + `}`
+	case "mrow", "mstyle", "semantics", "mpadded":
+		return m.children(s)
+	case "mfrac":
+		return `\frac{` + child(0) + `}{` + child(1) + `}`
+	case "msup":
+		return `{` + child(0) + `}^{` + child(1) + `}`
+	case "msub":
+		return `{` + child(0) + `}_{` + child(1) + `}`
+	case "msubsup":
+		return `{` + child(0) + `}_{` + child(1) + `}^{` + child(2) + `}`
+	case "msqrt":
+		return `\sqrt{` + m.children(s) + `}`
+	case "mroot":
+		return `\sqrt[` + child(1) + `]{` + child(0) + `}`
+	case "mfenced":
+		return `\left` + s.AttrOr("open", "(") + m.children(s) + `\right` + s.AttrOr("close", ")")
+	case "mspace":
+		return `\ `
+	case "annotation", "annotation-xml":
+		return ""
+	}
+	m.ok = false
+	return ""
+}
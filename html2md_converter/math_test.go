@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.math = mathKaTeX
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<p>Half is <math><mfrac><mn>1</mn><mn>2</mn></mfrac></math> and the area is $a_1 * b_2$.</p>
+<math display="block"><msup><mi>x</mi><mn>2</mn></msup><mo>≤</mo><mi>y</mi></math>
+<p>Annotated <math><semantics><mi>z</mi><annotation encoding="application/x-tex">\sqrt{z}</annotation></semantics></math></p>`,
+	})["page.md"]
+
+	for _, want := range []string{`$\frac{1}{2}$`, `$a_1 * b_2$`, "$$\n{x}^{2}\\leq y\n$$", `$\sqrt{z}$`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A component's opening or closing tag on a line of its own, as
+	// componentStyle writes them: a JSX element such as <Tab title="x">,
+	// a <details> collapsible, or the fence of a Docusaurus admonition
+	componentOpenRegex  = regexp.MustCompile(`^<([A-Z][A-Za-z0-9]*|details|div)(\s[^>]*[^/])?>$`)
+	componentCloseRegex = regexp.MustCompile(`^</([A-Z][A-Za-z0-9]*|details|div)>$`)
+	admonitionRegex     = regexp.MustCompile(`^(:{3,})([a-z]*)$`)
+	titleAttrRegex      = regexp.MustCompile(`\stitle="([^"]*)"`)
+	summaryLineRegex    = regexp.MustCompile(`^<summary>(.*)</summary>$`)
+)
+
+// openComponent is a component limitNesting is inside of.
+type openComponent struct {
+	// name is the tag, or the fence of an admonition
+	name      string
+	flattened bool
+}
+
+// limitNesting flattens the components of a converted page nested deeper
+// than maxDepth, for -max-output-depth, so that pathological nesting such as
+// tabs in accordions in callouts does not break the MDX build. A flattened
+// component loses its tags; a titled one, such as a tab or an accordion,
+// leaves its title in bold, and a callout its kind. Tabs of a <Tabs> and the
+// <div> columns of <Columns> count as a level of their own, besides the
+// element around them. Code blocks are left alone.
+func limitNesting(markdown string, maxDepth int) string {
+	lines := strings.Split(markdown, "\n")
+	out := lines[:0]
+	var stack []openComponent
+	flattened, deepest := 0, 0
+	fence := ""
+	// dropped tells whether tags were dropped since the last text, whose
+	// blank lines then run together
+	dropped := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			out = append(out, line)
+			continue
+		}
+
+		inFlattened := len(stack) > 0 && stack[len(stack)-1].flattened
+		name, title, opens, closes := componentTag(trimmed, stack)
+		switch {
+		case opens:
+			depth := len(stack) + 1
+			if depth > deepest {
+				deepest = depth
+			}
+			if depth <= maxDepth {
+				stack = append(stack, openComponent{name: name})
+				break
+			}
+			stack = append(stack, openComponent{name: name, flattened: true})
+			flattened++
+			if title != "" {
+				out = append(out, indent+"**"+title+"**")
+			}
+			dropped = true
+			continue
+		case closes:
+			stack = stack[:len(stack)-1]
+			if inFlattened {
+				dropped = true
+				continue
+			}
+		case inFlattened:
+			// The title of a flattened <details>
+			if m := summaryLineRegex.FindStringSubmatch(trimmed); m != nil {
+				out = append(out, indent+"**"+m[1]+"**")
+				continue
+			}
+		}
+		if trimmed == "" && dropped && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+			continue
+		}
+		dropped = dropped && trimmed == ""
+		out = append(out, line)
+	}
+	if flattened == 0 {
+		return markdown
+	}
+	fmt.Printf("  Warning: components nested %d deep, over -max-output-depth of %d; flattened %d to plain markdown\n", deepest, maxDepth, flattened)
+	return strings.Join(out, "\n")
+}
+
+// componentTag reports whether trimmed, a line of markdown, opens a
+// component, and with what name and title, or closes the innermost of
+// stack.
+func componentTag(trimmed string, stack []openComponent) (name, title string, opens, closes bool) {
+	innermost := ""
+	if len(stack) > 0 {
+		innermost = stack[len(stack)-1].name
+	}
+	if m := admonitionRegex.FindStringSubmatch(trimmed); m != nil {
+		if m[2] == "" {
+			return "", "", false, m[1] == innermost
+		}
+		return m[1], strings.ToUpper(m[2][:1]) + m[2][1:] + ":", true, false
+	}
+	if m := componentCloseRegex.FindStringSubmatch(trimmed); m != nil {
+		return "", "", false, m[1] == innermost
+	}
+	m := componentOpenRegex.FindStringSubmatch(trimmed)
+	if m == nil || (m[1] == "div" && innermost != "Columns") {
+		return "", "", false, false
+	}
+	name = m[1]
+	if t := titleAttrRegex.FindStringSubmatch(m[2]); t != nil {
+		title = t[1]
+	} else {
+		for _, kind := range calloutKinds {
+			if name == kind {
+				title = kind + ":"
+			}
+		}
+	}
+	return name, title, true, false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimitNesting(t *testing.T) {
+	markdown := `<Note>
+
+<Accordion title="Platforms">
+
+<Tabs>
+<Tab title="Linux">
+
+Run it.
+
+` + "```\n<Tab title=\"literal\">\n```" + `
+
+</Tab>
+</Tabs>
+
+</Accordion>
+
+</Note>
+`
+	tests := []struct {
+		depth int
+		want  string
+		warns bool
+	}{
+		{4, markdown, false},
+		{2, `<Note>
+
+<Accordion title="Platforms">
+
+**Linux**
+
+Run it.
+
+` + "```\n<Tab title=\"literal\">\n```" + `
+
+</Accordion>
+
+</Note>
+`, true},
+		{1, `<Note>
+
+**Platforms**
+
+**Linux**
+
+Run it.
+
+` + "```\n<Tab title=\"literal\">\n```" + `
+
+</Note>
+`, true},
+	}
+	for _, tt := range tests {
+		var got string
+		output := captureStdout(t, func() { got = limitNesting(markdown, tt.depth) })
+		if got != tt.want {
+			t.Errorf("depth %d: flattened to\n%s\nwant\n%s", tt.depth, got, tt.want)
+		}
+		if warned := strings.Contains(output, "components nested 4 deep, over -max-output-depth of"); warned != tt.warns {
+			t.Errorf("depth %d: output is %q", tt.depth, output)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Permissions of the files and directories a run writes. They are set from
+// -file-mode and -dir-mode at start-up; when either flag is given, written
+// files and created directories get exactly those permissions, whatever the
+// umask, rather than what the umask leaves of them.
+var (
+	outputFileMode os.FileMode = 0644
+	outputDirMode  os.FileMode = 0755
+	exactModes     bool
+)
+
+// parseMode parses an octal permission flag such as 0664 or 775.
+func parseMode(flagName, value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("-%s must be octal permissions such as 0644, not %q", flagName, value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// writeFile writes an output file with outputFileMode.
+func writeFile(name string, content []byte) error {
+	if err := os.WriteFile(name, content, outputFileMode); err != nil {
+		return err
+	}
+	if exactModes {
+		return os.Chmod(name, outputFileMode)
+	}
+	return nil
+}
+
+// mkdirAll creates an output directory and its missing parents with
+// outputDirMode.
+func mkdirAll(dir string) error {
+	var created []string
+	if exactModes {
+		for d := dir; ; d = filepath.Dir(d) {
+			if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+				break
+			}
+			created = append(created, d)
+		}
+	}
+	if err := os.MkdirAll(dir, outputDirMode); err != nil {
+		return err
+	}
+	for _, d := range created {
+		if err := os.Chmod(d, outputDirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
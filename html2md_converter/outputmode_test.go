@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]os.FileMode{"0664": 0664, "775": 0775, "0600": 0600}
+	for value, want := range tests {
+		if got, err := parseMode("file-mode", value); err != nil || got != want {
+			t.Errorf("parseMode(%q) = %o, %v, want %o", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0888", "rw-r--r--", "01777", "-1"} {
+		if _, err := parseMode("file-mode", value); err == nil {
+			t.Errorf("parseMode(%q) did not fail", value)
+		}
+	}
+}
+
+// TestOutputModes converts pages with -file-mode 0664 and -dir-mode 0750
+// under a umask that would take group write away, and checks the modes of
+// what was written.
+func TestOutputModes(t *testing.T) {
+	defer func(file, dir os.FileMode, exact bool) {
+		outputFileMode, outputDirMode, exactModes = file, dir, exact
+	}(outputFileMode, outputDirMode, exactModes)
+	outputFileMode, outputDirMode, exactModes = 0664, 0750, true
+	defer syscall.Umask(syscall.Umask(0022))
+
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "input.zip")
+	writeZip(t, zipPath, map[string]string{
+		"docs/guide/page.html": `<h1>Page</h1><p><img src="logo.png" alt="Logo"></p>`,
+		"docs/guide/logo.png":  "PNG",
+	})
+	outputDir := filepath.Join(dir, "output")
+	cfg := testConfig(t)
+	cfg.assetsLayout = assetsCentral
+	if err := convertZipToMarkdown(zipPath, outputDir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]os.FileMode{
+		"docs":               0750,
+		"docs/guide":         0750,
+		"docs/guide/page.md": 0664,
+		"assets":             0750,
+		"assets/logo.png":    0664,
+	}
+	for path, mode := range want {
+		info, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got := info.Mode().Perm(); got != mode {
+			t.Errorf("%s has mode %o, want %o", path, got, mode)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultPageNavSelector matches the "On this page" navigation Devsite embeds
+// in pages. The docs site generates its own table of contents.
+const defaultPageNavSelector = ".devsite-page-nav, nav.toc"
+
+// removePageNav removes the embedded page navigation matched by selector.
+// Only elements whose links all point into the page are removed, so that a
+// content list that happens to match is kept, with a warning unless quiet.
+func removePageNav(doc *goquery.Document, selector string, quiet bool) {
+	doc.Find(selector).Each(func(i int, nav *goquery.Selection) {
+		links := nav.Find("a[href]")
+		inPage := links.FilterFunction(func(i int, a *goquery.Selection) bool {
+			return strings.HasPrefix(a.AttrOr("href", ""), "#")
+		})
+		if links.Length() == 0 || inPage.Length() != links.Length() {
+			if !quiet {
+				fmt.Printf("  Warning: kept <%s> matching the page nav selector, which links outside the page\n", goquery.NodeName(nav))
+			}
+			return
+		}
+		nav.Remove()
+	})
+}
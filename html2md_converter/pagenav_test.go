@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPageNav checks that an embedded "On this page" list is removed and
+// that a matching list linking outside the page is kept.
+func TestPageNav(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<div class="devsite-page-nav"><ul><li><a href="#intro">Intro</a></li><li><a href="#usage">Usage</a></li></ul></div>
+<h2 id="intro">Intro</h2>
+<nav class="toc"><ul><li><a href="other.html">Other page</a></li></ul></nav>
+<ul><li><a href="#usage">See usage</a></li></ul>
+<h2 id="usage">Usage</h2>`,
+	}
+	tests := []struct {
+		selector string
+		want     []string
+		dropped  []string
+	}{
+		{defaultPageNavSelector, []string{"[Other page](other.html)", "[See usage](#usage)"}, []string{"[Intro](#intro)"}},
+		{"", []string{"[Intro](#intro)", "[Other page](other.html)"}, nil},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.pageNavSelector = tt.selector
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-page-nav-selector=%q: page does not contain %s:\n%s", tt.selector, want, page)
+			}
+		}
+		for _, dropped := range tt.dropped {
+			if strings.Contains(page, dropped) {
+				t.Errorf("-page-nav-selector=%q: page contains %s:\n%s", tt.selector, dropped, page)
+			}
+		}
+	}
+}
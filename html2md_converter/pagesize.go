@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// longPage is a converted page over -long-page-bytes or -long-page-words.
+type longPage struct {
+	path         string
+	bytes, words int
+}
+
+// checkPageSize warns, with -warn-on-long-pages, when the page is too long
+// to load and navigate comfortably, so that it can be split. maxBytes
+// limits the written markdown and maxWords its prose; 0 skips a limit.
+func (c *conversion) checkPageSize(pagePath, markdown, body string) {
+	words := len(strings.Fields(stripMarkdown(body)))
+	bytes := len(markdown)
+	if (c.cfg.longPageBytes == 0 || bytes <= c.cfg.longPageBytes) && (c.cfg.longPageWords == 0 || words <= c.cfg.longPageWords) {
+		return
+	}
+	fmt.Printf("  Warning: page is long (%d bytes, %d words); consider splitting it\n", bytes, words)
+	c.longPages = append(c.longPages, longPage{path: pagePath, bytes: bytes, words: words})
+	c.stats.longPages++
+}
+
+// printLongPages lists the pages checkPageSize warned about.
+func printLongPages(pages []longPage) {
+	if len(pages) == 0 {
+		return
+	}
+	fmt.Println("Pages over -long-page-bytes or -long-page-words:")
+	for _, p := range pages {
+		fmt.Printf("  %s (%d bytes, %d words)\n", p.path, p.bytes, p.words)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestLongPages checks that under -warn-on-long-pages a page over either
+// limit is reported, failing the run only with -strict-size, and that
+// shorter pages are not.
+func TestLongPages(t *testing.T) {
+	long := map[string]string{"long.html": "<h1>Long</h1><p>" + strings.Repeat("word ", 60) + "</p>"}
+	tests := []struct {
+		name         string
+		bytes, words int
+		strict       bool
+		fails        bool
+	}{
+		{"under the limits", 0, 100, true, false},
+		{"over the words", 0, 50, true, true},
+		{"over the bytes", 200, 0, true, true},
+		{"advisory", 200, 50, false, false},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.warnLongPages = true
+		cfg.longPageBytes = tt.bytes
+		cfg.longPageWords = tt.words
+		cfg.strict["size"] = tt.strict
+		err := convertTestZip(t, cfg, long)
+		var strictErr *strictError
+		switch {
+		case !tt.fails && err != nil:
+			t.Errorf("%s: run failed: %v", tt.name, err)
+		case tt.fails && !errors.As(err, &strictErr):
+			t.Errorf("%s: run returned %v, want a strict error", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// pathTemplate computes output paths from -path-template, e.g.
+// "reference/{section}/{name}.{ext}". {dir}, {name} and {ext} are the
+// directory, base name and output extension of the default path; groups of
+// -path-pattern, matched against the source path, are available by number
+// or name. Sources the pattern does not match keep their default path.
+type pathTemplate struct {
+	template string
+	pattern  *regexp.Regexp
+}
+
+func newPathTemplate(template, pattern string) (*pathTemplate, error) {
+	if template == "" {
+		if pattern != "" {
+			return nil, fmt.Errorf("-path-pattern needs a -path-template")
+		}
+		return nil, nil
+	}
+
+	t := &pathTemplate{template: template}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -path-pattern: %w", err)
+		}
+		t.pattern = re
+	}
+
+	for _, m := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		if !t.knows(m[1]) {
+			return nil, fmt.Errorf("-path-template: unknown placeholder {%s}", m[1])
+		}
+	}
+	if clean := path.Clean(strings.ReplaceAll(template, "{dir}", "")); path.IsAbs(clean) || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("-path-template must stay inside the output directory")
+	}
+	return t, nil
+}
+
+func (t *pathTemplate) knows(name string) bool {
+	switch name {
+	case "dir", "name", "ext":
+		return true
+	}
+	if t.pattern == nil {
+		return false
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		return n <= t.pattern.NumSubexp()
+	}
+	return t.pattern.SubexpIndex(name) >= 0
+}
+
+// apply returns the templated output path for a source whose default output
+// path is defaultPath.
+func (t *pathTemplate) apply(sourcePath, defaultPath string) string {
+	var groups []string
+	if t.pattern != nil {
+		if groups = t.pattern.FindStringSubmatch(sourcePath); groups == nil {
+			return defaultPath
+		}
+	}
+
+	dir, base := path.Split(defaultPath)
+	ext := path.Ext(base)
+	values := map[string]string{
+		"dir":  strings.TrimSuffix(dir, "/"),
+		"name": strings.TrimSuffix(base, ext),
+		"ext":  strings.TrimPrefix(ext, "."),
+	}
+	result := placeholderRegex.ReplaceAllStringFunc(t.template, func(m string) string {
+		name := m[1 : len(m)-1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		if n, err := strconv.Atoi(name); err == nil {
+			return groups[n]
+		}
+		return groups[t.pattern.SubexpIndex(name)]
+	})
+	return strings.TrimPrefix(path.Clean(result), "/")
+}
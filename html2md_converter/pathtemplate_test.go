@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathTemplate(t *testing.T) {
+	pages := map[string]string{
+		"docs/rules/cc/library.html": `<h1>Library</h1><p><a href="../../intro.html">Intro</a></p>`,
+		"docs/intro.html":            `<h1>Intro</h1><p><a href="rules/cc/library.html#usage">Library</a></p>`,
+	}
+	cfg := testConfig(t)
+	var err error
+	if cfg.pathTemplate, err = newPathTemplate("reference/{lang}/{name}.mdx", `^docs/rules/(?P<lang>\w+)/`); err != nil {
+		t.Fatal(err)
+	}
+	written := convertPages(t, cfg, pages)
+	library, ok := written["reference/cc/library.mdx"]
+	if !ok {
+		t.Fatal("the templated page was not written to reference/cc/library.mdx")
+	}
+	if !strings.Contains(library, "[Intro](../../docs/intro.md)") {
+		t.Errorf("templated page does not link the intro:\n%s", library)
+	}
+	if intro := written["docs/intro.md"]; !strings.Contains(intro, "[Library](../reference/cc/library.mdx#usage)") {
+		t.Errorf("unmatched page does not link the templated one:\n%s", intro)
+	}
+}
+
+func TestPathTemplateErrors(t *testing.T) {
+	tests := []struct{ template, pattern, want string }{
+		{"{name}.md", "(", "invalid -path-pattern"},
+		{"{section}/{name}.md", "", "unknown placeholder {section}"},
+		{"{2}/{name}.md", "^(docs)/", "unknown placeholder {2}"},
+		{"../{name}.md", "", "inside the output directory"},
+		{"", "^docs/", "needs a -path-template"},
+	}
+	for _, tt := range tests {
+		if _, err := newPathTemplate(tt.template, tt.pattern); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("newPathTemplate(%q, %q) = %v, want an error containing %q", tt.template, tt.pattern, err, tt.want)
+		}
+	}
+}
+
+// TestReshapedLinks checks that two pages linking to each other stay linked
+// after -path-template, or -rename-map, moves both of them. Links are
+// worked out between output paths; there is no -strip-prefix or -root
+// option, and these are the options that reshape the tree.
+func TestReshapedLinks(t *testing.T) {
+	pages := map[string]string{
+		"site/docs/a.html":       `<h1>A</h1><p><a href="guide/b.html#setup">B</a></p>`,
+		"site/docs/guide/b.html": `<h1>B</h1><p><a href="../a.html">A</a></p><h2 id="setup">Setup</h2>`,
+	}
+	mapPath := filepath.Join(t.TempDir(), "renames.yaml")
+	if err := os.WriteFile(mapPath, []byte("site/docs/a.html: a.md\nsite/docs/guide/b.html: guide/b.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renames, err := loadRenameMap(mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template, err := newPathTemplate("{1}.{ext}", `^site/docs/(.*)\.html$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(*config)
+	}{
+		{"path-template", func(cfg *config) { cfg.pathTemplate = template }},
+		{"rename-map", func(cfg *config) { cfg.renames = renames }},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		tt.configure(&cfg)
+		written := convertPages(t, cfg, pages)
+		if a := written["a.md"]; !strings.Contains(a, "[B](guide/b.md#setup)") {
+			t.Errorf("%s: a.md does not link b:\n%v", tt.name, written)
+		}
+		if b := written["guide/b.md"]; !strings.Contains(b, "[A](../a.md)") {
+			t.Errorf("%s: guide/b.md does not link a:\n%v", tt.name, written)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// pictureOptions configures the pictures rule set. By default a <picture>
+// becomes its fallback <img>, since markdown images have a single source.
+type pictureOptions struct {
+	// Passthrough keeps <picture> elements as JSX with all their sources.
+	Passthrough bool `yaml:"passthrough"`
+}
+
+// pictureAttrs are the attributes kept on passed through elements, with
+// their JSX names.
+var pictureAttrs = []struct{ html, jsx string }{
+	{"srcset", "srcSet"},
+	{"sizes", "sizes"},
+	{"media", "media"},
+	{"type", "type"},
+	{"src", "src"},
+	{"alt", "alt"},
+	{"width", "width"},
+	{"height", "height"},
+}
+
+func picturePlugin(options pictureOptions) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		if !options.Passthrough {
+			return nil
+		}
+		return []md.Rule{
+			{
+				Filter: []string{"picture"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					var b strings.Builder
+					b.WriteString("\n\n<picture>\n")
+					selec.Children().Each(func(i int, child *goquery.Selection) {
+						name := goquery.NodeName(child)
+						if name != "source" && name != "img" {
+							return
+						}
+						b.WriteString("  <" + name)
+						for _, attr := range pictureAttrs {
+							if value, ok := child.Attr(attr.html); ok {
+								b.WriteString(" " + attr.jsx + `="` + jsxAttrEscaper.Replace(value) + `"`)
+							}
+						}
+						b.WriteString(" />\n")
+					})
+					b.WriteString("</picture>\n\n")
+					return md.String(b.String())
+				},
+			},
+		}
+	}
+}
+
+// dropImageDimensions removes the width and height of images and picture
+// sources, so that passed through elements carry only what they show.
+func dropImageDimensions(doc *goquery.Document) {
+	doc.Find("img, source").RemoveAttr("width").RemoveAttr("height")
+}
+
+// sizedImageRule writes images that have a width or height as <img> tags,
+// for -keep-img-dimensions, since markdown images cannot carry them. Other
+// images stay markdown images.
+func sizedImageRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"img"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			_, hasWidth := selec.Attr("width")
+			_, hasHeight := selec.Attr("height")
+			if !hasWidth && !hasHeight {
+				return nil
+			}
+
+			var b strings.Builder
+			b.WriteString("<img")
+			for _, attr := range []string{"src", "alt", "title", "width", "height"} {
+				if value, ok := selec.Attr(attr); ok {
+					b.WriteString(" " + attr + `="` + jsxAttrEscaper.Replace(value) + `"`)
+				}
+			}
+			b.WriteString(" />")
+			return md.String(b.String())
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPictures(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<picture>
+<source srcset="img/hero.avif 1x, img/hero@2x.avif 2x" type="image/avif">
+<source srcset="img/hero.webp" type="image/webp" media="(min-width: 600px)">
+<img src="img/hero.png" alt="Hero">
+</picture>`,
+		"docs/img/hero.avif":    "AVIF",
+		"docs/img/hero@2x.avif": "AVIF2",
+		"docs/img/hero.webp":    "WEBP",
+		"docs/img/hero.png":     "PNG",
+	}
+	tests := []struct {
+		config string
+		want   []string
+	}{
+		{"", []string{"![Hero](../assets/hero.png)"}},
+		{"rules:\n  pictures:\n    passthrough: true\n", []string{
+			"<picture>\n",
+			`  <source srcSet="../assets/hero.avif 1x, ../assets/hero@2x.avif 2x" type="image/avif" />`,
+			`  <source srcSet="../assets/hero.webp" media="(min-width: 600px)" type="image/webp" />`,
+			`  <img src="../assets/hero.png" alt="Hero" />`,
+			"</picture>",
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.assetsLayout = assetsCentral
+		if tt.config != "" {
+			ruleSets, _, err := loadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.ruleSets = ruleSets
+		}
+		written := convertPages(t, cfg, pages)
+		for _, want := range tt.want {
+			if !strings.Contains(written["docs/page.md"], want) {
+				t.Errorf("config %q: page lacks %s:\n%s", tt.config, want, written["docs/page.md"])
+			}
+		}
+		for _, asset := range []string{"hero.avif", "hero@2x.avif", "hero.webp", "hero.png"} {
+			if _, ok := written["assets/"+asset]; !ok {
+				t.Errorf("config %q: %s not copied", tt.config, asset)
+			}
+		}
+	}
+}
+
+// TestImageDimensions checks that passed-through pictures keep only their
+// sources, alt text and type, and that -keep-img-dimensions keeps widths
+// and heights, writing sized images as <img> tags.
+func TestImageDimensions(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<picture><source srcset="img/a.webp" type="image/webp" width="640" height="480"><img src="img/a.png" alt="A" width="640" height="480" loading="lazy" decoding="async"></picture>
+<p><img src="img/b.png" alt="B" width="32" loading="lazy"> <img src="img/c.png" alt="C"></p>`,
+		"docs/img/a.webp": "WEBP",
+		"docs/img/a.png":  "A",
+		"docs/img/b.png":  "B",
+		"docs/img/c.png":  "C",
+	}
+	tests := []struct {
+		keep bool
+		want []string
+	}{
+		{false, []string{
+			`  <source srcSet="../assets/a.webp" type="image/webp" />`,
+			`  <img src="../assets/a.png" alt="A" />`,
+			"![B](../assets/b.png) ![C](../assets/c.png)",
+		}},
+		{true, []string{
+			`  <source srcSet="../assets/a.webp" type="image/webp" width="640" height="480" />`,
+			`  <img src="../assets/a.png" alt="A" width="640" height="480" />`,
+			`<img src="../assets/b.png" alt="B" width="32" /> ![C](../assets/c.png)`,
+		}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.assetsLayout = assetsCentral
+		cfg.keepImgDimensions = tt.keep
+		ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  pictures:\n    passthrough: true\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ruleSets = ruleSets
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("-keep-img-dimensions=%v: page lacks %s:\n%s", tt.keep, want, page)
+			}
+		}
+		if strings.Contains(page, "loading") || strings.Contains(page, "decoding") {
+			t.Errorf("-keep-img-dimensions=%v: page keeps loading or decoding:\n%s", tt.keep, page)
+		}
+	}
+}
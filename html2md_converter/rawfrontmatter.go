@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v2"
+)
+
+// extractRawFrontmatter finds YAML frontmatter that a page embeds between
+// marker lines in a comment or <pre>, and removes the node it came from. Only
+// the first node of the document, of its <head> and of its <body> are
+// considered, so examples of frontmatter further down a page stay content.
+// A block that is not valid YAML is an error and stays in the page.
+func extractRawFrontmatter(doc *goquery.Document, marker string) (frontmatter, error) {
+	for _, n := range rawFrontmatterCandidates(doc) {
+		var text string
+		switch {
+		case n.Type == html.CommentNode:
+			text = n.Data
+		case n.Type == html.ElementNode && n.Data == "pre":
+			text = goquery.NewDocumentFromNode(n).Text()
+		default:
+			continue
+		}
+
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		if len(lines) < 2 || strings.TrimSpace(lines[0]) != marker || strings.TrimSpace(lines[len(lines)-1]) != marker {
+			continue
+		}
+
+		var fm frontmatter
+		var fields yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:len(lines)-1], "\n")), &fields); err != nil {
+			return nil, fmt.Errorf("embedded frontmatter is not valid YAML: %w", err)
+		}
+		for _, item := range fields {
+			field, err := yamlField(fmt.Sprint(item.Key), item.Value)
+			if err != nil {
+				return nil, err
+			}
+			fm = append(fm, field)
+		}
+		n.Parent.RemoveChild(n)
+		return fm, nil
+	}
+	return nil, nil
+}
+
+func rawFrontmatterCandidates(doc *goquery.Document) []*html.Node {
+	var nodes []*html.Node
+	for _, parent := range []*html.Node{doc.Nodes[0], doc.Find("head").Get(0), doc.Find("body").Get(0)} {
+		if parent == nil {
+			continue
+		}
+		for n := parent.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type == html.DoctypeNode || n.Type == html.TextNode && strings.TrimSpace(n.Data) == "" {
+				continue
+			}
+			nodes = append(nodes, n)
+			break
+		}
+	}
+	return nodes
+}
+
+// yamlField turns an embedded value into a frontmatter field. Strings are
+// quoted like computed fields; other values are written back as YAML, with
+// lists and mappings as an indented block.
+func yamlField(key string, value interface{}) (frontmatterField, error) {
+	if s, ok := value.(string); ok {
+		return frontmatterField{Key: key, Value: s}, nil
+	}
+
+	raw, err := yaml.Marshal(value)
+	if err != nil {
+		return frontmatterField{}, fmt.Errorf("failed to encode embedded frontmatter field %s: %w", key, err)
+	}
+	text := strings.TrimSuffix(string(raw), "\n")
+	switch value.(type) {
+	case []interface{}, yaml.MapSlice:
+		if !strings.HasPrefix(text, "[") && !strings.HasPrefix(text, "{") {
+			text = "\n  " + strings.ReplaceAll(text, "\n", "\n  ")
+		}
+	}
+	return frontmatterField{Key: key, Value: text, Plain: true}, nil
+}
+
+// merge returns fm with the fields of override replacing those with the same
+// key; keys fm lacks are added at the end.
+func (fm frontmatter) merge(override frontmatter) frontmatter {
+	merged := append(frontmatter(nil), fm...)
+	for _, field := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Key == field.Key {
+				merged[i] = field
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, field)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// redirectsFile is written to the output directory when a run produces
+// redirects. It uses the entry format of the "redirects" list in Mintlify's
+// docs.json, so its contents can be merged there directly.
+const redirectsFile = "redirects.json"
+
+type redirect struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+func newRedirect(fromPage, toPage string) redirect {
+	return redirect{Source: pageURL(fromPage), Destination: pageURL(toPage)}
+}
+
+// pageURL turns an output page path such as "rules/lib/foo.md" into the URL
+// path the site serves it at, "/rules/lib/foo".
+func pageURL(pagePath string) string {
+	return "/" + strings.TrimSuffix(pagePath, path.Ext(pagePath))
+}
+
+func writeRedirects(outputDir string, redirects []redirect, trailingSlash string) error {
+	if len(redirects) == 0 {
+		return nil
+	}
+
+	if trailingSlash == slashAdd {
+		for i := range redirects {
+			redirects[i].Source += "/"
+			redirects[i].Destination += "/"
+		}
+	}
+
+	content, err := json.MarshalIndent(redirects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode redirects: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, redirectsFile)
+	if err := writeFile(outputPath, append(content, '\n')); err != nil {
+		return fmt.Errorf("failed to write redirects: %w", err)
+	}
+
+	fmt.Printf("Wrote %d redirect(s) to %s\n", len(redirects), outputPath)
+	return nil
+}
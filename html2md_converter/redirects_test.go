@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDedupPages(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.dedupPages = true
+	written := convertPages(t, cfg, map[string]string{
+		"docs/a.html":   `<h1>Moved</h1><p>Moved to the new page.</p>`,
+		"docs/b.html":   `<h1>Moved</h1><p>Moved to the new page.</p>`,
+		"docs/own.html": `<p>Its own content.</p>`,
+	})
+
+	if _, ok := written["docs/a.md"]; !ok {
+		t.Error("canonical page not written")
+	}
+	if _, ok := written["docs/b.md"]; ok {
+		t.Error("duplicate page written")
+	}
+	if _, ok := written["docs/own.md"]; !ok {
+		t.Error("distinct page not written")
+	}
+	want := `"source": "/docs/b",
+    "destination": "/docs/a"`
+	if !strings.Contains(written[redirectsFile], want) || strings.Count(written[redirectsFile], "source") != 1 {
+		t.Errorf("redirects are\n%s\nwant one of\n%s", written[redirectsFile], want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An inline link with its text, URL and optional title
+var inlineRefLinkRegex = regexp.MustCompile(`\[([^\[\]]*)\]\(([^()\s]+)((?:\s+"[^"]*")?)\)`)
+
+// collectRefs rewrites, for -collect-refs, the inline links of a page as
+// reference links to numbered definitions at its end, so that link-heavy
+// prose reads without the URLs. Links to the same URL and title share a
+// definition. Links in code and numbers the page already defines are left
+// alone.
+func collectRefs(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	used := make(map[string]bool)
+	for _, line := range lines {
+		if m := linkDefinitionRegex.FindString(line); m != "" {
+			used[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(m)[1:], "]:"))] = true
+		}
+	}
+
+	labels := make(map[string]string)
+	var definitions []string
+	next := 1
+	label := func(target string) string {
+		if l, ok := labels[target]; ok {
+			return l
+		}
+		for used[strconv.Itoa(next)] {
+			next++
+		}
+		l := strconv.Itoa(next)
+		next++
+		labels[target] = l
+		definitions = append(definitions, "["+l+"]: "+target)
+		return l
+	}
+	replace := func(text string) string {
+		var b strings.Builder
+		last := 0
+		for _, m := range inlineRefLinkRegex.FindAllStringSubmatchIndex(text, -1) {
+			if m[0] > 0 && (text[m[0]-1] == '!' || text[m[0]-1] == '\\') {
+				// An image, or an escaped bracket
+				continue
+			}
+			b.WriteString(text[last:m[0]])
+			b.WriteString("[" + text[m[2]:m[3]] + "][" + label(text[m[4]:m[7]]) + "]")
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+		return b.String()
+	}
+
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			continue
+		}
+
+		// Replace outside inline code only
+		var b strings.Builder
+		last := 0
+		for _, span := range inlineCodeRegex.FindAllStringIndex(line, -1) {
+			b.WriteString(replace(line[last:span[0]]))
+			b.WriteString(line[span[0]:span[1]])
+			last = span[1]
+		}
+		b.WriteString(replace(line[last:]))
+		lines[i] = b.String()
+	}
+
+	if len(definitions) == 0 {
+		return markdown
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n\n" + strings.Join(definitions, "\n") + "\n"
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestCollectRefs(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{
+			"See [the rules](https://bazel.build/rules) and [rules](https://bazel.build/rules), or [the FAQ](faq.md \"FAQ\").\n",
+			"See [the rules][1] and [rules][1], or [the FAQ][2].\n\n[1]: https://bazel.build/rules\n[2]: faq.md \"FAQ\"\n",
+		},
+		{
+			"Run `[x](y)`, see ![Logo](logo.png) and [docs](docs.md).\n\n[1]: other.md\n",
+			"Run `[x](y)`, see ![Logo](logo.png) and [docs][2].\n\n[1]: other.md\n\n[2]: docs.md\n",
+		},
+		{"```\n[x](y)\n```\n", "```\n[x](y)\n```\n"},
+	}
+	for _, tt := range tests {
+		if got := collectRefs(tt.in); got != tt.want {
+			t.Errorf("collectRefs(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+		}
+	}
+}
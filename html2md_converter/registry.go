@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"gopkg.in/yaml.v2"
+)
+
+// ruleSet is a named group of conversion rules that the -config file can
+// turn off or configure.
+type ruleSet struct {
+	name string
+	// newOptions returns a pointer to the rule set's options holding the
+	// defaults; the config file entry is decoded on top of them. It is nil
+	// for rule sets without options.
+	newOptions func() interface{}
+	// schema describes the options in the config file; nil for rule sets
+	// without options.
+	schema *jsonSchema
+	// plugin builds the rules; those emitting components write them in
+	// the given style.
+	plugin func(options interface{}, style componentStyle) md.Plugin
+}
+
+// ruleSets are applied in order, so later sets take precedence for the
+// elements they share.
+var ruleSets = []ruleSet{
+	{
+		name:       "code-blocks",
+		newOptions: func() interface{} { return &codeOptions{DiagramLanguage: "text"} },
+		schema: closedObject("Options of the code-blocks rule set.", map[string]*jsonSchema{
+			"languages":            {Type: "object", Description: "Renames detected languages, e.g. py: python.", AdditionalProperties: &jsonSchema{Type: "string"}},
+			"default_language":     {Type: "string", Description: "Language of blocks without a detected one; empty leaves the fence bare."},
+			"diagram_language":     {Type: "string", Description: "Language of undetected blocks that look like ASCII diagrams; empty treats them like other blocks."},
+			"drop_line_highlights": {Type: "boolean", Description: "Drop the data-highlight line ranges of blocks instead of writing them as {2,4-6} on the fence, for renderers without that syntax."},
+		}),
+		plugin: func(options interface{}, _ componentStyle) md.Plugin { return codePlugin(*options.(*codeOptions)) },
+	},
+	{
+		name:   "details",
+		plugin: func(_ interface{}, style componentStyle) md.Plugin { return detailsPlugin(style) },
+	},
+	{
+		name:   "devsite-tabs",
+		plugin: func(_ interface{}, style componentStyle) md.Plugin { return tabsPlugin(style) },
+	},
+	{
+		name:   "definition-lists",
+		plugin: func(interface{}, componentStyle) md.Plugin { return definitionListPlugin },
+	},
+	{
+		name:       "faq-accordions",
+		newOptions: func() interface{} { return &faqOptions{} },
+		schema: closedObject("Options of the faq-accordions rule set.", map[string]*jsonSchema{
+			"selector": {Type: "string", Description: "CSS selector of the definition lists that hold an FAQ, a question per <dt> answered by the <dd> after it, written as an accordion group; empty turns the rule set off."},
+		}),
+		plugin: func(options interface{}, style componentStyle) md.Plugin {
+			return faqPlugin(*options.(*faqOptions), style)
+		},
+	},
+	{
+		name: "tables",
+		newOptions: func() interface{} {
+			return &tableOptions{Layout: layoutColumns, LayoutMaxRows: 1, HeaderOnly: headerOnlyList, Nested: nestedHTML}
+		},
+		schema: closedObject("Options of the tables rule set.", map[string]*jsonSchema{
+			"layout":             {Type: "string", Description: "How layout tables are written: columns, stacked, or as a table like data tables.", Enum: stringEnum(layoutColumns, layoutStacked, layoutTable)},
+			"layout_max_rows":    {Type: "integer", Description: "Most rows a table can have to count as a layout table."},
+			"header_only":        {Type: "string", Description: "How tables with a header but no data rows are written: a list of the bold column names, the header with an empty row, or the header alone.", Enum: stringEnum(headerOnlyList, headerOnlyEmptyRow, headerOnlyTable)},
+			"auto_align_numbers": {Type: "boolean", Description: "Right-align the columns whose cells are all numbers."},
+			"nested":             {Type: "string", Description: "How tables in a cell of a data table are written: html writes the outer table and them as HTML tables, for MDX; list flattens each to a list of its rows, with a warning, for plain markdown.", Enum: stringEnum(nestedHTML, nestedList)},
+		}),
+		plugin: func(options interface{}, style componentStyle) md.Plugin {
+			return tablePlugin(*options.(*tableOptions), style)
+		},
+	},
+	{
+		name:       "signatures",
+		newOptions: func() interface{} { return &signatureOptions{Language: "starlark"} },
+		schema: closedObject("Options of the signatures rule set.", map[string]*jsonSchema{
+			"selector": {Type: "string", Description: "CSS selector of the tables that lay out a function signature, written as a code block of it; empty turns the rule set off."},
+			"language": {Type: "string", Description: "Language of the signature code blocks."},
+		}),
+		plugin: func(options interface{}, _ componentStyle) md.Plugin {
+			return signaturePlugin(*options.(*signatureOptions))
+		},
+	},
+	{
+		name:       "pictures",
+		newOptions: func() interface{} { return &pictureOptions{} },
+		schema: closedObject("Options of the pictures rule set.", map[string]*jsonSchema{
+			"passthrough": {Type: "boolean", Description: "Keep <picture> elements as JSX with all their sources."},
+		}),
+		plugin: func(options interface{}, _ componentStyle) md.Plugin {
+			return picturePlugin(*options.(*pictureOptions))
+		},
+	},
+	{
+		name:       "forms",
+		newOptions: func() interface{} { return &formOptions{Buttons: formControlsDrop} },
+		schema: closedObject("Options of the forms rule set.", map[string]*jsonSchema{
+			"buttons": {Type: "string", Description: "What happens to buttons of embedded forms: drop them, or keep their captions as text.", Enum: stringEnum(formControlsDrop, formControlsKeep)},
+		}),
+		plugin: func(options interface{}, _ componentStyle) md.Plugin {
+			return formPlugin(*options.(*formOptions))
+		},
+	},
+	{
+		name:       "devsite-aside",
+		newOptions: func() interface{} { return &asideOptions{} },
+		schema: &jsonSchema{
+			Type:                 "object",
+			Description:          "Component each Devsite <aside> or <blockquote> class becomes; empty leaves the element as is.",
+			AdditionalProperties: &jsonSchema{Type: "string", Enum: stringEnum(append([]string{""}, calloutKinds...)...)},
+		},
+		plugin: func(options interface{}, style componentStyle) md.Plugin {
+			return asidePlugin(*options.(*asideOptions), style)
+		},
+	},
+}
+
+// enabledRuleSet is a rule set the run uses, with its resolved options.
+type enabledRuleSet struct {
+	ruleSet
+	options interface{}
+}
+
+// configFile is the -config YAML file. Each rules entry is either false, to
+// turn the rule set off, or a mapping of the rule set's options:
+//
+//	rules:
+//	  tables: false
+//	  devsite-aside:
+//	    note: Note
+//	    warning: Warning
+//
+// before_convert lists the htmlTransform steps applied to each page.
+type configFile struct {
+	Rules         map[string]interface{} `yaml:"rules"`
+	BeforeConvert []htmlTransform        `yaml:"before_convert"`
+}
+
+// loadConfig reads the config file, if any, and returns the enabled rule
+// sets with their options and the before_convert steps.
+func loadConfig(configPath string) ([]enabledRuleSet, []htmlTransform, error) {
+	var file configFile
+	if configPath != "" {
+		content, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		var raw interface{}
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config %s: %w", configPath, err)
+		}
+		if raw != nil {
+			if problems := configSchema().validate(raw, ""); len(problems) > 0 {
+				return nil, nil, fmt.Errorf("invalid config %s:\n  %s", configPath, strings.Join(problems, "\n  "))
+			}
+		}
+		if err := yaml.UnmarshalStrict(content, &file); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse config %s: %w", configPath, err)
+		}
+		if err := validateTransforms(file.BeforeConvert); err != nil {
+			return nil, nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+		}
+	}
+
+	ruleSets, err := enableRuleSets(file, configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ruleSets, file.BeforeConvert, nil
+}
+
+// enableRuleSets returns the rule sets the config file leaves on, with
+// their options.
+func enableRuleSets(file configFile, configPath string) ([]enabledRuleSet, error) {
+
+	known := make(map[string]bool, len(ruleSets))
+	var enabled []enabledRuleSet
+	for _, set := range ruleSets {
+		known[set.name] = true
+
+		var options interface{}
+		if set.newOptions != nil {
+			options = set.newOptions()
+		}
+
+		switch entry := file.Rules[set.name].(type) {
+		case nil:
+		case bool:
+			if !entry {
+				continue
+			}
+		default:
+			if options == nil {
+				return nil, fmt.Errorf("config %s: rule %s takes no options", configPath, set.name)
+			}
+			raw, err := yaml.Marshal(entry)
+			if err != nil {
+				return nil, err
+			}
+			if err := yaml.UnmarshalStrict(raw, options); err != nil {
+				return nil, fmt.Errorf("config %s: options of rule %s: %w", configPath, set.name, err)
+			}
+		}
+		enabled = append(enabled, enabledRuleSet{ruleSet: set, options: options})
+	}
+
+	var unknown []string
+	for name := range file.Rules {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("config %s: unknown rule(s) %s", configPath, strings.Join(unknown, ", "))
+	}
+	return enabled, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a -config file and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return configPath
+}
+
+func TestRuleSetOptions(t *testing.T) {
+	html := `<aside class="note"><p>Noted.</p></aside><aside class="warning"><p>Careful.</p></aside><pre class="lang-py">x = 1</pre>`
+	tests := []struct {
+		name, config string
+		want         []string
+	}{
+		{"defaults", "", []string{"<Note>\n", "<Warning>\n", "```py\n"}},
+		{"customized", "rules:\n  devsite-aside:\n    note: Tip\n  code-blocks:\n    languages:\n      py: python\n", []string{"<Tip>\n", "<Warning>\n", "```python\n"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		if tt.config != "" {
+			ruleSets, _, err := loadConfig(writeConfig(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.ruleSets = ruleSets
+		}
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page lacks %q:\n%s", tt.name, want, page)
+			}
+		}
+	}
+}
+
+func TestRuleSetConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		"rules:\n  no-such-rule: false\n",
+		"rules:\n  tables:\n    option: 1\n",
+		"rules:\n  code-blocks:\n    no-such-option: 1\n",
+	} {
+		if _, _, err := loadConfig(writeConfig(t, config)); err == nil {
+			t.Errorf("config %q accepted", config)
+		}
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	_, _, err := loadConfig(writeConfig(t, "rules:\n  devsite-aside:\n    note: Notee\n  code-blocks:\n    languages: python\n    typo: 1\n  pictures: yes please\n"))
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, want := range []string{
+		`rules.devsite-aside.note: "Notee" is not one of`,
+		"rules.code-blocks.languages: ",
+		"rules.code-blocks.typo: ",
+		"rules.pictures: ",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not report %q:\n%v", want, err)
+		}
+	}
+
+	if _, _, err := loadConfig(writeConfig(t, "rules:\n  devsite-aside:\n    note: Tip\n  code-blocks:\n    languages:\n      py: python\n  pictures: false\n")); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadRenameMap reads a -rename-map file: a YAML mapping from source paths in
+// the zip to the output paths they should be written to, e.g.
+//
+//	be/general.html: reference/build-encyclopedia.md
+func loadRenameMap(mapPath string) (map[string]string, error) {
+	if mapPath == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(mapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename map: %w", err)
+	}
+
+	var raw map[string]string
+	if err := yaml.UnmarshalStrict(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse rename map %s: %w", mapPath, err)
+	}
+
+	renames := make(map[string]string, len(raw))
+	for source, target := range raw {
+		clean := path.Clean(target)
+		if target == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("rename map %s: output path %q for %s must stay inside the output directory", mapPath, target, source)
+		}
+		renames[path.Clean(source)] = clean
+	}
+	return renames, nil
+}
+
+// warnUnusedRenames reports rename map entries that match no zip entry.
+func warnUnusedRenames(renames map[string]string, files []*zip.File) {
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		names[f.Name] = true
+	}
+
+	var unused []string
+	for source := range renames {
+		if !names[source] {
+			unused = append(unused, source)
+		}
+	}
+	sort.Strings(unused)
+	for _, source := range unused {
+		fmt.Printf("Warning: rename map entry %s matches no file in the zip\n", source)
+	}
+}
+
+// pagePaths returns the path a source page would be written to by default,
+// after -path-template and -output-case, and the path it is actually written
+// to, which differs when the rename map has an entry for it.
+func (c *conversion) pagePaths(sourcePath string) (defaultPath, outputPath string) {
+	defaultPath = sourcePath
+	if isHTMLFile(sourcePath) || c.cfg.apiPages.matches(sourcePath) {
+		defaultPath = changeExtension(sourcePath, ".md")
+	}
+	if c.cfg.pathTemplate != nil {
+		defaultPath = c.cfg.pathTemplate.apply(sourcePath, defaultPath)
+	}
+	defaultPath = applyOutputCase(defaultPath, c.cfg.outputCase)
+
+	if target, ok := c.cfg.renames[sourcePath]; ok {
+		return defaultPath, target
+	}
+	return defaultPath, defaultPath
+}
+
+// outputPathFor returns where a source page is written. Renamed pages get a
+// redirect from the path they would have had.
+func (c *conversion) outputPathFor(sourcePath string) string {
+	defaultPath, outputPath := c.pagePaths(sourcePath)
+	if outputPath != defaultPath {
+		c.redirects = append(c.redirects, newRedirect(defaultPath, outputPath))
+		fmt.Printf("  -> Renamed to %s\n", outputPath)
+	}
+	return outputPath
+}
+
+// Modes accepted by -output-case.
+const (
+	// Keep the path's case but lowercase the extension (.HTML -> .md, .MD -> .md).
+	casePreserve = "preserve"
+	// Lowercase the whole path.
+	caseLower = "lower"
+)
+
+func applyOutputCase(p, mode string) string {
+	if mode == caseLower {
+		return strings.ToLower(p)
+	}
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + strings.ToLower(ext)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenameMap(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "renames.yaml")
+	if err := os.WriteFile(mapPath, []byte("be/general.html: reference/build-encyclopedia.md\nbe/gone.html: reference/gone.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renames, err := loadRenameMap(mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t)
+	cfg.renames = renames
+	written := convertPages(t, cfg, map[string]string{
+		"be/general.html": `<h2>General</h2>`,
+		"be/other.html":   `<h2>Other</h2>`,
+	})
+
+	if !strings.Contains(written["reference/build-encyclopedia.md"], "## General") {
+		t.Errorf("mapped page not at its override path; wrote %v", written)
+	}
+	if _, ok := written["be/general.md"]; ok {
+		t.Error("mapped page also written at its default path")
+	}
+	if !strings.Contains(written["be/other.md"], "## Other") {
+		t.Error("unmapped page not at its default path")
+	}
+	if !strings.Contains(written[redirectsFile], `"source": "/be/general"`) || !strings.Contains(written[redirectsFile], `"destination": "/reference/build-encyclopedia"`) {
+		t.Errorf("redirects lack the rename:\n%s", written[redirectsFile])
+	}
+}
+
+func TestRenameMapOutsideOutput(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "renames.yaml")
+	if err := os.WriteFile(mapPath, []byte("a.html: ../a.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRenameMap(mapPath); err == nil {
+		t.Error("rename map escaping the output directory accepted")
+	}
+}
+
+func TestOutputCase(t *testing.T) {
+	pages := map[string]string{
+		"Docs/Page.HTML": `<p>Page</p>`,
+		"Docs/Other.htm": `<p>Other</p>`,
+		"Docs/README.MD": "Readme",
+	}
+	want := map[string][]string{
+		casePreserve: {"Docs/Page.md", "Docs/Other.md", "Docs/README.md"},
+		caseLower:    {"docs/page.md", "docs/other.md", "docs/readme.md"},
+	}
+	for mode, paths := range want {
+		cfg := testConfig(t)
+		cfg.outputCase = mode
+		written := convertPages(t, cfg, pages)
+		for _, path := range paths {
+			if _, ok := written[path]; !ok {
+				t.Errorf("%s: %s not written; wrote %v", mode, path, written)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+)
+
+// roundTripMarkdown renders converted markdown back to HTML, passing raw
+// HTML and components through, for -check.
+var roundTripMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// sourceWords returns the words of the text the converter is given, without
+// that of elements it drops on purpose.
+func sourceWords(doc *goquery.Document) []string {
+	body := doc.Find("body").Clone()
+	body.Find("script, style, textarea, template, noscript").Remove()
+	return textWords(body)
+}
+
+// textWords splits the text of s into lowercase words of letters and
+// digits, so that markup and escaping on either side do not count as
+// differences. Text nodes are kept apart, since the text of adjacent blocks
+// would otherwise run together.
+func textWords(s *goquery.Selection) []string {
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	for _, n := range s.Nodes {
+		collect(n)
+	}
+	return strings.FieldsFunc(strings.ToLower(b.String()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// checkRoundTrip renders the page's markdown body back to HTML and warns
+// when more than threshold of the source words are missing from its text,
+// which means a rule dropped content.
+func (c *conversion) checkRoundTrip(source []string, body string, threshold float64) error {
+	var rendered bytes.Buffer
+	if err := roundTripMarkdown.Convert([]byte(body), &rendered); err != nil {
+		return fmt.Errorf("failed to render markdown for -check: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(&rendered)
+	if err != nil {
+		return fmt.Errorf("failed to parse rendered markdown for -check: %w", err)
+	}
+
+	// Words are compared as multisets, so moved text is not a loss
+	counts := make(map[string]int)
+	for _, word := range textWords(doc.Selection) {
+		counts[word]++
+	}
+	missing := make(map[string]int)
+	lost := 0
+	for _, word := range source {
+		if counts[word] > 0 {
+			counts[word]--
+			continue
+		}
+		missing[word]++
+		lost++
+	}
+
+	ratio := 0.0
+	if len(source) > 0 {
+		ratio = float64(lost) / float64(len(source))
+	}
+	fmt.Printf("  Check: %.1f%% of %d source words missing\n", ratio*100, len(source))
+	if ratio > threshold {
+		fmt.Printf("  Warning: more than -check-threshold of the text was lost, including %s\n", strings.Join(mostMissing(missing, 5), ", "))
+		c.stats.lossyPages++
+	}
+	return nil
+}
+
+// mostMissing returns up to n of the missing words, most frequent first.
+func mostMissing(missing map[string]int, n int) []string {
+	words := make([]string, 0, len(missing))
+	for word := range missing {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if missing[words[i]] != missing[words[j]] {
+			return missing[words[i]] > missing[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	for i, word := range words {
+		words[i] = strconv.Quote(word)
+	}
+	return words
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// droppingRuleSet converts <x-drop> elements to nothing, standing in for a
+// rule that loses content.
+var droppingRuleSet = enabledRuleSet{ruleSet: ruleSet{
+	name: "dropping",
+	plugin: func(interface{}, componentStyle) md.Plugin {
+		return func(conv *md.Converter) []md.Rule {
+			return []md.Rule{{
+				Filter: []string{"x-drop"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					return md.String("")
+				},
+			}}
+		}
+	},
+}}
+
+// TestCheck checks that -check flags the page a rule dropped text from, and
+// not the pages converted faithfully or whose hidden text is dropped on
+// purpose.
+func TestCheck(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.ruleSets = append(cfg.ruleSets, droppingRuleSet)
+	cfg.check = true
+	cfg.strict["check"] = true
+
+	faithful := map[string]string{
+		"a.html":      `<h1>A</h1><p>Text with <em>emphasis</em>, <code>code</code> and <a href="https://bazel.build">a link</a>.</p><ul><li>One</li><li>Two</li></ul>`,
+		"hidden.html": `<h1>Hidden</h1><p>Shown</p><div hidden>Gone on purpose</div><script>var x = 1;</script>`,
+	}
+	if err := convertTestZip(t, cfg, faithful); err != nil {
+		t.Errorf("faithful pages: run returned %v, want no error", err)
+	}
+
+	lossy := map[string]string{
+		"lossy.html": `<h1>Lossy</h1><p>Kept words</p><x-drop>these words vanish in conversion entirely</x-drop>`,
+	}
+	var strictErr *strictError
+	if err := convertTestZip(t, cfg, lossy); !errors.As(err, &strictErr) || strictErr.code != 64 {
+		t.Errorf("lossy page: run returned %v, want exit status 64", err)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// newConverter builds the converter for a run. One converter may be shared
+// by goroutines converting different pages: the library guards its rules
+// with a lock, and the rules here keep no state between calls, since their
+// options and the package-level tables they read are never written after
+// start-up, and the -rules-dry-run counts are locked. Each goroutine still
+// needs its own documents, since the passes that prepare a page for
+// conversion modify it in place.
+func newConverter(cfg config) *md.Converter {
+	converter := md.NewConverter("", true, nil)
+	add := func(name string, rules ...md.Rule) {
+		converter.AddRules(cfg.ruleCounts.wrap(name, rules)...)
+	}
+	// The <title> would otherwise leak into the body; it becomes the
+	// frontmatter title instead
+	converter.Remove("head")
+	add("emphasis", emphasisRules()...)
+	add("inter-element-space", interElementSpaceRule())
+	add("quotes", quoteRule(cfg.quoteStyle))
+	add("samples", sampleRules(cfg.varStyle)...)
+	add("changes", changeRules(cfg.insStyle)...)
+	add("gauges", gaugeRule(cfg.gaugeStyle))
+	add("name-anchors", nameAnchorRule())
+	add("embeds", embedRule())
+	add("templates", templateRule(cfg.templateAsCode))
+	add("list-items", listItemRule())
+	for _, set := range cfg.ruleSets {
+		converter.Use(cfg.ruleCounts.wrapPlugin(set.name, set.plugin(set.options, cfg.components)))
+	}
+
+	if cfg.paramFields {
+		add("param-fields", paramFieldRule())
+	}
+	if cfg.math == mathKaTeX {
+		add("math", mathRules(cfg.dataAttrs)...)
+	}
+	if cfg.externalNewTab {
+		add("external-links", externalLinkRule(cfg.externalLinkClass))
+	}
+	if cfg.preserveHeadingIDs || cfg.anchorPrefix != "" {
+		add("explicit-anchors", explicitAnchorRule())
+	}
+	if cfg.preserveSectionIDs {
+		add("section-anchors", sectionAnchorRule())
+	}
+	if cfg.emoji == emojiShortcode {
+		add("emoji", emojiRule())
+	}
+	if cfg.maxHeadingDepth > 0 {
+		add("heading-depth", headingDepthRule(cfg.maxHeadingDepth))
+	}
+	if cfg.keepImgDimensions {
+		add("sized-images", sizedImageRule())
+	}
+
+	return converter
+}
+
+// headingDepthRule turns headings deeper than maxDepth into bold paragraphs,
+// keeping their text but taking them out of the page's heading structure.
+// A demoted heading with an id, or the anchor -anchor-prefix gives it, keeps
+// it as an explicit anchor in front of the paragraph, since no anchor is
+// generated for it, so that links to it still land.
+func headingDepthRule(maxDepth int) md.Rule {
+	return md.Rule{
+		Filter: []string{"h1", "h2", "h3", "h4", "h5", "h6"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			if headingLevel(goquery.NodeName(selec)) <= maxDepth {
+				// Fall back to the default heading rule
+				return nil
+			}
+
+			text := strings.TrimSpace(content)
+			if text == "" {
+				return md.String("")
+			}
+			anchor := ""
+			id, ok := selec.Attr(attrExplicitAnchor)
+			if !ok {
+				id = selec.AttrOr("id", "")
+			}
+			if id != "" {
+				anchor = "<a id=\"" + jsxAttrEscaper.Replace(id) + "\"></a>\n\n"
+			}
+			return md.String("\n\n" + anchor + opt.StrongDelimiter + text + opt.StrongDelimiter + "\n\n")
+		},
+	}
+}
+
+// removeHidden drops elements with the hidden attribute or
+// aria-hidden="true". They usually hold icons or text that duplicates
+// visible content.
+func removeHidden(doc *goquery.Document) {
+	doc.Find(`[hidden], [aria-hidden="true"]`).Remove()
+}
+
+// visuallyHiddenSelector matches the classes sites hide text from sight,
+// but not from screen readers, with.
+const visuallyHiddenSelector = ".sr-only, .visually-hidden, .visuallyhidden, .screen-reader-text, .screen-reader-only, .a11y-hidden"
+
+// removeVisuallyHidden drops screen-reader-only elements, for
+// -drop-visually-hidden, as their text mostly repeats what the page shows,
+// such as a "(opens in a new tab)" note or a table caption restating the
+// heading above it. Elements matching keep, a selector that may be empty,
+// stay, for hidden text that is the only label of a link or button.
+func removeVisuallyHidden(doc *goquery.Document, keep string) {
+	hidden := doc.Find(visuallyHiddenSelector)
+	if keep != "" {
+		hidden = hidden.Not(keep)
+	}
+	hidden.Remove()
+}
+
+// unwrapHeadingSelfLinks removes Devsite permalinks that wrap a heading's
+// whole text, <h2><a href="#x">Title</a></h2>, which would otherwise become
+// the linked heading "## [Title](#x)". The link target becomes the heading's
+// id if it has none.
+func unwrapHeadingSelfLinks(doc *goquery.Document) {
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		a := h.Children()
+		if a.Length() != 1 || !a.Is(`a[href^="#"]`) || collapseWhitespace(a.Text()) != collapseWhitespace(h.Text()) {
+			return
+		}
+
+		fragment := strings.TrimPrefix(a.AttrOr("href", ""), "#")
+		id := h.AttrOr("id", "")
+		if id != "" && fragment != id {
+			return
+		}
+		if id == "" && fragment != "" {
+			h.SetAttr("id", fragment)
+		}
+		a.ReplaceWithSelection(a.Contents())
+	})
+}
+
+// attrNameAnchor marks the empty anchors left where legacy <a name>
+// anchors stood, for nameAnchorRule.
+const attrNameAnchor = "data-html2md-name"
+
+// convertNameAnchors turns legacy <a name="x"> anchors into anchor targets.
+// In a heading without an id the name becomes the heading's id, which
+// fragment links are rewritten against; elsewhere a marked empty anchor is
+// left in front of the element. Names that repeat an id are dropped.
+func convertNameAnchors(doc *goquery.Document) {
+	ids := make(map[string]bool)
+	doc.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		ids[s.AttrOr("id", "")] = true
+	})
+
+	doc.Find("a[name]").Each(func(i int, a *goquery.Selection) {
+		name := strings.TrimSpace(a.AttrOr("name", ""))
+		a.RemoveAttr("name")
+		if name != "" && !ids[name] {
+			ids[name] = true
+			marker := &html.Node{Type: html.ElementNode, Data: "a", Attr: []html.Attribute{{Key: attrNameAnchor, Val: name}}}
+			if h := a.Closest("h1, h2, h3, h4, h5, h6"); h.Length() == 0 {
+				a.BeforeNodes(marker)
+			} else if _, ok := h.Attr("id"); ok {
+				h.BeforeNodes(marker)
+			} else {
+				h.SetAttr("id", name)
+			}
+		}
+		if _, ok := a.Attr("href"); !ok {
+			a.ReplaceWithSelection(a.Contents())
+		}
+	})
+}
+
+// nameAnchorRule writes the anchors left by convertNameAnchors.
+func nameAnchorRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"a"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			name, ok := selec.Attr(attrNameAnchor)
+			if !ok {
+				return nil
+			}
+			return md.String(`<a id="` + jsxAttrEscaper.Replace(name) + `"></a>`)
+		},
+	}
+}
+
+// attrExplicitAnchor marks headings whose source id differs from the anchor
+// the renderer generates, so that explicitAnchorRule keeps the id.
+const attrExplicitAnchor = "data-html2md-anchor"
+
+// markExplicitAnchors marks the headings whose id would be lost because the
+// generated anchor differs from it.
+func markExplicitAnchors(doc *goquery.Document, cfg config) {
+	slugs := headingAnchors(doc, cfg)
+	doc.Find("h1[id], h2[id], h3[id], h4[id], h5[id], h6[id]").Each(func(i int, h *goquery.Selection) {
+		id := h.AttrOr("id", "")
+		if slug, ok := slugs[id]; ok && slug != id {
+			h.SetAttr(attrExplicitAnchor, id)
+		}
+	})
+}
+
+// explicitAnchorRule keeps a marked heading's source id as an empty anchor
+// in front of the heading, so links to the old id keep working.
+func explicitAnchorRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"h1", "h2", "h3", "h4", "h5", "h6"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			id, ok := selec.Attr(attrExplicitAnchor)
+			text := collapseWhitespace(content)
+			if !ok || text == "" {
+				return nil
+			}
+
+			prefix := strings.Repeat("#", headingLevel(goquery.NodeName(selec)))
+			return md.String("\n\n<a id=\"" + jsxAttrEscaper.Replace(id) + "\"></a>\n\n" + prefix + " " + text + "\n\n")
+		},
+	}
+}
+
+// attrSectionAnchor marks the sections whose id markSectionAnchors keeps.
+const attrSectionAnchor = "data-html2md-section-anchor"
+
+// markSectionAnchors marks, for -preserve-section-ids-as-anchors, the
+// <section> elements whose id no heading carries, neither as its own id nor
+// as the anchor generated from its text, so that links to the section keep
+// working. Tabs of a selector are left alone: their ids are not reachable
+// on the rendered tabs.
+func markSectionAnchors(doc *goquery.Document, cfg config) {
+	carried := make(map[string]bool)
+	anchors := newAnchorSet(cfg.anchors)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		carried[anchors.add(h.Text())] = true
+		if id := h.AttrOr("id", ""); id != "" {
+			carried[id] = true
+		}
+	})
+	doc.Find("section[id]").Each(func(i int, s *goquery.Selection) {
+		if id := s.AttrOr("id", ""); id != "" && !carried[id] && !s.Parent().Is(tabSetSelector) {
+			s.SetAttr(attrSectionAnchor, "")
+		}
+	})
+}
+
+// sectionAnchorRule writes an empty anchor with the id of a marked section
+// at its start. The id is read here, after -anchor-prefix has prefixed it.
+func sectionAnchorRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"section"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			_, ok := selec.Attr(attrSectionAnchor)
+			id := selec.AttrOr("id", "")
+			if !ok || id == "" {
+				// Sections have no default rule to fall back to
+				return md.String(content)
+			}
+			return md.String("\n\n<a id=\"" + jsxAttrEscaper.Replace(id) + "\"></a>\n\n" + content + "\n\n")
+		},
+	}
+}
+
+func headingLevel(tag string) int {
+	if len(tag) != 2 || tag[0] != 'h' || tag[1] < '1' || tag[1] > '6' {
+		return 0
+	}
+	return int(tag[1] - '0')
+}
+
+// Quotation marks accepted by -quote-style.
+const (
+	quotesCurly    = "curly"
+	quotesStraight = "straight"
+)
+
+// quoteMarks are the opening and closing marks of <q> elements, for the
+// outer level and for quotes nested within it.
+var quoteMarks = map[string][2][2]string{
+	quotesCurly:    {{"“", "”"}, {"‘", "’"}},
+	quotesStraight: {{`"`, `"`}, {"'", "'"}},
+}
+
+// quoteRule puts the text of inline <q> quotations between quotation marks,
+// which browsers add but markdown does not. Nested quotes alternate between
+// double and single marks.
+func quoteRule(style string) md.Rule {
+	return md.Rule{
+		Filter: []string{"q"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			marks := quoteMarks[style][selec.ParentsFiltered("q").Length()%2]
+			return md.String(marks[0] + content + marks[1])
+		},
+	}
+}
+
+// defaultDetailsTitle titles accordions made from <details> without a
+// <summary>.
+const defaultDetailsTitle = "Details"
+
+// detailsPlugin renders <details> disclosure widgets as accordions, using
+// the <summary> as the title. <details open> stays expanded by default.
+// Nested <details> become nested accordions, since a <summary> only titles
+// the <details> it is a direct child of. A run of sibling <details>, such as
+// an FAQ, becomes a single accordion group, written by the first of them.
+func detailsPlugin(style componentStyle) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"summary"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if goquery.NodeName(selec.Parent()) != "details" {
+						return nil
+					}
+					// Rendered as the accordion title instead
+					return md.String("")
+				},
+			},
+			{
+				Filter: []string{"details"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if adjacentDetails(selec.Nodes[0], previousSibling) != nil {
+						// Written in the group of the first <details>
+						return md.String("")
+					}
+					run := []*html.Node{selec.Nodes[0]}
+					for n := adjacentDetails(run[0], nextSibling); n != nil; n = adjacentDetails(n, nextSibling) {
+						run = append(run, n)
+					}
+					if len(run) == 1 {
+						return md.String(detailsAccordion(style, selec, content))
+					}
+
+					accordions := make([]string, len(run))
+					accordions[0] = detailsAccordion(style, selec, content)
+					for i, n := range run[1:] {
+						details := goquery.NewDocumentFromNode(n).Selection
+						accordions[i+1] = detailsAccordion(style, details, conv.Convert(details))
+					}
+					return md.String(style.accordionGroup(accordions))
+				},
+			},
+		}
+	}
+}
+
+func detailsAccordion(style componentStyle, details *goquery.Selection, content string) string {
+	title := strings.Join(strings.Fields(details.ChildrenFiltered("summary").First().Text()), " ")
+	if title == "" {
+		title = defaultDetailsTitle
+	}
+	_, open := details.Attr("open")
+	return style.accordion(title, open, content)
+}
+
+func previousSibling(n *html.Node) *html.Node { return n.PrevSibling }
+func nextSibling(n *html.Node) *html.Node     { return n.NextSibling }
+
+// adjacentDetails returns the <details> element next returns from n, past
+// whitespace and comments, if there is one.
+func adjacentDetails(n *html.Node, next func(*html.Node) *html.Node) *html.Node {
+	for n = next(n); n != nil; n = next(n) {
+		switch {
+		case n.Type == html.CommentNode:
+		case n.Type == html.TextNode && strings.TrimSpace(n.Data) == "":
+		case n.Type == html.ElementNode && n.Data == "details":
+			return n
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// jsxAttrEscaper escapes text for a double-quoted JSX attribute value.
+var jsxAttrEscaper = strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;")
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxHeadingDepth(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.maxHeadingDepth = 4
+	page := convertPages(t, cfg, map[string]string{
+		"page.html": `<h2>Kept</h2><h4>Also kept</h4><h5 id="deep">Deep heading</h5><p>Text</p><h6>Deeper</h6>`,
+	})["page.md"]
+
+	for _, want := range []string{"## Kept\n", "#### Also kept\n", "<a id=\"deep\"></a>\n\n**Deep heading**\n\nText", "**Deeper**"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "##### ") || strings.Contains(page, "###### ") {
+		t.Errorf("page keeps a heading deeper than 4:\n%s", page)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<details open><summary>Shown</summary><p>Visible</p></details><details><summary>Say "hi"</summary><p>Hidden</p></details><details><p>Untitled</p></details>`,
+	})["page.md"]
+
+	for _, want := range []string{
+		"<Accordion title=\"Shown\" defaultOpen>\n\nVisible\n\n</Accordion>",
+		"<Accordion title=\"Say &quot;hi&quot;\">\n\nHidden\n\n</Accordion>",
+		"<Accordion title=\"Details\">\n\nUntitled\n\n</Accordion>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestHeadingSelfLinks(t *testing.T) {
+	html := `<h2><a href="#install-bazel">Install Bazel</a></h2>
+<h2 id="old_id"><a href="#old_id">New Title</a></h2>
+<p><a href="#old_id">back</a></p>`
+	for _, preserve := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.preserveHeadingIDs = preserve
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+
+		for _, want := range []string{"## Install Bazel\n", "## New Title\n", "[back](#new-title)"} {
+			if !strings.Contains(page, want) {
+				t.Errorf("-preserve-heading-ids=%v: page lacks %q:\n%s", preserve, want, page)
+			}
+		}
+		if got := strings.Contains(page, "<a id=\"old_id\"></a>\n\n## New Title"); got != preserve {
+			t.Errorf("-preserve-heading-ids=%v: page keeps the id %v:\n%s", preserve, got, page)
+		}
+		if strings.Contains(page, "install-bazel\"") {
+			t.Errorf("-preserve-heading-ids=%v: anchor kept for an id matching its slug:\n%s", preserve, page)
+		}
+	}
+}
+
+func TestPreserveHidden(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1><p>Visible</p><div hidden>Hidden div</div><p><span aria-hidden="true">Icon</span> label</p>`,
+	}
+	for _, preserve := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.preserveHidden = preserve
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, text := range []string{"Hidden div", "Icon"} {
+			if strings.Contains(page, text) != preserve {
+				t.Errorf("-preserve-hidden=%v: page has %q: %v:\n%s", preserve, text, !preserve, page)
+			}
+		}
+		if !strings.Contains(page, "Visible") {
+			t.Errorf("-preserve-hidden=%v: page lost its visible text:\n%s", preserve, page)
+		}
+	}
+}
+
+func TestDropVisuallyHidden(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1><p><a href="https://bazel.build">Bazel<span class="sr-only"> (opens in a new tab)</span></a></p>
+<p><a class="icon-link" href="https://github.com/bazelbuild/bazel"><span class="visually-hidden">GitHub repository</span></a></p>`,
+	}
+	tests := []struct {
+		drop       bool
+		keep       string
+		has, lacks []string
+	}{
+		{false, "", []string{"opens in a new tab", "GitHub repository"}, nil},
+		{true, "", nil, []string{"opens in a new tab", "GitHub repository"}},
+		{true, ".icon-link .visually-hidden", []string{"[GitHub repository](https://github.com/bazelbuild/bazel)"}, []string{"opens in a new tab"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.dropVisuallyHidden = tt.drop
+		cfg.visuallyHiddenKeep = tt.keep
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, text := range tt.has {
+			if !strings.Contains(page, text) {
+				t.Errorf("-drop-visually-hidden=%v -visually-hidden-keep=%q: page lacks %q:\n%s", tt.drop, tt.keep, text, page)
+			}
+		}
+		for _, text := range tt.lacks {
+			if strings.Contains(page, text) {
+				t.Errorf("-drop-visually-hidden=%v -visually-hidden-keep=%q: page has %q:\n%s", tt.drop, tt.keep, text, page)
+			}
+		}
+		if !strings.Contains(page, "[Bazel") {
+			t.Errorf("-drop-visually-hidden=%v: page lost its visible text:\n%s", tt.drop, page)
+		}
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	html := `<p><q>Outer <q>inner <q>innermost</q></q> text</q></p>`
+	tests := map[string]string{
+		quotesCurly:    "“Outer ‘inner “innermost”’ text”",
+		quotesStraight: `"Outer 'inner "innermost"' text"`,
+	}
+	for style, want := range tests {
+		cfg := testConfig(t)
+		cfg.quoteStyle = style
+		got, err := newConverter(cfg).ConvertString(html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(got) != want {
+			t.Errorf("-quote-style=%s: got %q, want %q", style, got, want)
+		}
+	}
+}
+
+func TestIcons(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<p><a href="https://github.com/bazelbuild/bazel" aria-label="GitHub"><svg viewBox="0 0 16 16"><path d="M8 0"></path></svg></a>
+<a href="https://bazel.build/next"><span class="material-icons" aria-label="Next page">arrow_forward</span></a>
+<a href="https://bazel.build/x"><svg><title>Docs</title><path d="M0"></path></svg></a>
+<a href="https://bazel.build/y" title="Settings"><i class="material-icons">settings</i></a>
+Text <svg aria-hidden="true"><path d="M1"></path></svg><span class="material-icons" role="presentation">star</span>after</p>`,
+	}
+	for _, preserve := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.preserveHidden = preserve
+		page := convertPages(t, cfg, pages)["docs/page.md"]
+		for _, want := range []string{
+			"[GitHub](https://github.com/bazelbuild/bazel)",
+			"[Next page](https://bazel.build/next)",
+			"[Docs](https://bazel.build/x)",
+			`[Settings](https://bazel.build/y "Settings")`,
+			"Text after",
+		} {
+			if !strings.Contains(page, want) {
+				t.Errorf("-preserve-hidden=%v: page lacks %q:\n%s", preserve, want, page)
+			}
+		}
+		for _, leaked := range []string{"arrow_forward", "settings", "star", "M8"} {
+			if strings.Contains(page, leaked) {
+				t.Errorf("-preserve-hidden=%v: page contains %q:\n%s", preserve, leaked, page)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ruleCounts counts, for -rules-dry-run, how many elements each group of
+// rules converted on the page being converted. The rules of a group are
+// counted when they handle an element rather than falling through to
+// another rule. A nil *ruleCounts counts nothing.
+type ruleCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// wrap returns the rules, counting under name each element they handle.
+func (r *ruleCounts) wrap(name string, rules []md.Rule) []md.Rule {
+	if r == nil {
+		return rules
+	}
+	wrapped := make([]md.Rule, len(rules))
+	for i, rule := range rules {
+		replacement := rule.Replacement
+		wrapped[i] = md.Rule{
+			Filter: rule.Filter,
+			Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+				result := replacement(content, selec, opt)
+				if result != nil {
+					r.mu.Lock()
+					r.counts[name]++
+					r.mu.Unlock()
+				}
+				return result
+			},
+		}
+	}
+	return wrapped
+}
+
+// wrapPlugin counts the rules of a rule set under its name.
+func (r *ruleCounts) wrapPlugin(name string, plugin md.Plugin) md.Plugin {
+	if r == nil {
+		return plugin
+	}
+	return func(conv *md.Converter) []md.Rule {
+		return r.wrap(name, plugin(conv))
+	}
+}
+
+// take returns the counts of the page as "name count" pairs, most used
+// first, or "none", and starts counting the next page.
+func (r *ruleCounts) take() string {
+	r.mu.Lock()
+	counts := r.counts
+	r.counts = make(map[string]int)
+	r.mu.Unlock()
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " " + strconv.Itoa(counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRuleCounts enables the signatures rule set and checks that a page
+// with a signature table reports its count, while a page without one does
+// not, and that -rules-dry-run writes no pages.
+func TestRuleCounts(t *testing.T) {
+	const table = `<h2 id="glob">glob</h2><table class="signature"><tr><th>Parameter</th><th>Default</th></tr><tr><td><code>include</code></td><td><code>[]</code></td></tr></table>`
+	cfg := testConfig(t)
+	ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  signatures:\n    selector: table.signature\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ruleSets = ruleSets
+	cfg.ruleCounts = &ruleCounts{counts: make(map[string]int)}
+
+	converter := newConverter(cfg)
+	for _, tt := range []struct{ html, want string }{
+		{table + table + `<p>Text</p>`, "signatures 2"},
+		{`<p>Text</p>`, ""},
+	} {
+		if _, err := converter.ConvertString(tt.html); err != nil {
+			t.Fatal(err)
+		}
+		got := cfg.ruleCounts.take()
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: rules %s, want %s", tt.html, got, tt.want)
+		}
+		if tt.want == "" && strings.Contains(got, "signatures") {
+			t.Errorf("%s: rules %s, want no signatures", tt.html, got)
+		}
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, map[string]string{"page.html": table})
+	outputDir := filepath.Join(t.TempDir(), "output")
+	if err := convertZipToMarkdown(zipPath, outputDir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "page.md")); err == nil {
+		t.Error("-rules-dry-run wrote the page")
+	}
+}
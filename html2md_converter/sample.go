@@ -0,0 +1,45 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// isPageEntry reports whether the zip entry becomes a page of the output:
+// an HTML page, a markdown file or a rendered API dump.
+func (c *conversion) isPageEntry(f *zip.File) bool {
+	if f.FileInfo().IsDir() {
+		return false
+	}
+	return isHTMLFile(f.Name) || isMarkdownFile(f.Name) || c.cfg.apiPages.matches(f.Name)
+}
+
+// sampleEntries returns, for -sample, n pages of the zip picked at random
+// with the given seed, in zip order, so that the same seed picks the same
+// pages. Links to the other pages are still rewritten, since they stay in
+// c.files.
+func (c *conversion) sampleEntries(entries []*zip.File, n int, seed int64) []*zip.File {
+	var pages []int
+	for i, f := range entries {
+		if c.isPageEntry(f) {
+			pages = append(pages, i)
+		}
+	}
+	if n >= len(pages) {
+		n = len(pages)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(pages), func(i, j int) { pages[i], pages[j] = pages[j], pages[i] })
+	picked := pages[:n]
+	sort.Ints(picked)
+
+	sample := make([]*zip.File, len(picked))
+	for i, index := range picked {
+		sample[i] = entries[index]
+	}
+	fmt.Printf("Sampled %d of %d page(s) with seed %d\n", len(sample), len(pages), seed)
+	return sample
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// samplePages are eight pages and an image, which is not a page.
+func samplePages() map[string]string {
+	pages := map[string]string{"docs/logo.png": "PNG"}
+	for i := 1; i <= 8; i++ {
+		pages[fmt.Sprintf("docs/page%d.html", i)] = fmt.Sprintf("<h1>Page %d</h1><p>Body</p>", i)
+	}
+	return pages
+}
+
+// writtenPages returns the sorted paths of the pages written.
+func writtenPages(written map[string]string) []string {
+	var pages []string
+	for path := range written {
+		if strings.HasSuffix(path, ".md") {
+			pages = append(pages, path)
+		}
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+func TestMaxFiles(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.maxFiles = 5
+	cfg.manifestPath = filepath.Join(t.TempDir(), "manifest.json")
+	pages := writtenPages(convertPages(t, cfg, samplePages()))
+	if len(pages) != 5 {
+		t.Errorf("-max-files 5 wrote %q", pages)
+	}
+
+	content, err := os.ReadFile(cfg.manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []manifestRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Errorf("manifest of -max-files 5 has %d record(s): %v", len(records), records)
+	}
+}
+
+// TestSample checks that -sample converts the given number of pages, and
+// that a seed always picks the same ones.
+func TestSample(t *testing.T) {
+	picks := make(map[int64][]string)
+	for _, seed := range []int64{1, 1, 2} {
+		cfg := testConfig(t)
+		cfg.sample = 3
+		cfg.sampleSeed = seed
+		pages := writtenPages(convertPages(t, cfg, samplePages()))
+		if len(pages) != 3 {
+			t.Errorf("-sample 3 wrote %q", pages)
+		}
+		if picked, ok := picks[seed]; ok && strings.Join(picked, " ") != strings.Join(pages, " ") {
+			t.Errorf("seed %d picked %q, then %q", seed, picked, pages)
+		}
+		picks[seed] = pages
+	}
+}
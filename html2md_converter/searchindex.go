@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// indexEntry is one line of the -out-index JSONL file, in the shape search
+// indexers such as Algolia or Typesense ingest.
+type indexEntry struct {
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Headings []string `json:"headings"`
+	Text     string   `json:"text"`
+}
+
+func newIndexEntry(pagePath, title, body string) indexEntry {
+	headings := []string{}
+	for _, line := range proseLines(body) {
+		if m := headingLineRegex.FindStringSubmatch(line.Raw); m != nil {
+			headings = append(headings, collapseWhitespace(headingText(m[1])))
+		}
+	}
+	return indexEntry{Path: pagePath, Title: title, Headings: headings, Text: stripMarkdown(body)}
+}
+
+var (
+	listMarkerRegex     = regexp.MustCompile(`^(?:[-*+]|\d+\.)\s+`)
+	tableDelimiterRegex = regexp.MustCompile(`^\|?(?:\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	htmlTagRegex        = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	emphasisRegex       = regexp.MustCompile(`(\*\*|__|\*|~~)(\S(?:.*?\S)?)(\*\*|__|\*|~~)`)
+)
+
+// stripMarkdown reduces a page to its plain text on a single line: markup,
+// frontmatter and tags are removed while link, image and code text is kept.
+func stripMarkdown(md string) string {
+	var words []string
+	inFrontmatter := strings.HasPrefix(md, "---\n")
+	for i, line := range strings.Split(md, "\n") {
+		if inFrontmatter {
+			if i > 0 && line == "---" {
+				inFrontmatter = false
+			}
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") || tableDelimiterRegex.MatchString(line) {
+			continue
+		}
+		if m := headingLineRegex.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		for strings.HasPrefix(line, ">") {
+			line = strings.TrimSpace(line[1:])
+		}
+		line = listMarkerRegex.ReplaceAllString(line, "")
+		line = inlineLinkRegex.ReplaceAllString(line, "$1")
+		line = htmlTagRegex.ReplaceAllString(line, " ")
+		line = emphasisRegex.ReplaceAllString(line, "$2")
+		line = strings.NewReplacer("`", "", "|", " ", `\`, "").Replace(line)
+		words = append(words, strings.Fields(html.UnescapeString(line))...)
+	}
+	return strings.Join(words, " ")
+}
+
+func writeSearchIndex(outputPath string, entries []indexEntry) error {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode index entry for %s: %w", entry.Path, err)
+		}
+	}
+
+	if err := writeFile(outputPath, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+
+	fmt.Printf("Wrote %d index entries to %s\n", len(entries), outputPath)
+	return nil
+}
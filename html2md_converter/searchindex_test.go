@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	md := "---\ntitle: 'Page'\n---\n\n## Build **fast**\n\n> A [linked](other.md) note with `code` &amp; ![an image](x.png).\n\n- one\n- two\n\n| A | B |\n| --- | --- |\n| 1 | 2 |\n\n```\nbazel build //...\n```\n\n<Note>Tagged</Note>\n"
+	want := "Build fast A linked note with code & an image. one two A B 1 2 bazel build //... Tagged"
+	if got := stripMarkdown(md); got != want {
+		t.Errorf("stripMarkdown = %q, want %q", got, want)
+	}
+}
+
+func TestOutIndex(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.outIndex = filepath.Join(t.TempDir(), "index.jsonl")
+	convertPages(t, cfg, map[string]string{
+		"docs/page.html": `<h1>Sample page</h1><p>Intro with <a href="https://bazel.build">a link</a>.</p><h2>First <code>step</code></h2><p>Do <strong>this</strong>.</p><h3>Detail</h3>`,
+	})
+	content, err := os.ReadFile(cfg.outIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("index has %d lines, want 1:\n%s", len(lines), content)
+	}
+	var entry indexEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := indexEntry{Path: "docs/page.md", Title: "Sample page", Headings: []string{"First step", "Detail"}, Text: "Intro with a link. First step Do this. Detail"}
+	if entry.Path != want.Path || entry.Title != want.Title || strings.Join(entry.Headings, "|") != strings.Join(want.Headings, "|") || entry.Text != want.Text {
+		t.Errorf("index entry is %+v, want %+v", entry, want)
+	}
+}
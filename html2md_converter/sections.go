@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// parseSectionList splits a comma-separated -drop-sections value into
+// heading texts, compared case-insensitively.
+func parseSectionList(list string) map[string]bool {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(collapseWhitespace(name)); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// dropSections removes the sections whose heading text is in names: the
+// heading and everything after it up to the next heading of the same or a
+// higher level. A section that reaches the end of an element wrapping its
+// heading goes on after that element, as the document outline does.
+func dropSections(doc *goquery.Document, names map[string]bool) {
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, h *goquery.Selection) {
+		// Headings inside an earlier dropped section are already gone
+		if h.Closest("body").Length() == 0 || !names[strings.ToLower(collapseWhitespace(h.Text()))] {
+			return
+		}
+		level := headingLevel(goquery.NodeName(h))
+
+		node := h.Nodes[0]
+		section := []*html.Node{node}
+	walk:
+		for {
+			for next := node.NextSibling; next != nil; next = next.NextSibling {
+				if containsHeading(next, level) {
+					break walk
+				}
+				section = append(section, next)
+			}
+			node = node.Parent
+			if node == nil || node.Data == "body" {
+				break
+			}
+		}
+		for _, n := range section {
+			n.Parent.RemoveChild(n)
+		}
+	})
+}
+
+// containsHeading reports whether n is or contains a heading of level or
+// higher.
+func containsHeading(n *html.Node, level int) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if l := headingLevel(n.Data); l > 0 && l <= level {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if containsHeading(c, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimTrailingEmptySections removes the headings that end the converted
+// markdown with nothing after them, such as a See also section whose links
+// were all page chrome, for -trim-trailing-empty-sections. A heading left
+// last by that is removed too, and so are the explicit anchors in front of
+// a removed heading.
+func trimTrailingEmptySections(markdown string) string {
+	trimmed := strings.TrimRight(markdown, "\n")
+	lines := strings.Split(trimmed, "\n")
+	end := len(lines)
+	for end > 0 && headingLineRegex.MatchString(lines[end-1]) {
+		end--
+		for end > 0 && (strings.TrimSpace(lines[end-1]) == "" || anchorLineRegex.MatchString(strings.TrimSpace(lines[end-1]))) {
+			end--
+		}
+	}
+	if end == len(lines) {
+		return markdown
+	}
+	// Keep the line breaks the markdown ends with
+	return strings.Join(lines[:end], "\n") + markdown[len(trimmed):]
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDropSections(t *testing.T) {
+	pages := map[string]string{
+		"docs/page.html": `<h1>Page</h1>
+<h2>Usage</h2><p>Kept usage.</p>
+<h2>Was this   HELPFUL?</h2><p>Dropped rating.</p><h3>Details</h3><p>Dropped details.</p>
+<h2>Next</h2><p>Kept next.</p>
+<div class="footer"><h3>Feedback</h3><p>Dropped feedback.</p></div><p>Dropped after the wrapper.</p>
+<h2>Last</h2><p>Kept last.</p>`,
+		"docs/other.html": `<h1>Other</h1><p><a href="page.html#next">Next</a></p>`,
+	}
+	cfg := testConfig(t)
+	cfg.dropSections = parseSectionList("Was this helpful?, Feedback")
+	written := convertPages(t, cfg, pages)
+	page := written["docs/page.md"]
+	for _, want := range []string{"## Usage\n\nKept usage.", "## Next\n\nKept next.", "## Last\n\nKept last."} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "Dropped") || strings.Contains(page, "HELPFUL") {
+		t.Errorf("page keeps a dropped section:\n%s", page)
+	}
+	if other := written["docs/other.md"]; !strings.Contains(other, "(page.md#next)") {
+		t.Errorf("other page does not link the kept section:\n%s", other)
+	}
+}
+
+func TestTrimTrailingEmptySections(t *testing.T) {
+	tests := []struct{ markdown, want string }{
+		{"Intro\n\n## Usage\n\nText.\n\n## See also\n", "Intro\n\n## Usage\n\nText.\n"},
+		{"Intro\n\n## More\n\n### Empty\n\n<a id=\"x\"></a>\n\n### Also empty\n\n", "Intro\n\n"},
+		{"Intro\n\n## Usage\n\nText.\n", "Intro\n\n## Usage\n\nText.\n"},
+		{"## Only\n", "\n"},
+	}
+	for _, tt := range tests {
+		if got := trimTrailingEmptySections(tt.markdown); got != tt.want {
+			t.Errorf("trimTrailingEmptySections(%q) = %q, want %q", tt.markdown, got, tt.want)
+		}
+	}
+
+	html := `<h1>Page</h1><h2>Usage</h2><p>Text.</p><h2>See also</h2><ul hidden><li><a href="a.html">A</a></li></ul>`
+	for _, trim := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.trimTrailingEmptySections = trim
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		if strings.Contains(page, "## See also") == trim || !strings.HasSuffix(strings.TrimSpace(page), "Text.") == trim {
+			t.Errorf("trim %v: page is\n%s", trim, page)
+		}
+	}
+}
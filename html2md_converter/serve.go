@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// previewPollInterval is how often -serve checks the input for changes.
+const previewPollInterval = time.Second
+
+// previewPage is the HTML around a page rendered by -serve. The script
+// reloads the page once the preview is rebuilt.
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 50em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+code { font-size: 90%; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+{{.Body}}
+<script>
+(function poll(version) {
+  setTimeout(function () {
+    fetch("/_preview/version").then(function (r) { return r.text(); }).then(function (v) {
+      if (v !== version) { location.reload(); } else { poll(version); }
+    }, function () { poll(version); });
+  }, 1000);
+})("{{.Version}}");
+</script>
+</body>
+</html>
+`))
+
+// previewServer serves the output of the latest conversion of the input,
+// converting it again whenever the input changes.
+type previewServer struct {
+	inputPath string
+	cfg       config
+
+	mu      sync.RWMutex
+	dir     string
+	version int
+}
+
+// runPreviewServer converts the input into a temporary directory and serves
+// its pages, rendered to HTML, at addr until interrupted.
+func runPreviewServer(addr, inputPath string, cfg config) error {
+	if inputPath == "-" {
+		return fmt.Errorf("-serve needs a -zip file to watch, not stdin")
+	}
+	p := &previewServer{inputPath: inputPath, cfg: cfg}
+	modified, err := p.rebuild()
+	if err != nil {
+		return err
+	}
+	defer func() { os.RemoveAll(p.outputDir()) }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go p.watch(ctx, modified)
+
+	server := &http.Server{Addr: addr, Handler: p}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	fmt.Printf("Serving a preview of %s at http://%s/\n", inputPath, addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// rebuild converts the input into a new directory, which replaces the one
+// served. It returns the modification time of the input it converted.
+func (p *previewServer) rebuild() (time.Time, error) {
+	info, err := os.Stat(p.inputPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	dir, err := os.MkdirTemp("", "html2md-preview-*")
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Warnings treated as errors still leave pages worth looking at
+	var strictErr *strictError
+	if err := convertZipToMarkdown(p.inputPath, dir, p.cfg); err != nil && !errors.As(err, &strictErr) {
+		os.RemoveAll(dir)
+		return time.Time{}, err
+	}
+
+	p.mu.Lock()
+	old := p.dir
+	p.dir = dir
+	p.version++
+	p.mu.Unlock()
+	if old != "" {
+		os.RemoveAll(old)
+	}
+	return info.ModTime(), nil
+}
+
+// watch rebuilds the preview whenever the input's modification time
+// changes. A failed rebuild keeps the previous output.
+func (p *previewServer) watch(ctx context.Context, modified time.Time) {
+	ticker := time.NewTicker(previewPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(p.inputPath)
+		if err != nil || info.ModTime().Equal(modified) {
+			continue
+		}
+		fmt.Printf("%s changed, converting it again\n", p.inputPath)
+		if m, err := p.rebuild(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			modified = info.ModTime()
+		} else {
+			modified = m
+		}
+	}
+}
+
+func (p *previewServer) outputDir() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.dir
+}
+
+func (p *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	dir, version := p.dir, p.version
+	p.mu.RUnlock()
+
+	if r.URL.Path == "/_preview/version" {
+		fmt.Fprint(w, version)
+		return
+	}
+
+	target := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if target == "" {
+		target = "."
+	}
+	file, found := resolveLocalTarget(dir, target)
+	if !found && target == "." {
+		p.serveIndex(w, dir, version)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	if !isPageFile(file) {
+		http.ServeFile(w, r, file)
+		return
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	title, body := splitPreviewFrontmatter(string(content))
+	var rendered bytes.Buffer
+	if err := roundTripMarkdown.Convert([]byte(body), &rendered); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if title == "" {
+		title = target
+	}
+	p.render(w, title, rendered.String(), version)
+}
+
+// serveIndex lists the pages of the preview when there is no index page.
+func (p *previewServer) serveIndex(w http.ResponseWriter, dir string, version int) {
+	var pages []string
+	filepath.WalkDir(dir, func(file string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && isPageFile(file) {
+			pages = append(pages, filepath.ToSlash(mustRel(dir, file)))
+		}
+		return nil
+	})
+	sort.Strings(pages)
+
+	var b strings.Builder
+	b.WriteString("<h1>Pages</h1>\n<ul>\n")
+	for _, page := range pages {
+		b.WriteString(`<li><a href="/` + template.HTMLEscapeString(page) + `">` + template.HTMLEscapeString(page) + "</a></li>\n")
+	}
+	b.WriteString("</ul>\n")
+	p.render(w, "Pages", b.String(), version)
+}
+
+func (p *previewServer) render(w http.ResponseWriter, title, body string, version int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	previewPage.Execute(w, struct {
+		Title   string
+		Body    template.HTML
+		Version string
+	}{title, template.HTML(body), strconv.Itoa(version)})
+}
+
+// splitPreviewFrontmatter returns the title in the page's frontmatter, if
+// any, and the page without the frontmatter.
+func splitPreviewFrontmatter(content string) (title, body string) {
+	fm, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		return "", content
+	}
+	end := strings.Index(fm, "\n---\n")
+	if end < 0 {
+		return "", content
+	}
+	var fields struct {
+		Title string `yaml:"title"`
+	}
+	yaml.Unmarshal([]byte(fm[:end]), &fields)
+	return fields.Title, fm[end+len("\n---\n"):]
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPreviewServer converts a zip as -serve does and checks that the
+// server returns the converted page rendered to HTML, and lists the pages
+// at the root.
+func TestPreviewServer(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, map[string]string{
+		"docs/page.html": `<h1>Page title</h1><h2>Usage</h2><p>Run <code>bazel build</code>.</p><table><tr><th>Flag</th></tr><tr><td>-c</td></tr></table>`,
+	})
+	p := &previewServer{inputPath: zipPath, cfg: testConfig(t)}
+	if _, err := p.rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(p.outputDir())
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/docs/page", []string{"<title>Page title</title>", "<h2>Usage</h2>", "<code>bazel build</code>", "<th>Flag</th>"}},
+		{"/docs/page.md", []string{"<h2>Usage</h2>"}},
+		{"/", []string{`<a href="/docs/page.md">docs/page.md</a>`}},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", tt.path, resp.StatusCode)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("GET %s: response lacks %s:\n%s", tt.path, want, body)
+			}
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing: status %d, want 404", resp.StatusCode)
+	}
+}
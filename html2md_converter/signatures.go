@@ -0,0 +1,184 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// signatureOptions configures the signatures rule set, which writes the
+// tables some function reference pages lay signatures out in as a code
+// block of the signature.
+type signatureOptions struct {
+	// Selector matches the signature tables; empty turns the rule set off.
+	Selector string `yaml:"selector"`
+	// Language tags the code block.
+	Language string `yaml:"language"`
+}
+
+// signatureMaxLine is the longest signature written on one line; longer
+// ones get a line per parameter.
+const signatureMaxLine = 80
+
+var (
+	// A function name, e.g. glob or native.cc_library
+	signatureNameRegex = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+	// A parameter, *args or **kwargs, or a lone * or / marker
+	signatureParamRegex = regexp.MustCompile(`^(?:\*{0,2}[A-Za-z_]\w*|\*|/)$`)
+	// "default is X" or "defaults to X" in a cell of a parameter's row
+	signatureDefaultRegex = regexp.MustCompile(`(?i)\bdefault(?:s to| is| value is|:)\s*(.+?)\.?$`)
+)
+
+// signatureParam is a parameter of a reconstructed signature.
+type signatureParam struct {
+	name, value string
+	// keywordOnly and positionalOnly come from the row's kind column
+	keywordOnly, positionalOnly bool
+}
+
+func signaturePlugin(options signatureOptions) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		if options.Selector == "" {
+			return nil
+		}
+		return []md.Rule{
+			{
+				Filter: []string{"table"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if !selec.Is(options.Selector) {
+						return nil
+					}
+					signature, ok := tableSignature(selec)
+					if !ok {
+						// Fall back to the tables rule set
+						return nil
+					}
+					return md.String(fencedCode(signature, options.Language, "", opt))
+				},
+			},
+		}
+	}
+}
+
+// tableSignature reconstructs the signature a table lays out, in the order
+// of its rows. The function is named by the table's data-function
+// attribute, its caption or the heading before it. Each row names a
+// parameter in its first column, or the one headed Parameter, Argument or
+// Name; a Default column, or "default is X" in the row, gives its default,
+// and a Kind column saying keyword-only or positional-only places the * and
+// / markers. Tables with any other column, such as descriptions, are not
+// signatures.
+func tableSignature(table *goquery.Selection) (string, bool) {
+	name := strings.TrimSuffix(collapseWhitespace(table.AttrOr("data-function", "")), "()")
+	if name == "" {
+		name = strings.TrimSuffix(collapseWhitespace(table.ChildrenFiltered("caption").Text()), "()")
+	}
+	if name == "" {
+		name = strings.TrimSuffix(collapseWhitespace(table.PrevAllFiltered("h1, h2, h3, h4, h5, h6").First().Text()), "()")
+	}
+	if !signatureNameRegex.MatchString(name) {
+		return "", false
+	}
+
+	rows := tableRows(table)
+	nameColumn, defaultColumn, kindColumn := 0, -1, -1
+	if isHeaderRow(table) {
+		for i, cell := range rows.First().ChildrenFiltered("th").Nodes {
+			switch strings.ToLower(collapseWhitespace(goquery.NewDocumentFromNode(cell).Text())) {
+			case "parameter", "parameters", "argument", "arguments", "name":
+				nameColumn = i
+			case "default", "default value":
+				defaultColumn = i
+			case "kind":
+				kindColumn = i
+			case "type":
+			default:
+				return "", false
+			}
+		}
+		rows = rows.Slice(1, rows.Length())
+	}
+	if rows.Length() == 0 {
+		return "", false
+	}
+
+	var params []signatureParam
+	ok := true
+	rows.EachWithBreak(func(i int, tr *goquery.Selection) bool {
+		cells := tr.ChildrenFiltered("td, th")
+		cell := func(column int) string {
+			if column < 0 || column >= cells.Length() {
+				return ""
+			}
+			return collapseWhitespace(cells.Eq(column).Text())
+		}
+		p := signatureParam{name: cell(nameColumn)}
+		if !signatureParamRegex.MatchString(p.name) {
+			ok = false
+			return false
+		}
+		if defaultColumn >= 0 {
+			p.value = cell(defaultColumn)
+		} else {
+			for column := range cells.Nodes {
+				if m := signatureDefaultRegex.FindStringSubmatch(cell(column)); m != nil {
+					p.value = m[1]
+				}
+			}
+		}
+		switch strings.ToLower(p.value) {
+		case "-", "–", "—", "n/a", "required", "mandatory":
+			p.value = ""
+		}
+		kind := strings.ToLower(cell(kindColumn))
+		p.keywordOnly = strings.Contains(kind, "keyword")
+		p.positionalOnly = strings.Contains(kind, "positional")
+		params = append(params, p)
+		return true
+	})
+	if !ok {
+		return "", false
+	}
+	return formatSignature(name, params), true
+}
+
+// formatSignature writes the signature on one line, or with a line per
+// parameter when that line would be too long. A * goes before the first
+// keyword-only parameter unless *args or a * row precedes it, and a / after
+// the last positional-only one.
+func formatSignature(name string, params []signatureParam) string {
+	lastPositional := -1
+	for i, p := range params {
+		if p.positionalOnly {
+			lastPositional = i
+		}
+	}
+
+	var parts []string
+	starred := false
+	for i, p := range params {
+		if strings.HasPrefix(p.name, "*") {
+			starred = true
+		}
+		if p.keywordOnly && !starred {
+			parts = append(parts, "*")
+			starred = true
+		}
+		part := p.name
+		if p.value != "" {
+			part += "=" + p.value
+		}
+		parts = append(parts, part)
+		if i == lastPositional {
+			parts = append(parts, "/")
+		}
+	}
+
+	line := name + "(" + strings.Join(parts, ", ") + ")"
+	if len(line) <= signatureMaxLine {
+		return line
+	}
+	return name + "(\n    " + strings.Join(parts, ",\n    ") + ",\n)"
+}
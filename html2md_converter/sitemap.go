@@ -0,0 +1,79 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// sitemapFile is written at the root of the output directory with
+// -emit-sitemap.
+const sitemapFile = "sitemap.xml"
+
+// sitemapEntry is a converted page as the sitemap lists it.
+type sitemapEntry struct {
+	Path string
+	// Modified is the zip entry's modification time, when -preserve-mtime
+	// gives it to the page
+	Modified time.Time
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapEntry returns the sitemap entry of the page written for f.
+func (c *conversion) sitemapEntry(f *zip.File, pagePath string) sitemapEntry {
+	entry := sitemapEntry{Path: pagePath}
+	if c.cfg.preserveMtime {
+		entry.Modified = f.Modified
+	}
+	return entry
+}
+
+// validSiteBaseURL reports whether -site-base-url is an absolute http or
+// https URL, as sitemap locations must be.
+func validSiteBaseURL(base string) bool {
+	u, err := url.Parse(base)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// writeSitemap writes the sitemap.xml of the converted pages, in the order
+// they were converted. Each page is at its served URL under baseURL, as
+// -link-base links to it.
+func writeSitemap(outputDir, baseURL, trailingSlash string, entries []sitemapEntry) error {
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, entry := range entries {
+		u := sitemapURL{Loc: absoluteLink(baseURL, entry.Path, trailingSlash)}
+		if parsed, err := url.Parse(u.Loc); err == nil {
+			// Locations must be escaped, e.g. spaces in paths
+			u.Loc = parsed.String()
+		}
+		if !entry.Modified.IsZero() {
+			u.LastMod = entry.Modified.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", sitemapFile, err)
+	}
+	outputPath := filepath.Join(outputDir, sitemapFile)
+	if err := writeFile(outputPath, append([]byte(xml.Header), append(data, '\n')...)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sitemapFile, err)
+	}
+
+	fmt.Printf("Wrote %d page(s) to %s\n", len(entries), outputPath)
+	return nil
+}
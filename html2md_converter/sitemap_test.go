@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestSitemap(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html":          `<h1>A</h1><p>Body</p>`,
+		"docs/guide/b c.html":  `<h1>B</h1><p>Body</p>`,
+		"docs/images/logo.png": "PNG",
+	}
+	cfg := testConfig(t)
+	cfg.emitSitemap = true
+	cfg.siteBaseURL = "https://bazel.build"
+	written := convertPages(t, cfg, pages)
+
+	var set sitemapURLSet
+	if err := xml.Unmarshal([]byte(written[sitemapFile]), &set); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://bazel.build/docs/a", "https://bazel.build/docs/guide/b%20c"}
+	if len(set.URLs) != len(want) {
+		t.Fatalf("sitemap lists %v, want %q", set.URLs, want)
+	}
+	for i, u := range set.URLs {
+		if u.Loc != want[i] {
+			t.Errorf("sitemap location %d is %s, want %s", i, u.Loc, want[i])
+		}
+		if u.LastMod == "" {
+			t.Errorf("%s has no lastmod under -preserve-mtime", u.Loc)
+		}
+	}
+}
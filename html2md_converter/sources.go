@@ -0,0 +1,62 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// openSources opens every zip directly inside the -sources-root directory, in
+// name order. Pages are only converted from the main zip, but images they
+// reference are looked up in these too, so a page can use an image that
+// another zip of the same site holds at the same path.
+func openSources(root string) ([]*zip.ReadCloser, error) {
+	if root == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read -sources-root: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var sources []*zip.ReadCloser
+	for _, name := range names {
+		r, err := zip.OpenReader(filepath.Join(root, name))
+		if err != nil {
+			closeSources(sources)
+			return nil, fmt.Errorf("failed to open source zip %s: %w", name, err)
+		}
+		sources = append(sources, r)
+	}
+	fmt.Printf("Resolving assets across %d zip(s) in %s\n", len(sources), root)
+	return sources, nil
+}
+
+func closeSources(sources []*zip.ReadCloser) {
+	for _, r := range sources {
+		r.Close()
+	}
+}
+
+// sourceFiles indexes the entries of each source zip by name.
+func sourceFiles(sources []*zip.ReadCloser) []map[string]*zip.File {
+	indexes := make([]map[string]*zip.File, len(sources))
+	for i, r := range sources {
+		indexes[i] = make(map[string]*zip.File, len(r.File))
+		for _, f := range r.File {
+			indexes[i][f.Name] = f
+		}
+	}
+	return indexes
+}
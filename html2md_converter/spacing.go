@@ -0,0 +1,92 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// interElementSpace stands in for whitespace-only text between two inline
+// elements until restoreInterElementSpaces decides on the space.
+const interElementSpace = "\uE002"
+
+// Spaces around one or more interElementSpace stand-ins
+var interElementSpaceRegex = regexp.MustCompile(`[ \x{E002}]*\x{E002}[ \x{E002}]*`)
+
+// interElementSpaceRule keeps the whitespace-only text between two inline
+// elements. The library drops such text and relies on links, code and
+// emphasis to pad themselves, so "<span>a</span> <img>" lost its space,
+// while a padded element next to a space that is kept would get two.
+func interElementSpaceRule() md.Rule {
+	return md.Rule{
+		Filter: []string{"#text"},
+		Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+			node := selec.Nodes[0]
+			if strings.TrimSpace(node.Data) != "" || !isInlineNode(node.PrevSibling) || !isInlineNode(node.NextSibling) {
+				return nil
+			}
+			if selec.Closest("pre, code").Length() > 0 {
+				return nil
+			}
+			return md.String(interElementSpace)
+		},
+	}
+}
+
+// extraInlineElements are inline in HTML but missing from the library's
+// list of inline elements.
+var extraInlineElements = map[string]bool{
+	"progress": true, "meter": true,
+}
+
+// isInlineNode reports whether n is text or an inline element other than a
+// line break.
+func isInlineNode(n *html.Node) bool {
+	if n == nil {
+		return false
+	}
+	if n.Type == html.TextNode {
+		return true
+	}
+	return n.Type == html.ElementNode && n.Data != "br" && (md.IsInlineElement(n.Data) || markupElements[n.Data] || extraInlineElements[n.Data])
+}
+
+// restoreInterElementSpaces turns each run of stand-ins, together with the
+// padding the converter put next to them, into a single space. At the start
+// of a line only the indentation is kept, and at the end nothing.
+func restoreInterElementSpaces(markdown string) string {
+	if !strings.Contains(markdown, interElementSpace) {
+		return markdown
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range interElementSpaceRegex.FindAllStringIndex(markdown, -1) {
+		b.WriteString(markdown[last:loc[0]])
+		last = loc[1]
+		switch {
+		case loc[1] == len(markdown) || markdown[loc[1]] == '\n':
+		case loc[0] == 0 || markdown[loc[0]-1] == '\n':
+			b.WriteString(strings.ReplaceAll(markdown[loc[0]:loc[1]], interElementSpace, ""))
+		default:
+			b.WriteString(" ")
+		}
+	}
+	b.WriteString(markdown[last:])
+	return b.String()
+}
+
+// dropWordBreaks removes <wbr> line break hints, which long flags and paths
+// carry, and joins the text around them, so that --some<wbr>-long-flag is a
+// single word again for the passes that read text nodes, such as glossary
+// linking, and for the rules that fit text into code.
+func dropWordBreaks(doc *goquery.Document) {
+	breaks := doc.Find("wbr")
+	if breaks.Length() == 0 {
+		return
+	}
+	breaks.Remove()
+	mergeTextNodes(doc.Nodes[0])
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestInlineSpacing(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`<p><a href="https://bazel.build">foo</a> <code>bar</code></p>`, "[foo](https://bazel.build) `bar`"},
+		{`<p><span>x</span> <span>y</span></p>`, "x y"},
+		{"<p><span>x</span> \n\t <span>y</span></p>", "x y"},
+		{`<p><img src="a.png" alt="A"> <img src="b.png" alt="B"></p>`, "![A](a.png) ![B](b.png)"},
+		{`<p><kbd>Ctrl</kbd> <kbd>C</kbd> <em>then</em> <strong>go</strong></p>`, "`Ctrl` `C` _then_ **go**"},
+		{`<p><span>x</span><span>y</span></p>`, "xy"},
+		{"<pre><span>a</span>  <span>b</span></pre>", "```\na  b\n```"},
+	}
+	converter := newConverter(testConfig(t))
+	for _, tt := range tests {
+		got, err := converter.ConvertString(tt.html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got = strings.TrimSpace(restoreInterElementSpaces(got)); got != tt.want {
+			t.Errorf("%q converted to %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestDropWordBreaks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<p>Pass --some<wbr>-long<wbr/>-flag to <code>bazel<wbr>build</code>.</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropWordBreaks(doc)
+	p := doc.Find("p").Nodes[0]
+	if p.FirstChild.Data != "Pass --some-long-flag to " || p.FirstChild.NextSibling.FirstChild.Data != "bazelbuild" {
+		t.Errorf("word breaks dropped to %q", doc.Find("p").Text())
+	}
+
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<h1>Flags</h1><p>Pass --some<wbr>-long<wbr>-flag.</p>`,
+	})["page.md"]
+	if !strings.Contains(page, "Pass --some-long-flag.") {
+		t.Errorf("page is\n%s", page)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// pageSplit configures -split-on-heading: the pages matching one of
+// patterns are split into a file per section at level.
+type pageSplit struct {
+	level    int
+	patterns []string
+}
+
+func newPageSplit(heading, patterns string) (*pageSplit, error) {
+	if heading == "" {
+		if patterns != "" {
+			return nil, fmt.Errorf("-split-pages needs -split-on-heading")
+		}
+		return nil, nil
+	}
+	level := headingLevel(strings.ToLower(heading))
+	if level == 0 {
+		return nil, fmt.Errorf("-split-on-heading must be a heading level from h1 to h6, not %q", heading)
+	}
+	s := &pageSplit{level: level}
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid -split-pages pattern %q: %w", pattern, err)
+		}
+		s.patterns = append(s.patterns, pattern)
+	}
+	if len(s.patterns) == 0 {
+		return nil, fmt.Errorf("-split-on-heading needs -split-pages to name the pages to split")
+	}
+	return s, nil
+}
+
+// matches reports whether the page at sourcePath, its path in the zip, is
+// split.
+func (s *pageSplit) matches(sourcePath string) bool {
+	if s == nil {
+		return false
+	}
+	for _, pattern := range s.patterns {
+		if ok, _ := path.Match(pattern, sourcePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPart is a section of a split page, written to a file of its own.
+type splitPart struct {
+	path, title, body string
+	// heading is the markdown of the heading, which the page links to
+	// the part with
+	heading string
+}
+
+var (
+	// An empty explicit anchor on a line of its own, as explicitAnchorRule
+	// and nameAnchorRule write in front of headings
+	anchorLineRegex = regexp.MustCompile(`^<a id="([^"]+)"></a>$`)
+	// A link to a fragment of the page itself, in markdown or HTML
+	fragmentLinkRegex = regexp.MustCompile(`(\]\(|href=")#([^)"\s]+)`)
+)
+
+// splitTarget is where an anchor of the page ends up after the split: in
+// the part at index part, or the index page at -1, as anchor, which is ""
+// for the heading a part starts with.
+type splitTarget struct {
+	part   int
+	anchor string
+}
+
+// splitPlan follows the headings and anchors of a page, fed to it in order,
+// and works out the parts the page splits into and where each anchor goes.
+// split feeds it the lines of the converted page, and planDocument the
+// prepared HTML of a page other pages link to, so that the links agree
+// with the split.
+type splitPlan struct {
+	level    int
+	strategy AnchorStrategy
+	// dir and ext make the paths of the parts
+	dir, ext string
+	anchors  *anchorSet
+	// local hands out the anchors of the headings within the current part
+	local   *anchorSet
+	paths   []string
+	targets map[string]splitTarget
+	// pending are the anchors since the last text, which go along with
+	// the part starting next
+	pending []string
+}
+
+func (s *pageSplit) plan(pagePath string, strategy AnchorStrategy) *splitPlan {
+	ext := path.Ext(pagePath)
+	return &splitPlan{
+		level:    s.level,
+		strategy: strategy,
+		dir:      strings.TrimSuffix(pagePath, ext),
+		ext:      ext,
+		anchors:  newAnchorSet(strategy),
+		targets:  make(map[string]splitTarget),
+	}
+}
+
+// current returns the index of the current part, or -1 before the first.
+func (p *splitPlan) current() int {
+	return len(p.paths) - 1
+}
+
+// anchor notes an explicit anchor.
+func (p *splitPlan) anchor(id string) {
+	p.targets[id] = splitTarget{part: p.current(), anchor: id}
+	p.pending = append(p.pending, id)
+}
+
+// text notes content other than anchors and headings.
+func (p *splitPlan) text() {
+	p.pending = nil
+}
+
+// heading notes a heading, and reports whether it starts a part, whose path
+// is then the last of p.paths.
+func (p *splitPlan) heading(level int, text string) bool {
+	anchor := p.anchors.add(text)
+	if level > p.level {
+		if p.local != nil {
+			p.targets[anchor] = splitTarget{part: p.current(), anchor: p.local.add(text)}
+		} else {
+			p.targets[anchor] = splitTarget{part: -1, anchor: anchor}
+		}
+		p.pending = nil
+		return false
+	}
+
+	name := anchor
+	if name == "" {
+		name = "section-" + strconv.Itoa(len(p.paths)+1)
+	}
+	p.paths = append(p.paths, p.dir+"/"+name+p.ext)
+	p.local = newAnchorSet(p.strategy)
+	p.targets[anchor] = splitTarget{part: p.current()}
+	for _, id := range p.pending {
+		p.targets[id] = splitTarget{part: p.current()}
+	}
+	p.pending = nil
+	return true
+}
+
+// target returns the part the anchor of the page moved to, and the anchor
+// there, "" for the heading the part starts with. It reports false for
+// anchors that stay on the page, and for pages that are not split, whose
+// plan is nil.
+func (p *splitPlan) target(anchor string) (file, fragment string, ok bool) {
+	if p == nil || len(p.paths) == 0 {
+		return "", "", false
+	}
+	t, found := p.targets[anchor]
+	if !found || t.part < 0 {
+		return "", "", false
+	}
+	return p.paths[t.part], t.anchor, true
+}
+
+// planDocument plans the split of the page at pagePath from its HTML, as
+// prepareHeadings leaves it, the way split plans it from the converted
+// page: each heading the page keeps, each id and named anchor, and text.
+func (s *pageSplit) planDocument(doc *goquery.Document, pagePath string, cfg config) *splitPlan {
+	plan := s.plan(pagePath, cfg.anchors)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			if strings.TrimSpace(n.Data) != "" {
+				plan.text()
+			}
+			return
+		case html.ElementNode:
+			sel := doc.FindNodes(n)
+			level := headingLevel(n.Data)
+			if level > 0 && (cfg.maxHeadingDepth == 0 || level <= cfg.maxHeadingDepth) {
+				plan.heading(level, sel.Text())
+				return
+			}
+			id := sel.AttrOr("id", "")
+			if id == "" && n.Data == "a" {
+				id = sel.AttrOr("name", "")
+			}
+			if id != "" {
+				plan.anchor(id)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+	return plan
+}
+
+// split splits the converted body of the page at pagePath at each heading
+// of s.level or above. Each section goes to a file named after its
+// heading's anchor, in a directory named after the page, and is titled by
+// the heading; what comes before the first section stays on the page,
+// followed by a list of links to the sections. Links to anchors of the
+// page are pointed at the file the anchor moved to. It returns the body of
+// the page and the parts, or none when the page has no such heading.
+func (s *pageSplit) split(body, pagePath string, cfg config) (string, []splitPart) {
+	lines := strings.Split(body, "\n")
+
+	// Cut the lines into the page's own and each part's, and note where
+	// every anchor goes
+	plan := s.plan(pagePath, cfg.anchors)
+	var parts []splitPart
+	var partLines [][]string
+	own := []string{}
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			plan.text()
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			plan.text()
+		default:
+			m := headingLineRegex.FindStringSubmatch(line)
+			if m == nil {
+				if m := anchorLineRegex.FindStringSubmatch(trimmed); m != nil {
+					plan.anchor(m[1])
+				} else if trimmed != "" {
+					plan.text()
+				}
+				break
+			}
+			text := headingText(m[1])
+			if !plan.heading(len(line)-len(strings.TrimLeft(line, "#")), text) {
+				break
+			}
+
+			// A new part, which takes the anchors in front of its heading
+			// along
+			previous := &own
+			if len(partLines) > 0 {
+				previous = &partLines[len(partLines)-1]
+			}
+			for n := len(*previous); n > 0; n-- {
+				if last := strings.TrimSpace((*previous)[n-1]); last != "" && !anchorLineRegex.MatchString(last) {
+					break
+				}
+				*previous = (*previous)[:n-1]
+			}
+			parts = append(parts, splitPart{path: plan.paths[plan.current()], title: text, heading: m[1]})
+			partLines = append(partLines, nil)
+			continue
+		}
+		if len(partLines) == 0 {
+			own = append(own, line)
+		} else {
+			partLines[len(partLines)-1] = append(partLines[len(partLines)-1], line)
+		}
+	}
+	if len(parts) == 0 {
+		return body, nil
+	}
+
+	link := func(from, to string) string {
+		if cfg.linkBase != "" {
+			return absoluteLink(cfg.linkBase, to, cfg.trailingSlash)
+		}
+		return pageLink(from, to, cfg.trailingSlash, cfg.stripLinkExtensions)
+	}
+	// relink points the fragment links of the text at part, -1 for the
+	// page, where their anchors went
+	relink := func(text string, part int, from string) string {
+		return fragmentLinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+			sub := fragmentLinkRegex.FindStringSubmatch(m)
+			target, ok := plan.targets[sub[2]]
+			if !ok {
+				return m
+			}
+			if target.part == part && target.anchor != "" {
+				return sub[1] + "#" + target.anchor
+			}
+			to := pagePath
+			if target.part >= 0 {
+				to = parts[target.part].path
+			}
+			href := link(from, to)
+			if target.anchor != "" {
+				href += "#" + target.anchor
+			}
+			return sub[1] + href
+		})
+	}
+
+	for i := range parts {
+		parts[i].body = relink(strings.Trim(strings.Join(partLines[i], "\n"), "\n"), i, parts[i].path) + "\n"
+	}
+	var b strings.Builder
+	if intro := strings.Trim(strings.Join(own, "\n"), "\n"); intro != "" {
+		b.WriteString(relink(intro, -1, pagePath) + "\n\n")
+	}
+	for _, part := range parts {
+		b.WriteString("- [" + part.heading + "](" + link(pagePath, part.path) + ")\n")
+	}
+	return b.String(), parts
+}
+
+// pageFile is a file a converted page is written to.
+type pageFile struct {
+	path, title, content string
+	// body is the content after the frontmatter, banner and source
+	// comment
+	body string
+}
+
+// pageFiles returns the files of the page converted from sourcePath: the
+// page itself, markdown, and with -split-on-heading the parts split from
+// body. The parts get the banner and source comment of the page, with
+// their heading as title.
+func (c *conversion) pageFiles(sourcePath, pagePath, title, markdown, body string) []pageFile {
+	files := []pageFile{{path: pagePath, title: title, content: markdown, body: body}}
+	if !c.cfg.split.matches(sourcePath) {
+		return files
+	}
+	index, parts := c.cfg.split.split(body, pagePath, c.cfg)
+	if len(parts) == 0 {
+		return files
+	}
+
+	head := sourceComment(c.cfg, sourcePath) + pageBanner(c.cfg, sourcePath)
+	files[0].content = strings.TrimSuffix(markdown, body) + index
+	files[0].body = index
+	for _, part := range parts {
+		content := frontmatter{{Key: "title", Value: part.title}}.String() + head + part.body
+		if c.cfg.noFrontmatter {
+			content = head + "# " + part.heading + "\n\n" + part.body
+		}
+		files = append(files, pageFile{path: part.path, title: part.title, content: content, body: part.body})
+	}
+	fmt.Printf("  Split into %d section(s) at h%d\n", len(parts), c.cfg.split.level)
+	return files
+}
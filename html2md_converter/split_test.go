@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestSplitGolden splits a page with three h2 sections into an index and
+// three parts, with a page linking to its anchors, and compares every file
+// written, and the search index, with testdata/split/output.
+func TestSplitGolden(t *testing.T) {
+	cfg := testConfig(t)
+	split, err := newPageSplit("h2", "be/encyclopedia.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.split = split
+	cfg.preserveMtime = false
+	cfg.emitLLMsTxt = true
+	cfg.emitSitemap = true
+	cfg.siteBaseURL = "https://bazel.build"
+	cfg.outIndex = filepath.Join(t.TempDir(), "index.jsonl")
+
+	inputDir := filepath.Join("testdata", "split", "input")
+	pages := make(map[string]string)
+	err = filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(inputDir, path)
+		pages[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written := convertPages(t, cfg, pages)
+	index, err := os.ReadFile(cfg.outIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written["index.jsonl"] = string(index)
+
+	outputDir := filepath.Join("testdata", "split", "output")
+	if *update {
+		if err := os.RemoveAll(outputDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var paths []string
+	for path := range written {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		checkGolden(t, filepath.Join(outputDir, filepath.FromSlash(path)), written[path])
+	}
+
+	var want []string
+	err = filepath.Walk(outputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		want = append(want, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(want)
+	if len(want) != len(paths) {
+		t.Errorf("wrote %q, want %q", paths, want)
+	}
+}
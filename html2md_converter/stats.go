@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stats counts what a run did. The same numbers feed the end-of-run summary
+// and the -metrics export.
+type stats struct {
+	start time.Time
+
+	pagesConverted    int
+	pagesDeduped      int
+	filesCopied       int
+	filesSkipped      int
+	assetsCopied      int
+	brokenLinks       int
+	emptyPages        int
+	mdxIssues         int
+	collisions        int
+	missingAssets     int
+	oversizedAssets   int
+	lossyPages        int
+	frontmatterIssues int
+	longPages         int
+	parseAnomalies    int
+	errors            int
+
+	bytesRead    int64
+	bytesWritten int64
+}
+
+func newStats() *stats {
+	return &stats{start: time.Now()}
+}
+
+func (s *stats) printSummary() {
+	fmt.Printf("Converted %d page(s), copied %d file(s) and %d asset(s), skipped %d file(s)\n",
+		s.pagesConverted, s.filesCopied, s.assetsCopied, s.filesSkipped)
+	if s.pagesDeduped > 0 {
+		fmt.Printf("Redirected %d duplicate page(s)\n", s.pagesDeduped)
+	}
+	if s.brokenLinks > 0 {
+		fmt.Printf("Found %d link(s) to pages missing from the zip\n", s.brokenLinks)
+	}
+	if s.emptyPages > 0 {
+		fmt.Printf("Found %d empty page(s)\n", s.emptyPages)
+	}
+	if s.mdxIssues > 0 {
+		fmt.Printf("Found %d line(s) that MDX cannot parse\n", s.mdxIssues)
+	}
+	if s.collisions > 0 {
+		fmt.Printf("Found %d output path collision(s)\n", s.collisions)
+	}
+	if s.missingAssets > 0 {
+		fmt.Printf("Found %d reference(s) to missing images\n", s.missingAssets)
+	}
+	if s.oversizedAssets > 0 {
+		fmt.Printf("Found %d image(s) over -assets-max-bytes or -assets-max-width\n", s.oversizedAssets)
+	}
+	if s.lossyPages > 0 {
+		fmt.Printf("Found %d page(s) that lost text in conversion\n", s.lossyPages)
+	}
+	if s.longPages > 0 {
+		fmt.Printf("Found %d page(s) over -long-page-bytes or -long-page-words\n", s.longPages)
+	}
+	if s.frontmatterIssues > 0 {
+		fmt.Printf("Found %d frontmatter problem(s) against -frontmatter-schema\n", s.frontmatterIssues)
+	}
+	if s.parseAnomalies > 0 {
+		fmt.Printf("Found %d repair(s) of broken HTML\n", s.parseAnomalies)
+	}
+}
+
+// writeMetrics writes the stats in the Prometheus text exposition format, for
+// a node_exporter textfile collector or similar to pick up.
+func (s *stats) writeMetrics(metricsPath string) error {
+	var b strings.Builder
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	metric("html2md_pages_converted_total", "counter", "HTML pages converted to markdown.", s.pagesConverted)
+	metric("html2md_pages_deduplicated_total", "counter", "Pages replaced by a redirect to an identical page.", s.pagesDeduped)
+	metric("html2md_files_copied_total", "counter", "Markdown files copied unchanged.", s.filesCopied)
+	metric("html2md_files_skipped_total", "counter", "Zip entries that were neither converted nor copied.", s.filesSkipped)
+	metric("html2md_assets_copied_total", "counter", "Images copied next to the converted pages.", s.assetsCopied)
+	metric("html2md_broken_links_total", "counter", "Links to pages missing from the zip.", s.brokenLinks)
+	metric("html2md_empty_pages_total", "counter", "Converted pages without any content.", s.emptyPages)
+	metric("html2md_mdx_issues_total", "counter", "Lines that MDX cannot parse.", s.mdxIssues)
+	metric("html2md_output_collisions_total", "counter", "Zip entries written to an already used output path.", s.collisions)
+	metric("html2md_missing_assets_total", "counter", "References to images missing from every input zip.", s.missingAssets)
+	metric("html2md_oversized_assets_total", "counter", "Copied images over -assets-max-bytes or -assets-max-width.", s.oversizedAssets)
+	metric("html2md_lossy_pages_total", "counter", "Pages that -check found to have lost text in conversion.", s.lossyPages)
+	metric("html2md_long_pages_total", "counter", "Converted pages over -long-page-bytes or -long-page-words.", s.longPages)
+	metric("html2md_frontmatter_issues_total", "counter", "Frontmatter problems found against -frontmatter-schema.", s.frontmatterIssues)
+	metric("html2md_parse_anomalies_total", "counter", "Broken HTML that -html-parser=strict found the parser had to repair.", s.parseAnomalies)
+	metric("html2md_errors_total", "counter", "Files that failed to convert.", s.errors)
+	metric("html2md_input_bytes_total", "counter", "Uncompressed bytes read from the zip.", s.bytesRead)
+	metric("html2md_output_bytes_total", "counter", "Bytes written to the output directory.", s.bytesWritten)
+	metric("html2md_duration_seconds", "gauge", "Wall-clock duration of the run.", time.Since(s.start).Seconds())
+
+	if err := writeFile(metricsPath, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// statsReport is the -stats-json form of the stats.
+type statsReport struct {
+	PagesConverted  int     `json:"pages_converted"`
+	PagesDeduped    int     `json:"pages_deduplicated"`
+	FilesCopied     int     `json:"files_copied"`
+	FilesSkipped    int     `json:"files_skipped"`
+	AssetsCopied    int     `json:"assets_copied"`
+	Errors          int     `json:"errors"`
+	InputBytes      int64   `json:"input_bytes"`
+	OutputBytes     int64   `json:"output_bytes"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Warnings has an entry for every -strict-<category> category
+	Warnings []warningReport `json:"warnings"`
+	// Error is why the run failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+type warningReport struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	// Strict reports whether the category fails the run
+	Strict bool `json:"strict"`
+}
+
+// writeJSON writes the stats, the warning counts of each category and the
+// error the run failed with, if any, as JSON for CI and dashboards.
+func (s *stats) writeJSON(reportPath string, strict map[string]bool, runErr error) error {
+	report := statsReport{
+		PagesConverted:  s.pagesConverted,
+		PagesDeduped:    s.pagesDeduped,
+		FilesCopied:     s.filesCopied,
+		FilesSkipped:    s.filesSkipped,
+		AssetsCopied:    s.assetsCopied,
+		Errors:          s.errors,
+		InputBytes:      s.bytesRead,
+		OutputBytes:     s.bytesWritten,
+		DurationSeconds: time.Since(s.start).Seconds(),
+		Warnings:        []warningReport{},
+	}
+	for _, cat := range warningCategories {
+		report.Warnings = append(report.Warnings, warningReport{Category: cat.name, Count: cat.count(s), Strict: strict[cat.name]})
+	}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode -stats-json: %w", err)
+	}
+	if err := writeFile(reportPath, append(content, '\n')); err != nil {
+		return fmt.Errorf("failed to write -stats-json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	metricsPath := filepath.Join(t.TempDir(), "metrics.prom")
+	s := newStats()
+	s.pagesConverted = 3
+	s.brokenLinks = 1
+	s.bytesRead = 2048
+	if err := s.writeMetrics(metricsPath); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(metricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := string(content)
+
+	for _, want := range []string{
+		"# HELP html2md_pages_converted_total HTML pages converted to markdown.\n# TYPE html2md_pages_converted_total counter\nhtml2md_pages_converted_total 3\n",
+		"\nhtml2md_broken_links_total 1\n",
+		"\nhtml2md_input_bytes_total 2048\n",
+		"# TYPE html2md_duration_seconds gauge\n",
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics lack %q:\n%s", want, metrics)
+		}
+	}
+	// Every sample line is a metric name and a number
+	sample := regexp.MustCompile(`^[a-z_][a-z0-9_]* [0-9.e+-]+$`)
+	for _, line := range strings.Split(strings.TrimSuffix(metrics, "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") && !sample.MatchString(line) {
+			t.Errorf("malformed sample line %q", line)
+		}
+	}
+}
+
+// TestStatsJSON converts pages, one with a broken link, with -stats-json and
+// checks the summary written, for a run that passes and for one that
+// -strict-links fails.
+func TestStatsJSON(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html":   `<h1>A</h1><p><a href="missing.html">Missing</a></p>`,
+		"docs/b.html":   `<h1>B</h1>`,
+		"docs/data.txt": "data",
+	}
+	for _, strict := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.statsJSON = filepath.Join(t.TempDir(), "stats.json")
+		cfg.strict["links"] = strict
+		runErr := convertTestZip(t, cfg, pages)
+		if (runErr != nil) != strict {
+			t.Errorf("strict %v: run returned %v", strict, runErr)
+		}
+
+		content, err := os.ReadFile(cfg.statsJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report statsReport
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatal(err)
+		}
+		if report.PagesConverted != 2 || report.FilesSkipped != 1 || report.InputBytes == 0 || report.OutputBytes == 0 || report.DurationSeconds <= 0 {
+			t.Errorf("strict %v: summary is %+v", strict, report)
+		}
+		if (report.Error != "") != strict {
+			t.Errorf("strict %v: summary has the error %q", strict, report.Error)
+		}
+		if len(report.Warnings) != len(warningCategories) {
+			t.Errorf("strict %v: summary has warnings %+v, want one entry for each category", strict, report.Warnings)
+		}
+		for _, w := range report.Warnings {
+			if w.Category == "links" && (w.Count != 1 || w.Strict != strict) {
+				t.Errorf("strict %v: links warnings are %+v", strict, w)
+			}
+		}
+		for _, field := range []string{`"pages_converted": 2`, `"input_bytes"`, `"duration_seconds"`, `"category": "links"`} {
+			if !strings.Contains(string(content), field) {
+				t.Errorf("strict %v: summary lacks %s:\n%s", strict, field, content)
+			}
+		}
+	}
+}
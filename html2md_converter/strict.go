@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// warningCategory is a kind of warning that -strict-<name> turns into a
+// failure. A run that fails this way exits with the exitBit of every
+// failing category set, so the exit status tells them apart; other errors
+// exit with 1. The exit status has no bits left past 128, so later
+// categories have no exitBit and exit with 1 when they fail alone.
+type warningCategory struct {
+	name    string
+	exitBit int
+	help    string
+	count   func(s *stats) int
+}
+
+var warningCategories = []warningCategory{
+	{"links", 2, "links to pages or directories missing from the zip", func(s *stats) int { return s.brokenLinks }},
+	{"empty", 4, "converted pages without any content", func(s *stats) int { return s.emptyPages }},
+	{"mdx", 8, "text that MDX cannot parse, such as literal braces", func(s *stats) int { return s.mdxIssues }},
+	{"collisions", 16, "several zip entries written to the same output path", func(s *stats) int { return s.collisions }},
+	{"assets", 32, "images missing from the zip and every -sources-root zip", func(s *stats) int { return s.missingAssets }},
+	{"check", 64, "pages that -check finds lost more than -check-threshold of their text", func(s *stats) int { return s.lossyPages }},
+	{"images", 0, "copied images over -assets-max-bytes or -assets-max-width", func(s *stats) int { return s.oversizedAssets }},
+	{"frontmatter", 0, "frontmatter that breaks -frontmatter-schema", func(s *stats) int { return s.frontmatterIssues }},
+	{"size", 0, "pages over -long-page-bytes or -long-page-words, with -warn-on-long-pages", func(s *stats) int { return s.longPages }},
+	{"html", 0, "broken HTML the parser had to repair, with -html-parser=strict", func(s *stats) int { return s.parseAnomalies }},
+}
+
+// strictError is returned by a run that completed but produced warnings in
+// categories configured as errors.
+type strictError struct {
+	failed []string
+	code   int
+	// links are the broken links when the links category failed
+	links []*linkError
+}
+
+func (e *strictError) Error() string {
+	return "warnings treated as errors: " + strings.Join(e.failed, ", ")
+}
+
+// Unwrap returns the broken links behind a failed links category, so that
+// errors.As finds them.
+func (e *strictError) Unwrap() []error {
+	errs := make([]error, len(e.links))
+	for i, l := range e.links {
+		errs[i] = l
+	}
+	return errs
+}
+
+// checkStrict returns a *strictError if any category in strict produced
+// warnings.
+func checkStrict(s *stats, strict map[string]bool, links []*linkError) error {
+	var e strictError
+	for _, cat := range warningCategories {
+		if strict[cat.name] && cat.count(s) > 0 {
+			e.failed = append(e.failed, fmt.Sprintf("%s (%d)", cat.name, cat.count(s)))
+			e.code |= cat.exitBit
+		}
+	}
+	if len(e.failed) == 0 {
+		return nil
+	}
+	if e.code == 0 {
+		e.code = 1
+	}
+	if strict["links"] {
+		e.links = links
+	}
+	return &e
+}
+
+var (
+	// A brace not escaped with a backslash
+	bareBraceRegex = regexp.MustCompile(`(?:^|[^\\])[{}]`)
+	// A JSX tag, of a component or an HTML element such as the <col> of
+	// an HTML table, whose attributes may be expressions
+	componentTagRegex = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^>]*)?>`)
+	// An MDX comment, such as the one of -include-source-comment
+	mdxCommentRegex = regexp.MustCompile(`\{/\*.*?\*/\}`)
+)
+
+// checkMDX warns about prose lines that MDX would fail to parse. Braces
+// start JavaScript expressions in MDX, so literal ones break the page.
+func (c *conversion) checkMDX(markdown string) {
+	for _, line := range proseLines(markdown) {
+		text := mdxCommentRegex.ReplaceAllString(componentTagRegex.ReplaceAllString(line.Text, ""), "")
+		if bareBraceRegex.MatchString(text) {
+			fmt.Printf("  Warning: line %d has a literal brace, which MDX reads as an expression\n", line.Number)
+			c.stats.mdxIssues++
+		}
+	}
+}
+
+// A lowercase HTML start tag; JSX components are capitalized
+var rawHTMLTagRegex = regexp.MustCompile(`<([a-z][a-z0-9-]*)(?:\s[^<>]*)?/?>`)
+
+// checkRawHTML records the HTML tags left in the prose of a page, apart
+// from the allowed ones, for -fail-on-raw-html. Markdown output has no
+// use for them, so each is likely markup no rule converts.
+func (c *conversion) checkRawHTML(pagePath, markdown string) {
+	counts := make(map[string]int)
+	for _, line := range proseLines(markdown) {
+		for _, m := range rawHTMLTagRegex.FindAllStringSubmatch(line.Text, -1) {
+			if !c.cfg.rawHTMLAllowed[m[1]] {
+				counts["<"+m[1]+">"]++
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	printDroppedAttrs("  Warning: raw HTML left in the markdown:", counts)
+	for tag := range counts {
+		c.rawHTML[tag] = append(c.rawHTML[tag], pagePath)
+	}
+}
+
+// rawHTMLError lists the pages with each raw HTML tag and returns the error
+// failing the run, if any were found.
+func rawHTMLError(rawHTML map[string][]string) error {
+	if len(rawHTML) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(rawHTML))
+	pages := make(map[string]bool)
+	for tag, paths := range rawHTML {
+		tags = append(tags, tag)
+		for _, p := range paths {
+			pages[p] = true
+		}
+	}
+	sort.Strings(tags)
+	fmt.Println("Raw HTML left in the markdown:")
+	for _, tag := range tags {
+		fmt.Printf("  %s in %s\n", tag, strings.Join(rawHTML[tag], ", "))
+	}
+	return fmt.Errorf("raw HTML left in %d page(s), see -fail-on-raw-html", len(pages))
+}
+
+// claimOutput records that sourcePath is written to outputPath and warns when
+// an earlier entry was already written there.
+func (c *conversion) claimOutput(outputPath, sourcePath string) {
+	if earlier, ok := c.written[outputPath]; ok {
+		fmt.Printf("  Warning: %s overwrites %s, which was also written to %s\n", sourcePath, earlier, outputPath)
+		c.stats.collisions++
+		return
+	}
+	c.written[outputPath] = sourcePath
+}
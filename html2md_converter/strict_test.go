@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestStrictCategories converts a zip with a broken link and an empty page,
+// and checks that only the enabled categories fail the run, each with its
+// exit status bit.
+func TestStrictCategories(t *testing.T) {
+	pages := map[string]string{
+		"docs/a.html":     `<p>See <a href="missing.html">the missing page</a>.</p>`,
+		"docs/empty.html": `<html><head><title>Empty</title></head><body></body></html>`,
+	}
+	tests := []struct {
+		strict []string
+		code   int
+	}{
+		{nil, 0},
+		{[]string{"links"}, 2},
+		{[]string{"empty"}, 4},
+		{[]string{"mdx", "collisions"}, 0},
+		{[]string{"links", "empty"}, 6},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		for _, name := range tt.strict {
+			cfg.strict[name] = true
+		}
+		err := convertTestZip(t, cfg, pages)
+		var strictErr *strictError
+		switch {
+		case tt.code == 0 && err != nil:
+			t.Errorf("-strict-%v: run failed: %v", tt.strict, err)
+		case tt.code != 0 && !errors.As(err, &strictErr):
+			t.Errorf("-strict-%v: run returned %v, want a strict error", tt.strict, err)
+		case tt.code != 0 && strictErr.code != tt.code:
+			t.Errorf("-strict-%v: exit status %d, want %d", tt.strict, strictErr.code, tt.code)
+		}
+	}
+}
+
+// TestStrictExitBits checks that every category has its own exit status
+// bit, above the 1 of other errors and below 128, since statuses from 128
+// up mean the process was killed by a signal.
+func TestStrictExitBits(t *testing.T) {
+	seen := 1
+	for _, cat := range warningCategories {
+		if cat.exitBit&seen != 0 || cat.exitBit&(cat.exitBit-1) != 0 || cat.exitBit >= 128 {
+			t.Errorf("category %s has exit status bit %d, which is not a bit of its own below 128", cat.name, cat.exitBit)
+		}
+		seen |= cat.exitBit
+	}
+}
+
+// convertTestZip converts a zip of the pages and returns the error of the
+// run.
+func convertTestZip(t *testing.T, cfg config, pages map[string]string) error {
+	t.Helper()
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, pages)
+	return convertZipToMarkdown(zipPath, filepath.Join(t.TempDir(), "output"), cfg)
+}
+
+// TestFailOnRawHTML checks that a <span> left in the markdown fails the run
+// under -fail-on-raw-html unless it is allowed, and that tags in code do
+// not count. The span is escaped text in the HTML, which the converter
+// writes as is, so it reaches the markdown as a tag.
+func TestFailOnRawHTML(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<p>Use &lt;span class="x"&gt; here.</p><pre>&lt;div&gt;code&lt;/div&gt;</pre><p><code>&lt;em&gt;</code></p>`,
+	}
+	tests := []struct {
+		allow string
+		fails bool
+	}{
+		{"br", true},
+		{"br,span", false},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.failOnRawHTML = true
+		cfg.rawHTMLAllowed = parseTagList(tt.allow)
+		err := convertTestZip(t, cfg, pages)
+		if tt.fails && err == nil {
+			t.Errorf("-raw-html-allow=%s: run succeeded with a <span> left", tt.allow)
+		}
+		if !tt.fails && err != nil {
+			t.Errorf("-raw-html-allow=%s: run failed: %v", tt.allow, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// syncDelta is what a sync changes in the target tree, by slash-separated
+// relative path.
+type syncDelta struct {
+	added, modified, removed []string
+	unchanged                int
+}
+
+// runSync converts into a scratch directory and brings targetDir in line
+// with the result, for -sync-to: new and changed files are written,
+// unchanged ones are left alone, mtime and all, and files no longer
+// generated are removed. With dryRun, it only reports what would change.
+func runSync(zipPath, targetDir string, dryRun bool, cfg config) error {
+	tmpDir, err := os.MkdirTemp("", "html2md-sync-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := convertZipToMarkdown(zipPath, tmpDir, cfg); err != nil {
+		return err
+	}
+	delta, err := syncTrees(tmpDir, targetDir, dryRun, cfg.preserveMtime)
+	if err != nil {
+		return err
+	}
+	delta.print(targetDir, dryRun)
+	return nil
+}
+
+// syncTrees makes targetDir hold the files of sourceDir. A missing
+// targetDir counts as empty.
+func syncTrees(sourceDir, targetDir string, dryRun, preserveMtime bool) (syncDelta, error) {
+	var delta syncDelta
+	newFiles, err := treeFiles(sourceDir)
+	if err != nil {
+		return delta, err
+	}
+	oldFiles, err := treeFiles(targetDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		oldFiles = make(fileSet)
+	} else if err != nil {
+		return delta, err
+	}
+
+	for _, rel := range newFiles.sorted() {
+		content, err := os.ReadFile(filepath.Join(sourceDir, rel))
+		if err != nil {
+			return delta, err
+		}
+		if oldFiles[rel] {
+			existing, err := os.ReadFile(filepath.Join(targetDir, rel))
+			if err != nil {
+				return delta, err
+			}
+			if bytes.Equal(existing, content) {
+				delta.unchanged++
+				continue
+			}
+			delta.modified = append(delta.modified, rel)
+		} else {
+			delta.added = append(delta.added, rel)
+		}
+		if dryRun {
+			continue
+		}
+
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(rel))
+		if err := mkdirAll(filepath.Dir(targetPath)); err != nil {
+			return delta, err
+		}
+		if err := writeFile(targetPath, content); err != nil {
+			return delta, err
+		}
+		if preserveMtime {
+			info, err := os.Stat(filepath.Join(sourceDir, rel))
+			if err != nil {
+				return delta, err
+			}
+			if err := os.Chtimes(targetPath, info.ModTime(), info.ModTime()); err != nil {
+				return delta, err
+			}
+		}
+	}
+
+	for _, rel := range oldFiles.sorted() {
+		if newFiles[rel] {
+			continue
+		}
+		delta.removed = append(delta.removed, rel)
+		if dryRun {
+			continue
+		}
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(rel))
+		if err := os.Remove(targetPath); err != nil {
+			return delta, err
+		}
+		// Drop the directories the removal left empty
+		for dir := filepath.Dir(targetPath); dir != filepath.Clean(targetDir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return delta, nil
+}
+
+func (d syncDelta) print(targetDir string, dryRun bool) {
+	verb := "Synced"
+	if dryRun {
+		verb = "Would sync"
+	}
+	fmt.Printf("%s %s: %d added, %d modified, %d removed, %d unchanged\n", verb, targetDir, len(d.added), len(d.modified), len(d.removed), d.unchanged)
+	for _, change := range []struct {
+		mark  string
+		paths []string
+	}{{"+", d.added}, {"~", d.modified}, {"-", d.removed}} {
+		for _, rel := range change.paths {
+			fmt.Printf("  %s %s\n", change.mark, rel)
+		}
+	}
+}
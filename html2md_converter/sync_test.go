@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSync seeds a missing target tree with -sync-to, then syncs it to a
+// run where one page is unchanged, one modified, one new and one gone,
+// first with -sync-dry-run.
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	cfg := testConfig(t)
+
+	zipPath := filepath.Join(dir, "v1.zip")
+	writeZip(t, zipPath, map[string]string{
+		"a.html":     "<h1>A</h1><p>Same.</p>",
+		"b.html":     "<h1>B</h1><p>Before.</p>",
+		"old/c.html": "<h1>C</h1><p>Gone next.</p>",
+	})
+	if err := runSync(zipPath, target, false, cfg); err != nil {
+		t.Fatal(err)
+	}
+	seeded := readTree(t, target)
+	if len(seeded) != 3 || seeded["old/c.md"] == "" {
+		t.Fatalf("seeded %v", seeded)
+	}
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(target, "a.md"), stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath = filepath.Join(dir, "v2.zip")
+	writeZip(t, zipPath, map[string]string{
+		"a.html": "<h1>A</h1><p>Same.</p>",
+		"b.html": "<h1>B</h1><p>After.</p>",
+		"d.html": "<h1>D</h1><p>New.</p>",
+	})
+	source := filepath.Join(dir, "v2")
+	if err := convertZipToMarkdown(zipPath, source, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, dryRun := range []bool{true, false} {
+		delta, err := syncTrees(source, target, dryRun, cfg.preserveMtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join([]string{strings.Join(delta.added, " "), strings.Join(delta.modified, " "), strings.Join(delta.removed, " ")}, "|")
+		if got != "d.md|b.md|old/c.md" || delta.unchanged != 1 {
+			t.Errorf("dry run %v: delta is %s with %d unchanged, want d.md|b.md|old/c.md with 1", dryRun, got, delta.unchanged)
+		}
+		if dryRun {
+			if tree := readTree(t, target); len(tree) != len(seeded) || tree["b.md"] != seeded["b.md"] {
+				t.Errorf("dry run changed the tree to %v", tree)
+			}
+		}
+	}
+
+	tree := readTree(t, target)
+	want := readTree(t, source)
+	if len(tree) != len(want) {
+		t.Errorf("synced tree has %d files, want %d: %v", len(tree), len(want), tree)
+	}
+	for path, content := range want {
+		if tree[path] != content {
+			t.Errorf("%s is %q, want %q", path, tree[path], content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "old")); !os.IsNotExist(err) {
+		t.Errorf("emptied directory old is left: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(target, "a.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(stamp) {
+		t.Errorf("unchanged a.md was rewritten at %v", info.ModTime())
+	}
+}
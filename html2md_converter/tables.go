@@ -0,0 +1,521 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// tableRow is a row of converted cell contents.
+type tableRow []string
+
+// tableOptions configures the tables rule set.
+type tableOptions struct {
+	// Layout is how tables used for page layout rather than data are
+	// written: side by side in the component style's "columns", "stacked"
+	// one cell after another, or as a "table" like data tables.
+	Layout string `yaml:"layout"`
+	// LayoutMaxRows is the most rows a layout table can have.
+	LayoutMaxRows int `yaml:"layout_max_rows"`
+	// HeaderOnly is how tables with a header but no data rows are
+	// written: as a "list" of the bold column names, with an "empty_row"
+	// below the header, or as a "table" of the header alone.
+	HeaderOnly string `yaml:"header_only"`
+	// AutoAlignNumbers right-aligns the columns whose cells are all
+	// numbers.
+	AutoAlignNumbers bool `yaml:"auto_align_numbers"`
+	// Nested is how tables in a cell of a data table, which pipe tables
+	// cannot hold, are written: the outer table and they as "html"
+	// tables, for MDX, or each as a "list" of its rows, with a warning,
+	// for plain markdown.
+	Nested string `yaml:"nested"`
+}
+
+// alignNumberColumns turns on AutoAlignNumbers for -auto-align-numbers. It
+// does nothing if the config file turns the tables rule set off.
+func alignNumberColumns(ruleSets []enabledRuleSet) {
+	for _, set := range ruleSets {
+		if options, ok := set.options.(*tableOptions); ok {
+			options.AutoAlignNumbers = true
+		}
+	}
+}
+
+// Values of the tables layout option.
+const (
+	layoutColumns = "columns"
+	layoutStacked = "stacked"
+	layoutTable   = "table"
+)
+
+// Values of the tables header_only option.
+const (
+	headerOnlyList     = "list"
+	headerOnlyEmptyRow = "empty_row"
+	headerOnlyTable    = "table"
+)
+
+// Values of the tables nested option.
+const (
+	nestedHTML = "html"
+	nestedList = "list"
+)
+
+// attrFlattenedTable marks the nested tables the tables rule set warned
+// about flattening.
+const attrFlattenedTable = "data-html2md-flattened"
+
+// Elements whose presence in a cell makes it layout rather than data
+const layoutBlockSelector = "p, div, ul, ol, dl, pre, blockquote, table, figure, h1, h2, h3, h4, h5, h6"
+
+// Elements that cannot be fitted onto a table line, so that a data table
+// with them in a cell is written as an HTML table
+const htmlTableBlockSelector = "pre, table"
+
+// tablePlugin converts data tables into GFM pipe tables. The table is read
+// section by section rather than in source order: the first <thead> row is
+// the header, <tbody> rows (and rows directly under <table>) follow in
+// order, and <tfoot> rows come last, in bold to set them apart. Layout
+// tables, which a pipe table would squash onto single lines, become
+// columns or stacked blocks instead.
+func tablePlugin(options tableOptions, style componentStyle) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"table"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if inDataTable(selec, options) {
+						if options.Nested == nestedList {
+							// The rule runs again as the outer table's cells
+							// are converted, but warns once
+							if _, warned := selec.Attr(attrFlattenedTable); !warned {
+								selec.SetAttr(attrFlattenedTable, "")
+								fmt.Printf("  Warning: table nested in a table cell is flattened to a list\n")
+							}
+							return md.String(renderTableList(conv, selec, opt))
+						}
+						return md.String("\n\n" + renderHTMLTable(conv, selec) + "\n\n")
+					}
+					if options.Layout != layoutTable && isLayoutTable(selec, options.LayoutMaxRows) {
+						return md.String(renderLayoutTable(conv, selec, options.Layout, style))
+					}
+					return md.String(renderDataTable(conv, selec, options, opt))
+				},
+			},
+		}
+	}
+}
+
+func renderDataTable(conv *md.Converter, selec *goquery.Selection, options tableOptions, opt *md.Options) string {
+	var table string
+	blocks := htmlTableBlockSelector
+	if options.Nested == nestedList {
+		// Nested tables become lists, which fit onto a table line
+		blocks = "pre"
+	}
+	if tableRows(selec).ChildrenFiltered("td, th").Find(blocks).Length() > 0 {
+		table = renderHTMLTable(conv, selec)
+	} else {
+		table = renderTable(conv, selec, options, opt)
+	}
+
+	// The caption becomes a bold line above the table, or below
+	// it when the source places it at the bottom
+	if caption := tableCaption(conv, selec); caption != "" {
+		caption = opt.StrongDelimiter + caption + opt.StrongDelimiter
+		if captionAtBottom(selec.ChildrenFiltered("caption").First()) {
+			table += "\n\n" + caption
+		} else {
+			table = caption + "\n\n" + table
+		}
+	}
+	return "\n\n" + table + "\n\n"
+}
+
+// tableRows returns the rows of the table itself, not of nested tables.
+func tableRows(table *goquery.Selection) *goquery.Selection {
+	return table.ChildrenFiltered("tr").AddSelection(table.ChildrenFiltered("thead, tbody, tfoot").ChildrenFiltered("tr"))
+}
+
+// inDataTable reports whether the table is in a cell of a data table,
+// rather than in the columns of a layout table.
+func inDataTable(table *goquery.Selection, options tableOptions) bool {
+	outer := table.Parent().Closest("table")
+	if outer.Length() == 0 {
+		return false
+	}
+	return options.Layout == layoutTable || !isLayoutTable(outer, options.LayoutMaxRows)
+}
+
+// renderTableList writes a table nested in a data table as a list with an
+// item per row, each cell labeled with its column's header when the table
+// has a header row.
+func renderTableList(conv *md.Converter, table *goquery.Selection, opt *md.Options) string {
+	var header tableRow
+	var b strings.Builder
+	tableRows(table).Each(func(i int, tr *goquery.Selection) {
+		row := convertRow(conv, tr, opt.StrongDelimiter)
+		if i == 0 && (goquery.NodeName(tr.Parent()) == "thead" || tr.ChildrenFiltered("td").Length() == 0) {
+			header = row
+			return
+		}
+		var cells []string
+		for j, cell := range row {
+			if cell == "" {
+				continue
+			}
+			if j < len(header) && header[j] != "" {
+				cell = header[j] + ": " + cell
+			}
+			cells = append(cells, cell)
+		}
+		if len(cells) > 0 {
+			b.WriteString("- " + strings.Join(cells, "; ") + "\n")
+		}
+	})
+	return "\n\n" + strings.TrimSuffix(b.String(), "\n") + "\n\n"
+}
+
+// isLayoutTable reports whether the table lays out blocks side by side: it
+// has role="presentation", or at most maxRows rows of several <td> cells,
+// no header cells or caption, and block content in some cell.
+func isLayoutTable(table *goquery.Selection, maxRows int) bool {
+	if strings.EqualFold(table.AttrOr("role", ""), "presentation") {
+		return true
+	}
+	rows := tableRows(table)
+	if rows.Length() == 0 || rows.Length() > maxRows || rows.ChildrenFiltered("th").Length() > 0 || table.ChildrenFiltered("caption").Length() > 0 {
+		return false
+	}
+	cells := rows.ChildrenFiltered("td")
+	return cells.Length() > rows.Length() && cells.Find(layoutBlockSelector).Length() > 0
+}
+
+// renderLayoutTable writes each row's non-empty cells as columns, or one
+// after another for the stacked layout.
+func renderLayoutTable(conv *md.Converter, table *goquery.Selection, layout string, style componentStyle) string {
+	var b strings.Builder
+	tableRows(table).Each(func(i int, tr *goquery.Selection) {
+		var blocks []string
+		tr.ChildrenFiltered("td, th").Each(func(i int, cell *goquery.Selection) {
+			if block := strings.TrimSpace(conv.Convert(cell)); block != "" {
+				blocks = append(blocks, block)
+			}
+		})
+		switch {
+		case len(blocks) == 0:
+		case layout == layoutStacked || len(blocks) == 1:
+			b.WriteString(stackBlocks(blocks))
+		default:
+			b.WriteString(style.columns(blocks))
+		}
+	})
+	return b.String()
+}
+
+func renderTable(conv *md.Converter, table *goquery.Selection, options tableOptions, opt *md.Options) string {
+	var header tableRow
+	var body, footer []tableRow
+
+	thead := table.ChildrenFiltered("thead").First()
+	thead.ChildrenFiltered("tr").Each(func(i int, tr *goquery.Selection) {
+		if i == 0 {
+			header = convertRow(conv, tr, opt.StrongDelimiter)
+		} else {
+			body = append(body, convertRow(conv, tr, opt.StrongDelimiter))
+		}
+	})
+
+	table.Children().Each(func(i int, section *goquery.Selection) {
+		switch goquery.NodeName(section) {
+		case "tbody":
+			section.ChildrenFiltered("tr").Each(func(i int, tr *goquery.Selection) {
+				body = append(body, convertRow(conv, tr, opt.StrongDelimiter))
+			})
+		case "tr":
+			body = append(body, convertRow(conv, section, opt.StrongDelimiter))
+		case "tfoot":
+			section.ChildrenFiltered("tr").Each(func(i int, tr *goquery.Selection) {
+				footer = append(footer, emphasizeRow(convertRow(conv, tr, opt.StrongDelimiter), opt.StrongDelimiter))
+			})
+		}
+	})
+
+	// Without a <thead>, a leading row of <th> cells is the header
+	if header == nil && len(body) > 0 && isHeaderRow(table) {
+		header, body = body[0], body[1:]
+	}
+
+	rows := append(body, footer...)
+	if header != nil && len(rows) == 0 {
+		switch options.HeaderOnly {
+		case headerOnlyList:
+			var b strings.Builder
+			for _, name := range header {
+				if name != "" {
+					b.WriteString("- " + opt.StrongDelimiter + name + opt.StrongDelimiter + "\n")
+				}
+			}
+			return strings.TrimSuffix(b.String(), "\n")
+		case headerOnlyEmptyRow:
+			rows = []tableRow{{}}
+		}
+	}
+	columns := len(header)
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writeTableRow(&b, header, columns)
+	b.WriteString("|")
+	for i := 0; i < columns; i++ {
+		if options.AutoAlignNumbers && isNumericColumn(rows, i, opt.StrongDelimiter) {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		writeTableRow(&b, row, columns)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// renderHTMLTable writes the table as an HTML table whose cells hold their
+// converted markdown, set off by blank lines so that it is parsed as
+// markdown, in source row order.
+func renderHTMLTable(conv *md.Converter, table *goquery.Selection) string {
+	var b strings.Builder
+	b.WriteString("<table>\n")
+	b.WriteString(tableColgroup(table))
+	tableRows(table).Each(func(i int, tr *goquery.Selection) {
+		b.WriteString("<tr>\n")
+		tr.ChildrenFiltered("td, th").Each(func(i int, cell *goquery.Selection) {
+			name := goquery.NodeName(cell)
+			b.WriteString("<" + name)
+			for _, attr := range []struct{ html, jsx string }{{"colspan", "colSpan"}, {"rowspan", "rowSpan"}} {
+				if value, ok := cell.Attr(attr.html); ok {
+					b.WriteString(" " + attr.jsx + `="` + jsxAttrEscaper.Replace(value) + `"`)
+				}
+			}
+			b.WriteString(">")
+			if content := strings.TrimSpace(conv.Convert(cell)); content != "" {
+				b.WriteString("\n\n" + content + "\n\n")
+			}
+			b.WriteString("</" + name + ">\n")
+		})
+		b.WriteString("</tr>\n")
+	})
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// tableColgroup writes the column widths the table declares with <col> and
+// <colgroup>, in their style or legacy width attribute, as a colgroup of
+// MDX style objects, so that the HTML table keeps its proportions. Pipe
+// tables have no widths. It returns "" when no column has a width.
+func tableColgroup(table *goquery.Selection) string {
+	var cols []string
+	hasWidth := false
+	table.ChildrenFiltered("colgroup").Each(func(i int, group *goquery.Selection) {
+		columns := group.ChildrenFiltered("col")
+		if columns.Length() == 0 {
+			// A colgroup without cols stands for its span of columns
+			columns = group
+		}
+		columns.Each(func(i int, col *goquery.Selection) {
+			var b strings.Builder
+			b.WriteString("<col")
+			if span, err := strconv.Atoi(col.AttrOr("span", "")); err == nil && span > 1 {
+				b.WriteString(` span="` + strconv.Itoa(span) + `"`)
+			}
+			if width := columnWidth(col); width != "" {
+				hasWidth = true
+				b.WriteString(" style={{ width: " + strconv.Quote(width) + " }}")
+			}
+			cols = append(cols, b.String()+" />")
+		})
+	})
+	if !hasWidth {
+		return ""
+	}
+	return "<colgroup>\n" + strings.Join(cols, "\n") + "\n</colgroup>\n"
+}
+
+// columnWidth returns the width of a <col> or <colgroup>, from its style,
+// else its width attribute, where a bare number is in pixels.
+func columnWidth(col *goquery.Selection) string {
+	for _, decl := range strings.Split(col.AttrOr("style", ""), ";") {
+		prop, value, _ := strings.Cut(decl, ":")
+		if strings.EqualFold(strings.TrimSpace(prop), "width") {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	width := strings.TrimSpace(col.AttrOr("width", ""))
+	if _, err := strconv.ParseFloat(width, 64); err == nil {
+		width += "px"
+	}
+	return width
+}
+
+func tableCaption(conv *md.Converter, table *goquery.Selection) string {
+	caption := table.ChildrenFiltered("caption").First()
+	if caption.Length() == 0 {
+		return ""
+	}
+	return collapseWhitespace(conv.Convert(caption))
+}
+
+// captionAtBottom reports whether the caption is placed below its table,
+// with the legacy align attribute or the caption-side style property.
+func captionAtBottom(caption *goquery.Selection) bool {
+	if strings.EqualFold(caption.AttrOr("align", ""), "bottom") {
+		return true
+	}
+	style := strings.ToLower(strings.ReplaceAll(caption.AttrOr("style", ""), " ", ""))
+	return strings.Contains(style, "caption-side:bottom")
+}
+
+// isHeaderRow reports whether the table's first row consists of <th> cells.
+func isHeaderRow(table *goquery.Selection) bool {
+	first := table.Find("tr").First()
+	cells := first.ChildrenFiltered("td, th")
+	return cells.Length() > 0 && cells.Length() == cells.Filter("th").Length()
+}
+
+// convertRow converts each cell of the row, repeating empty cells for
+// colspan so that later columns stay aligned. Row headers, <th scope="row">,
+// are bold, since pipe tables only have a header row.
+func convertRow(conv *md.Converter, tr *goquery.Selection, strong string) tableRow {
+	var row tableRow
+	tr.ChildrenFiltered("td, th").Each(func(i int, cell *goquery.Selection) {
+		text := tableCell(conv.Convert(cell))
+		if cell.Is(`th[scope="row" i]`) && text != "" && !strings.HasPrefix(text, strong) {
+			text = strong + text + strong
+		}
+		row = append(row, text)
+		if span, err := strconv.Atoi(cell.AttrOr("colspan", "1")); err == nil {
+			for ; span > 1; span-- {
+				row = append(row, "")
+			}
+		}
+	})
+	return row
+}
+
+func emphasizeRow(row tableRow, delimiter string) tableRow {
+	for i, cell := range row {
+		if cell != "" && !(strings.HasPrefix(cell, delimiter) && strings.HasSuffix(cell, delimiter)) {
+			row[i] = delimiter + cell + delimiter
+		}
+	}
+	return row
+}
+
+var (
+	blankLineRegex = regexp.MustCompile(`\n\s*\n`)
+	// A list item line of converted markdown: indentation, marker and text
+	listItemLineRegex = regexp.MustCompile(`^( *)([-*+]|\d+[.)]) +(.*)$`)
+)
+
+// tableCell fits converted cell markdown onto a single table line, joining
+// paragraphs with <br /> and escaping pipes. List items, which a cell
+// cannot hold, get a line each, with a • for bullets, indented by nesting
+// level.
+func tableCell(markdown string) string {
+	var lines []string
+	for _, p := range blankLineRegex.Split(strings.TrimSpace(markdown), -1) {
+		var indents []int
+		start := len(lines)
+		for _, line := range strings.Split(p, "\n") {
+			m := listItemLineRegex.FindStringSubmatch(line)
+			if m == nil {
+				if text := strings.Join(strings.Fields(line), " "); text != "" {
+					if len(lines) > start {
+						lines[len(lines)-1] += " " + text
+					} else {
+						lines = append(lines, text)
+					}
+				}
+				continue
+			}
+
+			for len(indents) > 0 && indents[len(indents)-1] >= len(m[1]) {
+				indents = indents[:len(indents)-1]
+			}
+			marker := m[2]
+			if !unicode.IsDigit(rune(marker[0])) {
+				marker = "•"
+			}
+			lines = append(lines, strings.Repeat("&nbsp;&nbsp;", 2*len(indents))+marker+" "+strings.Join(strings.Fields(m[3]), " "))
+			indents = append(indents, len(m[1]))
+		}
+	}
+	return escapePipes(strings.Join(lines, "<br />"))
+}
+
+// escapePipes escapes the pipes that the markdown escaping left alone.
+func escapePipes(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '|' && !escaped {
+			b.WriteRune('\\')
+		}
+		escaped = r == '\\' && !escaped
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// A number as tables show them, e.g. 42, -1.5, 1,024, 3e8, $10 or 75%
+var tableNumberRegex = regexp.MustCompile(`^[-+−]?[$€£¥]?(?:\d{1,3}(?:,\d{3})+|\d+)?(?:\.\d+)?(?:[eE][-+]?\d+)?%?$`)
+
+// isNumericColumn reports whether the cells of the column are all numbers,
+// ignoring empty cells and the emphasis of footer rows, and there is one.
+func isNumericColumn(rows []tableRow, column int, strong string) bool {
+	found := false
+	for _, row := range rows {
+		if column >= len(row) {
+			continue
+		}
+		cell := strings.TrimSpace(row[column])
+		if strings.HasPrefix(cell, strong) && strings.HasSuffix(cell, strong) && len(cell) >= 2*len(strong) {
+			cell = strings.TrimSpace(cell[len(strong) : len(cell)-len(strong)])
+		}
+		if cell == "" {
+			continue
+		}
+		if !tableNumberRegex.MatchString(cell) || !strings.ContainsAny(cell, "0123456789") {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+func writeTableRow(b *strings.Builder, row tableRow, columns int) {
+	b.WriteString("|")
+	for i := 0; i < columns; i++ {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		b.WriteString(" " + cell + " |")
+	}
+	b.WriteString("\n")
+}
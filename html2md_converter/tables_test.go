@@ -0,0 +1,206 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTableSections converts a table with all three sections, its <tfoot>
+// first as HTML allows, and two <tbody>s.
+func TestTableSections(t *testing.T) {
+	page := convertPages(t, testConfig(t), map[string]string{
+		"page.html": `<table>
+<tfoot><tr><td>Total</td><td>3</td></tr></tfoot>
+<thead><tr><th>Name</th><th>Count</th></tr></thead>
+<tbody><tr><td>a</td><td>1</td></tr></tbody>
+<tbody><tr><td colspan="2">b</td></tr><tr><td><p>c</p><p>d</p></td><td>2</td></tr></tbody>
+</table>`,
+	})["page.md"]
+
+	want := `| Name | Count |
+| --- | --- |
+| a | 1 |
+| b |  |
+| c<br />d | 2 |
+| **Total** | **3** |`
+	if !strings.Contains(page, want) {
+		t.Errorf("table converted to\n%s\nwant\n%s", page, want)
+	}
+}
+
+// TestLayoutTables converts a two-column layout table, and a data table
+// beside it, with each tables layout option and component style.
+func TestLayoutTables(t *testing.T) {
+	pages := map[string]string{
+		"page.html": `<h1>Page</h1>
+<table><tr><td><p>Left text.</p><ul><li>Item</li></ul></td><td><p>Right text.</p></td></tr></table>
+<table><tr><td>a</td><td>1</td></tr></table>`,
+	}
+	tests := []struct {
+		layout, style, want string
+	}{
+		{layoutColumns, "mintlify", "<Columns cols={2}>\n<div>\n\nLeft text.\n\n- Item\n\n</div>\n<div>\n\nRight text.\n\n</div>\n</Columns>"},
+		{layoutColumns, "docusaurus", "# Page\n\nLeft text.\n\n- Item\n\nRight text.\n\n|  |  |"},
+		{layoutStacked, "mintlify", "# Page\n\nLeft text.\n\n- Item\n\nRight text.\n\n|  |  |"},
+		{layoutTable, "mintlify", "| Left text.<br />• Item | Right text. |"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  tables:\n    layout: "+tt.layout+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ruleSets = ruleSets
+		cfg.components = componentStyles[tt.style]
+		cfg.noFrontmatter = true
+		page := convertPages(t, cfg, pages)["page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("%s, %s: page is\n%s\nwant it to contain\n%s", tt.layout, tt.style, page, tt.want)
+		}
+		if !strings.Contains(page, "| a | 1 |") {
+			t.Errorf("%s, %s: data table not kept as a table:\n%s", tt.layout, tt.style, page)
+		}
+	}
+}
+
+// TestCellLists converts a table with lists in its cells, which become
+// lines of the cell, and one with a code block, which becomes an HTML
+// table.
+func TestCellLists(t *testing.T) {
+	tests := []struct{ html, want string }{
+		{`<table><tr><th>Rule</th><th>Attributes</th></tr>
+<tr><td>cc_library</td><td><ul><li>srcs</li><li>deps <code>a|b</code><ul><li>nested</li></ul></li></ul></td></tr>
+<tr><td>steps</td><td><ol start="2"><li>Build</li><li>Test</li></ol></td></tr></table>`,
+			"| Rule | Attributes |\n| --- | --- |\n| cc\\_library | • srcs<br />• deps `a\\|b`<br />&nbsp;&nbsp;&nbsp;&nbsp;• nested |\n| steps | 2. Build<br />3. Test |"},
+		{`<table><tr><th>Rule</th><th>Example</th></tr>
+<tr><td colspan="2"><pre>bazel build //...</pre></td></tr></table>`,
+			"<table>\n<tr>\n<th>\n\nRule\n\n</th>\n<th>\n\nExample\n\n</th>\n</tr>\n<tr>\n<td colSpan=\"2\">\n\n```\nbazel build //...\n```\n\n</td>\n</tr>\n</table>"},
+	}
+	for _, tt := range tests {
+		got := convertPages(t, testConfig(t), map[string]string{"page.html": tt.html})["page.md"]
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("table converted to\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
+
+// TestHeaderOnlyTables converts a table with a header but no data rows with
+// each header_only option.
+func TestHeaderOnlyTables(t *testing.T) {
+	page := `<table><thead><tr><th>Name</th><th></th><th>Default</th></tr></thead><tbody></tbody></table>`
+	tests := []struct{ headerOnly, want string }{
+		{headerOnlyList, "- **Name**\n- **Default**"},
+		{headerOnlyEmptyRow, "| Name |  | Default |\n| --- | --- | --- |\n|  |  |  |"},
+		{headerOnlyTable, "| Name |  | Default |\n| --- | --- | --- |"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  tables:\n    header_only: "+tt.headerOnly+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ruleSets = ruleSets
+		got := strings.TrimSpace(convertPages(t, cfg, map[string]string{"page.html": page})["page.md"])
+		if got != "---\ntitle: 'page'\n---\n\n"+tt.want {
+			t.Errorf("header_only: %s: table converted to\n%s\nwant\n%s", tt.headerOnly, got, tt.want)
+		}
+	}
+}
+
+// TestAutoAlignNumbers checks that with auto_align_numbers, set in the
+// config file or by -auto-align-numbers, numeric columns get a ---:
+// separator and mixed ones stay left-aligned.
+func TestAutoAlignNumbers(t *testing.T) {
+	page := `<table><thead><tr><th>Target</th><th>Size</th><th>Share</th><th>Notes</th></tr></thead>
+<tbody><tr><td>//a</td><td>1,024</td><td>12.5%</td><td>3</td></tr>
+<tr><td>//b</td><td>$2.5e3</td><td>-4%</td><td>n/a</td></tr></tbody>
+<tfoot><tr><td>Total</td><td>3,524</td><td>8.5%</td><td></td></tr></tfoot></table>`
+	tests := []struct {
+		name      string
+		configure func(*testing.T, *config)
+		want      string
+	}{
+		{"off", func(*testing.T, *config) {}, "| --- | --- | --- | --- |"},
+		{"config", func(t *testing.T, cfg *config) {
+			ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  tables:\n    auto_align_numbers: true\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.ruleSets = ruleSets
+		}, "| --- | ---: | ---: | --- |"},
+		{"flag", func(t *testing.T, cfg *config) { alignNumberColumns(cfg.ruleSets) }, "| --- | ---: | ---: | --- |"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		tt.configure(t, &cfg)
+		got := convertPages(t, cfg, map[string]string{"page.html": page})["page.md"]
+		if !strings.Contains(got, "| Target | Size | Share | Notes |\n"+tt.want+"\n") {
+			t.Errorf("%s: table converted to\n%s\nwant the separator %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRowHeaders(t *testing.T) {
+	page := `<table><thead><tr><th>Attribute</th><th>Type</th></tr></thead>
+<tbody><tr><th scope="row">name</th><td>Name</td></tr><tr><th scope="row"><code>deps</code></th><td>List of labels</td></tr></tbody>
+<tfoot><tr><th scope="row">Total</th><td>2</td></tr></tfoot></table>`
+	want := "| Attribute | Type |\n| --- | --- |\n| **name** | Name |\n| **`deps`** | List of labels |\n| **Total** | **2** |"
+	got := convertPages(t, testConfig(t), map[string]string{"page.html": page})["page.md"]
+	if !strings.Contains(got, want) {
+		t.Errorf("table converted to\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestNestedTables converts a data table with a table in a cell, which
+// nested: html writes as HTML tables and nested: list as a pipe table with
+// the inner table as a list, with a warning.
+func TestNestedTables(t *testing.T) {
+	page := `<table><thead><tr><th>Flag</th><th>Values</th></tr></thead>
+<tbody><tr><td><code>--mode</code></td><td><table><tr><th>Value</th><th>Effect</th></tr><tr><td>fast</td><td>Skips checks</td></tr><tr><td>safe</td><td>Runs them</td></tr></table></td></tr></tbody></table>`
+	tests := []struct {
+		nested, want string
+		warns        bool
+	}{
+		{nestedHTML, "<table>\n", false},
+		{nestedList, "| Flag | Values |\n| --- | --- |\n| `--mode` | • Value: fast; Effect: Skips checks<br />• Value: safe; Effect: Runs them |", true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		ruleSets, _, err := loadConfig(writeConfig(t, "rules:\n  tables:\n    nested: "+tt.nested+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.ruleSets = ruleSets
+		var got string
+		output := captureStdout(t, func() {
+			got = convertPages(t, cfg, map[string]string{"page.html": page})["page.md"]
+		})
+		if !strings.Contains(got, tt.want) || strings.Contains(got, "| Value | Effect |") {
+			t.Errorf("nested: %s: table converted to\n%s\nwant it to contain\n%s", tt.nested, got, tt.want)
+		}
+		if warned := strings.Count(output, "table nested in a table cell is flattened") == 1; warned != tt.warns {
+			t.Errorf("nested: %s: output is\n%s", tt.nested, output)
+		}
+	}
+}
+
+// TestColumnWidths checks that an HTML table fallback keeps the widths of
+// its <colgroup>, which -strict-mdx accepts, and that a pipe table drops
+// them.
+func TestColumnWidths(t *testing.T) {
+	colgroup := `<colgroup><col style="width: 30%"><col width="200"></colgroup>`
+	tests := []struct{ cell, want string }{
+		{"<pre>bazel build //...</pre>", "<table>\n<colgroup>\n<col style={{ width: \"30%\" }} />\n<col style={{ width: \"200px\" }} />\n</colgroup>\n"},
+		{"<code>//...</code>", "| Command | Targets |\n| --- | --- |\n| build | `//...` |"},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		cfg.strict["mdx"] = true
+		page := convertPages(t, cfg, map[string]string{
+			"page.html": `<h1>Commands</h1><table>` + colgroup + `<tr><th>Command</th><th>Targets</th></tr><tr><td>build</td><td>` + tt.cell + `</td></tr></table>`,
+		})["page.md"]
+		if !strings.Contains(page, tt.want) {
+			t.Errorf("page is\n%s\nwant it to contain\n%s", page, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// tabSetSelector matches Devsite selectors, which show alternatives such as
+// the Bzlmod and WORKSPACE setup of a module side by side as tabs.
+const tabSetSelector = "devsite-selector, .ds-selector-tabs"
+
+// Elements a code tab consists of
+const codeTabSelector = "pre, devsite-code"
+
+// tab is a converted tab of a selector.
+type tab struct {
+	title, body string
+}
+
+// tabsPlugin converts Devsite selectors into tab components. Each <section>
+// of the selector is a tab, titled by the heading it starts with. The
+// {% dynamic setvar %} variables the tabs use are substituted before the
+// page is parsed, so the tabs hold their values. Selectors whose tabs are
+// each a code block, the same example in several languages, become a code
+// group instead; their code blocks may also be children of the selector
+// without sections.
+func tabsPlugin(style componentStyle) md.Plugin {
+	return func(conv *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"devsite-selector", "div"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if !selec.Is(tabSetSelector) {
+						return nil
+					}
+					sections := selec.ChildrenFiltered("section")
+					if sections.Length() == 0 {
+						sections = selec.ChildrenFiltered(codeTabSelector)
+					}
+					if sections.Length() == 0 {
+						return nil
+					}
+					if tabs, ok := codeTabs(conv, selec, sections); ok {
+						return md.String(style.codeGroup(tabs))
+					}
+					if goquery.NodeName(sections) != "section" {
+						return nil
+					}
+
+					var tabs []tab
+					sections.Each(func(i int, section *goquery.Selection) {
+						section = section.Clone()
+						heading := section.ChildrenFiltered("h1, h2, h3, h4, h5, h6").First()
+						title := collapseWhitespace(heading.Text())
+						heading.Remove()
+						if title == "" {
+							title = "Tab " + strconv.Itoa(i+1)
+						}
+						tabs = append(tabs, tab{title: title, body: strings.TrimSpace(conv.Convert(section))})
+					})
+					return md.String(style.tabs(tabs))
+				},
+			},
+		}
+	}
+}
+
+// codeTabs converts the tabs of a selector when there are several and each
+// holds nothing but a code block, besides its heading. A tab is titled by
+// its heading, else the block's filename, else its language.
+func codeTabs(conv *md.Converter, selector, tabs *goquery.Selection) ([]tab, bool) {
+	if tabs.Length() < 2 || selector.Children().Length() != tabs.Length() {
+		return nil, false
+	}
+
+	var result []tab
+	ok := tabs.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		wrapper := goquery.NewDocumentFromNode(&html.Node{Type: html.ElementNode, Data: "div"}).Selection
+		wrapper.AppendSelection(s.Clone())
+		title := ""
+		if goquery.NodeName(s) == "section" {
+			section := wrapper.Children()
+			heading := section.ChildrenFiltered("h1, h2, h3, h4, h5, h6").First()
+			title = collapseWhitespace(heading.Text())
+			heading.Remove()
+			block := section.Children()
+			if block.Length() != 1 || !block.Is(codeTabSelector) || strings.TrimSpace(section.Text()) != strings.TrimSpace(block.Text()) {
+				return false
+			}
+		}
+
+		code := wrapper.Find(codeTabSelector).First()
+		if title == "" {
+			title = codeFilename(code)
+		}
+		if title == "" {
+			if goquery.NodeName(code) == "devsite-code" {
+				code = code.Find("pre").First()
+			}
+			title = codeLanguage(code)
+		}
+		if title == "" {
+			title = "Tab " + strconv.Itoa(i+1)
+		}
+		result = append(result, tab{title: title, body: strings.TrimSpace(conv.Convert(wrapper))})
+		return true
+	}).Length() > 0 && len(result) == tabs.Length()
+	return result, ok
+}
+
+// retitleFence replaces the text after the language on the opening fence of
+// a code block with meta, keeping the lines it highlights.
+func retitleFence(block, meta string) string {
+	line, rest, _ := strings.Cut(block, "\n")
+	m := fenceOpenRegex.FindStringSubmatch(line)
+	if m == nil {
+		return block
+	}
+	language := "text"
+	if fields := strings.Fields(m[3]); len(fields) > 0 {
+		language = fields[0]
+		if last := fields[len(fields)-1]; len(fields) > 1 && strings.HasPrefix(last, "{") && lineHighlightRegex.MatchString(strings.Trim(last, "{}")) {
+			meta += " " + last
+		}
+	}
+	fence := m[2]
+	if fence[0] == '`' && strings.Contains(meta, "`") {
+		// A backtick fence cannot carry backticks in its info string
+		fence = strings.Repeat("~", len(fence))
+		rest = strings.TrimSuffix(rest, m[2]) + fence
+	}
+	return m[1] + fence + language + " " + meta + "\n" + rest
+}
@@ -0,0 +1,29 @@
+package main
+
+import "context"
+
+// withDeadline runs f and returns its result, or ctx.Err() if ctx is done
+// before f returns. f keeps running in the background then, so it must only
+// touch state of its own, as parsing and converting a page's document do.
+func withDeadline[T any](ctx context.Context, f func() (T, error)) (T, error) {
+	if ctx.Done() == nil {
+		return f()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := f()
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
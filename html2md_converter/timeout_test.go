@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// slowRuleSet converts <x-slow> elements, standing in for a pathological
+// page, only after a delay.
+var slowRuleSet = enabledRuleSet{ruleSet: ruleSet{
+	name: "slow",
+	plugin: func(interface{}, componentStyle) md.Plugin {
+		return func(conv *md.Converter) []md.Rule {
+			return []md.Rule{{
+				Filter: []string{"x-slow"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					time.Sleep(2 * time.Second)
+					return &content
+				},
+			}}
+		}
+	},
+}}
+
+// TestFileTimeout checks that a page converted too slowly is skipped, that
+// the other pages are still converted, and that the run fails at the end.
+func TestFileTimeout(t *testing.T) {
+	pages := map[string]string{
+		"a.html":    `<h1>A</h1><p>First</p>`,
+		"slow.html": `<h1>Slow</h1><p><x-slow>Stuck</x-slow></p>`,
+		"z.html":    `<h1>Z</h1><p>Last</p>`,
+	}
+	cfg := testConfig(t)
+	cfg.ruleSets = append(cfg.ruleSets, slowRuleSet)
+	cfg.fileTimeout = 100 * time.Millisecond
+
+	zipPath := filepath.Join(t.TempDir(), "input.zip")
+	writeZip(t, zipPath, pages)
+	outputDir := filepath.Join(t.TempDir(), "output")
+	err := convertZipToMarkdown(zipPath, outputDir, cfg)
+	if err == nil || !strings.Contains(err.Error(), "1 page(s) were not converted within -file-timeout") {
+		t.Errorf("run returned %v, want the timed-out page reported", err)
+	}
+	for name, written := range map[string]bool{"a.md": true, "slow.md": false, "z.md": true} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); (err == nil) != written {
+			t.Errorf("%s written: %v, want %v", name, err == nil, written)
+		}
+	}
+
+	cfg.fileTimeout = 0
+	cfg.totalTimeout = 100 * time.Millisecond
+	if err := convertTestZip(t, cfg, pages); err == nil || !strings.Contains(err.Error(), "-total-timeout") {
+		t.Errorf("run returned %v, want the total timeout reported", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/atom"
+	"gopkg.in/yaml.v2"
+)
+
+// htmlTransform is a step of the before_convert list of the -config file,
+// which fixes site-specific markup before conversion:
+//
+//	before_convert:
+//	  - remove: [".ads", "devsite-feedback"]
+//	  - unwrap: ["span.plain"]
+//	  - rename: {b: strong, "div.note": aside}
+//
+// Each step does one of the three; steps apply in order, and so do the
+// selectors of a step.
+type htmlTransform struct {
+	// Remove drops the matching elements with their content.
+	Remove []string `yaml:"remove"`
+	// Unwrap replaces the matching elements with their content.
+	Unwrap []string `yaml:"unwrap"`
+	// Rename changes the tag of the elements matching each selector,
+	// keeping their attributes and content.
+	Rename yaml.MapSlice `yaml:"rename"`
+}
+
+// A tag name rename accepts
+var tagNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// validateTransforms checks that each step does exactly one thing with
+// valid selectors and tag names.
+func validateTransforms(transforms []htmlTransform) error {
+	for i, t := range transforms {
+		step := fmt.Sprintf("before_convert[%d]", i)
+		ops := 0
+		for _, n := range []int{len(t.Remove), len(t.Unwrap), len(t.Rename)} {
+			if n > 0 {
+				ops++
+			}
+		}
+		if ops != 1 {
+			return fmt.Errorf("%s: a step takes exactly one of remove, unwrap or rename", step)
+		}
+
+		selectors := append(append([]string(nil), t.Remove...), t.Unwrap...)
+		for _, item := range t.Rename {
+			selector, ok := item.Key.(string)
+			tag, tagOK := item.Value.(string)
+			if !ok || !tagOK {
+				return fmt.Errorf("%s: rename maps selectors to tag names", step)
+			}
+			if !tagNameRegex.MatchString(tag) {
+				return fmt.Errorf("%s: %q is not a tag name", step, tag)
+			}
+			selectors = append(selectors, selector)
+		}
+		for _, selector := range selectors {
+			if _, err := cascadia.ParseGroup(selector); err != nil {
+				return fmt.Errorf("%s: invalid selector %q: %w", step, selector, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyTransforms runs the before_convert steps on the page.
+func applyTransforms(doc *goquery.Document, transforms []htmlTransform) {
+	for _, t := range transforms {
+		for _, selector := range t.Remove {
+			doc.Find(selector).Remove()
+		}
+		for _, selector := range t.Unwrap {
+			doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+				s.ReplaceWithSelection(s.Contents())
+			})
+		}
+		for _, item := range t.Rename {
+			tag := item.Value.(string)
+			doc.Find(item.Key.(string)).Each(func(i int, s *goquery.Selection) {
+				s.Nodes[0].Data = tag
+				s.Nodes[0].DataAtom = atom.Lookup([]byte(tag))
+			})
+		}
+	}
+	mergeTextNodes(doc.Selection.Nodes[0])
+}
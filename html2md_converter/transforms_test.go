@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	html := `<h1>Page</h1><div class="ads"><p>Buy now</p></div>
+<p>Some <a class="plain" href="other.html">plain</a> and <b>bold</b> text.</p>
+<div class="note"><p>Noted.</p></div>`
+	tests := []struct {
+		name, config string
+		want, lacks  []string
+	}{
+		{"remove", "before_convert:\n  - remove: [\".ads\"]\n", nil, []string{"Buy now"}},
+		{"unwrap", "before_convert:\n  - unwrap: [\"a.plain\"]\n", []string{"Some plain and"}, []string{"[plain]"}},
+		{"rename", "before_convert:\n  - rename: {b: em, \"div.note\": aside}\n", []string{"_bold_", "<Note>\n"}, []string{"**bold**"}},
+		// The second step sees the elements the first one renamed
+		{"in order", "before_convert:\n  - rename: {\"div.ads\": section}\n  - remove: [section]\n", nil, []string{"Buy now"}},
+	}
+	for _, tt := range tests {
+		cfg := testConfig(t)
+		var err error
+		if cfg.ruleSets, cfg.transforms, err = loadConfig(writeConfig(t, tt.config)); err != nil {
+			t.Fatal(err)
+		}
+		page := convertPages(t, cfg, map[string]string{"page.html": html})["page.md"]
+		for _, want := range tt.want {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page lacks %q:\n%s", tt.name, want, page)
+			}
+		}
+		for _, lack := range tt.lacks {
+			if strings.Contains(page, lack) {
+				t.Errorf("%s: page has %q:\n%s", tt.name, lack, page)
+			}
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	for _, config := range []string{
+		"before_convert:\n  - remove: [\".ads\"]\n    unwrap: [span]\n",
+		"before_convert:\n  - {}\n",
+		"before_convert:\n  - remove: [\"div[\"]\n",
+		"before_convert:\n  - rename: {b: \"not a tag\"}\n",
+		"before_convert:\n  - drop: [span]\n",
+	} {
+		if _, _, err := loadConfig(writeConfig(t, config)); err == nil {
+			t.Errorf("config %q accepted", config)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// The blockquote and list markers a prose line starts with
+	linePrefixRegex = regexp.MustCompile(`^( {0,3}(?:> ?)*)((?:[-*+]|\d{1,9}[.)]) +)?`)
+	// [label]: url
+	linkDefinitionRegex = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:`)
+	// Words that would start a block if they began a line
+	blockStartRegex = regexp.MustCompile(`^(?:[#>|=+*-]|\d{1,9}[.)]$)`)
+)
+
+// wrapMarkdown breaks prose lines longer than width at spaces. Frontmatter,
+// code blocks, headings, tables, link definitions and lines of HTML or JSX
+// are left alone, and hard line breaks are kept. Continuation lines repeat
+// blockquote markers and are indented under list items.
+func wrapMarkdown(markdown string, width int) string {
+	lines := strings.Split(markdown, "\n")
+	var out []string
+	fence := ""
+	inFrontmatter := strings.HasPrefix(markdown, "---\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inFrontmatter:
+			if i > 0 && line == "---" {
+				inFrontmatter = false
+			}
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case utf8.RuneCountInString(line) > width && isWrappableLine(line, trimmed):
+			out = append(out, wrapLine(line, width)...)
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func isWrappableLine(line, trimmed string) bool {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	switch trimmed[0] {
+	case '#', '|', '<', '{':
+		return false
+	}
+	return !linkDefinitionRegex.MatchString(line)
+}
+
+// wrapLine breaks a single line. Spaces inside inline code and HTML tags
+// are not break points, and neither are those before a word that would
+// start a new block.
+func wrapLine(line string, width int) []string {
+	m := linePrefixRegex.FindStringSubmatch(line)
+	prefix, marker := m[1], m[2]
+	indent := prefix + strings.Repeat(" ", len(marker))
+
+	// A trailing hard break stays on the last line
+	body := line[len(m[0]):]
+	hardBreak := ""
+	if strings.HasSuffix(body, "  ") {
+		hardBreak = "  "
+	}
+
+	var lines []string
+	current := prefix + marker
+	currentLen := utf8.RuneCountInString(current)
+	empty := true
+	for _, word := range splitWords(strings.TrimRight(body, " ")) {
+		wordLen := utf8.RuneCountInString(word)
+		if !empty && currentLen+1+wordLen > width && !blockStartRegex.MatchString(word) {
+			lines = append(lines, current)
+			current, currentLen = indent+word, utf8.RuneCountInString(indent)+wordLen
+			continue
+		}
+		if !empty {
+			current += " "
+			currentLen++
+		}
+		current += word
+		currentLen += wordLen
+		empty = false
+	}
+	return append(lines, current+hardBreak)
+}
+
+// splitWords splits prose at single spaces outside inline code and tags.
+func splitWords(text string) []string {
+	var words []string
+	var b strings.Builder
+	ticks, inTag := 0, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '`':
+			// A code span is closed by a run of as many backticks
+			run := 1
+			for i+run < len(text) && text[i+run] == '`' {
+				run++
+			}
+			if ticks == 0 {
+				ticks = run
+			} else if run == ticks {
+				ticks = 0
+			}
+			b.WriteString(text[i : i+run])
+			i += run - 1
+			continue
+		case ticks == 0 && c == '<' && i+1 < len(text) && (isASCIILetter(text[i+1]) || text[i+1] == '/'):
+			inTag = true
+		case ticks == 0 && c == '>':
+			inTag = false
+		case c == ' ' && ticks == 0 && !inTag:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
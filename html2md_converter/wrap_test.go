@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	long := strings.Repeat("words in a long paragraph ", 8)
+	markdown := long + "\n\n```\n" + long + "\n```\n\n- " + long + "\n\n> " + long + "\n\n| a | " + long + " |\n"
+	got := wrapMarkdown(markdown, 40)
+	paragraph := strings.Split(got[:strings.Index(got, "\n\n")], "\n")
+	if len(paragraph) < 2 {
+		t.Errorf("paragraph was not wrapped:\n%s", got)
+	}
+	for _, line := range paragraph {
+		if len(line) > 40 {
+			t.Errorf("line longer than 40 columns: %q", line)
+		}
+	}
+	if !strings.Contains(got, "```\n"+long+"\n```") {
+		t.Errorf("fenced code was reflowed:\n%s", got)
+	}
+	if !strings.Contains(got, "| a | "+long+" |") {
+		t.Errorf("table was reflowed:\n%s", got)
+	}
+	if !strings.Contains(got, "- words in a long paragraph words in a\n  long") {
+		t.Errorf("list item continuation is not indented:\n%s", got)
+	}
+	if !strings.Contains(got, "\n> words in a long paragraph words in a\n> long") {
+		t.Errorf("blockquote continuation lacks its marker:\n%s", got)
+	}
+}
@@ -53,9 +53,10 @@ docker run --rm \
     echo '==> Initializing Go module...'
     go mod init html-to-md-converter
     go get github.com/JohannesKaufmann/html-to-markdown
+    go mod tidy
     
     echo '==> Building converter...'
-    go build -o html-to-md main.go
+    go build -o html-to-md .
     
     echo '==> Running conversion...'
     ./html-to-md -zip /input/reference-docs.zip -output /output
@@ -79,7 +79,7 @@ docker run --rm -it \
     go mod tidy
 
     echo "==> Building converter…"
-    go build -o html-to-md main.go
+    go build -o html-to-md .
 
     echo "==> Running converter…"
     ./html-to-md -zip "$1" -output "/app/'"$REFERENCE_DIR"'"